@@ -0,0 +1,337 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"goarrg.com/debug"
+	"goarrg.com/rhi/vxr/internal/container"
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+/*
+BindlessHeapSet is the set index reserved for the bindless heap in every PipelineLayout when Config.BindlessHeap is enabled,
+it matches VXR_BINDLESS_SET in <vxr/bindless.glsl>.
+*/
+const BindlessHeapSet = 3
+
+const (
+	bindlessSampledImageBinding = iota
+	bindlessStorageImageBinding
+	bindlessSamplerBinding
+	bindlessStorageBufferBinding
+	numBindlessBindings
+)
+
+// the heap is clamped to these sizes as the descriptor indexing limits are often far larger than anything needs.
+const (
+	maxBindlessSampledImages  = 1 << 16
+	maxBindlessStorageImages  = 1 << 14
+	maxBindlessSamplers       = 1 << 11
+	maxBindlessStorageBuffers = 1 << 16
+)
+
+/*
+the heap is in every pipeline layout so its descriptors count towards the same per stage update after bind limits as the user sets,
+it only takes 1/bindlessHeapLimitShare of them to leave the rest to sets 0-2.
+*/
+const bindlessHeapLimitShare = 2
+
+type bindlessArray struct {
+	len       uint32
+	cap       uint32
+	freeStack container.Stack[uint32]
+	// released is indexed by slot and set from Release until the slot is pushed again, to catch double releases.
+	released []bool
+	// pending holds the released slots until the frame they were released in is done.
+	pending []bindlessRelease
+}
+
+type bindlessRelease struct {
+	frame  uint64
+	handle uint32
+}
+
+func (a *bindlessArray) push() (uint32, bool) {
+	completed := instance.graphics.completedFrame.Load()
+	n := 0
+	for _, r := range a.pending {
+		if r.frame > completed {
+			a.pending[n] = r
+			n++
+			continue
+		}
+		a.freeStack.Push(r.handle)
+	}
+	a.pending = a.pending[:n]
+
+	if !a.freeStack.Empty() {
+		i := a.freeStack.Pop()
+		a.released[i] = false
+		return i, true
+	}
+	if a.len >= a.cap {
+		return 0, false
+	}
+	a.len++
+	a.released = append(a.released, false)
+	return a.len - 1, true
+}
+
+/*
+BindlessHeap is one global descriptor set of large arrays of sampled images, storage images, samplers and storage buffers,
+bound at BindlessHeapSet of every PipelineLayout. Shaders access it through #include <vxr/bindless.glsl>
+and index it with the stable handles returned by the Push functions.
+It is safe for concurrent use, it is the user's responsibility to handle sync and layout changes of the resources.
+*/
+type BindlessHeap struct {
+	mtx              sync.Mutex
+	layout           descriptorSetLayout
+	vkDescriptorPool C.VkDescriptorPool
+	set              descriptorSetAllocation
+	arrays           [numBindlessBindings]bindlessArray
+}
+
+func bindlessDescriptorType(binding int) DescriptorType {
+	switch binding {
+	case bindlessSampledImageBinding:
+		return DescriptorTypeSampledImage
+	case bindlessStorageImageBinding:
+		return DescriptorTypeStorageImage
+	case bindlessSamplerBinding:
+		return DescriptorTypeSampler
+	case bindlessStorageBufferBinding:
+		return DescriptorTypeStorageBuffer
+	default:
+		abort("Unknown bindless binding: %d", binding)
+	}
+	return 0
+}
+
+func bindlessBinding(t DescriptorType) int {
+	switch t {
+	case DescriptorTypeSampledImage:
+		return bindlessSampledImageBinding
+	case DescriptorTypeStorageImage:
+		return bindlessStorageImageBinding
+	case DescriptorTypeSampler:
+		return bindlessSamplerBinding
+	case DescriptorTypeStorageBuffer:
+		return bindlessStorageBufferBinding
+	default:
+		abort("DescriptorType [%s] is not part of the bindless heap", t.String())
+	}
+	return 0
+}
+
+func initBindlessHeap() {
+	if !instance.config.bindlessHeap {
+		return
+	}
+
+	limits := instance.deviceProperties.Limits.DescriptorIndexing
+	resources := limits.MaxResourceCount / bindlessHeapLimitShare
+	counts := [numBindlessBindings]uint32{}
+	counts[bindlessSampledImageBinding] = min(maxBindlessSampledImages, limits.MaxSampledImageCount/bindlessHeapLimitShare, resources/2)
+	resources -= counts[bindlessSampledImageBinding]
+	counts[bindlessStorageImageBinding] = min(maxBindlessStorageImages, limits.MaxStorageImageCount/bindlessHeapLimitShare, resources/2)
+	resources -= counts[bindlessStorageImageBinding]
+	counts[bindlessStorageBufferBinding] = min(maxBindlessStorageBuffers, limits.MaxSBOCount/bindlessHeapLimitShare, resources)
+	// samplers do not count towards MaxResourceCount
+	counts[bindlessSamplerBinding] = min(maxBindlessSamplers, limits.MaxSamplerCount/bindlessHeapLimitShare,
+		instance.deviceProperties.Limits.Global.MaxSamplerAllocationCount)
+
+	h := &instance.bindlessHeap
+	h.layout.bindings = make([]descriptorSetBinding, numBindlessBindings)
+	cBindings := make([]C.VkDescriptorSetLayoutBinding, numBindlessBindings)
+	for i := range h.layout.bindings {
+		if counts[i] == 0 {
+			abort("Device does not support %s in the bindless heap", bindlessDescriptorType(i).String())
+		}
+		h.arrays[i].cap = counts[i]
+		h.layout.bindings[i] = descriptorSetBinding{
+			shaderStage:     vk.SHADER_STAGE_ALL,
+			descriptorType:  C.VkDescriptorType(bindlessDescriptorType(i)),
+			descriptorCount: C.uint32_t(counts[i]),
+		}
+		cBindings[i] = C.VkDescriptorSetLayoutBinding{
+			binding:         C.uint32_t(i),
+			descriptorType:  h.layout.bindings[i].descriptorType,
+			descriptorCount: h.layout.bindings[i].descriptorCount,
+			stageFlags:      h.layout.bindings[i].shaderStage,
+		}
+	}
+	h.layout.key = h.layout.genKey()
+	h.layout.name = fmt.Sprintf("[bindless:%d,%d,%d,%d]", counts[0], counts[1], counts[2], counts[3])
+
+	C.vxr_vk_shader_createBindlessHeap(instance.cInstance, C.size_t(len(h.layout.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(h.layout.name))),
+		C.uint32_t(len(cBindings)), unsafe.SliceData(cBindings), &h.layout.cDescriptorSetLayout, &h.vkDescriptorPool, &h.set.cDescriptorSet)
+	runtime.KeepAlive(cBindings)
+	if instance.descriptorBuffer.enabled() {
+		h.set.descriptorBufferOffset = C.VkDeviceSize(instance.descriptorBuffer.allocate(
+			instance.descriptorBuffer.layoutSize(h.layout.cDescriptorSetLayout)))
+	}
+	instance.logger.VPrintf("Created bindless heap: %s", h.layout.name)
+}
+
+func destroyBindlessHeap() {
+	h := &instance.bindlessHeap
+	if h.layout.cDescriptorSetLayout == nil {
+		return
+	}
+	if instance.descriptorBuffer.enabled() {
+		instance.descriptorBuffer.release(uint64(h.set.descriptorBufferOffset),
+			instance.descriptorBuffer.layoutSize(h.layout.cDescriptorSetLayout))
+	} else {
+		C.vxr_vk_shader_destroyDescriptorPool(instance.cInstance, h.vkDescriptorPool)
+	}
+	C.vxr_vk_shader_destroyDescriptorSetLayout(instance.cInstance, h.layout.cDescriptorSetLayout)
+	instance.bindlessHeap = BindlessHeap{}
+}
+
+// Bindless returns the global bindless heap, it requires Config.BindlessHeap.
+func Bindless() *BindlessHeap {
+	if instance.bindlessHeap.layout.cDescriptorSetLayout == nil {
+		abort("Bindless requires Config.BindlessHeap")
+	}
+	return &instance.bindlessHeap
+}
+
+func (h *BindlessHeap) push(binding int, write C.VkWriteDescriptorSet) uint32 {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+
+	i, ok := h.arrays[binding].push()
+	if !ok {
+		abort("Bindless heap is out of %s slots: %d", bindlessDescriptorType(binding).String(), h.arrays[binding].cap)
+	}
+	write.sType = vk.STRUCTURE_TYPE_WRITE_DESCRIPTOR_SET
+	write.dstBinding = C.uint32_t(binding)
+	write.dstArrayElement = C.uint32_t(i)
+	write.descriptorCount = 1
+	write.descriptorType = C.VkDescriptorType(bindlessDescriptorType(binding))
+	updateDescriptorSet(&h.layout, h.set, write)
+	return i
+}
+
+// PushSampledImage returns the index of info.Image in the sampled image arrays e.g. vxrTexture2D.
+func (h *BindlessHeap) PushSampledImage(info DescriptorImageInfo) uint32 {
+	if !info.Image.usage().HasBits(ImageUsageSampled) {
+		abort("Trying to push an image without ImageUsageSampled into the bindless heap")
+	}
+	cInfo := info.vkDescriptorImageInfo()
+	defer runtime.KeepAlive(&cInfo)
+	return h.push(bindlessSampledImageBinding, C.VkWriteDescriptorSet{pImageInfo: &cInfo})
+}
+
+// PushStorageImage returns the index of info.Image in the storage image arrays declared with VXR_BINDLESS_STORAGE_IMAGE.
+func (h *BindlessHeap) PushStorageImage(info DescriptorImageInfo) uint32 {
+	if !info.Image.usage().HasBits(ImageUsageStorage) {
+		abort("Trying to push an image without ImageUsageStorage into the bindless heap")
+	}
+	cInfo := info.vkDescriptorImageInfo()
+	defer runtime.KeepAlive(&cInfo)
+	return h.push(bindlessStorageImageBinding, C.VkWriteDescriptorSet{pImageInfo: &cInfo})
+}
+
+// PushSampler returns the index of s in vxrSampler.
+func (h *BindlessHeap) PushSampler(s *Sampler) uint32 {
+	s.noCopy.Check()
+	cInfo := C.VkDescriptorImageInfo{sampler: s.cSampler}
+	defer runtime.KeepAlive(&cInfo)
+	return h.push(bindlessSamplerBinding, C.VkWriteDescriptorSet{pImageInfo: &cInfo})
+}
+
+// PushStorageBuffer returns the index of info.Buffer in the storage buffer arrays declared with VXR_BINDLESS_STORAGE_BUFFER.
+func (h *BindlessHeap) PushStorageBuffer(info DescriptorBufferInfo) uint32 {
+	if !info.Buffer.Usage().HasBits(BufferUsageStorageBuffer) {
+		abort("Trying to push a buffer without BufferUsageStorageBuffer into the bindless heap")
+	}
+	cInfo := info.vkDescriptorBufferInfo()
+	defer runtime.KeepAlive(&cInfo)
+	return h.push(bindlessStorageBufferBinding, C.VkWriteDescriptorSet{pBufferInfo: &cInfo})
+}
+
+/*
+Release marks handle of the array for descriptor type t as unused once f has finished execution,
+t must be one of DescriptorTypeSampledImage, DescriptorTypeStorageImage, DescriptorTypeSampler or DescriptorTypeStorageBuffer.
+Releasing a handle twice without it being returned by a Push in between aborts. Unlike f's methods, Release may be
+called from any goroutine while f is active as the handle is kept by the heap rather than queued on f.
+*/
+func (h *BindlessHeap) Release(f *Frame, t DescriptorType, handle uint32) {
+	binding := bindlessBinding(t)
+	h.mtx.Lock()
+	if handle >= h.arrays[binding].len {
+		h.mtx.Unlock()
+		abort("Trying to release %s handle [%d] that was never pushed", t.String(), handle)
+	}
+	if h.arrays[binding].released[handle] {
+		h.mtx.Unlock()
+		abort("Trying to release %s handle [%d] that was already released", t.String(), handle)
+	}
+	h.arrays[binding].released[handle] = true
+	h.arrays[binding].pending = append(h.arrays[binding].pending, bindlessRelease{frame: f.frame.serial, handle: handle})
+	h.mtx.Unlock()
+}
+
+// Len returns the number of slots of the array for descriptor type t that have ever been used, including released ones.
+func (h *BindlessHeap) Len(t DescriptorType) uint32 {
+	binding := bindlessBinding(t)
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	return h.arrays[binding].len
+}
+
+// Cap returns the number of slots of the array for descriptor type t.
+func (h *BindlessHeap) Cap(t DescriptorType) uint32 {
+	return h.arrays[bindlessBinding(t)].cap
+}
+
+/*
+validateBindlessShaderBinding reports whether a shader binding belongs to the bindless heap, in which case
+the heap's binding is used instead. Runtime arrays are only allowed in the heap as it is the only
+set that is sized at runtime.
+*/
+func validateBindlessShaderBinding(set, binding int, descriptorType DescriptorType, descriptorCount ShaderConstant) (bool, error) {
+	used := descriptorType != vk.DESCRIPTOR_TYPE_MAX_ENUM
+	if !instance.config.bindlessHeap || set != BindlessHeapSet {
+		if used && descriptorCount == (ShaderConstant{}) {
+			return false, debug.Errorf("set[%d] binding[%d] is a runtime array, which is only supported by the bindless heap at set [%d] with Config.BindlessHeap",
+				set, binding, BindlessHeapSet)
+		}
+		return false, nil
+	}
+	if !used {
+		return true, nil
+	}
+	if binding >= numBindlessBindings || bindlessDescriptorType(binding) != descriptorType {
+		return true, debug.Errorf("set[%d] binding[%d] of type %s does not match the bindless heap, see <vxr/bindless.glsl>",
+			set, binding, descriptorType.String())
+	}
+	return true, nil
+}
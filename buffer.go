@@ -46,6 +46,8 @@ const (
 	BufferUsageIndexBuffer        BufferUsageFlags = vk.BUFFER_USAGE_INDEX_BUFFER_BIT
 	BufferUsageVertexBuffer       BufferUsageFlags = vk.BUFFER_USAGE_VERTEX_BUFFER_BIT
 	BufferUsageIndirectBuffer     BufferUsageFlags = vk.BUFFER_USAGE_INDIRECT_BUFFER_BIT
+	// BufferUsageConditionalRendering requires the conditionalRendering feature.
+	BufferUsageConditionalRendering BufferUsageFlags = vk.BUFFER_USAGE_CONDITIONAL_RENDERING_BIT_EXT
 )
 
 func (u BufferUsageFlags) HasBits(want BufferUsageFlags) bool {
@@ -81,6 +83,9 @@ func (u BufferUsageFlags) String() string {
 	if u.HasBits(BufferUsageIndirectBuffer) {
 		str += "IndirectBuffer|"
 	}
+	if u.HasBits(BufferUsageConditionalRendering) {
+		str += "ConditionalRendering|"
+	}
 	return strings.TrimSuffix(str, "|")
 }
 
@@ -96,6 +101,7 @@ type HostBuffer struct {
 	bufferSize uint64
 	usageFlags BufferUsageFlags
 	cBuffer    C.vxr_vk_hostBuffer
+	tracker    resourceTracker
 }
 
 var _ interface {
@@ -174,7 +180,10 @@ func (b *HostBuffer) Destroy() {
 		return
 	}
 	b.noCopy.Check()
-	C.vxr_vk_destroyHostBuffer(instance.cInstance, b.cBuffer)
+	cBuffer := b.cBuffer
+	b.tracker.release(func() {
+		C.vxr_vk_destroyHostBuffer(instance.cInstance, cBuffer)
+	})
 	b.noCopy.Close()
 }
 
@@ -183,11 +192,16 @@ func (b *HostBuffer) vkBuffer() C.VkBuffer {
 	return b.cBuffer.vkBuffer
 }
 
+func (b *HostBuffer) resourceTracker() *resourceTracker {
+	return &b.tracker
+}
+
 type DeviceBuffer struct {
 	noCopy     util.NoCopy
 	bufferSize uint64
 	usageFlags BufferUsageFlags
 	cBuffer    C.vxr_vk_deviceBuffer
+	tracker    resourceTracker
 }
 
 var _ interface {
@@ -229,7 +243,10 @@ func (b *DeviceBuffer) Destroy() {
 		return
 	}
 	b.noCopy.Check()
-	C.vxr_vk_destroyDeviceBuffer(instance.cInstance, b.cBuffer)
+	cBuffer := b.cBuffer
+	b.tracker.release(func() {
+		C.vxr_vk_destroyDeviceBuffer(instance.cInstance, cBuffer)
+	})
 	b.noCopy.Close()
 }
 
@@ -237,3 +254,7 @@ func (b *DeviceBuffer) vkBuffer() C.VkBuffer {
 	b.noCopy.Check()
 	return b.cBuffer.vkBuffer
 }
+
+func (b *DeviceBuffer) resourceTracker() *resourceTracker {
+	return &b.tracker
+}
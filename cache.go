@@ -28,6 +28,7 @@ import (
 	"fmt"
 	"runtime"
 	"slices"
+	"sync"
 	"unsafe"
 
 	"goarrg.com/rhi/vxr/internal/container"
@@ -36,7 +37,7 @@ import (
 )
 
 type descriptorSetLayoutCache struct {
-	cache map[string]C.VkDescriptorSetLayout
+	cache map[hashKey]hashBucket[descriptorSetLayoutKey, C.VkDescriptorSetLayout]
 }
 
 func (c *descriptorSetLayoutCache) MarshalJSON() ([]byte, error) {
@@ -44,8 +45,10 @@ func (c *descriptorSetLayoutCache) MarshalJSON() ([]byte, error) {
 	buff.WriteString("{")
 
 	{
-		err := mapRunFuncSorted(c.cache, func(k string, v C.VkDescriptorSetLayout) error {
-			buff.WriteString(fmt.Sprintf("%q: %q,", k, toHex(v)))
+		err := mapRunFuncSorted(c.cache, func(k hashKey, b hashBucket[descriptorSetLayoutKey, C.VkDescriptorSetLayout]) error {
+			for i, e := range b {
+				buff.WriteString(fmt.Sprintf("%q: %q,", bucketKeyString(k, i), toHex(e.value)))
+			}
 			return nil
 		})
 		if err == nil {
@@ -59,18 +62,36 @@ func (c *descriptorSetLayoutCache) MarshalJSON() ([]byte, error) {
 
 func (c *descriptorSetLayoutCache) createOrRetrieveDescriptorSetLayout(descriptorSet *descriptorSetLayout, descriptorBindings []C.VkDescriptorSetLayoutBinding) {
 	var ok bool
-	descriptorSet.cDescriptorSetLayout, ok = c.cache[descriptorSet.id]
+	descriptorSet.cDescriptorSetLayout, ok = c.cache[descriptorSet.key].find(descriptorSet.cacheKey())
 	if !ok {
+		var flags C.VkDescriptorSetLayoutCreateFlags
+		if descriptorSet.push {
+			flags = vk.DESCRIPTOR_SET_LAYOUT_CREATE_PUSH_DESCRIPTOR_BIT_KHR
+		}
 		C.vxr_vk_shader_createDescriptorSetLayout(instance.cInstance, C.size_t(len(descriptorSet.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(descriptorSet.name))),
-			C.uint32_t(len(descriptorBindings)), unsafe.SliceData(descriptorBindings), &descriptorSet.cDescriptorSetLayout,
+			flags, C.uint32_t(len(descriptorBindings)), unsafe.SliceData(descriptorBindings), &descriptorSet.cDescriptorSetLayout,
 		)
 		runtime.KeepAlive(descriptorBindings)
-		c.cache[descriptorSet.id] = descriptorSet.cDescriptorSetLayout
+		key := descriptorSet.cacheKey()
+		key.bindings = slices.Clone(key.bindings)
+		c.cache[descriptorSet.key] = append(c.cache[descriptorSet.key],
+			hashBucketEntry[descriptorSetLayoutKey, C.VkDescriptorSetLayout]{key: key, value: descriptorSet.cDescriptorSetLayout})
 	}
 }
 
+// pipelineLayoutKey is the full key of a PipelineLayout that its key is the hash of,
+// set layouts are compared by handle as equal set layouts are deduplicated by descriptorSetLayoutCache.
+type pipelineLayoutKey struct {
+	pushConstantRange    C.VkPushConstantRange
+	descriptorSetLayouts []C.VkDescriptorSetLayout
+}
+
+func (k pipelineLayoutKey) equal(o pipelineLayoutKey) bool {
+	return k.pushConstantRange == o.pushConstantRange && slices.Equal(k.descriptorSetLayouts, o.descriptorSetLayouts)
+}
+
 type pipelineLayoutCache struct {
-	cache map[string]C.VkPipelineLayout
+	cache map[hashKey]hashBucket[pipelineLayoutKey, C.VkPipelineLayout]
 }
 
 func (c *pipelineLayoutCache) MarshalJSON() ([]byte, error) {
@@ -83,7 +104,9 @@ func (c *pipelineLayoutCache) MarshalJSON() ([]byte, error) {
 		slices.Sort(keys)
 		if len(keys) > 0 {
 			for _, k := range keys {
-				buff.WriteString(fmt.Sprintf("%q: %q,", k, toHex(c.cache[k])))
+				for i, e := range c.cache[k] {
+					buff.WriteString(fmt.Sprintf("%q: %q,", bucketKeyString(k, i), toHex(e.value)))
+				}
 			}
 			buff.Truncate(buff.Len() - 1)
 		}
@@ -95,15 +118,17 @@ func (c *pipelineLayoutCache) MarshalJSON() ([]byte, error) {
 }
 
 func (c *pipelineLayoutCache) createOrRetrievePipelineLayout(layout *PipelineLayout) {
+	descriptorSetLayouts := make([]C.VkDescriptorSetLayout, 0, len(layout.descriptorSetLayouts))
+	for _, set := range layout.descriptorSetLayouts {
+		descriptorSetLayouts = append(descriptorSetLayouts,
+			set.cDescriptorSetLayout,
+		)
+	}
+	key := pipelineLayoutKey{pushConstantRange: layout.pushConstantRange, descriptorSetLayouts: descriptorSetLayouts}
+
 	var ok bool
-	layout.vkPipelinelayout, ok = c.cache[layout.id]
+	layout.vkPipelinelayout, ok = c.cache[layout.key].find(key)
 	if !ok {
-		descriptorSetLayouts := []C.VkDescriptorSetLayout{}
-		for _, set := range layout.descriptorSetLayouts {
-			descriptorSetLayouts = append(descriptorSetLayouts,
-				set.cDescriptorSetLayout,
-			)
-		}
 		cInfo := C.vxr_vk_shader_pipelineLayoutCreateInfo{
 			numDescriptorSetLayouts: C.uint32_t(len(descriptorSetLayouts)),
 			descriptorSetLayouts:    unsafe.SliceData(descriptorSetLayouts),
@@ -116,18 +141,119 @@ func (c *pipelineLayoutCache) createOrRetrievePipelineLayout(layout *PipelineLay
 		}
 		C.vxr_vk_shader_createPipelineLayout(instance.cInstance, C.size_t(len(layout.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(layout.name))),
 			cInfo, &layout.vkPipelinelayout)
-		runtime.KeepAlive(layout.id)
+		runtime.KeepAlive(layout.name)
 		runtime.KeepAlive(descriptorSetLayouts)
-		c.cache[layout.id] = layout.vkPipelinelayout
+		c.cache[layout.key] = append(c.cache[layout.key],
+			hashBucketEntry[pipelineLayoutKey, C.VkPipelineLayout]{key: key, value: layout.vkPipelinelayout})
+	}
+}
+
+/*
+descriptorUpdateTemplate writes every binding of a set from one blob of size bytes,
+each binding with descriptors is an entry packed right after the previous one.
+*/
+type descriptorUpdateTemplate struct {
+	vkDescriptorUpdateTemplate C.VkDescriptorUpdateTemplate
+	entries                    []C.VkDescriptorUpdateTemplateEntry
+	size                       uintptr
+}
+
+type descriptorUpdateTemplateCache struct {
+	mtx   sync.Mutex
+	cache map[hashKey]hashBucket[descriptorSetLayoutKey, descriptorUpdateTemplate]
+}
+
+func (c *descriptorUpdateTemplateCache) MarshalJSON() ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	buff := bytes.Buffer{}
+	buff.WriteString("{")
+
+	{
+		err := mapRunFuncSorted(c.cache, func(k hashKey, b hashBucket[descriptorSetLayoutKey, descriptorUpdateTemplate]) error {
+			for i, e := range b {
+				buff.WriteString(fmt.Sprintf("%q: {\"vkDescriptorUpdateTemplate\": %q, \"entries\": %d, \"size\": %d},",
+					bucketKeyString(k, i), toHex(e.value.vkDescriptorUpdateTemplate), len(e.value.entries), e.value.size))
+			}
+			return nil
+		})
+		if err == nil {
+			buff.Truncate(buff.Len() - 1)
+		}
+	}
+
+	buff.WriteString("}")
+	return buff.Bytes(), nil
+}
+
+func (c *descriptorUpdateTemplateCache) createOrRetrieveDescriptorUpdateTemplate(layout *descriptorSetLayout) descriptorUpdateTemplate {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if t, ok := c.cache[layout.key].find(layout.cacheKey()); ok {
+		return t
+	}
+
+	var t descriptorUpdateTemplate
+	for i, binding := range layout.bindings {
+		if binding.descriptorCount == 0 {
+			continue
+		}
+		var stride uintptr
+		switch DescriptorType(binding.descriptorType) {
+		case DescriptorTypeUniformBuffer, DescriptorTypeStorageBuffer:
+			stride = unsafe.Sizeof(C.VkDescriptorBufferInfo{})
+		case DescriptorTypeSampler, DescriptorTypeCombinedImageSampler, DescriptorTypeSampledImage, DescriptorTypeStorageImage:
+			stride = unsafe.Sizeof(C.VkDescriptorImageInfo{})
+		default:
+			abort("Descriptor update templates do not support %s bindings", DescriptorType(binding.descriptorType).String())
+		}
+		t.entries = append(t.entries, C.VkDescriptorUpdateTemplateEntry{
+			dstBinding:      C.uint32_t(i),
+			descriptorCount: binding.descriptorCount,
+			descriptorType:  binding.descriptorType,
+			offset:          C.size_t(t.size),
+			stride:          C.size_t(stride),
+		})
+		t.size += stride * uintptr(binding.descriptorCount)
 	}
+
+	C.vxr_vk_shader_createDescriptorUpdateTemplate(instance.cInstance, C.size_t(len(layout.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(layout.name))),
+		layout.cDescriptorSetLayout, C.uint32_t(len(t.entries)), unsafe.SliceData(t.entries), &t.vkDescriptorUpdateTemplate)
+	runtime.KeepAlive(layout.name)
+	key := layout.cacheKey()
+	key.bindings = slices.Clone(key.bindings)
+	c.cache[layout.key] = append(c.cache[layout.key], hashBucketEntry[descriptorSetLayoutKey, descriptorUpdateTemplate]{key: key, value: t})
+	return t
 }
 
+func (c *descriptorUpdateTemplateCache) destroy() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	for _, b := range c.cache {
+		for _, e := range b {
+			C.vxr_vk_shader_destroyDescriptorUpdateTemplate(instance.cInstance, e.value.vkDescriptorUpdateTemplate)
+		}
+	}
+	clear(c.cache)
+}
+
+/*
+descriptorPoolBank holds cap sets of one layout, either in vkDescriptorPool or with descriptor buffers
+as a range of cap sets of stride bytes starting at descriptorBufferOffset.
+*/
 type descriptorPoolBank struct {
-	name             string
-	vkDescriptorPool C.VkDescriptorPool
-	len              int32
-	cap              int32
-	freeSets         container.Stack[C.VkDescriptorSet]
+	name                   string
+	vkDescriptorPool       C.VkDescriptorPool
+	descriptorBufferOffset uint64
+	stride                 uint64
+	// len is the number of sets allocated from the bank, live is the number of those that are in use
+	len      int32
+	live     int32
+	cap      int32
+	freeSets container.Stack[descriptorSetAllocation]
 }
 
 func (b *descriptorPoolBank) MarshalJSON() ([]byte, error) {
@@ -135,8 +261,14 @@ func (b *descriptorPoolBank) MarshalJSON() ([]byte, error) {
 	buff.WriteString("{")
 
 	{
-		buff.WriteString(fmt.Sprintf("\"vkDescriptorPool\": %q,", toHex(b.vkDescriptorPool)))
+		if instance.descriptorBuffer.enabled() {
+			buff.WriteString(fmt.Sprintf("\"descriptorBufferOffset\": %d,", b.descriptorBufferOffset))
+			buff.WriteString(fmt.Sprintf("\"stride\": %d,", b.stride))
+		} else {
+			buff.WriteString(fmt.Sprintf("\"vkDescriptorPool\": %q,", toHex(b.vkDescriptorPool)))
+		}
 		buff.WriteString(fmt.Sprintf("\"len\": %d,", b.len))
+		buff.WriteString(fmt.Sprintf("\"live\": %d,", b.live))
 		buff.WriteString(fmt.Sprintf("\"cap\": %d,", b.cap))
 	}
 
@@ -145,7 +277,7 @@ func (b *descriptorPoolBank) MarshalJSON() ([]byte, error) {
 		sets := b.freeSets.Data()
 		if len(sets) > 0 {
 			for _, s := range sets {
-				buff.WriteString(fmt.Sprintf("%q,", toHex(s)))
+				buff.WriteString(fmt.Sprintf("%q,", s.String()))
 			}
 			buff.Truncate(buff.Len() - 1)
 		}
@@ -168,7 +300,10 @@ func (b *descriptorPoolBank) createOrRetrieveDescriptorSet(layout descriptorSetL
 	descriptorSet.noCopy.Init()
 
 	if !b.freeSets.Empty() {
-		descriptorSet.cDescriptorSet = b.freeSets.Pop()
+		descriptorSet.descriptorSetAllocation = b.freeSets.Pop()
+	} else if instance.descriptorBuffer.enabled() {
+		descriptorSet.descriptorBufferOffset = C.VkDeviceSize(b.descriptorBufferOffset + uint64(b.len)*b.stride)
+		b.len++
 	} else {
 		descriptorSetLayout := layout.cDescriptorSetLayout
 		info := C.VkDescriptorSetAllocateInfo{
@@ -182,15 +317,44 @@ func (b *descriptorPoolBank) createOrRetrieveDescriptorSet(layout descriptorSetL
 			info, &descriptorSet.cDescriptorSet)
 		b.len++
 	}
+	b.live++
 	return &descriptorSet
 }
 
 func (b *descriptorPoolBank) releaseDescriptorSet(set *DescriptorSet) {
-	b.freeSets.Push(set.cDescriptorSet)
+	b.live--
+	if b.live == 0 {
+		// every set is free so return them all to the pool at once instead of keeping them on the stack
+		b.reset()
+		return
+	}
+	b.freeSets.Push(set.descriptorSetAllocation)
+}
+
+func (b *descriptorPoolBank) reset() {
+	// the range of a descriptor buffer bank stays with it, sets are handed out from its start again
+	if !instance.descriptorBuffer.enabled() {
+		C.vxr_vk_shader_resetDescriptorPool(instance.cInstance, b.vkDescriptorPool)
+	}
+	b.len = 0
+	b.freeSets = container.Stack[descriptorSetAllocation]{}
+}
+
+func (b *descriptorPoolBank) destroy() {
+	if instance.descriptorBuffer.enabled() {
+		instance.descriptorBuffer.release(b.descriptorBufferOffset, b.stride*uint64(b.cap))
+		return
+	}
+	C.vxr_vk_shader_destroyDescriptorPool(instance.cInstance, b.vkDescriptorPool)
 }
 
+// maxDescriptorPoolBankDescriptors limits how large adaptive bank sizes can grow for layouts with many descriptors per set.
+const maxDescriptorPoolBankDescriptors = 1 << 16
+
 type descriptorPool struct {
-	banks []*descriptorPoolBank
+	name       string
+	banks      []*descriptorPoolBank
+	numCreated int
 }
 
 func (p *descriptorPool) MarshalJSON() ([]byte, error) {
@@ -209,6 +373,40 @@ func (p *descriptorPool) MarshalJSON() ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
+func (p *descriptorPool) stats() DescriptorPoolStats {
+	s := DescriptorPoolStats{Layout: p.name, Banks: len(p.banks)}
+	for _, b := range p.banks {
+		s.Capacity += int(b.cap)
+		s.LiveSets += int(b.live)
+		s.FreeSets += int(b.len - b.live)
+	}
+	return s
+}
+
+/*
+nextBankSize returns the number of sets of the next bank, banks start at the configured size and
+grow to match the number of live sets so the number of banks grows logarithmically with demand.
+*/
+func (p *descriptorPool) nextBankSize(layout descriptorSetLayout) int32 {
+	size := instance.config.descriptorPoolBankSize
+	live := int32(0)
+	for _, b := range p.banks {
+		live += b.live
+	}
+	if live <= size {
+		return size
+	}
+
+	descriptorsPerSet := int32(0)
+	for _, b := range layout.bindings {
+		descriptorsPerSet += int32(b.descriptorCount)
+	}
+	if descriptorsPerSet > 0 {
+		live = min(live, max(size, maxDescriptorPoolBankDescriptors/descriptorsPerSet))
+	}
+	return live
+}
+
 func (p *descriptorPool) createOrRetrieveBank(layout descriptorSetLayout) *descriptorPoolBank {
 	for _, b := range p.banks {
 		if b.canAllocate() {
@@ -216,21 +414,30 @@ func (p *descriptorPool) createOrRetrieveBank(layout descriptorSetLayout) *descr
 		}
 	}
 
-	bank := &descriptorPoolBank{name: fmt.Sprintf("bank_%d", len(p.banks)), cap: instance.config.descriptorPoolBankSize}
+	size := p.nextBankSize(layout)
+	bank := &descriptorPoolBank{name: fmt.Sprintf("bank_%d", p.numCreated), cap: size}
+	p.numCreated++
+	if instance.descriptorBuffer.enabled() {
+		bank.stride = instance.descriptorBuffer.layoutSize(layout.cDescriptorSetLayout)
+		bank.descriptorBufferOffset = instance.descriptorBuffer.allocate(bank.stride * uint64(size))
+		p.banks = append(p.banks, bank)
+		return bank
+	}
 	poolSizes := make([]C.VkDescriptorPoolSize, 0, len(layout.bindings))
 	for _, b := range layout.bindings {
 		if b.descriptorCount > 0 {
 			poolSizes = append(poolSizes, C.VkDescriptorPoolSize{
 				_type:           b.descriptorType,
-				descriptorCount: b.descriptorCount * C.uint32_t(instance.config.descriptorPoolBankSize),
+				descriptorCount: b.descriptorCount * C.uint32_t(size),
 			})
 		}
 	}
 	info := C.VkDescriptorPoolCreateInfo{
 		sType: vk.STRUCTURE_TYPE_DESCRIPTOR_POOL_CREATE_INFO,
-		// flags: vk.DESCRIPTOR_POOL_CREATE_FREE_DESCRIPTOR_SET_BIT,
+		// sets are recycled through freeSets and banks are reset as a whole once empty,
+		// so vk.DESCRIPTOR_POOL_CREATE_FREE_DESCRIPTOR_SET_BIT is not needed.
 
-		maxSets:       C.uint32_t(instance.config.descriptorPoolBankSize),
+		maxSets:       C.uint32_t(size),
 		poolSizeCount: C.uint32_t(len(poolSizes)),
 		pPoolSizes:    unsafe.SliceData(poolSizes),
 	}
@@ -247,14 +454,31 @@ func (p *descriptorPool) createOrRetrieveDescriptorSet(layout descriptorSetLayou
 	return b.createOrRetrieveDescriptorSet(layout)
 }
 
+// trim destroys every bank without live sets and returns the number of banks left.
+func (p *descriptorPool) trim() int {
+	n := 0
+	for _, b := range p.banks {
+		if b.live > 0 {
+			p.banks[n] = b
+			n++
+			continue
+		}
+		b.destroy()
+	}
+	clear(p.banks[n:])
+	p.banks = p.banks[:n]
+	return n
+}
+
 func (p *descriptorPool) destroy() {
 	for _, b := range p.banks {
-		C.vxr_vk_shader_destroyDescriptorPool(instance.cInstance, b.vkDescriptorPool)
+		b.destroy()
 	}
 }
 
 type descriptorSetCache struct {
-	descriptorPools map[string]*descriptorPool
+	mtx             sync.Mutex
+	descriptorPools map[hashKey]hashBucket[descriptorSetLayoutKey, *descriptorPool]
 }
 
 func (a *descriptorSetCache) MarshalJSON() ([]byte, error) {
@@ -264,8 +488,10 @@ func (a *descriptorSetCache) MarshalJSON() ([]byte, error) {
 	{
 		buff.WriteString("\"descriptorPools\": {")
 		{
-			err := mapRunFuncSorted(a.descriptorPools, func(k string, v *descriptorPool) error {
-				buff.WriteString(fmt.Sprintf("%q: %s,", k, jsonString(v)))
+			err := mapRunFuncSorted(a.descriptorPools, func(k hashKey, b hashBucket[descriptorSetLayoutKey, *descriptorPool]) error {
+				for i, e := range b {
+					buff.WriteString(fmt.Sprintf("%q: %s,", bucketKeyString(k, i), jsonString(e.value)))
+				}
 				return nil
 			})
 			if err == nil {
@@ -280,10 +506,78 @@ func (a *descriptorSetCache) MarshalJSON() ([]byte, error) {
 }
 
 func (a *descriptorSetCache) createOrRetrieveDescriptorSet(layout descriptorSetLayout) *DescriptorSet {
-	pool, ok := a.descriptorPools[layout.id]
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	pool, ok := a.descriptorPools[layout.key].find(layout.cacheKey())
 	if !ok {
-		pool = &descriptorPool{}
-		a.descriptorPools[layout.id] = pool
+		pool = &descriptorPool{name: layout.name}
+		key := layout.cacheKey()
+		key.bindings = slices.Clone(key.bindings)
+		a.descriptorPools[layout.key] = append(a.descriptorPools[layout.key], hashBucketEntry[descriptorSetLayoutKey, *descriptorPool]{key: key, value: pool})
 	}
 	return pool.createOrRetrieveDescriptorSet(layout)
 }
+
+func (a *descriptorSetCache) releaseDescriptorSet(set *DescriptorSet) {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	set.bank.releaseDescriptorSet(set)
+}
+
+func (a *descriptorSetCache) trim() {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	for k, b := range a.descriptorPools {
+		b = slices.DeleteFunc(b, func(e hashBucketEntry[descriptorSetLayoutKey, *descriptorPool]) bool {
+			return e.value.trim() == 0
+		})
+		if len(b) == 0 {
+			delete(a.descriptorPools, k)
+		} else {
+			a.descriptorPools[k] = b
+		}
+	}
+}
+
+func (a *descriptorSetCache) stats() []DescriptorPoolStats {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	stats := make([]DescriptorPoolStats, 0, len(a.descriptorPools))
+	_ = mapRunFuncSorted(a.descriptorPools, func(_ hashKey, b hashBucket[descriptorSetLayoutKey, *descriptorPool]) error {
+		for _, e := range b {
+			stats = append(stats, e.value.stats())
+		}
+		return nil
+	})
+	return stats
+}
+
+// DescriptorPoolStats describes the descriptor pool of a descriptor set layout.
+type DescriptorPoolStats struct {
+	// Layout is the name of the descriptor set layout.
+	Layout string
+	Banks  int
+	// Capacity is the number of sets all banks can hold.
+	Capacity int
+	// LiveSets is the number of sets that have not been destroyed.
+	LiveSets int
+	// FreeSets is the number of sets that have been destroyed and are waiting to be reused.
+	FreeSets int
+}
+
+// DescriptorPoolStatistics returns the stats of the descriptor pool of every descriptor set layout that has allocated sets.
+func DescriptorPoolStatistics() []DescriptorPoolStats {
+	return instance.descriptorSetCache.stats()
+}
+
+/*
+TrimDescriptorPools destroys every descriptor pool bank that has no live sets,
+freeing the memory of layouts that are no longer used.
+*/
+func TrimDescriptorPools() {
+	instance.descriptorSetCache.trim()
+}
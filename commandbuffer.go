@@ -35,16 +35,81 @@ import (
 type commandBuffer struct {
 	noCopy          util.NoCopy
 	vkCommandBuffer C.VkCommandBuffer
+	// queue is the queue the command buffer was created for, commands it does not support abort.
+	queue Queue
+	// frameSerial is the serial of the frame the command buffer belongs to, it is 0 for a CommandBuffer.
+	frameSerial uint64
+	// renderPassViews is the number of views of the active render pass or 0 outside of one.
+	renderPassViews uint32
+
+	namedRegions    int
+	profiler        *ProfilerFrame
+	profilerRegions []int
+
+	descriptorBufferBound bool
+
+	// resources is every tracked resource recorded since the command buffer began, they are stamped on submission.
+	resources map[*resourceTracker]struct{}
+	hazards   hazardTracker
+}
+
+// bindDescriptorBuffer binds the descriptor buffer before the first command of the command buffer that binds sets from it.
+func (cb *commandBuffer) bindDescriptorBuffer() {
+	if cb.descriptorBufferBound || !instance.descriptorBuffer.enabled() {
+		return
+	}
+	C.vxr_vk_descriptorBuffer_bind(instance.cInstance, cb.vkCommandBuffer)
+	cb.descriptorBufferBound = true
+}
+
+/*
+AttachProfilerFrame makes every named region recorded after this call write timestamps into f,
+it must be called outside of any named region.
+*/
+func (cb *commandBuffer) AttachProfilerFrame(f *ProfilerFrame) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	f.noCopy.Check()
+	if cb.namedRegions != 0 {
+		abort("AttachProfilerFrame called inside a named region")
+	}
+	cb.profiler = f
 }
 
 func (cb *commandBuffer) BeginNamedRegion(name string) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	C.vxr_vk_commandBuffer_beginNamedRegion(instance.cInstance, cb.vkCommandBuffer, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))))
 	runtime.KeepAlive(name)
+	cb.namedRegions++
+	cb.hazards.beginRegion(name)
+	if cb.profiler != nil {
+		if cb.renderPassViews > 1 {
+			abort("Named region [%s] cannot be profiled inside a multiview render pass", name)
+		}
+		parent := -1
+		if len(cb.profilerRegions) > 0 {
+			parent = cb.profilerRegions[len(cb.profilerRegions)-1]
+		}
+		cb.profilerRegions = append(cb.profilerRegions, cb.profiler.beginRegion(cb, name, parent))
+	}
 }
 
 func (cb *commandBuffer) EndNamedRegion() {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.namedRegions == 0 {
+		abort("EndNamedRegion called without a matching BeginNamedRegion")
+	}
+	if cb.profiler != nil {
+		if cb.renderPassViews > 1 {
+			abort("Named regions cannot be profiled inside a multiview render pass")
+		}
+		cb.profiler.endRegion(cb, cb.profilerRegions[len(cb.profilerRegions)-1])
+		cb.profilerRegions = cb.profilerRegions[:len(cb.profilerRegions)-1]
+	}
+	cb.namedRegions--
+	cb.hazards.endRegion()
 	C.vxr_vk_commandBuffer_endNamedRegion(instance.cInstance, cb.vkCommandBuffer)
 }
 
@@ -100,10 +165,12 @@ type ImageBarrier struct {
 }
 
 func (cb *commandBuffer) CompoundBarrier(memoryBarriers []MemoryBarrier, bufferBarriers []BufferBarrier, imageBarriers []ImageBarrier) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 
 	memoryBarrierInfos := make([]C.VkMemoryBarrier2, 0, len(memoryBarriers))
 	for _, barrier := range memoryBarriers {
+		cb.hazards.barrier(nil, barrier.Src.Stage, barrier.Src.Access, barrier.Dst.Stage)
 		memoryBarrierInfos = append(memoryBarrierInfos,
 			C.VkMemoryBarrier2{
 				sType:         vk.STRUCTURE_TYPE_MEMORY_BARRIER_2,
@@ -129,6 +196,8 @@ func (cb *commandBuffer) CompoundBarrier(memoryBarriers []MemoryBarrier, bufferB
 				size:          vk.WHOLE_SIZE,
 			},
 		)
+		cb.track(barrier.Buffer)
+		cb.hazards.barrier(barrier.Buffer, barrier.Src.Stage, barrier.Src.Access, barrier.Dst.Stage)
 	}
 
 	imageBarrierInfos := make([]C.VkImageMemoryBarrier2, 0, len(imageBarriers))
@@ -136,6 +205,10 @@ func (cb *commandBuffer) CompoundBarrier(memoryBarriers []MemoryBarrier, bufferB
 		if barrier.Aspect == 0 {
 			barrier.Aspect = barrier.Image.Aspect()
 		}
+		oldLayout, newLayout := C.VkImageLayout(barrier.Src.Layout), C.VkImageLayout(barrier.Dst.Layout)
+		if s, ok := barrier.Image.(*Surface); ok {
+			oldLayout, newLayout = s.vkImageLayout(barrier.Src.Layout), s.vkImageLayout(barrier.Dst.Layout)
+		}
 		imageBarrierInfos = append(imageBarrierInfos,
 			C.VkImageMemoryBarrier2{
 				sType:         vk.STRUCTURE_TYPE_IMAGE_MEMORY_BARRIER_2,
@@ -143,8 +216,8 @@ func (cb *commandBuffer) CompoundBarrier(memoryBarriers []MemoryBarrier, bufferB
 				srcAccessMask: C.VkAccessFlags2(barrier.Src.Access),
 				dstStageMask:  C.VkPipelineStageFlags2(barrier.Dst.Stage),
 				dstAccessMask: C.VkAccessFlags2(barrier.Dst.Access),
-				oldLayout:     C.VkImageLayout(barrier.Src.Layout),
-				newLayout:     C.VkImageLayout(barrier.Dst.Layout),
+				oldLayout:     oldLayout,
+				newLayout:     newLayout,
 				image:         barrier.Image.vkImage(),
 				subresourceRange: C.VkImageSubresourceRange{
 					aspectMask:   C.VkImageAspectFlags(barrier.Aspect),
@@ -153,6 +226,8 @@ func (cb *commandBuffer) CompoundBarrier(memoryBarriers []MemoryBarrier, bufferB
 				},
 			},
 		)
+		cb.track(barrier.Image)
+		cb.hazards.barrier(barrier.Image, barrier.Src.Stage, barrier.Src.Access, barrier.Dst.Stage)
 	}
 
 	C.vxr_vk_commandBuffer_barrier(instance.cInstance, cb.vkCommandBuffer,
@@ -184,20 +259,28 @@ func (cb *commandBuffer) ImageBarrier(barriers ...ImageBarrier) {
 }
 
 func (cb *commandBuffer) FillBuffer(buffer Buffer, offset, size uint64, value uint32) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	C.vxr_vk_commandBuffer_fillBuffer(instance.cInstance, cb.vkCommandBuffer, buffer.vkBuffer(), C.VkDeviceSize(offset), C.VkDeviceSize(size), C.uint32_t(value))
+	cb.track(buffer)
+	cb.hazards.write(buffer, PipelineStageTransfer)
 }
 
 func (cb *commandBuffer) UpdateBuffer(buffer Buffer, offset uint64, data []byte) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if len(data) > 65536 {
 		abort("UpdateBuffer is limited to 65536 bytes")
 	}
 	C.vxr_vk_commandBuffer_updateBuffer(instance.cInstance, cb.vkCommandBuffer, buffer.vkBuffer(), C.VkDeviceSize(offset),
 		C.VkDeviceSize(len(data)), unsafe.Pointer(unsafe.SliceData(data)))
+	cb.track(buffer)
+	cb.hazards.write(buffer, PipelineStageTransfer)
 }
 
 func (cb *commandBuffer) ClearColorImage(img ColorImage, layout ImageLayout, value ColorImageClearValue, imgRange ImageSubresourceRange) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	cRange := C.VkImageSubresourceRange{
 		aspectMask:   C.VkImageAspectFlags(img.Aspect()),
 		baseMipLevel: C.uint32_t(imgRange.BaseMipLevel), levelCount: C.uint32_t(imgRange.NumMipLevels),
@@ -206,6 +289,8 @@ func (cb *commandBuffer) ClearColorImage(img ColorImage, layout ImageLayout, val
 
 	C.vxr_vk_commandBuffer_clearColorImage(instance.cInstance, cb.vkCommandBuffer, img.vkImage(), C.VkImageLayout(layout), value.vkClearValue(),
 		1, &cRange)
+	cb.track(img)
+	cb.hazards.write(img, PipelineStageTransfer)
 }
 
 type BufferCopyRegion struct {
@@ -215,7 +300,8 @@ type BufferCopyRegion struct {
 }
 
 func (cb *commandBuffer) CopyBuffer(bIn, bOut Buffer, regions []BufferCopyRegion) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 
 	cRegions := make([]C.VkBufferCopy, len(regions))
 	for i, r := range regions {
@@ -228,6 +314,10 @@ func (cb *commandBuffer) CopyBuffer(bIn, bOut Buffer, regions []BufferCopyRegion
 	C.vxr_vk_commandBuffer_copyBuffer(instance.cInstance, cb.vkCommandBuffer, bIn.vkBuffer(), bOut.vkBuffer(),
 		C.uint32_t(len(cRegions)), unsafe.SliceData(cRegions))
 	runtime.KeepAlive(cRegions)
+	cb.track(bIn)
+	cb.track(bOut)
+	cb.hazards.read(bIn, PipelineStageTransfer)
+	cb.hazards.write(bOut, PipelineStageTransfer)
 }
 
 type ImageBufferCopyable interface {
@@ -250,7 +340,8 @@ type BufferImageCopyRegion struct {
 }
 
 func (cb *commandBuffer) CopyBufferToImageAspect(buffer Buffer, image ImageBufferCopyable, layout ImageLayout, aspect ImageAspectFlags, regions []BufferImageCopyRegion) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 
 	if !image.Aspect().HasBits(aspect) {
 		abort("Calling CopyBufferToImageAspect with image that has not have aspect [%s] image has [%s]", aspect, image.Aspect().String())
@@ -281,8 +372,55 @@ func (cb *commandBuffer) CopyBufferToImageAspect(buffer Buffer, image ImageBuffe
 	C.vxr_vk_commandBuffer_copyBufferToImage(instance.cInstance, cb.vkCommandBuffer, buffer.vkBuffer(), image.vkImage(), C.VkImageLayout(layout),
 		C.uint32_t(len(cRegions)), unsafe.SliceData(cRegions))
 	runtime.KeepAlive(cRegions)
+	cb.track(buffer)
+	cb.track(image)
+	cb.hazards.read(buffer, PipelineStageTransfer)
+	cb.hazards.write(image, PipelineStageTransfer)
 }
 
 func (cb *commandBuffer) CopyBufferToImage(buffer Buffer, image ImageBufferCopyable, layout ImageLayout, regions []BufferImageCopyRegion) {
 	cb.CopyBufferToImageAspect(buffer, image, layout, image.Aspect(), regions)
 }
+
+func (cb *commandBuffer) CopyImageToBufferAspect(image ImageBufferCopyable, layout ImageLayout, aspect ImageAspectFlags, buffer Buffer, regions []BufferImageCopyRegion) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+
+	if !image.Aspect().HasBits(aspect) {
+		abort("Calling CopyImageToBufferAspect with image that has not have aspect [%s] image has [%s]", aspect, image.Aspect().String())
+	}
+
+	cRegions := make([]C.VkBufferImageCopy, len(regions))
+	for i, r := range regions {
+		cRegions[i] = C.VkBufferImageCopy{
+			bufferOffset: C.VkDeviceSize(r.BufferOffset),
+			imageSubresource: C.VkImageSubresourceLayers{
+				aspectMask:     C.VkImageAspectFlags(aspect),
+				mipLevel:       C.uint32_t(r.ImageSubresource.MipLevel),
+				baseArrayLayer: C.uint32_t(r.ImageSubresource.BaseArrayLayer),
+				layerCount:     C.uint32_t(r.ImageSubresource.NumArrayLayers),
+			},
+			imageOffset: C.VkOffset3D{
+				x: C.int32_t(r.ImageOffset.X),
+				y: C.int32_t(r.ImageOffset.Y),
+				z: C.int32_t(r.ImageOffset.Z),
+			},
+			imageExtent: C.VkExtent3D{
+				width:  C.uint32_t(r.ImageExtent.X),
+				height: C.uint32_t(r.ImageExtent.Y),
+				depth:  C.uint32_t(r.ImageExtent.Z),
+			},
+		}
+	}
+	C.vxr_vk_commandBuffer_copyImageToBuffer(instance.cInstance, cb.vkCommandBuffer, image.vkImage(), C.VkImageLayout(layout), buffer.vkBuffer(),
+		C.uint32_t(len(cRegions)), unsafe.SliceData(cRegions))
+	runtime.KeepAlive(cRegions)
+	cb.track(image)
+	cb.track(buffer)
+	cb.hazards.read(image, PipelineStageTransfer)
+	cb.hazards.write(buffer, PipelineStageTransfer)
+}
+
+func (cb *commandBuffer) CopyImageToBuffer(image ImageBufferCopyable, layout ImageLayout, buffer Buffer, regions []BufferImageCopyRegion) {
+	cb.CopyImageToBufferAspect(image, layout, image.Aspect(), buffer, regions)
+}
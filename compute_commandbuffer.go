@@ -37,28 +37,37 @@ type ComputeCommandBuffer struct {
 type DispatchInfo struct {
 	PushConstants  []byte
 	DescriptorSets []*DescriptorSet
-	ThreadCount    gmath.Extent3u32
+	// PushDescriptors are indexed by binding of the layout's push descriptor set.
+	PushDescriptors [][]DescriptorInfo
+	ThreadCount     gmath.Extent3u32
 }
 
 func (cb *ComputeCommandBuffer) Dispatch(p *ComputePipeline, info DispatchInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.queue == QueueTransfer {
+		abort("Dispatch called on a command buffer for %s", cb.queue)
+	}
 
-	if err := p.layout.cmdValidate(info.PushConstants, info.DescriptorSets); err != nil {
+	if err := p.layout.cmdValidate(info.PushConstants, info.DescriptorSets, info.PushDescriptors); err != nil {
 		abort("Failed to validate DispatchInfo: %s", err)
 	}
 
-	descriptorSets := make([]C.VkDescriptorSet, 0, len(info.DescriptorSets))
-	for _, s := range info.DescriptorSets {
-		s.noCopy.Check()
-		descriptorSets = append(descriptorSets, s.cDescriptorSet)
-	}
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+	descriptorSets, descriptorBufferOffsets := p.layout.vkDescriptorSets(info.DescriptorSets)
+	cb.bindDescriptorBuffer()
+	cb.trackBindings(info.DescriptorSets, info.PushDescriptors)
+	cb.hazards.bindings(p.layout, info.DescriptorSets, info.PushDescriptors)
 
 	cInfo := C.vxr_vk_compute_dispatchInfo{
 		layout:   p.layout.vkPipelinelayout,
 		pipeline: p.vkPipeline,
 
-		numDescriptorSets: C.uint32_t(len(descriptorSets)),
-		descriptorSets:    unsafe.SliceData(descriptorSets),
+		numDescriptorSets:       C.uint32_t(len(descriptorSets)),
+		descriptorSets:          unsafe.SliceData(descriptorSets),
+		descriptorBufferOffsets: unsafe.SliceData(descriptorBufferOffsets),
+		pushDescriptors:         p.layout.vkPushDescriptorSet(&pinner, info.PushDescriptors),
 
 		groupCount: C.VkExtent3D{
 			width:  C.uint32_t((info.ThreadCount.X + p.localSize.X - 1) / p.localSize.X),
@@ -82,19 +91,26 @@ func (cb *ComputeCommandBuffer) Dispatch(p *ComputePipeline, info DispatchInfo)
 	C.vxr_vk_compute_dispatch(instance.cInstance, cb.vkCommandBuffer, cInfo)
 	runtime.KeepAlive(info.PushConstants)
 	runtime.KeepAlive(descriptorSets)
+	runtime.KeepAlive(descriptorBufferOffsets)
 }
 
 type DispatchIndirectInfo struct {
 	PushConstants  []byte
 	DescriptorSets []*DescriptorSet
-	Buffer         Buffer
-	Offset         uint64
+	// PushDescriptors are indexed by binding of the layout's push descriptor set.
+	PushDescriptors [][]DescriptorInfo
+	Buffer          Buffer
+	Offset          uint64
 }
 
 func (cb *ComputeCommandBuffer) DispatchIndirect(p *ComputePipeline, info DispatchIndirectInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.queue == QueueTransfer {
+		abort("DispatchIndirect called on a command buffer for %s", cb.queue)
+	}
 
-	if err := p.layout.cmdValidate(info.PushConstants, info.DescriptorSets); err != nil {
+	if err := p.layout.cmdValidate(info.PushConstants, info.DescriptorSets, info.PushDescriptors); err != nil {
 		abort("Failed to validate DispatchIndirectInfo: %s", err)
 	}
 	if (info.Offset % 4) != 0 {
@@ -105,18 +121,21 @@ func (cb *ComputeCommandBuffer) DispatchIndirect(p *ComputePipeline, info Dispat
 			info.Offset, unsafe.Sizeof(C.VkDispatchIndirectCommand{}), info.Buffer.Size())
 	}
 
-	descriptorSets := make([]C.VkDescriptorSet, 0, len(info.DescriptorSets))
-	for _, s := range info.DescriptorSets {
-		s.noCopy.Check()
-		descriptorSets = append(descriptorSets, s.cDescriptorSet)
-	}
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+	descriptorSets, descriptorBufferOffsets := p.layout.vkDescriptorSets(info.DescriptorSets)
+	cb.bindDescriptorBuffer()
+	cb.trackBindings(info.DescriptorSets, info.PushDescriptors)
+	cb.hazards.bindings(p.layout, info.DescriptorSets, info.PushDescriptors)
 
 	cInfo := C.vxr_vk_compute_dispatchIndirectInfo{
 		layout:   p.layout.vkPipelinelayout,
 		pipeline: p.vkPipeline,
 
-		numDescriptorSets: C.uint32_t(len(descriptorSets)),
-		descriptorSets:    unsafe.SliceData(descriptorSets),
+		numDescriptorSets:       C.uint32_t(len(descriptorSets)),
+		descriptorSets:          unsafe.SliceData(descriptorSets),
+		descriptorBufferOffsets: unsafe.SliceData(descriptorBufferOffsets),
+		pushDescriptors:         p.layout.vkPushDescriptorSet(&pinner, info.PushDescriptors),
 
 		buffer: info.Buffer.vkBuffer(),
 		offset: C.VkDeviceSize(info.Offset),
@@ -128,6 +147,9 @@ func (cb *ComputeCommandBuffer) DispatchIndirect(p *ComputePipeline, info Dispat
 	}
 
 	C.vxr_vk_compute_dispatchIndirect(instance.cInstance, cb.vkCommandBuffer, cInfo)
+	cb.track(info.Buffer)
+	cb.hazards.read(info.Buffer, PipelineStageIndirect)
 	runtime.KeepAlive(info.PushConstants)
 	runtime.KeepAlive(descriptorSets)
+	runtime.KeepAlive(descriptorBufferOffsets)
 }
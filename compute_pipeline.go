@@ -28,6 +28,7 @@ import (
 	"runtime"
 	"unsafe"
 
+	"goarrg.com/debug"
 	"goarrg.com/gmath"
 	"goarrg.com/rhi/vxr/internal/util"
 	"goarrg.com/rhi/vxr/internal/vk"
@@ -42,7 +43,6 @@ const (
 
 type ComputePipeline struct {
 	noCopy     util.NoCopy
-	id         string
 	name       string
 	layout     *PipelineLayout
 	vkPipeline C.VkPipeline
@@ -52,11 +52,44 @@ type ComputePipeline struct {
 type ComputePipelineCreateInfo struct {
 	StageFlags           ComputeStageFlags
 	RequiredSubgroupSize uint32
-	SpecConstants        []uint32
+	// SpecConstants nil inherits the PipelineLayoutCreateInfo.SpecConstants of the compute stage,
+	// see ShaderMetadata.SpecConstantValues to create them by name.
+	SpecConstants []uint32
 }
 
 func NewComputePipeline(pipelineLayout *PipelineLayout, s *Shader, entryPoint ShaderEntryPointLayout, info ComputePipelineCreateInfo) *ComputePipeline {
-	computeEntryPoint := entryPoint.(ShaderEntryPointComputeLayout)
+	specConstants, err := pipelineLayout.resolveSpecConstants(ShaderStageCompute, info.SpecConstants)
+	if err != nil {
+		abort("Failed to validate ComputePipelineCreateInfo.SpecConstants: %s", err)
+	}
+	info.SpecConstants = specConstants
+	localSize, err := computeLocalSize(entryPoint, info)
+	if err != nil {
+		abort("%s", err)
+	}
+
+	pipeline := &ComputePipeline{
+		name:      fmt.Sprintf("[%q,%s,%s]", s.ID, entryPoint.EntryPointName(), jsonString(info.SpecConstants)),
+		layout:    pipelineLayout,
+		localSize: localSize,
+	}
+	pipeline.noCopy.Init()
+	pipeline.vkPipeline = createComputePipeline(pipeline.name, pipelineLayout, s, entryPoint, info)
+
+	return pipeline
+}
+
+func computeLocalSize(entryPoint ShaderEntryPointLayout, info ComputePipelineCreateInfo) (gmath.Extent3u32, error) {
+	computeEntryPoint, ok := entryPoint.(ShaderEntryPointComputeLayout)
+	if !ok {
+		return gmath.Extent3u32{}, debug.Errorf("Entry point %q is not a compute shader entry point", entryPoint.EntryPointName())
+	}
+	for _, c := range computeEntryPoint.LocalSize {
+		if c.IsSpecConstant && int(c.Value) >= len(info.SpecConstants) {
+			return gmath.Extent3u32{}, debug.Errorf("Shader's local size is spec constant [%d] but only %d spec constants were given",
+				c.Value, len(info.SpecConstants))
+		}
+	}
 	localSize := gmath.Extent3u32{X: computeEntryPoint.LocalSize[0].Value, Y: computeEntryPoint.LocalSize[1].Value, Z: computeEntryPoint.LocalSize[2].Value}
 	if computeEntryPoint.LocalSize[0].IsSpecConstant {
 		localSize.X = info.SpecConstants[localSize.X]
@@ -69,31 +102,27 @@ func NewComputePipeline(pipelineLayout *PipelineLayout, s *Shader, entryPoint Sh
 	}
 	if !localSize.InRange(gmath.Extent3u32{}, instance.deviceProperties.Limits.Compute.MaxLocalSize) {
 		limit := instance.deviceProperties.Limits.Compute.MaxLocalSize
-		abort("Shader's local sizes [%d,%d,%d] is greater than Properties.Limits.Compute.MaxLocalSize [%d,%d,%d]",
+		return localSize, debug.Errorf("Shader's local sizes [%d,%d,%d] is greater than Properties.Limits.Compute.MaxLocalSize [%d,%d,%d]",
 			localSize.X, localSize.Y, localSize.Z, limit.X, limit.Y, limit.Z)
 	}
 	if info.RequiredSubgroupSize > 0 {
 		if !instance.deviceProperties.Limits.Compute.SubgroupSize.CheckValue(info.RequiredSubgroupSize) {
-			abort("ComputePipelineCreateInfo.RequiredSubgroupSize [%d] is not within Properties.Limits.Compute.SubgroupSize [%+v] ",
+			return localSize, debug.Errorf("ComputePipelineCreateInfo.RequiredSubgroupSize [%d] is not within Properties.Limits.Compute.SubgroupSize [%+v] ",
 				info.RequiredSubgroupSize, instance.deviceProperties.Limits.Compute.SubgroupSize)
 		}
 		if maxWorkgroupThreads := info.RequiredSubgroupSize * instance.deviceProperties.Limits.Compute.Workgroup.MaxSubgroupCount; localSize.Volume() > maxWorkgroupThreads {
-			abort("Shader's local sizes [%d*%d*%d] is greater than ComputePipelineCreateInfo.RequiredSubgroupSize * Properties.Limits.Compute..Workgroup.MaxSubgroupCount [%d]",
+			return localSize, debug.Errorf("Shader's local sizes [%d*%d*%d] is greater than ComputePipelineCreateInfo.RequiredSubgroupSize * Properties.Limits.Compute..Workgroup.MaxSubgroupCount [%d]",
 				localSize.X, localSize.Y, localSize.Z, maxWorkgroupThreads)
 		}
 	} else if localSize.Volume() > instance.deviceProperties.Limits.Compute.Workgroup.MaxInvocations {
-		abort("Shader's local sizes [%d*%d*%d] is greater than Properties.Limits.Compute..Workgroup.MaxInvocations [%d]",
+		return localSize, debug.Errorf("Shader's local sizes [%d*%d*%d] is greater than Properties.Limits.Compute..Workgroup.MaxInvocations [%d]",
 			localSize.X, localSize.Y, localSize.Z, instance.deviceProperties.Limits.Compute.Workgroup.MaxInvocations)
 	}
+	return localSize, nil
+}
 
+func createComputePipeline(name string, pipelineLayout *PipelineLayout, s *Shader, entryPoint ShaderEntryPointLayout, info ComputePipelineCreateInfo) C.VkPipeline {
 	entryPointName := entryPoint.EntryPointName()
-	pipeline := &ComputePipeline{
-		name:      fmt.Sprintf("[%q,%s,%s]", s.ID, entryPointName, jsonString(info.SpecConstants)),
-		layout:    pipelineLayout,
-		localSize: localSize,
-	}
-	pipeline.noCopy.Init()
-
 	pipelineInfo := C.vxr_vk_compute_shaderPipelineCreateInfo{
 		stageFlags:     C.VkPipelineShaderStageCreateFlags(info.StageFlags),
 		layout:         pipelineLayout.vkPipelinelayout,
@@ -107,14 +136,15 @@ func NewComputePipeline(pipelineLayout *PipelineLayout, s *Shader, entryPoint Sh
 		numSpecConstants:     C.uint32_t(len(info.SpecConstants)),
 		specConstants:        (*C.uint32_t)(unsafe.SliceData(info.SpecConstants)),
 	}
-	C.vxr_vk_compute_createShaderPipeline(instance.cInstance, C.size_t(len(pipeline.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(pipeline.name))),
-		pipelineInfo, &pipeline.vkPipeline)
+	var vkPipeline C.VkPipeline
+	C.vxr_vk_compute_createShaderPipeline(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+		pipelineInfo, &vkPipeline)
+	runtime.KeepAlive(name)
 	runtime.KeepAlive(entryPointName)
 	runtime.KeepAlive(s.SPIRV)
 	runtime.KeepAlive(info.SpecConstants)
 
-	pipeline.id = genID(pipeline.vkPipeline)
-	return pipeline
+	return vkPipeline
 }
 
 func (p *ComputePipeline) Destroy() {
@@ -123,5 +153,6 @@ func (p *ComputePipeline) Destroy() {
 	}
 	p.noCopy.Check()
 	C.vxr_vk_shader_destroyPipeline(instance.cInstance, p.vkPipeline)
+	p.vkPipeline = nil
 	p.noCopy.Close()
 }
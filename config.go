@@ -38,6 +38,8 @@ const (
 	defaultImageCountPadding      = 1
 	defaultMaxFramesInFlight      = 2
 	defaultDescriptorPoolBankSize = 8
+	defaultDescriptorBufferSize   = 32 << 20
+	defaultFrameStatsWindow       = 120
 )
 
 type Config struct {
@@ -45,7 +47,36 @@ type Config struct {
 	API                        uint32
 	SwapchainImageCountPadding int32
 	MaxFramesInFlight          int32
-	DescriptorPoolBankSize     int32
+	// PresentModes is the list of present modes in order of preference, the first one the surface supports is used.
+	// Defaults to FIFORelaxed then FIFO, FIFO is used if none of the modes are supported.
+	PresentModes []PresentMode
+	// SurfaceFormats is the list of surface formats in order of preference, the first one the surface supports is used.
+	// Defaults to B8G8R8A8_SRGB then R8G8B8A8_SRGB, the first format the surface reports is used if none are supported.
+	SurfaceFormats []SurfaceFormat
+	// DescriptorPoolBankSize is the number of sets in the first descriptor pool bank of a descriptor set layout,
+	// later banks grow with the number of live sets of the layout.
+	DescriptorPoolBankSize int32
+	// DescriptorBufferSize is the size in bytes of the buffer descriptor sets are written into when the device
+	// supports VK_EXT_descriptor_buffer, it is clamped to the device's descriptor buffer limits.
+	DescriptorBufferSize uint64
+	// FrameStatsWindow is the number of frames FrameStatistics summarizes, a negative value disables the statistics.
+	FrameStatsWindow int32
+	// Offscreen renders to a ring of offscreen images instead of a swapchain when not nil, see OffscreenConfig.
+	// No surface is created and VK_KHR_swapchain is not required, so the device does not need presentation support.
+	Offscreen *OffscreenConfig
+
+	// Multiview requires the device to support VkPhysicalDeviceVulkan11Features.Multiview,
+	// allowing RenderParameters.ViewMask to render to multiple layers of the attachments with one draw.
+	Multiview bool
+	// BindlessHeap requires the device to support the descriptor indexing features needed for update after bind
+	// runtime descriptor arrays, enabling the global heap returned by Bindless. The heap takes half of the device's
+	// update after bind limits, PipelineLayouts whose sets do not fit in the other half abort.
+	BindlessHeap bool
+	// DisableDescriptorBuffer keeps descriptor sets in descriptor pools even when the device supports VK_EXT_descriptor_buffer.
+	DisableDescriptorBuffer bool
+	// ValidateResourceUse aborts when a command buffer uses a resource that was destroyed before it was submitted,
+	// either directly or through a DescriptorSet, reporting where the resource was destroyed.
+	ValidateResourceUse bool
 
 	RequiredExtensions []string
 	OptionalExtensions []string
@@ -68,7 +99,34 @@ func (c *Config) MarshalJSON() ([]byte, error) {
 	buff.WriteString(fmt.Sprintf("\"PreferredVkPhysicalDevice\": %q,", toHex(c.PreferredVkPhysicalDevice)))
 	buff.WriteString(fmt.Sprintf("\"API\": %q,", vkAPI2String(c.API)))
 	buff.WriteString(fmt.Sprintf("\"MaxFramesInFlight\": %d,", c.MaxFramesInFlight))
+	{
+		buff.WriteString("\"PresentModes\": [")
+		for _, m := range c.PresentModes {
+			buff.WriteString(fmt.Sprintf("%q,", m.String()))
+		}
+		if len(c.PresentModes) > 0 {
+			buff.Truncate(buff.Len() - 1)
+		}
+		buff.WriteString("],")
+	}
+	{
+		buff.WriteString("\"SurfaceFormats\": [")
+		for _, f := range c.SurfaceFormats {
+			buff.WriteString(fmt.Sprintf("%q,", f.String()))
+		}
+		if len(c.SurfaceFormats) > 0 {
+			buff.Truncate(buff.Len() - 1)
+		}
+		buff.WriteString("],")
+	}
 	buff.WriteString(fmt.Sprintf("\"DescriptorPoolBankSize\": %d,", c.DescriptorPoolBankSize))
+	buff.WriteString(fmt.Sprintf("\"DescriptorBufferSize\": %d,", c.DescriptorBufferSize))
+	buff.WriteString(fmt.Sprintf("\"FrameStatsWindow\": %d,", c.FrameStatsWindow))
+	buff.WriteString(fmt.Sprintf("\"Offscreen\": %s,", jsonString(c.Offscreen)))
+	buff.WriteString(fmt.Sprintf("\"Multiview\": %t,", c.Multiview))
+	buff.WriteString(fmt.Sprintf("\"BindlessHeap\": %t,", c.BindlessHeap))
+	buff.WriteString(fmt.Sprintf("\"DisableDescriptorBuffer\": %t,", c.DisableDescriptorBuffer))
+	buff.WriteString(fmt.Sprintf("\"ValidateResourceUse\": %t,", c.ValidateResourceUse))
 
 	buff.WriteString(fmt.Sprintf("\"RequiredExtensions\": %s,", jsonString(c.RequiredExtensions)))
 	buff.WriteString(fmt.Sprintf("\"OptionalExtensions\": %s,", jsonString(c.OptionalExtensions)))
@@ -120,30 +178,53 @@ func (c *Config) validate() {
 	} else if c.MaxFramesInFlight < 0 {
 		abort("Config.MaxFramesInFlight must be >= 0")
 	}
+	c.PresentModes = validatePresentModes(c.PresentModes)
+	c.SurfaceFormats = validateSurfaceFormats(c.SurfaceFormats)
 	if c.DescriptorPoolBankSize == 0 {
 		c.DescriptorPoolBankSize = defaultDescriptorPoolBankSize
 	} else if c.DescriptorPoolBankSize < 0 {
 		abort("Config.DescriptorPoolBankSize must be >= 0")
 	}
+	if c.DescriptorBufferSize == 0 {
+		c.DescriptorBufferSize = defaultDescriptorBufferSize
+	}
+	if c.FrameStatsWindow == 0 {
+		c.FrameStatsWindow = defaultFrameStatsWindow
+	}
+	if c.Offscreen != nil {
+		offscreen := *c.Offscreen
+		offscreen.validate()
+		c.Offscreen = &offscreen
+	}
 }
 
 func (c *Config) createDeviceSelector(surface uint64) C.vxr_vk_device_selector {
 	// process required
 	{
 		c.RequiredExtensions = append([]string{
-			C.VK_KHR_SWAPCHAIN_EXTENSION_NAME,
 			C.VK_EXT_MEMORY_BUDGET_EXTENSION_NAME,
 		}, c.RequiredExtensions...)
 		c.RequiredFeatures = append([]VkFeatureStruct{
 			VkPhysicalDeviceFeatures{
 				FillModeNonSolid: true,
 			},
-			VkPhysicalDeviceVulkan11Features{},
+			VkPhysicalDeviceVulkan11Features{
+				Multiview: c.Multiview,
+			},
 			VkPhysicalDeviceVulkan12Features{
 				DescriptorIndexing:                        true,
 				DescriptorBindingUpdateUnusedWhilePending: true,
 				DescriptorBindingPartiallyBound:           true,
 				TimelineSemaphore:                         true,
+				BufferDeviceAddress:                       true,
+
+				RuntimeDescriptorArray:                        c.BindlessHeap,
+				ShaderSampledImageArrayNonUniformIndexing:     c.BindlessHeap,
+				ShaderStorageImageArrayNonUniformIndexing:     c.BindlessHeap,
+				ShaderStorageBufferArrayNonUniformIndexing:    c.BindlessHeap,
+				DescriptorBindingSampledImageUpdateAfterBind:  c.BindlessHeap,
+				DescriptorBindingStorageImageUpdateAfterBind:  c.BindlessHeap,
+				DescriptorBindingStorageBufferUpdateAfterBind: c.BindlessHeap,
 			},
 			VkPhysicalDeviceVulkan13Features{
 				PipelineCreationCacheControl: true,
@@ -163,15 +244,18 @@ func (c *Config) createDeviceSelector(surface uint64) C.vxr_vk_device_selector {
 				ExtendedDynamicState3ColorBlendEquation:   true,
 				ExtendedDynamicState3ColorWriteMask:       true,
 			},
-			VkPhysicalDeviceSwapchainMaintenance1FeaturesEXT{
-				SwapchainMaintenance1: true,
-			},
 			/*
 				VkPhysicalDeviceMaintenance5FeaturesKHR{
 					Maintenance5: true,
 				},
 			*/
 		}, c.RequiredFeatures...)
+		if c.Offscreen == nil {
+			c.RequiredExtensions = append(c.RequiredExtensions, C.VK_KHR_SWAPCHAIN_EXTENSION_NAME)
+			c.RequiredFeatures = append(c.RequiredFeatures, VkPhysicalDeviceSwapchainMaintenance1FeaturesEXT{
+				SwapchainMaintenance1: true,
+			})
+		}
 		if c.API < C.VK_API_VERSION_1_4 {
 			c.RequiredFeatures = append(c.RequiredFeatures, VkPhysicalDeviceLineRasterizationFeaturesEXT{
 				BresenhamLines: true,
@@ -202,12 +286,47 @@ func (c *Config) createDeviceSelector(surface uint64) C.vxr_vk_device_selector {
 
 	// process optional
 	{
-		c.OptionalExtensions = append([]string{}, c.OptionalExtensions...)
+		if c.Offscreen == nil {
+			c.OptionalExtensions = append([]string{"VK_EXT_hdr_metadata"}, c.OptionalExtensions...)
+		}
 		c.OptionalFeatures = append([]VkFeatureStruct{
 			VkPhysicalDeviceFeatures{
-				WideLines: true,
+				DepthClamp:              true,
+				DepthBiasClamp:          true,
+				DepthBounds:             true,
+				WideLines:               true,
+				LogicOp:                 true,
+				MultiDrawIndirect:       true,
+				PipelineStatisticsQuery: true,
+				OcclusionQueryPrecise:   true,
+			},
+			VkPhysicalDeviceVulkan12Features{
+				DrawIndirectCount: true,
+				HostQueryReset:    true,
+			},
+			VkPhysicalDeviceExtendedDynamicState3FeaturesEXT{
+				ExtendedDynamicState3DepthClampEnable:      true,
+				ExtendedDynamicState3SampleMask:            true,
+				ExtendedDynamicState3AlphaToCoverageEnable: true,
+				ExtendedDynamicState3LogicOpEnable:         true,
+			},
+			VkPhysicalDeviceConditionalRenderingFeaturesEXT{
+				ConditionalRendering: true,
 			},
 		}, c.OptionalFeatures...)
+		if c.API < C.VK_API_VERSION_1_4 {
+			c.OptionalExtensions = append(c.OptionalExtensions, "VK_KHR_push_descriptor")
+		} else {
+			c.OptionalFeatures = append(c.OptionalFeatures, VkPhysicalDeviceVulkan14Features{
+				PushDescriptor: true,
+			})
+		}
+		if !c.DisableDescriptorBuffer {
+			c.OptionalFeatures = append(c.OptionalFeatures, VkPhysicalDeviceDescriptorBufferFeaturesEXT{
+				DescriptorBuffer:                true,
+				DescriptorBufferPushDescriptors: true,
+			})
+		}
 		for _, s := range c.OptionalFeatures {
 			if s.extension() != "" {
 				c.OptionalExtensions = append(c.OptionalExtensions, s.extension())
@@ -303,13 +422,29 @@ func (c *Config) createDeviceSelector(surface uint64) C.vxr_vk_device_selector {
 type config struct {
 	swapchainImageCountPadding int32
 	maxFramesInFlight          int32
+	presentModes               []PresentMode
+	surfaceFormats             []SurfaceFormat
 	descriptorPoolBankSize     int32
+	bindlessHeap               bool
+	descriptorBuffer           bool
+	descriptorBufferSize       uint64
+	frameStatsWindow           int32
+	offscreen                  *OffscreenConfig
+	validateResourceUse        bool
 }
 
 func (c *config) use(user Config) {
 	c.swapchainImageCountPadding = user.SwapchainImageCountPadding
 	c.maxFramesInFlight = user.MaxFramesInFlight
+	c.presentModes = user.PresentModes
+	c.surfaceFormats = user.SurfaceFormats
 	c.descriptorPoolBankSize = user.DescriptorPoolBankSize
+	c.bindlessHeap = user.BindlessHeap
+	c.descriptorBuffer = !user.DisableDescriptorBuffer && instance.graphics.features.descriptorBuffer
+	c.descriptorBufferSize = user.DescriptorBufferSize
+	c.frameStatsWindow = user.FrameStatsWindow
+	c.offscreen = user.Offscreen
+	c.validateResourceUse = user.ValidateResourceUse
 
 	for k := range user.RequiredFormatFeatures {
 		_ = instance.formatProperties.colorFeatures(k)
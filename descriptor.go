@@ -27,8 +27,10 @@ import (
 	"bytes"
 	"fmt"
 	"runtime"
+	"slices"
 	"unsafe"
 
+	"goarrg.com/debug"
 	"goarrg.com/rhi/vxr/internal/util"
 	"goarrg.com/rhi/vxr/internal/vk"
 )
@@ -91,7 +93,8 @@ func (d DescriptorBufferInfo) vkDescriptorBufferInfo() C.VkDescriptorBufferInfo
 	return C.VkDescriptorBufferInfo{
 		buffer: d.Buffer.vkBuffer(),
 		offset: C.VkDeviceSize(d.Offset),
-		_range: vk.WHOLE_SIZE,
+		// descriptor buffers need the actual range, it is what vk.WHOLE_SIZE would resolve to
+		_range: C.VkDeviceSize(d.Buffer.Size() - d.Offset),
 	}
 }
 
@@ -127,11 +130,30 @@ func (d DescriptorCombinedImageSamplerInfo) vkDescriptorImageInfo() C.VkDescript
 	}
 }
 
+// descriptorSetAllocation identifies a set by its handle with descriptor pools or by its offset with descriptor buffers.
+type descriptorSetAllocation struct {
+	cDescriptorSet         C.VkDescriptorSet
+	descriptorBufferOffset C.VkDeviceSize
+}
+
+func (a descriptorSetAllocation) String() string {
+	if a.cDescriptorSet == nil {
+		return fmt.Sprintf("offset_%d", a.descriptorBufferOffset)
+	}
+	return toHex(a.cDescriptorSet)
+}
+
 type DescriptorSet struct {
 	noCopy              util.NoCopy
 	descriptorSetLayout descriptorSetLayout
-	cDescriptorSet      C.VkDescriptorSet
-	bank                *descriptorPoolBank
+	descriptorSetAllocation
+	bank *descriptorPoolBank
+	// set is the index the set was created for in its PipelineLayout, BindByName resolves names against it
+	set int
+
+	tracker resourceTracker
+	// descriptors is indexed by binding and holds what was bound to the set, command buffers that use the set track them.
+	descriptors [][]DescriptorInfo
 }
 
 func (s *DescriptorSet) MaxDescriptorCount(bindingIndex int) int {
@@ -141,16 +163,186 @@ func (s *DescriptorSet) MaxDescriptorCount(bindingIndex int) int {
 
 func (s *DescriptorSet) Bind(bindingIndex, descriptorIndex int, descriptors ...DescriptorInfo) {
 	s.noCopy.Check()
+	binding := s.bindingFor(bindingIndex, len(descriptors))
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+	updateDescriptorSet(&s.descriptorSetLayout, s.descriptorSetAllocation,
+		vkWriteDescriptorSet(&pinner, binding, bindingIndex, descriptorIndex, descriptors))
+	s.retain(bindingIndex, descriptorIndex, descriptors)
+}
+
+/*
+Unbind forgets count descriptors of bindingIndex starting at descriptorIndex, command buffers that use the set no longer
+track them nor validate that they are alive. The descriptors are not overwritten so shaders must not access them
+until they are bound again, it is meant for array slots that are no longer used e.g. before destroying their resources.
+*/
+func (s *DescriptorSet) Unbind(bindingIndex, descriptorIndex, count int) {
+	s.noCopy.Check()
+	s.bindingFor(bindingIndex, count)
+	if s.descriptors == nil {
+		return
+	}
+	bound := s.descriptors[bindingIndex]
+	if descriptorIndex >= len(bound) {
+		return
+	}
+	clear(bound[descriptorIndex:min(descriptorIndex+count, len(bound))])
+}
+
+// retain records what was bound to the set so the resources can be tracked when the set is used.
+func (s *DescriptorSet) retain(bindingIndex, descriptorIndex int, descriptors []DescriptorInfo) {
+	if s.descriptors == nil {
+		s.descriptors = make([][]DescriptorInfo, len(s.descriptorSetLayout.bindings))
+	}
+	bound := s.descriptors[bindingIndex]
+	if n := descriptorIndex + len(descriptors); n > len(bound) {
+		bound = append(bound, make([]DescriptorInfo, n-len(bound))...)
+	}
+	copy(bound[descriptorIndex:], descriptors)
+	s.descriptors[bindingIndex] = bound
+}
+
+// forEachResource calls f with each resource bound to the set.
+func (s *DescriptorSet) forEachResource(f func(bindingIndex, descriptorIndex int, r any)) {
+	for i, bound := range s.descriptors {
+		for j, d := range bound {
+			forEachDescriptorResource(d, func(r any) {
+				f(i, j, r)
+			})
+		}
+	}
+}
+
+// bindingFor returns the binding descriptors are bound to, aborting if the binding cannot hold them.
+func (s *DescriptorSet) bindingFor(bindingIndex, numDescriptors int) descriptorSetBinding {
 	if bindingIndex >= len(s.descriptorSetLayout.bindings) {
 		abort("Trying to bind to descriptor index %d while layout's max is %d", bindingIndex, len(s.descriptorSetLayout.bindings)-1)
 	}
 	binding := s.descriptorSetLayout.bindings[bindingIndex]
-	if len(descriptors) > int(binding.descriptorCount) {
-		abort("Trying to bind %d descriptors while layout's max is %d", len(descriptors), binding.descriptorCount)
+	if numDescriptors > int(binding.descriptorCount) {
+		abort("Trying to bind %d descriptors while layout's max is %d", numDescriptors, binding.descriptorCount)
+	}
+	return binding
+}
+
+/*
+BindByName binds descriptors to the binding of the shader variable name starting at descriptorIndex.
+Unlike Bind the binding is resolved through the shader's metadata and every descriptor is checked against it,
+so the type, usage flags, image view type and buffer size must all match what the shader declares.
+*/
+func (s *DescriptorSet) BindByName(meta *ShaderMetadata, name string, descriptorIndex int, descriptors ...DescriptorInfo) error {
+	s.noCopy.Check()
+	if meta == nil {
+		return debug.Errorf("Trying to bind %q without ShaderMetadata", name)
+	}
+	m, ok := meta.DescriptorSetBindings[name]
+	if !ok {
+		return debug.Errorf("Shader has no descriptor named %q", name)
+	}
+	info := m.Info()
+	if info.Set != s.set {
+		return debug.Errorf("Descriptor %q is in set [%d] but the DescriptorSet was created for set [%d]", name, info.Set, s.set)
+	}
+	if info.Binding >= len(s.descriptorSetLayout.bindings) || s.descriptorSetLayout.bindings[info.Binding].descriptorCount == 0 {
+		return debug.Errorf("Descriptor %q set[%d] binding[%d] does not exist in the DescriptorSet's layout %s",
+			name, info.Set, info.Binding, s.descriptorSetLayout.name)
+	}
+	binding := s.descriptorSetLayout.bindings[info.Binding]
+	if DescriptorType(binding.descriptorType) != info.DescriptorType {
+		return debug.Errorf("Descriptor %q is [%s] in the shader but [%s] in the DescriptorSet's layout",
+			name, info.DescriptorType.String(), DescriptorType(binding.descriptorType).String())
+	}
+	if len(descriptors) == 0 {
+		return debug.Errorf("Trying to bind %q without descriptors", name)
+	}
+	if descriptorIndex < 0 || descriptorIndex+len(descriptors) > int(binding.descriptorCount) {
+		return debug.Errorf("Trying to bind %q descriptors [%d, %d) while layout's max is %d",
+			name, descriptorIndex, descriptorIndex+len(descriptors), binding.descriptorCount)
+	}
+	for i, d := range descriptors {
+		if !descriptorInfoMatchesType(d, info.DescriptorType) {
+			return debug.Errorf("Descriptor %q [%d] %#v cannot be written to a %s binding",
+				name, descriptorIndex+i, d, info.DescriptorType.String())
+		}
+		if err := m.ValidateDescriptor(d); err != nil {
+			return debug.ErrorWrapf(err, "Descriptor %q [%d] is invalid", name, descriptorIndex+i)
+		}
+	}
+	s.Bind(info.Binding, descriptorIndex, descriptors...)
+	return nil
+}
+
+func (s *DescriptorSet) Destroy() {
+	if s == nil {
+		return
+	}
+	s.noCopy.Check()
+	s.tracker.release(func() {
+		instance.descriptorSetCache.releaseDescriptorSet(s)
+	})
+	s.descriptors = nil
+	s.noCopy.Close()
+}
+
+func (s *DescriptorSet) resourceTracker() *resourceTracker {
+	return &s.tracker
+}
+
+// descriptorInfoMatchesType reports whether the info is of the kind that can be written to a binding of the type.
+func descriptorInfoMatchesType(info DescriptorInfo, t DescriptorType) bool {
+	switch d := info.(type) {
+	case DescriptorBufferInfo:
+		return t == DescriptorTypeUniformBuffer || t == DescriptorTypeStorageBuffer
+	case *Sampler:
+		return d != nil && t == DescriptorTypeSampler
+	case DescriptorImageInfo:
+		return t == DescriptorTypeSampledImage || t == DescriptorTypeStorageImage
+	case DescriptorCombinedImageSamplerInfo:
+		return d.Sampler != nil && t == DescriptorTypeCombinedImageSampler
+	default:
+		return false
+	}
+}
+
+// validateDescriptorUsage checks that the resource of info was created with the usage a binding of type t needs.
+func validateDescriptorUsage(info DescriptorInfo, t DescriptorType) error {
+	switch d := info.(type) {
+	case DescriptorBufferInfo:
+		usage := BufferUsageUniformBuffer
+		if t == DescriptorTypeStorageBuffer {
+			usage = BufferUsageStorageBuffer
+		}
+		if !d.Buffer.Usage().HasBits(usage) {
+			return debug.Errorf("Buffer cannot be bound as %s, it wasn't created with the proper usage flags, have flags: %s",
+				t.String(), d.Buffer.Usage().String())
+		}
+	case DescriptorImageInfo:
+		usage := ImageUsageSampled
+		if t == DescriptorTypeStorageImage {
+			usage = ImageUsageStorage
+		}
+		if !d.Image.usage().HasBits(usage) {
+			return debug.Errorf("Image cannot be bound as %s, it wasn't created with the proper usage flags, have flags: %s",
+				t.String(), d.Image.usage().String())
+		}
+	case DescriptorCombinedImageSamplerInfo:
+		if !d.Image.usage().HasBits(ImageUsageSampled) {
+			return debug.Errorf("Image cannot be bound as %s, it wasn't created with the proper usage flags, have flags: %s",
+				t.String(), d.Image.usage().String())
+		}
 	}
+	return nil
+}
+
+/*
+vkWriteDescriptorSet returns the write of descriptors into the binding starting at descriptorIndex,
+the descriptor infos it points to are pinned with pinner so the write can be handed to C inside other Go memory.
+*/
+func vkWriteDescriptorSet(pinner *runtime.Pinner, binding descriptorSetBinding, bindingIndex, descriptorIndex int,
+	descriptors []DescriptorInfo,
+) C.VkWriteDescriptorSet {
 	writeDescriptorSet := C.VkWriteDescriptorSet{
 		sType:           vk.STRUCTURE_TYPE_WRITE_DESCRIPTOR_SET,
-		dstSet:          s.cDescriptorSet,
 		dstBinding:      C.uint32_t(bindingIndex),
 		dstArrayElement: C.uint32_t(descriptorIndex),
 		descriptorCount: C.uint32_t(len(descriptors)),
@@ -162,42 +354,33 @@ func (s *DescriptorSet) Bind(bindingIndex, descriptorIndex int, descriptors ...D
 		for _, d := range descriptors {
 			s = append(s, d.(DescriptorBufferInfo).vkDescriptorBufferInfo())
 		}
-		defer runtime.KeepAlive(s)
+		pinner.Pin(unsafe.SliceData(s))
 		writeDescriptorSet.pBufferInfo = unsafe.SliceData(s)
 	case *Sampler:
 		s := make([]C.VkDescriptorImageInfo, 0, len(descriptors))
 		for _, d := range descriptors {
 			s = append(s, C.VkDescriptorImageInfo{sampler: d.(*Sampler).cSampler})
 		}
-		defer runtime.KeepAlive(s)
+		pinner.Pin(unsafe.SliceData(s))
 		writeDescriptorSet.pImageInfo = unsafe.SliceData(s)
 	case DescriptorImageInfo:
 		s := make([]C.VkDescriptorImageInfo, 0, len(descriptors))
 		for _, d := range descriptors {
 			s = append(s, d.(DescriptorImageInfo).vkDescriptorImageInfo())
 		}
-		defer runtime.KeepAlive(s)
+		pinner.Pin(unsafe.SliceData(s))
 		writeDescriptorSet.pImageInfo = unsafe.SliceData(s)
 	case DescriptorCombinedImageSamplerInfo:
 		s := make([]C.VkDescriptorImageInfo, 0, len(descriptors))
 		for _, d := range descriptors {
 			s = append(s, d.(DescriptorCombinedImageSamplerInfo).vkDescriptorImageInfo())
 		}
-		defer runtime.KeepAlive(s)
+		pinner.Pin(unsafe.SliceData(s))
 		writeDescriptorSet.pImageInfo = unsafe.SliceData(s)
 	default:
 		abort("Trying to bind unknown descriptor type: %#v", binding)
 	}
-	C.vxr_vk_shader_updateDescriptorSet(instance.cInstance, writeDescriptorSet)
-}
-
-func (s *DescriptorSet) Destroy() {
-	if s == nil {
-		return
-	}
-	s.noCopy.Check()
-	s.bank.releaseDescriptorSet(s)
-	s.noCopy.Close()
+	return writeDescriptorSet
 }
 
 type descriptorSetBinding struct {
@@ -219,19 +402,22 @@ func (b descriptorSetBinding) MarshalJSON() ([]byte, error) {
 }
 
 type descriptorSetLayout struct {
-	id                   string
+	key                  hashKey
 	name                 string
 	cDescriptorSetLayout C.VkDescriptorSetLayout
 	bindings             []descriptorSetBinding
+	// push layouts are never allocated, their descriptors are pushed with the draw or dispatch
+	push bool
 }
 
 func (s *descriptorSetLayout) MarshalJSON() ([]byte, error) {
 	buff := bytes.Buffer{}
 	buff.WriteString("{")
 
-	buff.WriteString(fmt.Sprintf("\"id\": %q,", s.id))
+	buff.WriteString(fmt.Sprintf("\"key\": %q,", s.key.String()))
 	buff.WriteString(fmt.Sprintf("\"name\": %q,", s.name))
 	buff.WriteString(fmt.Sprintf("\"cDescriptorSetLayout\": %q,", toHex(s.cDescriptorSetLayout)))
+	buff.WriteString(fmt.Sprintf("\"push\": %t,", s.push))
 
 	buff.WriteString("\"bindings\": [")
 	if len(s.bindings) > 0 {
@@ -245,3 +431,42 @@ func (s *descriptorSetLayout) MarshalJSON() ([]byte, error) {
 	buff.WriteString("}")
 	return buff.Bytes(), nil
 }
+
+func (s *descriptorSetLayout) genKey() hashKey {
+	h := newHasher()
+	h.writeUint32(uint32(len(s.bindings)))
+	for _, b := range s.bindings {
+		h.writeUint32(uint32(b.shaderStage))
+		h.writeUint32(uint32(b.descriptorType))
+		h.writeUint32(uint32(b.descriptorCount))
+	}
+	if s.push {
+		h.writeByte(1)
+	} else {
+		h.writeByte(0)
+	}
+	return h.sum
+}
+
+// descriptorSetLayoutKey is the full key of a descriptorSetLayout that its key is the hash of.
+type descriptorSetLayoutKey struct {
+	bindings []descriptorSetBinding
+	push     bool
+}
+
+func (k descriptorSetLayoutKey) equal(o descriptorSetLayoutKey) bool {
+	return k.push == o.push && slices.Equal(k.bindings, o.bindings)
+}
+
+func (s *descriptorSetLayout) cacheKey() descriptorSetLayoutKey {
+	return descriptorSetLayoutKey{bindings: s.bindings, push: s.push}
+}
+
+func (s *descriptorSetLayout) empty() bool {
+	for _, b := range s.bindings {
+		if b.descriptorCount > 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"sync"
+	"unsafe"
+
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+type descriptorBufferRange struct {
+	offset uint64
+	size   uint64
+}
+
+/*
+descriptorBuffer is the VK_EXT_descriptor_buffer backend of DescriptorSet, every set and the bindless heap are
+ranges of one host visible buffer that descriptors are written into directly and bound by offset.
+Ranges are handed out first fit and merged with their neighbours when freed.
+*/
+type descriptorBuffer struct {
+	mtx         sync.Mutex
+	size        uint64
+	alignment   uint64
+	used        uint64
+	free        []descriptorBufferRange
+	layoutSizes map[C.VkDescriptorSetLayout]uint64
+}
+
+func initDescriptorBuffer() {
+	if !instance.config.descriptorBuffer {
+		return
+	}
+
+	size := C.VkDeviceSize(instance.config.descriptorBufferSize)
+	var alignment C.VkDeviceSize
+	var pushDescriptors C.VkBool32
+	if instance.graphics.features.pushDescriptor {
+		pushDescriptors = vk.TRUE
+	}
+	C.vxr_vk_descriptorBuffer_init(instance.cInstance, pushDescriptors, &size, &alignment)
+
+	b := &instance.descriptorBuffer
+	b.size = uint64(size)
+	b.alignment = max(uint64(alignment), 1)
+	b.free = []descriptorBufferRange{{offset: 0, size: b.size}}
+	b.layoutSizes = map[C.VkDescriptorSetLayout]uint64{}
+	instance.logger.IPrintf("Using descriptor buffer: %d bytes, offset alignment %d", b.size, b.alignment)
+}
+
+func destroyDescriptorBuffer() {
+	C.vxr_vk_descriptorBuffer_destroy(instance.cInstance)
+	instance.descriptorBuffer = descriptorBuffer{}
+}
+
+func (b *descriptorBuffer) enabled() bool {
+	return b.size > 0
+}
+
+func (b *descriptorBuffer) align(size uint64) uint64 {
+	return (size + b.alignment - 1) / b.alignment * b.alignment
+}
+
+// layoutSize returns the size of a set of the layout rounded up so sets can be placed back to back.
+func (b *descriptorBuffer) layoutSize(layout C.VkDescriptorSetLayout) uint64 {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	size, ok := b.layoutSizes[layout]
+	if !ok {
+		var cSize C.VkDeviceSize
+		C.vxr_vk_descriptorBuffer_getLayoutSize(instance.cInstance, layout, &cSize)
+		size = b.align(uint64(cSize))
+		b.layoutSizes[layout] = size
+	}
+	return size
+}
+
+func (b *descriptorBuffer) allocate(size uint64) uint64 {
+	if size == 0 {
+		return 0
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	size = b.align(size)
+	for i, r := range b.free {
+		if r.size < size {
+			continue
+		}
+		if r.size == size {
+			b.free = append(b.free[:i], b.free[i+1:]...)
+		} else {
+			b.free[i] = descriptorBufferRange{offset: r.offset + size, size: r.size - size}
+		}
+		b.used += size
+		return r.offset
+	}
+
+	abort("Descriptor buffer is out of space allocating %d bytes with %d of %d bytes in use, increase Config.DescriptorBufferSize",
+		size, b.used, b.size)
+	return 0
+}
+
+func (b *descriptorBuffer) release(offset, size uint64) {
+	if size == 0 {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	size = b.align(size)
+	b.used -= size
+	i := 0
+	for i < len(b.free) && b.free[i].offset < offset {
+		i++
+	}
+	b.free = append(b.free, descriptorBufferRange{})
+	copy(b.free[i+1:], b.free[i:])
+	b.free[i] = descriptorBufferRange{offset: offset, size: size}
+
+	if (i+1 < len(b.free)) && (b.free[i].offset+b.free[i].size == b.free[i+1].offset) {
+		b.free[i].size += b.free[i+1].size
+		b.free = append(b.free[:i+1], b.free[i+2:]...)
+	}
+	if (i > 0) && (b.free[i-1].offset+b.free[i-1].size == b.free[i].offset) {
+		b.free[i-1].size += b.free[i].size
+		b.free = append(b.free[:i], b.free[i+1:]...)
+	}
+}
+
+// write writes the descriptors of w into the set at offset, sets never overlap so it does not need the lock.
+func (b *descriptorBuffer) write(offset C.VkDeviceSize, layout *descriptorSetLayout, w C.VkWriteDescriptorSet) {
+	C.vxr_vk_descriptorBuffer_write(instance.cInstance, offset, layout.cDescriptorSetLayout,
+		layout.bindings[w.dstBinding].descriptorCount, w)
+}
+
+/*
+updateDescriptorSet writes w into the set of the layout through whichever backend the device uses,
+the set is identified by its handle with descriptor pools and by its offset with descriptor buffers.
+*/
+func updateDescriptorSet(layout *descriptorSetLayout, set descriptorSetAllocation, w C.VkWriteDescriptorSet) {
+	if instance.descriptorBuffer.enabled() {
+		instance.descriptorBuffer.write(set.descriptorBufferOffset, layout, w)
+		return
+	}
+	w.dstSet = set.cDescriptorSet
+	C.vxr_vk_shader_updateDescriptorSet(instance.cInstance, w)
+}
+
+// descriptorWrite is a write into a set of the layout waiting to be flushed by updateDescriptorSets.
+type descriptorWrite struct {
+	layout *descriptorSetLayout
+	set    descriptorSetAllocation
+	write  C.VkWriteDescriptorSet
+}
+
+/*
+updateDescriptorSets is updateDescriptorSet for many writes at once with a single call into C,
+the descriptor infos the writes point to must be pinned.
+*/
+func updateDescriptorSets(writes []descriptorWrite) {
+	if len(writes) == 0 {
+		return
+	}
+	if instance.descriptorBuffer.enabled() {
+		cWrites := make([]C.vxr_vk_descriptorBuffer_writeInfo, len(writes))
+		for i, w := range writes {
+			cWrites[i] = C.vxr_vk_descriptorBuffer_writeInfo{
+				setOffset:              w.set.descriptorBufferOffset,
+				layout:                 w.layout.cDescriptorSetLayout,
+				bindingDescriptorCount: w.layout.bindings[w.write.dstBinding].descriptorCount,
+				write:                  w.write,
+			}
+		}
+		C.vxr_vk_descriptorBuffer_writes(instance.cInstance, C.uint32_t(len(cWrites)), unsafe.SliceData(cWrites))
+		return
+	}
+	cWrites := make([]C.VkWriteDescriptorSet, len(writes))
+	for i, w := range writes {
+		cWrites[i] = w.write
+		cWrites[i].dstSet = w.set.cDescriptorSet
+	}
+	C.vxr_vk_shader_updateDescriptorSets(instance.cInstance, C.uint32_t(len(cWrites)), unsafe.SliceData(cWrites))
+}
@@ -0,0 +1,163 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"runtime"
+	"slices"
+	"unsafe"
+
+	"goarrg.com/rhi/vxr/internal/util"
+)
+
+/*
+DescriptorWriteBatch collects binds across DescriptorSets and writes all of them with a single call on Flush,
+use it over DescriptorSet.Bind when many descriptors are rebound at once. Binds only reach their sets once flushed
+so the sets must not be destroyed before then, Reset drops them instead. The zero value is ready to use and
+a batch is not safe for concurrent use.
+*/
+type DescriptorWriteBatch struct {
+	noCopy util.NoCopy
+	binds  []descriptorBatchBind
+}
+
+type descriptorBatchBind struct {
+	set             *DescriptorSet
+	bindingIndex    int
+	descriptorIndex int
+	descriptors     []DescriptorInfo
+}
+
+func (b *DescriptorWriteBatch) Bind(set *DescriptorSet, bindingIndex, descriptorIndex int, descriptors ...DescriptorInfo) {
+	b.noCopy.InitLazy()
+	set.noCopy.Check()
+	_ = set.bindingFor(bindingIndex, len(descriptors))
+	b.binds = append(b.binds, descriptorBatchBind{
+		set:             set,
+		bindingIndex:    bindingIndex,
+		descriptorIndex: descriptorIndex,
+		descriptors:     slices.Clone(descriptors),
+	})
+}
+
+// Len returns the number of binds waiting to be flushed.
+func (b *DescriptorWriteBatch) Len() int {
+	return len(b.binds)
+}
+
+// Flush writes every bind since the last Flush or Reset, the batch can be reused afterwards.
+func (b *DescriptorWriteBatch) Flush() {
+	if b.noCopy.InitLazy() {
+		return
+	}
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+	writes := make([]descriptorWrite, 0, len(b.binds))
+	for _, bind := range b.binds {
+		bind.set.noCopy.Check()
+		binding := bind.set.bindingFor(bind.bindingIndex, len(bind.descriptors))
+		writes = append(writes, descriptorWrite{
+			layout: &bind.set.descriptorSetLayout,
+			set:    bind.set.descriptorSetAllocation,
+			write:  vkWriteDescriptorSet(&pinner, binding, bind.bindingIndex, bind.descriptorIndex, bind.descriptors),
+		})
+	}
+	updateDescriptorSets(writes)
+	for _, bind := range b.binds {
+		bind.set.retain(bind.bindingIndex, bind.descriptorIndex, bind.descriptors)
+	}
+	b.Reset()
+}
+
+// Reset drops every bind since the last Flush without writing them.
+func (b *DescriptorWriteBatch) Reset() {
+	clear(b.binds)
+	b.binds = b.binds[:0]
+}
+
+/*
+Update rewrites every binding of the set at once, descriptors is indexed by binding and must give every descriptor
+of every binding in the set's layout. With descriptor pools this is a single vkUpdateDescriptorSetWithTemplate through
+a template built from the layout the first time a set of it is updated, with descriptor buffers the writes are batched instead.
+It is meant for sets that are rewritten wholesale every frame, use Bind to change some of the descriptors.
+*/
+func (s *DescriptorSet) Update(descriptors [][]DescriptorInfo) {
+	s.noCopy.Check()
+	layout := &s.descriptorSetLayout
+	if len(descriptors) > len(layout.bindings) {
+		abort("Trying to update %d bindings while layout has %d", len(descriptors), len(layout.bindings))
+	}
+
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+	writes := make([]descriptorWrite, 0, len(layout.bindings))
+	for i, binding := range layout.bindings {
+		var bindingDescriptors []DescriptorInfo
+		if i < len(descriptors) {
+			bindingDescriptors = descriptors[i]
+		}
+		if len(bindingDescriptors) != int(binding.descriptorCount) {
+			abort("Trying to update binding [%d] with %d descriptors while layout has %d", i, len(bindingDescriptors), binding.descriptorCount)
+		}
+		if binding.descriptorCount == 0 {
+			continue
+		}
+		for j, d := range bindingDescriptors {
+			if !descriptorInfoMatchesType(d, DescriptorType(binding.descriptorType)) {
+				abort("Trying to update binding [%d] descriptor [%d] of type %s with %#v",
+					i, j, DescriptorType(binding.descriptorType).String(), d)
+			}
+		}
+		writes = append(writes, descriptorWrite{
+			layout: layout,
+			set:    s.descriptorSetAllocation,
+			write:  vkWriteDescriptorSet(&pinner, binding, i, 0, bindingDescriptors),
+		})
+		s.retain(i, 0, bindingDescriptors)
+	}
+
+	if instance.descriptorBuffer.enabled() {
+		updateDescriptorSets(writes)
+		return
+	}
+	if len(writes) == 0 {
+		return
+	}
+
+	// the writes are in binding order, the same order the template entries were created in
+	t := instance.descriptorUpdateTemplateCache.createOrRetrieveDescriptorUpdateTemplate(layout)
+	data := make([]uint64, (t.size+7)/8)
+	base := unsafe.Pointer(unsafe.SliceData(data))
+	for i, e := range t.entries {
+		w := writes[i].write
+		dst := unsafe.Add(base, e.offset)
+		if w.pBufferInfo != nil {
+			copy(unsafe.Slice((*C.VkDescriptorBufferInfo)(dst), e.descriptorCount), unsafe.Slice(w.pBufferInfo, w.descriptorCount))
+		} else {
+			copy(unsafe.Slice((*C.VkDescriptorImageInfo)(dst), e.descriptorCount), unsafe.Slice(w.pImageInfo, w.descriptorCount))
+		}
+	}
+	C.vxr_vk_shader_updateDescriptorSetWithTemplate(instance.cInstance, s.cDescriptorSet, t.vkDescriptorUpdateTemplate, base)
+	runtime.KeepAlive(data)
+}
@@ -0,0 +1,50 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// #include <vxr/bindless.glsl> to access the heap returned by vxr.Bindless, indices are the handles returned by its Push functions.
+
+#ifndef VXR_BINDLESS_GLSL
+#define VXR_BINDLESS_GLSL
+
+#extension GL_EXT_nonuniform_qualifier : require
+
+#define VXR_BINDLESS_SET 3
+#define VXR_BINDLESS_SAMPLED_IMAGE_BINDING 0
+#define VXR_BINDLESS_STORAGE_IMAGE_BINDING 1
+#define VXR_BINDLESS_SAMPLER_BINDING 2
+#define VXR_BINDLESS_STORAGE_BUFFER_BINDING 3
+
+layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_SAMPLED_IMAGE_BINDING) uniform texture2D vxrTexture2D[];
+layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_SAMPLED_IMAGE_BINDING) uniform texture2DArray vxrTexture2DArray[];
+layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_SAMPLED_IMAGE_BINDING) uniform texture3D vxrTexture3D[];
+layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_SAMPLED_IMAGE_BINDING) uniform textureCube vxrTextureCube[];
+
+layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_SAMPLER_BINDING) uniform sampler vxrSampler[];
+
+// VXR_BINDLESS_STORAGE_IMAGE(rgba8, image2D, myImages); declares a view of the storage images with the given format and type.
+#define VXR_BINDLESS_STORAGE_IMAGE(FORMAT, TYPE, NAME) \
+	layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_STORAGE_IMAGE_BINDING, FORMAT) uniform TYPE NAME[]
+
+// VXR_BINDLESS_STORAGE_BUFFER(MyBlock) { uint data[]; } myBuffers[]; declares a view of the storage buffers with the given block.
+#define VXR_BINDLESS_STORAGE_BUFFER(BLOCK) \
+	layout(set = VXR_BINDLESS_SET, binding = VXR_BINDLESS_STORAGE_BUFFER_BINDING, std430) buffer BLOCK
+
+#define vxrSampler2D(IMAGE, SAMPLER) sampler2D(vxrTexture2D[nonuniformEXT(IMAGE)], vxrSampler[nonuniformEXT(SAMPLER)])
+#define vxrSampler2DArray(IMAGE, SAMPLER) sampler2DArray(vxrTexture2DArray[nonuniformEXT(IMAGE)], vxrSampler[nonuniformEXT(SAMPLER)])
+#define vxrSampler3D(IMAGE, SAMPLER) sampler3D(vxrTexture3D[nonuniformEXT(IMAGE)], vxrSampler[nonuniformEXT(SAMPLER)])
+#define vxrSamplerCube(IMAGE, SAMPLER) samplerCube(vxrTextureCube[nonuniformEXT(IMAGE)], vxrSampler[nonuniformEXT(SAMPLER)])
+
+#endif
@@ -27,30 +27,174 @@ import (
 	"bytes"
 	"fmt"
 	"runtime"
-	"strings"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"unsafe"
 
 	"goarrg.com/rhi/vxr/internal/vk"
-	"golang.org/x/exp/maps"
 )
 
+type cachedPipeline struct {
+	name       string
+	vkPipeline C.VkPipeline
+}
+
+func (p cachedPipeline) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("{\"name\": %q, \"vkPipeline\": %q}", p.name, toHex(p.vkPipeline))), nil
+}
+
+// executablePipelineKey identifies a linked pipeline by the libraries it was linked from,
+// it is comparable so draw time lookups do not allocate.
+type executablePipelineKey struct {
+	vertexInput    C.VkPipeline
+	vertexShader   C.VkPipeline
+	fragmentShader C.VkPipeline
+	fragmentOutput C.VkPipeline
+}
+
+func (k executablePipelineKey) String() string {
+	return fmt.Sprintf("[%s,%s,%s,%s]", toHex(k.vertexInput), toHex(k.vertexShader), toHex(k.fragmentShader), toHex(k.fragmentOutput))
+}
+
+// newExecutablePipelineKey returns the key of the pipeline linked from p's libraries and the render pass' fragment output library.
+func newExecutablePipelineKey(p GraphicsPipelineLibrary, fragmentOutput C.VkPipeline) executablePipelineKey {
+	return executablePipelineKey{
+		vertexInput:    p.VertexInput.vkPipeline,
+		vertexShader:   p.VertexShader.vkPipeline,
+		fragmentShader: p.FragmentShader.vkPipeline,
+		fragmentOutput: fragmentOutput,
+	}
+}
+
+func (k executablePipelineKey) libraries() [4]C.VkPipeline {
+	return [4]C.VkPipeline{k.vertexInput, k.vertexShader, k.fragmentShader, k.fragmentOutput}
+}
+
+/*
+pipelineLibraryKey is the full key of a pipeline library that the hashKey it is cached under is the hash of,
+vertex input libraries are identified by name alone while fragment output libraries by their attachments.
+*/
+type pipelineLibraryKey struct {
+	name          string
+	colorFormats  []C.VkFormat
+	depthFormat   C.VkFormat
+	stencilFormat C.VkFormat
+	logicOp       C.VkLogicOp
+	viewMask      uint32
+}
+
+func (k pipelineLibraryKey) equal(o pipelineLibraryKey) bool {
+	return k.name == o.name && slices.Equal(k.colorFormats, o.colorFormats) && k.depthFormat == o.depthFormat &&
+		k.stencilFormat == o.stencilFormat && k.logicOp == o.logicOp && k.viewMask == o.viewMask
+}
+
 type graphicsPipelineCache struct {
-	mtx   sync.RWMutex
-	cache map[string]C.VkPipeline
+	mtx         sync.RWMutex
+	libraries   map[hashKey]hashBucket[pipelineLibraryKey, cachedPipeline]
+	executables map[executablePipelineKey]cachedPipeline
+	// dependents maps a pipeline library to every executable pipeline linked with it,
+	// so destroying a library only touches the executables that depend on it.
+	dependents map[C.VkPipeline]map[executablePipelineKey]struct{}
 
 	mtxShader   sync.Mutex
 	cacheShader map[string]*GraphicsShaderPipeline
 }
 
+// graphicsFeatures holds the optional features that change how pipelines are created and draws are recorded,
+// dynamic states backed by a missing feature are neither declared nor set.
+type graphicsFeatures struct {
+	depthBiasClamp  bool
+	depthBounds     bool
+	depthClamp      bool
+	logicOp         bool
+	alphaToCoverage bool
+	sampleMask      bool
+	multiview       bool
+
+	multiDrawIndirect bool
+	drawIndirectCount bool
+
+	hostQueryReset          bool
+	pipelineStatisticsQuery bool
+	occlusionQueryPrecise   bool
+	conditionalRendering    bool
+
+	descriptorBuffer   bool
+	pushDescriptor     bool
+	maxPushDescriptors uint32
+
+	hdrMetadata bool
+}
+
+func (f *graphicsFeatures) init(features VkFeatureMap, extensions []string) {
+	core, _ := features["VkPhysicalDeviceFeatures"].(VkPhysicalDeviceFeatures)
+	vk11, _ := features["VkPhysicalDeviceVulkan11Features"].(VkPhysicalDeviceVulkan11Features)
+	vk12, _ := features["VkPhysicalDeviceVulkan12Features"].(VkPhysicalDeviceVulkan12Features)
+	vk14, _ := features["VkPhysicalDeviceVulkan14Features"].(VkPhysicalDeviceVulkan14Features)
+	eds3, _ := features["VkPhysicalDeviceExtendedDynamicState3FeaturesEXT"].(VkPhysicalDeviceExtendedDynamicState3FeaturesEXT)
+	conditionalRendering, _ := features["VkPhysicalDeviceConditionalRenderingFeaturesEXT"].(VkPhysicalDeviceConditionalRenderingFeaturesEXT)
+	descriptorBuffer, _ := features["VkPhysicalDeviceDescriptorBufferFeaturesEXT"].(VkPhysicalDeviceDescriptorBufferFeaturesEXT)
+
+	f.depthBiasClamp = core.DepthBiasClamp
+	f.depthBounds = core.DepthBounds
+	f.depthClamp = core.DepthClamp && eds3.ExtendedDynamicState3DepthClampEnable
+	f.logicOp = core.LogicOp && eds3.ExtendedDynamicState3LogicOpEnable
+	f.alphaToCoverage = eds3.ExtendedDynamicState3AlphaToCoverageEnable
+	f.sampleMask = eds3.ExtendedDynamicState3SampleMask
+	f.multiview = vk11.Multiview
+
+	f.multiDrawIndirect = core.MultiDrawIndirect
+	f.drawIndirectCount = vk12.DrawIndirectCount
+
+	f.hostQueryReset = vk12.HostQueryReset
+	f.pipelineStatisticsQuery = core.PipelineStatisticsQuery
+	f.occlusionQueryPrecise = core.OcclusionQueryPrecise
+	f.conditionalRendering = conditionalRendering.ConditionalRendering
+
+	f.descriptorBuffer = descriptorBuffer.DescriptorBuffer
+	// with descriptor buffers push descriptors need their own feature on top of the extension
+	f.pushDescriptor = (vk14.PushDescriptor || slices.Contains(extensions, "VK_KHR_push_descriptor")) &&
+		(!f.descriptorBuffer || descriptorBuffer.DescriptorBufferPushDescriptors)
+	if f.pushDescriptor {
+		var maxPushDescriptors C.uint32_t
+		C.vxr_vk_device_getMaxPushDescriptors(instance.cInstance, &maxPushDescriptors)
+		f.maxPushDescriptors = uint32(maxPushDescriptors)
+	}
+
+	f.hdrMetadata = slices.Contains(extensions, "VK_EXT_hdr_metadata")
+}
+
+func (f *graphicsFeatures) cOptionalDynamicStates() C.vxr_vk_graphics_optionalDynamicStates {
+	var states C.vxr_vk_graphics_optionalDynamicStates
+	if f.depthClamp {
+		states.depthClampEnable = vk.TRUE
+	}
+	if f.logicOp {
+		states.logicOpEnable = vk.TRUE
+	}
+	if f.alphaToCoverage {
+		states.alphaToCoverageEnable = vk.TRUE
+	}
+	if f.sampleMask {
+		states.sampleMask = vk.TRUE
+	}
+	return states
+}
+
 type graphicsState struct {
+	features      graphicsFeatures
 	pipelineCache graphicsPipelineCache
 
 	frameStarted   bool
 	frameIndex     int
 	framesInFlight []frame
+	// frameSerial is the serial of the last frame to begin, completedFrame that of the last frame the GPU finished.
+	frameSerial    uint64
+	completedFrame atomic.Uint64
 
 	destroyerChan chan Destroyer
+	frameStats    frameStats
 }
 
 func (c *graphicsPipelineCache) MarshalJSON() ([]byte, error) {
@@ -58,9 +202,22 @@ func (c *graphicsPipelineCache) MarshalJSON() ([]byte, error) {
 	buff.WriteString("{")
 
 	{
-		buff.WriteString("\"cache\": {")
-		err := mapRunFuncSorted(c.cache, func(k string, v C.VkPipeline) error {
-			buff.WriteString(fmt.Sprintf("%q: %q,", k, toHex(v)))
+		buff.WriteString("\"libraries\": {")
+		err := mapRunFuncSorted(c.libraries, func(k hashKey, b hashBucket[pipelineLibraryKey, cachedPipeline]) error {
+			for i, e := range b {
+				buff.WriteString(fmt.Sprintf("%q: %s,", bucketKeyString(k, i), jsonString(e.value)))
+			}
+			return nil
+		})
+		if err == nil {
+			buff.Truncate(buff.Len() - 1)
+		}
+		buff.WriteString("},")
+	}
+	{
+		buff.WriteString("\"executables\": {")
+		err := mapRunFuncStringSorted(c.executables, func(k executablePipelineKey, v cachedPipeline) error {
+			buff.WriteString(fmt.Sprintf("%q: %s,", k.String(), jsonString(v)))
 			return nil
 		})
 		if err == nil {
@@ -84,37 +241,52 @@ func (c *graphicsPipelineCache) MarshalJSON() ([]byte, error) {
 	return buff.Bytes(), nil
 }
 
-func (c *graphicsPipelineCache) destroyPipeline(id string, pipeline C.VkPipeline) {
-	c.mtx.Lock()
-	maps.DeleteFunc(c.cache, func(k string, v C.VkPipeline) bool {
-		destroy := strings.Contains(k, id)
-		if destroy {
-			go func() {
-				instance.graphics.destroyerChan <- destroyFunc{
-					func() {
-						instance.logger.VPrintf("Destroying pipeline: %s", k)
-						C.vxr_vk_shader_destroyPipeline(instance.cInstance, v)
-					},
-				}
-			}()
+// removeExecutable must be called with mtx held.
+func (c *graphicsPipelineCache) removeExecutable(key executablePipelineKey) {
+	p, ok := c.executables[key]
+	if !ok {
+		return
+	}
+	delete(c.executables, key)
+	for _, l := range key.libraries() {
+		if deps, ok := c.dependents[l]; ok {
+			delete(deps, key)
+			if len(deps) == 0 {
+				delete(c.dependents, l)
+			}
 		}
-		return destroy
-	})
+	}
+	go func() {
+		instance.graphics.destroyerChan <- destroyFunc{
+			func() {
+				instance.logger.VPrintf("Destroying pipeline: %s", p.name)
+				C.vxr_vk_shader_destroyPipeline(instance.cInstance, p.vkPipeline)
+			},
+		}
+	}()
+}
+
+func (c *graphicsPipelineCache) destroyPipeline(name string, pipeline C.VkPipeline) {
+	c.mtx.Lock()
+	for k := range c.dependents[pipeline] {
+		c.removeExecutable(k)
+	}
+	delete(c.dependents, pipeline)
 	c.mtx.Unlock()
 
 	go func() {
 		instance.graphics.destroyerChan <- destroyFunc{
 			func() {
-				instance.logger.VPrintf("Destroying pipeline: %s", id)
+				instance.logger.VPrintf("Destroying pipeline: %s", name)
 				C.vxr_vk_shader_destroyPipeline(instance.cInstance, pipeline)
 			},
 		}
 	}()
 }
 
-func (c *graphicsPipelineCache) createOrRetrievePipeline(id string, f func() C.VkPipeline) C.VkPipeline {
+func (c *graphicsPipelineCache) createOrRetrievePipeline(hash hashKey, key pipelineLibraryKey, f func() cachedPipeline) cachedPipeline {
 	c.mtx.RLock()
-	pipeline, ok := c.cache[id]
+	pipeline, ok := c.libraries[hash].find(key)
 	c.mtx.RUnlock()
 	if ok {
 		return pipeline
@@ -122,56 +294,76 @@ func (c *graphicsPipelineCache) createOrRetrievePipeline(id string, f func() C.V
 
 	pipeline = f()
 	c.mtx.Lock()
-	if c.cache[id] == nil {
-		c.cache[id] = pipeline
+	if existing, ok := c.libraries[hash].find(key); !ok {
+		key.colorFormats = slices.Clone(key.colorFormats)
+		c.libraries[hash] = append(c.libraries[hash], hashBucketEntry[pipelineLibraryKey, cachedPipeline]{key: key, value: pipeline})
 	} else {
-		defer C.vxr_vk_shader_destroyPipeline(instance.cInstance, pipeline)
-		pipeline = c.cache[id]
+		defer C.vxr_vk_shader_destroyPipeline(instance.cInstance, pipeline.vkPipeline)
+		pipeline = existing
 	}
 	c.mtx.Unlock()
 	return pipeline
 }
 
-func (c *graphicsPipelineCache) linkOrRetrieveExecutablePipeline(id, name string, layout C.VkPipelineLayout, pipelines []C.VkPipeline) C.VkPipeline {
+func (c *graphicsPipelineCache) linkOrRetrieveExecutablePipeline(key executablePipelineKey, layout C.VkPipelineLayout, nameFunc func() string) C.VkPipeline {
 	c.mtx.RLock()
-	pipeline, ok := c.cache[id]
+	pipeline, ok := c.executables[key]
 	c.mtx.RUnlock()
 	if ok {
-		return pipeline
+		return pipeline.vkPipeline
 	}
 
+	// everything below is only reached on a cache miss, keep the variables captured by
+	// the optimizing goroutine declared here so the hit path above stays allocation free
+	name := nameFunc()
+	pipelines := key.libraries()
+	var executable C.VkPipeline
 	optimized := C.vxr_vk_graphics_linkPipelines(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
-		layout, C.uint32_t(len(pipelines)), unsafe.SliceData(pipelines), &pipeline,
+		layout, C.uint32_t(len(pipelines)), &pipelines[0], &executable,
 	)
 	runtime.KeepAlive(name)
 	runtime.KeepAlive(pipelines)
 	c.mtx.Lock()
-	if c.cache[id] == nil {
-		c.cache[id] = pipeline
+	if existing, ok := c.executables[key]; !ok {
+		c.executables[key] = cachedPipeline{name: name, vkPipeline: executable}
+		for _, l := range pipelines {
+			deps, ok := c.dependents[l]
+			if !ok {
+				deps = map[executablePipelineKey]struct{}{}
+				c.dependents[l] = deps
+			}
+			deps[key] = struct{}{}
+		}
 		if optimized == vk.FALSE {
+			unoptimized := executable
 			go func() {
 				var optimized C.VkPipeline
 				C.vxr_vk_graphics_linkOptimizePipelines(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
-					layout, C.uint32_t(len(pipelines)), unsafe.SliceData(pipelines), &optimized,
+					layout, C.uint32_t(len(pipelines)), &pipelines[0], &optimized,
 				)
 				runtime.KeepAlive(name)
 				runtime.KeepAlive(pipelines)
 				c.mtx.Lock()
-				old := c.cache[id]
-				c.cache[id] = optimized
+				old := optimized
+				// the libraries may have been destroyed while we were optimizing and their handles reused by new libraries,
+				// so only replace the pipeline we linked, otherwise the optimized pipeline is discarded
+				if current, ok := c.executables[key]; ok && current.vkPipeline == unoptimized {
+					old = unoptimized
+					c.executables[key] = cachedPipeline{name: name, vkPipeline: optimized}
+				}
 				c.mtx.Unlock()
 				instance.graphics.destroyerChan <- destroyFunc{
 					func() {
-						instance.logger.VPrintf("Destroying unoptimized pipeline: %s", id)
+						instance.logger.VPrintf("Destroying unoptimized pipeline: %s", name)
 						C.vxr_vk_shader_destroyPipeline(instance.cInstance, old)
 					},
 				}
 			}()
 		}
 	} else {
-		defer C.vxr_vk_shader_destroyPipeline(instance.cInstance, pipeline)
-		pipeline = c.cache[id]
+		defer C.vxr_vk_shader_destroyPipeline(instance.cInstance, executable)
+		executable = existing.vkPipeline
 	}
 	c.mtx.Unlock()
-	return pipeline
+	return executable
 }
@@ -25,18 +25,20 @@ import "C"
 
 import (
 	"fmt"
+	"math/bits"
 	"runtime"
 	"strings"
 	"unsafe"
 
+	"goarrg.com/debug"
 	"goarrg.com/gmath"
 	"goarrg.com/rhi/vxr/internal/vk"
 )
 
 type renderPass struct {
-	id                     string
 	name                   string
 	numColorAttachments    int
+	viewMask               uint32
 	fragmentOutputPipeline C.VkPipeline
 }
 
@@ -45,6 +47,19 @@ type GraphicsCommandBuffer struct {
 
 	cFrame            C.vxr_vk_graphics_frame
 	currentRenderPass renderPass
+
+	occlusionQuery       occlusionQuery
+	conditionalRendering conditionalRenderingState
+
+	// recorder is set for command buffers created from a CommandRecorder,
+	// these are ended by End and submitted in batches by Frame.SubmitCommandBuffers.
+	recorder *CommandRecorder
+	ended    bool
+
+	// colorWriteMasks tracks the masks set for the current renderpass so they can be restored
+	// after a draw overrides them with DrawParameters.ColorWriteMasks.
+	colorWriteMasks           []C.VkColorComponentFlags
+	colorWriteMasksOverridden bool
 }
 
 type RenderParameters struct {
@@ -52,6 +67,64 @@ type RenderParameters struct {
 	// goarrg assumes a bottom left origin, so viewports are flipped by default,
 	// enable to flip again to use vulkan's default top left origin.
 	FlipViewport bool
+
+	// LogicOp is the logic op used by draws with DrawParameters.LogicOpEnable,
+	// it is baked into the renderpass as only enabling it can be dynamic.
+	LogicOp LogicOp
+
+	// ViewMask enables multiview rendering when != 0, each set bit renders to the attachment layer of the same index
+	// with gl_ViewIndex set to that index. All attachments must have enough array layers for the highest set bit
+	// and the pipelines drawn must be created with the same GraphicsShaderPipelineCreateInfo.ViewMask.
+	// Requires Config.Multiview.
+	ViewMask uint32
+}
+
+func (p *RenderParameters) validate(attachments RenderAttachments) error {
+	if p.ViewMask == 0 {
+		return nil
+	}
+	if !instance.graphics.features.multiview {
+		return debug.Errorf("RenderParameters.ViewMask [%#b] requires Config.Multiview", p.ViewMask)
+	}
+	numViews := int32(bits.Len32(p.ViewMask))
+	if uint32(numViews) > instance.deviceProperties.Limits.Multiview.MaxViewCount {
+		return debug.Errorf("RenderParameters.ViewMask [%#b] uses views beyond DeviceProperties.Limits.Multiview.MaxViewCount [%d]",
+			p.ViewMask, instance.deviceProperties.Limits.Multiview.MaxViewCount)
+	}
+	validateLayers := func(attachment string, img Image) error {
+		if img != nil && img.numArrayLayers() < numViews {
+			return debug.Errorf("%s has [%d] array layers but RenderParameters.ViewMask [%#b] requires at least [%d]",
+				attachment, img.numArrayLayers(), p.ViewMask, numViews)
+		}
+		return nil
+	}
+	for i, attachment := range attachments.Color {
+		if err := validateLayers(fmt.Sprintf("RenderAttachments.Color[%d].Image", i), attachment.Image); err != nil {
+			return err
+		}
+		if attachment.ImageMultiSampled != nil {
+			if err := validateLayers(fmt.Sprintf("RenderAttachments.Color[%d].ImageMultiSampled", i), attachment.ImageMultiSampled); err != nil {
+				return err
+			}
+		}
+	}
+	if err := validateLayers("RenderAttachments.Depth.Image", attachments.Depth.Image); err != nil {
+		return err
+	}
+	if attachments.Depth.ImageMultiSampled != nil {
+		if err := validateLayers("RenderAttachments.Depth.ImageMultiSampled", attachments.Depth.ImageMultiSampled); err != nil {
+			return err
+		}
+	}
+	if err := validateLayers("RenderAttachments.Stencil.Image", attachments.Stencil.Image); err != nil {
+		return err
+	}
+	if attachments.Stencil.ImageMultiSampled != nil {
+		if err := validateLayers("RenderAttachments.Stencil.ImageMultiSampled", attachments.Stencil.ImageMultiSampled); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 type RenderAttachmentLoadOp C.VkAttachmentLoadOp
@@ -189,7 +262,11 @@ type RenderAttachments struct {
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32, parameters RenderParameters, attachments RenderAttachments) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.queue != QueueGraphics {
+		abort("RenderPassBegin called on a command buffer for %s", cb.queue)
+	}
 	if cb.currentRenderPass != (renderPass{}) {
 		abort("RenderPassBegin called when there's an active renderpass")
 	}
@@ -200,6 +277,9 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 		(attachments.Depth.ImageMultiSampled != attachments.Stencil.ImageMultiSampled) {
 		abort("Depth and Stencil ImageViews must be the same if both are not nil")
 	}
+	if err := parameters.validate(attachments); err != nil {
+		abort("Failed to validate RenderParameters: %s", err)
+	}
 
 	cAttachments := make([]C.VkRenderingAttachmentInfo, len(attachments.Color))
 	cColorBlendEnable := make([]C.VkBool32, len(attachments.Color))
@@ -207,10 +287,6 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 	cColorComponentFlags := make([]C.VkColorComponentFlags, len(attachments.Color))
 	vkColorFormats := make([]C.VkFormat, len(attachments.Color))
 
-	if len(attachments.Color) == 0 {
-		cb.currentRenderPass.id = "null,"
-		cb.currentRenderPass.name = "null,"
-	}
 	var sampleCount SampleCountFlags
 	if len(attachments.Color) > 0 {
 		sampleCount = attachments.Color[0].ImageMultiSampled.sampleCount()
@@ -239,6 +315,12 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 					storeOp:     C.VkAttachmentStoreOp(attachment.StoreOp),
 				}
 			}
+			if sampleCount > 0 {
+				cb.track(attachment.ImageMultiSampled)
+				cb.hazards.write(attachment.ImageMultiSampled, PipelineStageRenderAttachmentWrite)
+			}
+			cb.track(attachment.Image)
+			cb.hazards.write(attachment.Image, PipelineStageRenderAttachmentWrite)
 			if attachment.ColorBlend.Enable {
 				cColorBlendEnable[i] = vk.TRUE
 			}
@@ -253,8 +335,6 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 			}
 			cColorComponentFlags[i] = C.VkColorComponentFlags(attachment.ColorBlend.ComponentFlags)
 			vkColorFormats[i] = attachments.Color[i].Image.vkFormat()
-			cb.currentRenderPass.id += fmt.Sprintf("%s,", toHex(vkColorFormats[i]))
-			cb.currentRenderPass.name += fmt.Sprintf("%s,", attachments.Color[i].Image.Format().String())
 			if attachment.LoadOp == RenderAttachmentLoadOpClear && attachments.Color[i].ClearValue != nil {
 				cAttachments[i].clearValue = attachments.Color[i].ClearValue.vkClearValue()
 			}
@@ -270,6 +350,7 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 					extent: C.VkExtent2D{C.uint32_t(area.W), C.uint32_t(area.H)},
 				},
 				layerCount:           1,
+				viewMask:             C.uint32_t(parameters.ViewMask),
 				colorAttachmentCount: C.uint32_t(len(attachments.Color)),
 				pColorAttachments:    unsafe.SliceData(cAttachments),
 			},
@@ -311,10 +392,14 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 					clearValue:  attachments.Depth.ClearValue.vkClearValue(),
 				}
 			}
+			if sampleCount > 0 {
+				cb.track(attachments.Depth.ImageMultiSampled)
+				cb.hazards.write(attachments.Depth.ImageMultiSampled, PipelineStageFragmentTests)
+			}
+			cb.track(attachments.Depth.Image)
+			cb.hazards.write(attachments.Depth.Image, PipelineStageFragmentTests)
 			defer runtime.KeepAlive(depthAttachment)
 			cInfo.renderingInfo.pDepthAttachment = depthAttachment
-			cb.currentRenderPass.id += fmt.Sprintf("%s,", toHex(attachments.Depth.Image.vkFormat()))
-			cb.currentRenderPass.name += fmt.Sprintf("%s,", attachments.Depth.Image.Format().String())
 		}
 		//nolint: dupl
 		if attachments.Stencil.Image != nil {
@@ -344,10 +429,19 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 					clearValue:  attachments.Stencil.ClearValue.vkClearValue(),
 				}
 			}
+			if sampleCount > 0 {
+				cb.track(attachments.Stencil.ImageMultiSampled)
+			}
+			cb.track(attachments.Stencil.Image)
+			// a combined depth stencil image was already written as the depth attachment
+			if attachments.Stencil.Image != attachments.Depth.Image {
+				if sampleCount > 0 {
+					cb.hazards.write(attachments.Stencil.ImageMultiSampled, PipelineStageFragmentTests)
+				}
+				cb.hazards.write(attachments.Stencil.Image, PipelineStageFragmentTests)
+			}
 			defer runtime.KeepAlive(stencilAttachment)
 			cInfo.renderingInfo.pStencilAttachment = stencilAttachment
-			cb.currentRenderPass.id += fmt.Sprintf("%s,", toHex(attachments.Stencil.Image.vkFormat()))
-			cb.currentRenderPass.name += fmt.Sprintf("%s,", attachments.Stencil.Image.Format().String())
 		}
 
 		C.vxr_vk_graphics_renderPassBegin(instance.cInstance, cb.vkCommandBuffer,
@@ -360,32 +454,77 @@ func (cb *GraphicsCommandBuffer) RenderPassBegin(name string, area gmath.Recti32
 	}
 
 	{
-		cb.currentRenderPass.id = fmt.Sprintf("[fragment_output:[%s]]", strings.TrimSuffix(cb.currentRenderPass.id, ","))
-		cb.currentRenderPass.name = fmt.Sprintf("[%s]", strings.TrimSuffix(cb.currentRenderPass.name, ","))
-		cb.currentRenderPass.numColorAttachments = len(attachments.Color)
-		cb.currentRenderPass.fragmentOutputPipeline = instance.graphics.pipelineCache.createOrRetrievePipeline(cb.currentRenderPass.id, func() C.VkPipeline {
-			cInfo := C.vxr_vk_graphics_fragmentOutputPipelineCreateInfo{
-				numColorAttachments:    C.uint32_t(len(attachments.Color)),
-				colorAttachmentFormats: unsafe.SliceData(vkColorFormats),
+		var depthFormat, stencilFormat C.VkFormat
+		if attachments.Depth.Image != nil {
+			depthFormat = attachments.Depth.Image.vkFormat()
+		}
+		if attachments.Stencil.Image != nil {
+			stencilFormat = attachments.Stencil.Image.vkFormat()
+		}
+		h := newHasher()
+		h.writeString("fragment_output")
+		h.writeUint32(uint32(len(vkColorFormats)))
+		for _, f := range vkColorFormats {
+			h.writeUint32(uint32(f))
+		}
+		h.writeUint32(uint32(depthFormat))
+		h.writeUint32(uint32(stencilFormat))
+		h.writeUint32(uint32(parameters.LogicOp))
+		h.writeUint32(parameters.ViewMask)
+
+		key := pipelineLibraryKey{
+			name:          "fragment_output",
+			colorFormats:  vkColorFormats,
+			depthFormat:   depthFormat,
+			stencilFormat: stencilFormat,
+			logicOp:       C.VkLogicOp(parameters.LogicOp),
+			viewMask:      parameters.ViewMask,
+		}
+		fragmentOutput := instance.graphics.pipelineCache.createOrRetrievePipeline(h.sum, key, func() cachedPipeline {
+			formats := make([]string, 0, len(attachments.Color)+2)
+			if len(attachments.Color) == 0 {
+				formats = append(formats, "null")
+			}
+			for _, attachment := range attachments.Color {
+				formats = append(formats, attachment.Image.Format().String())
 			}
 			if attachments.Depth.Image != nil {
-				cInfo.depthFormat = attachments.Depth.Image.vkFormat()
+				formats = append(formats, attachments.Depth.Image.Format().String())
 			}
 			if attachments.Stencil.Image != nil {
-				cInfo.stencilFormat = attachments.Stencil.Image.vkFormat()
+				formats = append(formats, attachments.Stencil.Image.Format().String())
+			}
+			p := cachedPipeline{name: "[" + strings.Join(formats, ",") + "]"}
+			cInfo := C.vxr_vk_graphics_fragmentOutputPipelineCreateInfo{
+				numColorAttachments:    C.uint32_t(len(attachments.Color)),
+				colorAttachmentFormats: unsafe.SliceData(vkColorFormats),
+				depthFormat:            depthFormat,
+				stencilFormat:          stencilFormat,
+				logicOp:                C.VkLogicOp(parameters.LogicOp),
+				viewMask:               C.uint32_t(parameters.ViewMask),
+
+				optionalDynamicStates: instance.graphics.features.cOptionalDynamicStates(),
 			}
 			C.vxr_vk_graphics_createFragmentOutputPipeline(instance.cInstance,
-				C.size_t(len(cb.currentRenderPass.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(cb.currentRenderPass.name))),
-				cInfo, &cb.currentRenderPass.fragmentOutputPipeline)
+				C.size_t(len(p.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(p.name))),
+				cInfo, &p.vkPipeline)
+			runtime.KeepAlive(p.name)
 			runtime.KeepAlive(vkColorFormats)
-			return cb.currentRenderPass.fragmentOutputPipeline
+			return p
 		})
-		cb.currentRenderPass.id = genID(cb.currentRenderPass.fragmentOutputPipeline)
+		cb.currentRenderPass.name = fragmentOutput.name
+		cb.currentRenderPass.numColorAttachments = len(attachments.Color)
+		cb.currentRenderPass.viewMask = parameters.ViewMask
+		cb.currentRenderPass.fragmentOutputPipeline = fragmentOutput.vkPipeline
+		cb.renderPassViews = max(1, uint32(bits.OnesCount32(parameters.ViewMask)))
+		cb.colorWriteMasks = cColorComponentFlags
+		cb.colorWriteMasksOverridden = false
 	}
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassSetViewport(flip bool, viewport gmath.Recti32) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("RenderPassSetViewport called outside a renderpass")
 	}
@@ -402,7 +541,8 @@ func (cb *GraphicsCommandBuffer) RenderPassSetViewport(flip bool, viewport gmath
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassSetScissor(rect gmath.Recti32) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("RenderPassSetScissor called outside a renderpass")
 	}
@@ -414,7 +554,8 @@ func (cb *GraphicsCommandBuffer) RenderPassSetScissor(rect gmath.Recti32) {
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassSetViewportAndScissor(flip bool, viewport gmath.Recti32, rect gmath.Recti32) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("RenderPassSetViewportAndScissor called outside a renderpass")
 	}
@@ -435,7 +576,8 @@ func (cb *GraphicsCommandBuffer) RenderPassSetViewportAndScissor(flip bool, view
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassSetColorBlendParameters(firstAttachment int, infos []RenderColorBlendParameters) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("RenderPassSetColorBlendParameters called outside a renderpass")
 	}
@@ -461,6 +603,7 @@ func (cb *GraphicsCommandBuffer) RenderPassSetColorBlendParameters(firstAttachme
 		}
 		cColorComponentFlags[i] = C.VkColorComponentFlags(info.ComponentFlags)
 	}
+	copy(cb.colorWriteMasks[firstAttachment:], cColorComponentFlags)
 
 	cInfo := C.vxr_vk_graphics_colorBlendInfo{
 		enable:         unsafe.SliceData(cColorBlendEnable),
@@ -471,7 +614,8 @@ func (cb *GraphicsCommandBuffer) RenderPassSetColorBlendParameters(firstAttachme
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassSetLineWidth(width float32) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("RenderPassSetLineWidth called outside a renderpass")
 	}
@@ -532,6 +676,27 @@ const (
 	StencilOpDecrementAndWrap  StencilOp = vk.STENCIL_OP_DECREMENT_AND_WRAP
 )
 
+type LogicOp C.VkLogicOp
+
+const (
+	LogicOpClear        LogicOp = vk.LOGIC_OP_CLEAR
+	LogicOpAnd          LogicOp = vk.LOGIC_OP_AND
+	LogicOpAndReverse   LogicOp = vk.LOGIC_OP_AND_REVERSE
+	LogicOpCopy         LogicOp = vk.LOGIC_OP_COPY
+	LogicOpAndInverted  LogicOp = vk.LOGIC_OP_AND_INVERTED
+	LogicOpNoOp         LogicOp = vk.LOGIC_OP_NO_OP
+	LogicOpXor          LogicOp = vk.LOGIC_OP_XOR
+	LogicOpOr           LogicOp = vk.LOGIC_OP_OR
+	LogicOpNor          LogicOp = vk.LOGIC_OP_NOR
+	LogicOpEquivalent   LogicOp = vk.LOGIC_OP_EQUIVALENT
+	LogicOpInvert       LogicOp = vk.LOGIC_OP_INVERT
+	LogicOpOrReverse    LogicOp = vk.LOGIC_OP_OR_REVERSE
+	LogicOpCopyInverted LogicOp = vk.LOGIC_OP_COPY_INVERTED
+	LogicOpOrInverted   LogicOp = vk.LOGIC_OP_OR_INVERTED
+	LogicOpNand         LogicOp = vk.LOGIC_OP_NAND
+	LogicOpSet          LogicOp = vk.LOGIC_OP_SET
+)
+
 type StencilTestParameters struct {
 	FailOp      StencilOp
 	PassOp      StencilOp
@@ -545,6 +710,8 @@ type StencilTestParameters struct {
 type DrawParameters struct {
 	PushConstants  []byte
 	DescriptorSets []*DescriptorSet
+	// PushDescriptors are indexed by binding of the layout's push descriptor set.
+	PushDescriptors [][]DescriptorInfo
 
 	PolygonMode PolygonMode
 
@@ -558,6 +725,74 @@ type DrawParameters struct {
 	StencilTestEnable          bool
 	StencilFrontFaceParameters StencilTestParameters
 	StencilBackFaceParameters  StencilTestParameters
+
+	// DepthBias.Clamp != 0 requires VkPhysicalDeviceFeatures.DepthBiasClamp
+	DepthBiasEnable bool
+	DepthBias       DepthBiasParameters
+
+	// requires VkPhysicalDeviceFeatures.DepthBounds
+	DepthBoundsTestEnable bool
+	DepthBounds           gmath.Bounds[float32]
+
+	// requires VkPhysicalDeviceFeatures.DepthClamp and
+	// VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3DepthClampEnable
+	DepthClampEnable bool
+
+	BlendConstants [4]float32
+
+	// requires VkPhysicalDeviceFeatures.LogicOp and
+	// VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3LogicOpEnable,
+	// the op itself is RenderParameters.LogicOp
+	LogicOpEnable bool
+
+	// ColorWriteMasks overrides the write masks of the color attachments starting from the first for this draw only,
+	// nil keeps the masks set with RenderPassBegin or RenderPassSetColorBlendParameters.
+	ColorWriteMasks []ColorComponentFlags
+
+	// requires VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3AlphaToCoverageEnable
+	AlphaToCoverageEnable bool
+
+	// requires VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3SampleMask,
+	// bit N of SampleMask enables sample N, all samples are enabled when SampleMaskEnable is false
+	SampleMaskEnable bool
+	SampleMask       uint64
+}
+
+type DepthBiasParameters struct {
+	ConstantFactor float32
+	Clamp          float32
+	SlopeFactor    float32
+}
+
+func (p *DrawParameters) validate(numColorAttachments int) error {
+	features := &instance.graphics.features
+	if p.DepthBiasEnable && p.DepthBias.Clamp != 0 && !features.depthBiasClamp {
+		return debug.Errorf("DepthBias.Clamp [%f] != 0 requires VkPhysicalDeviceFeatures.DepthBiasClamp", p.DepthBias.Clamp)
+	}
+	if p.DepthBoundsTestEnable {
+		if !features.depthBounds {
+			return debug.Errorf("DepthBoundsTestEnable requires VkPhysicalDeviceFeatures.DepthBounds")
+		}
+		if !gmath.InRange(p.DepthBounds.Min, 0, 1) || !gmath.InRange(p.DepthBounds.Max, 0, 1) || (p.DepthBounds.Min > p.DepthBounds.Max) {
+			return debug.Errorf("DepthBounds [%+v] must be within [0, 1] and Min must be <= Max", p.DepthBounds)
+		}
+	}
+	if p.DepthClampEnable && !features.depthClamp {
+		return debug.Errorf("DepthClampEnable requires VkPhysicalDeviceFeatures.DepthClamp and VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3DepthClampEnable")
+	}
+	if p.LogicOpEnable && !features.logicOp {
+		return debug.Errorf("LogicOpEnable requires VkPhysicalDeviceFeatures.LogicOp and VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3LogicOpEnable")
+	}
+	if len(p.ColorWriteMasks) > numColorAttachments {
+		return debug.Errorf("ColorWriteMasks has more masks [%d] than there are color attachments [%d]", len(p.ColorWriteMasks), numColorAttachments)
+	}
+	if p.AlphaToCoverageEnable && !features.alphaToCoverage {
+		return debug.Errorf("AlphaToCoverageEnable requires VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3AlphaToCoverageEnable")
+	}
+	if p.SampleMaskEnable && !features.sampleMask {
+		return debug.Errorf("SampleMaskEnable requires VkPhysicalDeviceExtendedDynamicState3FeaturesEXT.ExtendedDynamicState3SampleMask")
+	}
+	return nil
 }
 
 func (cb *GraphicsCommandBuffer) draw(p GraphicsPipelineLibrary, info DrawParameters, fn func(C.vxr_vk_graphics_drawParameters)) {
@@ -566,26 +801,31 @@ func (cb *GraphicsCommandBuffer) draw(p GraphicsPipelineLibrary, info DrawParame
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("Draw called outside a renderpass")
 	}
-	if err := p.validate(); err != nil {
+	if err := p.validate(cb.currentRenderPass.viewMask); err != nil {
 		abort("Failed to validate GraphicsPipeline: %s", err)
 	}
-	if err := p.Layout.cmdValidate(info.PushConstants, info.DescriptorSets); err != nil {
+	if err := p.Layout.cmdValidate(info.PushConstants, info.DescriptorSets, info.PushDescriptors); err != nil {
+		abort("Failed to validate DrawParameters: %s", err)
+	}
+	if err := info.validate(cb.currentRenderPass.numColorAttachments); err != nil {
 		abort("Failed to validate DrawParameters: %s", err)
 	}
 
-	descriptorSets := make([]C.VkDescriptorSet, 0, len(info.DescriptorSets))
+	pinner := runtime.Pinner{}
+	defer pinner.Unpin()
+	descriptorSets, descriptorBufferOffsets := p.Layout.vkDescriptorSets(info.DescriptorSets)
 	defer runtime.KeepAlive(descriptorSets)
-	for _, s := range info.DescriptorSets {
-		s.noCopy.Check()
-		descriptorSets = append(descriptorSets, s.cDescriptorSet)
-	}
+	defer runtime.KeepAlive(descriptorBufferOffsets)
+	cb.bindDescriptorBuffer()
+	cb.trackBindings(info.DescriptorSets, info.PushDescriptors)
+	cb.hazards.bindings(p.Layout, info.DescriptorSets, info.PushDescriptors)
 
-	id := chainIDs(p.VertexInput.id, p.VertexShader.id, p.FragmentShader.id, cb.currentRenderPass.id)
-	name := chainIDs(p.VertexInput.name, p.VertexShader.name, p.FragmentShader.name, cb.currentRenderPass.name)
+	key := newExecutablePipelineKey(p, cb.currentRenderPass.fragmentOutputPipeline)
 	cParameters := C.vxr_vk_graphics_drawParameters{
 		layout: p.Layout.vkPipelinelayout,
-		pipeline: instance.graphics.pipelineCache.linkOrRetrieveExecutablePipeline(id, name, p.Layout.vkPipelinelayout,
-			[]C.VkPipeline{p.VertexInput.vkPipeline, p.VertexShader.vkPipeline, p.FragmentShader.vkPipeline, cb.currentRenderPass.fragmentOutputPipeline}),
+		pipeline: instance.graphics.pipelineCache.linkOrRetrieveExecutablePipeline(key, p.Layout.vkPipelinelayout, func() string {
+			return p.VertexInput.name + p.VertexShader.name + p.FragmentShader.name + cb.currentRenderPass.name
+		}),
 
 		topology:    p.VertexInput.topology,
 		polygonMode: C.VkPolygonMode(info.PolygonMode),
@@ -614,8 +854,26 @@ func (cb *GraphicsCommandBuffer) draw(p GraphicsPipelineLibrary, info DrawParame
 			reference:   C.uint32_t(info.StencilBackFaceParameters.Reference),
 		},
 
-		numDescriptorSets: C.uint32_t(len(descriptorSets)),
-		descriptorSets:    unsafe.SliceData(descriptorSets),
+		depthBiasConstantFactor: C.float(info.DepthBias.ConstantFactor),
+		depthBiasClamp:          C.float(info.DepthBias.Clamp),
+		depthBiasSlopeFactor:    C.float(info.DepthBias.SlopeFactor),
+
+		minDepthBounds: C.float(info.DepthBounds.Min),
+		maxDepthBounds: C.float(info.DepthBounds.Max),
+
+		blendConstants: [4]C.float{
+			C.float(info.BlendConstants[0]), C.float(info.BlendConstants[1]),
+			C.float(info.BlendConstants[2]), C.float(info.BlendConstants[3]),
+		},
+
+		sampleMask: [2]C.VkSampleMask{C.VkSampleMask(^uint32(0)), C.VkSampleMask(^uint32(0))},
+
+		optionalDynamicStates: instance.graphics.features.cOptionalDynamicStates(),
+
+		numDescriptorSets:       C.uint32_t(len(descriptorSets)),
+		descriptorSets:          unsafe.SliceData(descriptorSets),
+		descriptorBufferOffsets: unsafe.SliceData(descriptorBufferOffsets),
+		pushDescriptors:         p.Layout.vkPushDescriptorSet(&pinner, info.PushDescriptors),
 	}
 
 	if p.Layout.pushConstantRange.size > 0 {
@@ -632,6 +890,39 @@ func (cb *GraphicsCommandBuffer) draw(p GraphicsPipelineLibrary, info DrawParame
 	if info.StencilTestEnable {
 		cParameters.stencilTestEnable = vk.TRUE
 	}
+	if info.DepthBiasEnable {
+		cParameters.depthBiasEnable = vk.TRUE
+	}
+	if info.DepthBoundsTestEnable {
+		cParameters.depthBoundsTestEnable = vk.TRUE
+	}
+	if info.DepthClampEnable {
+		cParameters.depthClampEnable = vk.TRUE
+	}
+	if info.LogicOpEnable {
+		cParameters.logicOpEnable = vk.TRUE
+	}
+	if info.AlphaToCoverageEnable {
+		cParameters.alphaToCoverageEnable = vk.TRUE
+	}
+	if info.SampleMaskEnable {
+		cParameters.sampleMask = [2]C.VkSampleMask{C.VkSampleMask(info.SampleMask), C.VkSampleMask(info.SampleMask >> 32)}
+	}
+	if len(info.ColorWriteMasks) > 0 {
+		colorWriteMasks := make([]C.VkColorComponentFlags, len(info.ColorWriteMasks))
+		defer runtime.KeepAlive(colorWriteMasks)
+		for i, m := range info.ColorWriteMasks {
+			colorWriteMasks[i] = C.VkColorComponentFlags(m)
+		}
+		cParameters.numColorWriteMasks = C.uint32_t(len(colorWriteMasks))
+		cParameters.colorWriteMasks = unsafe.SliceData(colorWriteMasks)
+		cb.colorWriteMasksOverridden = true
+	} else if cb.colorWriteMasksOverridden {
+		defer runtime.KeepAlive(cb.colorWriteMasks)
+		cParameters.numColorWriteMasks = C.uint32_t(len(cb.colorWriteMasks))
+		cParameters.colorWriteMasks = unsafe.SliceData(cb.colorWriteMasks)
+		cb.colorWriteMasksOverridden = false
+	}
 	fn(cParameters)
 }
 
@@ -643,7 +934,8 @@ type DrawInfo struct {
 }
 
 func (cb *GraphicsCommandBuffer) Draw(p GraphicsPipelineLibrary, info DrawInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	cb.draw(p, info.DrawParameters, func(cParmameters C.vxr_vk_graphics_drawParameters) {
 		cInfo := C.vxr_vk_graphics_drawInfo{
 			parameters: cParmameters,
@@ -657,23 +949,76 @@ func (cb *GraphicsCommandBuffer) Draw(p GraphicsPipelineLibrary, info DrawInfo)
 }
 
 type DrawIndirectBufferInfo struct {
-	Buffer    Buffer
-	Offset    uint64
+	Buffer Buffer
+	Offset uint64
+	// DrawCount is the number of commands read from Buffer, or the max number when used with a count buffer.
+	// Must be <= DeviceProperties.Limits.Indirect.MaxDrawCount and values > 1 without a count buffer
+	// require VkPhysicalDeviceFeatures.MultiDrawIndirect.
 	DrawCount uint32
+	// Stride is the byte distance between consecutive commands, 0 means tightly packed.
+	Stride uint32
 }
 
-func (i DrawIndirectBufferInfo) cIndirectBufferInfo() C.vxr_vk_graphics_drawIndirectBufferInfo {
+func (i DrawIndirectBufferInfo) cIndirectBufferInfo(commandSize uint64, hasCountBuffer bool) C.vxr_vk_graphics_drawIndirectBufferInfo {
 	if !i.Buffer.Usage().HasBits(BufferUsageIndirectBuffer) {
 		abort("DrawIndirectBufferInfo.Buffer was not created with BufferUsageIndirectBuffer")
 	}
-	if (i.Buffer.Size() - i.Offset) < (uint64(i.DrawCount) * uint64(unsafe.Sizeof(C.VkDrawIndirectCommand{}))) {
-		abort("DrawIndirectBufferInfo.Offset + (DrawIndirectBufferInfo.DrawCount * sizeof(VkDrawIndirectCommand)) [%d + (%d * %d)] overflows buffer [%d]",
-			i.Offset, i.DrawCount, unsafe.Sizeof(C.VkDrawIndirectCommand{}), i.Buffer.Size())
+	if (i.Offset % 4) != 0 {
+		abort("DrawIndirectBufferInfo.Offset [%d] is not a multiple of 4", i.Offset)
+	}
+	if i.DrawCount > instance.deviceProperties.Limits.Indirect.MaxDrawCount {
+		abort("DrawIndirectBufferInfo.DrawCount [%d] is greater than DeviceProperties.Limits.Indirect.MaxDrawCount [%d]",
+			i.DrawCount, instance.deviceProperties.Limits.Indirect.MaxDrawCount)
+	}
+	if !hasCountBuffer && i.DrawCount > 1 && !instance.graphics.features.multiDrawIndirect {
+		abort("DrawIndirectBufferInfo.DrawCount [%d] > 1 requires VkPhysicalDeviceFeatures.MultiDrawIndirect", i.DrawCount)
+	}
+	stride := uint64(i.Stride)
+	if stride == 0 {
+		stride = commandSize
+	} else if (stride%4) != 0 || stride < commandSize {
+		abort("DrawIndirectBufferInfo.Stride [%d] must be a multiple of 4 and >= the size of the indirect command [%d]",
+			i.Stride, commandSize)
+	}
+	if i.DrawCount > 0 {
+		sz := (uint64(i.DrawCount-1) * stride) + commandSize
+		if i.Offset > i.Buffer.Size() || (i.Buffer.Size()-i.Offset) < sz {
+			abort("DrawIndirectBufferInfo.Offset + ((DrawIndirectBufferInfo.DrawCount - 1) * Stride) + sizeof(command) [%d + (%d * %d) + %d] overflows buffer [%d]",
+				i.Offset, i.DrawCount-1, stride, commandSize, i.Buffer.Size())
+		}
 	}
 	return C.vxr_vk_graphics_drawIndirectBufferInfo{
 		vkBuffer:  i.Buffer.vkBuffer(),
 		offset:    C.VkDeviceSize(i.Offset),
 		drawCount: C.uint32_t(i.DrawCount),
+		stride:    C.uint32_t(stride),
+	}
+}
+
+// DrawIndirectCountBufferInfo points to the uint32 holding the number of draws, requires VkPhysicalDeviceVulkan12Features.DrawIndirectCount.
+// The actual number of draws is the min of the value in the buffer and DrawIndirectBufferInfo.DrawCount.
+type DrawIndirectCountBufferInfo struct {
+	Buffer Buffer
+	Offset uint64
+}
+
+func (i DrawIndirectCountBufferInfo) cCountBufferInfo() C.vxr_vk_graphics_drawIndirectCountBufferInfo {
+	if !instance.graphics.features.drawIndirectCount {
+		abort("Indirect count draws require VkPhysicalDeviceVulkan12Features.DrawIndirectCount")
+	}
+	if !i.Buffer.Usage().HasBits(BufferUsageIndirectBuffer) {
+		abort("DrawIndirectCountBufferInfo.Buffer was not created with BufferUsageIndirectBuffer")
+	}
+	if (i.Offset % 4) != 0 {
+		abort("DrawIndirectCountBufferInfo.Offset [%d] is not a multiple of 4", i.Offset)
+	}
+	if i.Offset > i.Buffer.Size() || (i.Buffer.Size()-i.Offset) < uint64(unsafe.Sizeof(C.uint32_t(0))) {
+		abort("DrawIndirectCountBufferInfo.Offset + sizeof(uint32) [%d + %d] overflows buffer [%d]",
+			i.Offset, unsafe.Sizeof(C.uint32_t(0)), i.Buffer.Size())
+	}
+	return C.vxr_vk_graphics_drawIndirectCountBufferInfo{
+		vkBuffer: i.Buffer.vkBuffer(),
+		offset:   C.VkDeviceSize(i.Offset),
 	}
 }
 
@@ -683,15 +1028,42 @@ type DrawIndirectInfo struct {
 }
 
 func (cb *GraphicsCommandBuffer) DrawIndirect(p GraphicsPipelineLibrary, info DrawIndirectInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 
 	cb.draw(p, info.DrawParameters, func(cParmameters C.vxr_vk_graphics_drawParameters) {
 		cInfo := C.vxr_vk_graphics_drawIndirectInfo{
 			parameters:     cParmameters,
-			indirectBuffer: info.IndirectBuffer.cIndirectBufferInfo(),
+			indirectBuffer: info.IndirectBuffer.cIndirectBufferInfo(uint64(unsafe.Sizeof(C.VkDrawIndirectCommand{})), false),
 		}
 		C.vxr_vk_graphics_drawIndirect(instance.cInstance, cb.vkCommandBuffer, cInfo)
 	})
+	cb.track(info.IndirectBuffer.Buffer)
+	cb.hazards.read(info.IndirectBuffer.Buffer, PipelineStageIndirect)
+}
+
+type DrawIndirectCountInfo struct {
+	DrawParameters DrawParameters
+	IndirectBuffer DrawIndirectBufferInfo
+	CountBuffer    DrawIndirectCountBufferInfo
+}
+
+func (cb *GraphicsCommandBuffer) DrawIndirectCount(p GraphicsPipelineLibrary, info DrawIndirectCountInfo) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+
+	cb.draw(p, info.DrawParameters, func(cParmameters C.vxr_vk_graphics_drawParameters) {
+		cInfo := C.vxr_vk_graphics_drawIndirectCountInfo{
+			parameters:     cParmameters,
+			indirectBuffer: info.IndirectBuffer.cIndirectBufferInfo(uint64(unsafe.Sizeof(C.VkDrawIndirectCommand{})), true),
+			countBuffer:    info.CountBuffer.cCountBufferInfo(),
+		}
+		C.vxr_vk_graphics_drawIndirectCount(instance.cInstance, cb.vkCommandBuffer, cInfo)
+	})
+	cb.track(info.IndirectBuffer.Buffer)
+	cb.hazards.read(info.IndirectBuffer.Buffer, PipelineStageIndirect)
+	cb.track(info.CountBuffer.Buffer)
+	cb.hazards.read(info.CountBuffer.Buffer, PipelineStageIndirect)
 }
 
 type DrawIndexedBufferInfo struct {
@@ -737,7 +1109,8 @@ type DrawIndexedInfo struct {
 }
 
 func (cb *GraphicsCommandBuffer) DrawIndexed(p GraphicsPipelineLibrary, info DrawIndexedInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	cb.draw(p, info.DrawParameters, func(cParmameters C.vxr_vk_graphics_drawParameters) {
 		cInfo := C.vxr_vk_graphics_drawIndexedInfo{
 			parameters:    cParmameters,
@@ -746,6 +1119,8 @@ func (cb *GraphicsCommandBuffer) DrawIndexed(p GraphicsPipelineLibrary, info Dra
 		}
 		C.vxr_vk_graphics_drawIndexed(instance.cInstance, cb.vkCommandBuffer, cInfo)
 	})
+	cb.track(info.IndexBuffer.Buffer)
+	cb.hazards.read(info.IndexBuffer.Buffer, PipelineStageVertexInput)
 }
 
 type DrawIndexedIndirectInfo struct {
@@ -755,31 +1130,110 @@ type DrawIndexedIndirectInfo struct {
 }
 
 func (cb *GraphicsCommandBuffer) DrawIndexedIndirect(p GraphicsPipelineLibrary, info DrawIndexedIndirectInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	cb.draw(p, info.DrawParameters, func(cParmameters C.vxr_vk_graphics_drawParameters) {
 		cInfo := C.vxr_vk_graphics_drawIndexedIndirectInfo{
 			parameters:     cParmameters,
 			indexBuffer:    info.IndexBuffer.cIndexBufferInfo(),
-			indirectBuffer: info.IndirectBuffer.cIndirectBufferInfo(),
+			indirectBuffer: info.IndirectBuffer.cIndirectBufferInfo(uint64(unsafe.Sizeof(C.VkDrawIndexedIndirectCommand{})), false),
 		}
 		C.vxr_vk_graphics_drawIndexedIndirect(instance.cInstance, cb.vkCommandBuffer, cInfo)
 	})
+	cb.track(info.IndexBuffer.Buffer)
+	cb.hazards.read(info.IndexBuffer.Buffer, PipelineStageVertexInput)
+	cb.track(info.IndirectBuffer.Buffer)
+	cb.hazards.read(info.IndirectBuffer.Buffer, PipelineStageIndirect)
+}
+
+type DrawIndexedIndirectCountInfo struct {
+	DrawParameters DrawParameters
+	IndexBuffer    DrawIndexedBufferInfo
+	IndirectBuffer DrawIndirectBufferInfo
+	CountBuffer    DrawIndirectCountBufferInfo
+}
+
+func (cb *GraphicsCommandBuffer) DrawIndexedIndirectCount(p GraphicsPipelineLibrary, info DrawIndexedIndirectCountInfo) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	cb.draw(p, info.DrawParameters, func(cParmameters C.vxr_vk_graphics_drawParameters) {
+		cInfo := C.vxr_vk_graphics_drawIndexedIndirectCountInfo{
+			parameters:     cParmameters,
+			indexBuffer:    info.IndexBuffer.cIndexBufferInfo(),
+			indirectBuffer: info.IndirectBuffer.cIndirectBufferInfo(uint64(unsafe.Sizeof(C.VkDrawIndexedIndirectCommand{})), true),
+			countBuffer:    info.CountBuffer.cCountBufferInfo(),
+		}
+		C.vxr_vk_graphics_drawIndexedIndirectCount(instance.cInstance, cb.vkCommandBuffer, cInfo)
+	})
+	cb.track(info.IndexBuffer.Buffer)
+	cb.hazards.read(info.IndexBuffer.Buffer, PipelineStageVertexInput)
+	cb.track(info.IndirectBuffer.Buffer)
+	cb.hazards.read(info.IndirectBuffer.Buffer, PipelineStageIndirect)
+	cb.track(info.CountBuffer.Buffer)
+	cb.hazards.read(info.CountBuffer.Buffer, PipelineStageIndirect)
 }
 
 func (cb *GraphicsCommandBuffer) RenderPassEnd() {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
 	if cb.currentRenderPass == (renderPass{}) {
 		abort("RenderPassEnd called when there's no active renderpass")
 	}
+	if cb.occlusionQuery != (occlusionQuery{}) {
+		abort("RenderPassEnd called when there's an active occlusion query")
+	}
+	if cb.conditionalRendering == conditionalRenderingRenderPass {
+		abort("RenderPassEnd called when there's conditional rendering that began inside the renderpass")
+	}
 	C.vxr_vk_graphics_renderPassEnd(instance.cInstance, cb.vkCommandBuffer)
 	cb.currentRenderPass = renderPass{}
+	cb.renderPassViews = 0
+	cb.colorWriteMasks = nil
+	cb.colorWriteMasksOverridden = false
+}
+
+/*
+End finishes recording of a command buffer created from a CommandRecorder, allowing the recorder
+to begin another one. It must be called from the goroutine that recorded the command buffer.
+*/
+func (cb *GraphicsCommandBuffer) End() {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.recorder == nil {
+		abort("End called on a command buffer that was not created from a CommandRecorder, use Submit instead")
+	}
+	if cb.ended {
+		abort("End called on a command buffer that has already ended")
+	}
+	if cb.currentRenderPass != (renderPass{}) {
+		abort("End called when there's an active renderpass")
+	}
+	if cb.conditionalRendering != conditionalRenderingNone {
+		abort("End called when there's active conditional rendering")
+	}
+	C.vxr_vk_graphics_commandRecorder_commandBufferEnd(instance.cInstance, cb.recorder.cRecorder, cb.vkCommandBuffer)
+	cb.ended = true
+	cb.recorder.noCopy.Release()
 }
 
 func (cb *GraphicsCommandBuffer) Submit(waitSemaphores []SemaphoreWaitInfo, signalSemaphores []SemaphoreSignalInfo) {
-	cb.noCopy.Check()
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.recorder != nil {
+		abort("Submit called on a command buffer created from a CommandRecorder, use Frame.SubmitCommandBuffers instead")
+	}
+	if cb.cFrame == nil {
+		abort("Submit called on a command buffer that does not belong to a frame")
+	}
 	if cb.currentRenderPass != (renderPass{}) {
 		abort("End called when there's an active renderpass")
 	}
+	if cb.conditionalRendering != conditionalRenderingNone {
+		abort("Submit called when there's active conditional rendering")
+	}
+	if instance.config.validateResourceUse {
+		cb.validateResources()
+	}
 
 	waitSemaphoreInfos := make([]C.VkSemaphoreSubmitInfo, 0, len(waitSemaphores))
 	signalSemaphoreInfos := make([]C.VkSemaphoreSubmitInfo, 0, len(signalSemaphores))
@@ -791,6 +1245,9 @@ func (cb *GraphicsCommandBuffer) Submit(waitSemaphores []SemaphoreWaitInfo, sign
 		signalSemaphoreInfos = append(signalSemaphoreInfos, info.Semaphore.vkSignalInfo(info.Stage))
 	}
 
+	q := QueueGraphics.state()
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
 	C.vxr_vk_graphics_frame_commandBufferSubmit(
 		instance.cInstance,
 		cb.cFrame,
@@ -800,5 +1257,6 @@ func (cb *GraphicsCommandBuffer) Submit(waitSemaphores []SemaphoreWaitInfo, sign
 	)
 	runtime.KeepAlive(waitSemaphores)
 	runtime.KeepAlive(signalSemaphores)
+	cb.submitResources(QueueGraphics, 0)
 	cb.noCopy.Close()
 }
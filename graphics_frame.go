@@ -26,6 +26,9 @@ import "C"
 import (
 	"fmt"
 	"runtime"
+	"slices"
+	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"goarrg.com/rhi/vxr/internal/util"
@@ -34,8 +37,11 @@ import (
 
 type frame struct {
 	cFrame     C.vxr_vk_graphics_frame
+	serial     uint64
 	waiter     *TimelineSemaphoreWaiter
 	destroyers []Destroyer
+	// readbackWaiter is the last offscreen readback submitted by the frame, which the user's waiter does not cover.
+	readbackWaiter *TimelineSemaphoreWaiter
 }
 
 func (f *frame) wait() {
@@ -43,6 +49,13 @@ func (f *frame) wait() {
 		f.waiter.Wait()
 		f.waiter = nil
 	}
+	if f.readbackWaiter != nil {
+		f.readbackWaiter.Wait()
+		f.readbackWaiter = nil
+	}
+	if f.serial > instance.graphics.completedFrame.Load() {
+		instance.graphics.completedFrame.Store(f.serial)
+	}
 	for _, d := range f.destroyers {
 		d.Destroy()
 	}
@@ -58,25 +71,64 @@ func (f *frame) destroy() {
 	C.vxr_vk_graphics_destroyFrame(f.cFrame)
 }
 
+/*
+Frame is not safe for concurrent use, all of its methods must be called from the goroutine that called
+FrameBegin, concurrent calls will abort. To record commands in parallel, create a CommandRecorder per
+goroutine with NewCommandRecorder and submit the resulting command buffers with SubmitCommandBuffers.
+*/
 type Frame struct {
 	noCopy     util.NoCopy
-	surface    *Surface
+	surfaces   []*Surface
 	frame      *frame
 	name       string
 	cancelable bool
+
+	recorders   []*CommandRecorder
+	unsubmitted atomic.Int32
+
+	// begin is when FrameBegin returned, CPUTime is measured from it.
+	begin time.Time
+	// timed is false for the first frame, which has no FrameTime and is not recorded.
+	timed   bool
+	timings FrameTimings
 }
 
 func FrameBegin() *Frame {
 	if instance.graphics.frameStarted {
 		abort("FrameBegin called when there's an active frame")
 	}
+	begin, frameTime, limiterSleep, timed := instance.graphics.frameStats.begin()
+	framesInFlight := int32(0)
+	for i := range instance.graphics.framesInFlight {
+		if w := instance.graphics.framesInFlight[i].waiter; w != nil && !w.Poll() {
+			framesInFlight++
+		}
+	}
 	f := &instance.graphics.framesInFlight[instance.graphics.frameIndex]
 	f.wait()
-	ret := Frame{frame: f, name: fmt.Sprintf("frame_%d", instance.graphics.frameIndex), cancelable: true}
+	gpuWait := time.Since(begin)
+	if instance.offscreen != nil {
+		instance.offscreen.poll()
+	}
+	instance.graphics.frameSerial++
+	f.serial = instance.graphics.frameSerial
+	ret := Frame{
+		frame: f, name: fmt.Sprintf("frame_%d", instance.graphics.frameIndex), cancelable: true,
+		timed: timed,
+		timings: FrameTimings{
+			FrameTime: frameTime, LimiterSleep: limiterSleep, GPUWait: gpuWait, FramesInFlight: framesInFlight,
+		},
+	}
 	ret.noCopy.Init()
+	reclaimQueues()
+	q := QueueGraphics.state()
+	q.mtx.Lock()
 	C.vxr_vk_graphics_frame_begin(instance.cInstance, C.size_t(len(ret.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(ret.name))),
 		ret.frame.cFrame)
+	q.mtx.Unlock()
 	instance.graphics.frameStarted = true
+	// CPUTime starts here so that it does not include GPUWait
+	ret.begin = time.Now()
 	return &ret
 }
 
@@ -85,29 +137,63 @@ func (f *Frame) Index() int {
 	return instance.graphics.frameIndex
 }
 
+// Surface is AcquireSurface of the swapchain created by InitDevice, or the next offscreen image when Config.Offscreen is set.
 func (f *Frame) Surface() *Surface {
-	f.noCopy.Check()
-	if instance.sleep {
+	if instance.offscreen != nil {
+		return f.acquireOffscreenSurface()
+	}
+	return f.AcquireSurface(instance.swapchain)
+}
+
+func (f *Frame) acquireOffscreenSurface() *Surface {
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
+	for _, surface := range f.surfaces {
+		if surface.offscreen != nil {
+			return surface
+		}
+	}
+	start := time.Now()
+	surface := instance.offscreen.acquire(f)
+	f.timings.AcquireTime += time.Since(start)
+	f.surfaces = append(f.surfaces, surface)
+	f.cancelable = false
+	return surface
+}
+
+/*
+AcquireSurface acquires the next image of the swapchain, or returns the one already acquired this frame.
+It returns nil if the swapchain is asleep or out of date, in which case it must be resized before it can be acquired again.
+*/
+func (f *Frame) AcquireSurface(s *Swapchain) *Surface {
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
+	s.noCopy.Check()
+	if s.sleep {
 		return nil
 	}
-	if f.surface != nil {
-		return f.surface
+	for _, surface := range f.surfaces {
+		if surface.swapchain == s {
+			return surface
+		}
 	}
+	start := time.Now()
+	defer func() { f.timings.AcquireTime += time.Since(start) }()
 	f.frame.waitSurface()
-	var surface Surface
+	surface := Surface{swapchain: s}
 	surface.noCopy.Init()
-	switch ret := C.vxr_vk_graphics_frame_acquireSurface(instance.cInstance, f.frame.cFrame, &surface.cSurface); ret {
+	switch ret := C.vxr_vk_graphics_frame_acquireSurface(instance.cInstance, f.frame.cFrame, s.cSwapchain, &surface.cSurface); ret {
 	case vk.SUCCESS:
 	case vk.SUBOPTIMAL_KHR:
 	case vk.ERROR_OUT_OF_DATE_KHR:
-		instance.sleep = true
+		s.sleep = true
 		return nil
 	default:
 		abort("Failed to acquire surface: %s", vkResultStr(ret))
 	}
-	f.surface = &surface
+	f.surfaces = append(f.surfaces, &surface)
 	f.cancelable = false
-	return f.surface
+	return &surface
 }
 
 type HostScratchBuffer struct {
@@ -120,7 +206,8 @@ type HostScratchBuffer struct {
 var _ Buffer = (*HostScratchBuffer)(nil)
 
 func (f *Frame) NewHostScratchBuffer(name string, size uint64, usage BufferUsageFlags) *HostScratchBuffer {
-	f.noCopy.Check()
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
 	b := HostScratchBuffer{bufferSize: size, usageFlags: usage}
 	b.noCopy.Init()
 	info := C.vxr_vk_bufferCreateInfo{
@@ -161,8 +248,14 @@ func (b *HostScratchBuffer) vkBuffer() C.VkBuffer {
 }
 
 func (f *Frame) NewSingleUseCommandBuffer(name string) *GraphicsCommandBuffer {
-	f.noCopy.Check()
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
+	return f.newSingleUseCommandBuffer(name)
+}
+
+func (f *Frame) newSingleUseCommandBuffer(name string) *GraphicsCommandBuffer {
 	cb := GraphicsCommandBuffer{cFrame: f.frame.cFrame}
+	cb.frameSerial = f.frame.serial
 	cb.noCopy.Init()
 	name = fmt.Sprintf("%s_%s", f.name, name)
 	C.vxr_vk_graphics_frame_commandBufferBegin(instance.cInstance, f.frame.cFrame,
@@ -172,12 +265,114 @@ func (f *Frame) NewSingleUseCommandBuffer(name string) *GraphicsCommandBuffer {
 	return &cb
 }
 
+/*
+CommandRecorder owns a command pool within a Frame. Command buffers from different recorders
+may be recorded concurrently, but a single recorder may only have one command buffer recording at a time,
+so the intended use is one recorder per goroutine. A recorder is only valid until the end of the frame.
+*/
+type CommandRecorder struct {
+	noCopy    util.NoCopy
+	frame     *Frame
+	cRecorder C.vxr_vk_graphics_commandRecorder
+	name      string
+}
+
+func (f *Frame) NewCommandRecorder(name string) *CommandRecorder {
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
+	r := CommandRecorder{frame: f, name: fmt.Sprintf("%s_%s", f.name, name)}
+	r.noCopy.Init()
+	C.vxr_vk_graphics_frame_createCommandRecorder(instance.cInstance, f.frame.cFrame,
+		C.size_t(len(r.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(r.name))), &r.cRecorder)
+	f.recorders = append(f.recorders, &r)
+	f.cancelable = false
+	return &r
+}
+
+/*
+NewCommandBuffer begins a command buffer that must be finished with End before the recorder can begin
+another one, and then submitted with Frame.SubmitCommandBuffers before the frame ends.
+It is safe to call this from any goroutine as long as the recorder itself is not used concurrently.
+*/
+func (r *CommandRecorder) NewCommandBuffer(name string) *GraphicsCommandBuffer {
+	r.noCopy.Acquire()
+	cb := GraphicsCommandBuffer{cFrame: r.frame.frame.cFrame, recorder: r}
+	cb.frameSerial = r.frame.frame.serial
+	cb.noCopy.Init()
+	name = fmt.Sprintf("%s_%s", r.name, name)
+	C.vxr_vk_graphics_commandRecorder_commandBufferBegin(instance.cInstance, r.cRecorder,
+		C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))), &cb.vkCommandBuffer)
+	runtime.KeepAlive(name)
+	r.frame.unsubmitted.Add(1)
+	return &cb
+}
+
+/*
+SubmitCommandBuffers submits command buffers created from CommandRecorders in a single batch, in the order given.
+All of the command buffers must have been ended and the goroutines that recorded them must have synchronized
+with the caller, e.g. with a sync.WaitGroup, before calling this.
+*/
+func (f *Frame) SubmitCommandBuffers(cbs []*GraphicsCommandBuffer, waitSemaphores []SemaphoreWaitInfo, signalSemaphores []SemaphoreSignalInfo) {
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
+
+	vkCommandBuffers := make([]C.VkCommandBuffer, 0, len(cbs))
+	waitSemaphoreInfos := make([]C.VkSemaphoreSubmitInfo, 0, len(waitSemaphores))
+	signalSemaphoreInfos := make([]C.VkSemaphoreSubmitInfo, 0, len(signalSemaphores))
+
+	for i, cb := range cbs {
+		cb.noCopy.Check()
+		if cb.recorder == nil || cb.recorder.frame != f {
+			abort("cbs[%d] was not created from a CommandRecorder of this frame", i)
+		}
+		if j := slices.Index(cbs[:i], cb); j >= 0 {
+			abort("cbs[%d] is the same command buffer as cbs[%d]", i, j)
+		}
+		if !cb.ended {
+			abort("cbs[%d] must be ended before being submitted", i)
+		}
+		if instance.config.validateResourceUse {
+			cb.validateResources()
+		}
+		vkCommandBuffers = append(vkCommandBuffers, cb.vkCommandBuffer)
+	}
+	for _, info := range waitSemaphores {
+		waitSemaphoreInfos = append(waitSemaphoreInfos, info.Semaphore.vkWaitInfo(info.Stage))
+	}
+	for _, info := range signalSemaphores {
+		signalSemaphoreInfos = append(signalSemaphoreInfos, info.Semaphore.vkSignalInfo(info.Stage))
+	}
+
+	q := QueueGraphics.state()
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	C.vxr_vk_graphics_frame_commandBuffersSubmit(
+		instance.cInstance,
+		f.frame.cFrame,
+		C.uint32_t(len(vkCommandBuffers)), unsafe.SliceData(vkCommandBuffers),
+		C.uint32_t(len(waitSemaphores)), unsafe.SliceData(waitSemaphoreInfos),
+		C.uint32_t(len(signalSemaphores)), unsafe.SliceData(signalSemaphoreInfos),
+	)
+	runtime.KeepAlive(waitSemaphores)
+	runtime.KeepAlive(signalSemaphores)
+
+	for _, cb := range cbs {
+		cb.submitResources(QueueGraphics, 0)
+		cb.noCopy.Close()
+	}
+	f.unsubmitted.Add(-int32(len(cbs)))
+}
+
 func (f *Frame) Cancel() {
-	f.noCopy.Check()
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
 	if !f.cancelable {
 		abort("Cannot cancel frame with acquired surface or after calling any of the New* functions")
 	}
+	q := QueueGraphics.state()
+	q.mtx.Lock()
 	C.vxr_vk_graphics_frame_end(instance.cInstance, f.frame.cFrame)
+	q.mtx.Unlock()
 	f.frame.waiter = nil
 	instance.graphics.frameStarted = false
 	f.noCopy.Close()
@@ -185,24 +380,53 @@ func (f *Frame) Cancel() {
 
 /*
 QueueDestory is a convenience function to avoid having to store destroyers until the end of the frame,
-it is eq to passing the destroyers to any of the End functions.
+it is eq to passing the destroyers to any of the End functions. Resources that defer their own destruction
+do not need it, see Destroyer.
 */
 func (f *Frame) QueueDestory(destroyers ...Destroyer) {
-	f.noCopy.Check()
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
 	f.frame.destroyers = append(f.frame.destroyers, destroyers...)
 }
 
 func (f *Frame) End(waiter *TimelineSemaphoreWaiter, destroyers ...Destroyer) {
-	f.noCopy.Check()
+	f.noCopy.Acquire()
+	defer f.noCopy.Release()
 	if waiter == nil {
 		abort("Cannot end frame without a TimelineSemaphoreWaiter")
 	}
-	if f.surface != nil {
-		if ret := C.vxr_vk_graphics_frame_submit(instance.cInstance, f.frame.cFrame); ret != vk.SUCCESS {
-			instance.sleep = true
+	if n := f.unsubmitted.Load(); n != 0 {
+		abort("Cannot end frame with %d command buffers from CommandRecorders that were not submitted", n)
+	}
+	for _, r := range f.recorders {
+		r.noCopy.Close()
+	}
+	f.recorders = nil
+	start := time.Now()
+	presented := make([]*Surface, 0, len(f.surfaces))
+	for _, surface := range f.surfaces {
+		if surface.offscreen != nil {
+			instance.offscreen.submitReadback(f, surface)
+		} else {
+			presented = append(presented, surface)
 		}
 	}
+	q := QueueGraphics.state()
+	q.mtx.Lock()
+	if len(presented) > 0 {
+		results := make([]C.VkResult, len(presented))
+		C.vxr_vk_graphics_frame_submit(instance.cInstance, f.frame.cFrame, unsafe.SliceData(results))
+		for i, surface := range presented {
+			if results[i] != vk.SUCCESS {
+				surface.swapchain.sleep = true
+			}
+		}
+	}
+	if len(f.surfaces) > 0 {
+		f.timings.PresentTime = time.Since(start)
+	}
 	C.vxr_vk_graphics_frame_end(instance.cInstance, f.frame.cFrame)
+	q.mtx.Unlock()
 loop:
 	for {
 		select {
@@ -216,5 +440,9 @@ loop:
 	f.frame.destroyers = append(f.frame.destroyers, destroyers...)
 	instance.graphics.frameIndex = (instance.graphics.frameIndex + 1) % len(instance.graphics.framesInFlight)
 	instance.graphics.frameStarted = false
+	if f.timed {
+		f.timings.CPUTime = time.Since(f.begin)
+		instance.graphics.frameStats.record(f.timings)
+	}
 	f.noCopy.Close()
 }
@@ -0,0 +1,229 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+// FrameTimings are the CPU side timings of a single frame, measured from FrameBegin to the end of Frame.End.
+type FrameTimings struct {
+	// FrameTime is the time between the FrameBegin of the previous frame and this one.
+	FrameTime time.Duration
+	// CPUTime is the time between FrameBegin returning and Frame.End returning.
+	CPUTime time.Duration
+	// LimiterSleep is the time FrameBegin slept to honor the frame limit, it is not part of CPUTime.
+	LimiterSleep time.Duration
+	// GPUWait is the time FrameBegin was blocked waiting for the GPU to finish the last submission of the frame.
+	GPUWait time.Duration
+	// AcquireTime is the time spent acquiring surfaces, including waiting for the frame's previous presents.
+	AcquireTime time.Duration
+	// PresentTime is the time Frame.End spent presenting the acquired surfaces.
+	PresentTime time.Duration
+	// FramesInFlight is the number of frames the GPU had not finished when FrameBegin was called.
+	FramesInFlight int32
+}
+
+/*
+FrameStats summarizes the timings of the last frames, the number of frames kept is Config.FrameStatsWindow.
+Min and Max are per field, so they may come from different frames.
+*/
+type FrameStats struct {
+	NumFrames int
+	Last      FrameTimings
+	Average   FrameTimings
+	Min       FrameTimings
+	Max       FrameTimings
+
+	// FrameTimeP50, FrameTimeP95 and FrameTimeP99 are percentiles of FrameTime, stutter shows up as a P99 far above P50.
+	FrameTimeP50 time.Duration
+	FrameTimeP95 time.Duration
+	FrameTimeP99 time.Duration
+	// FPS is the number of frames divided by the sum of their FrameTime.
+	FPS float64
+	// Occupancy is the average of FramesInFlight divided by the number of frames in flight, 1 means the CPU is always
+	// waiting on the GPU.
+	Occupancy float64
+}
+
+type frameStats struct {
+	mtx     sync.Mutex
+	timings []FrameTimings
+	// occupancy is FramesInFlight of timings divided by the number of frames in flight at the time, which changes on resize.
+	occupancy []float64
+	next      int
+	count     int
+	maxCount  int
+
+	limit time.Duration
+	// nextDeadline is when the next frame may begin, it advances by limit every frame so sleeping
+	// past one deadline shortens the next frame instead of lowering the frame rate.
+	nextDeadline time.Time
+	lastBegin    time.Time
+}
+
+func (s *frameStats) init(window int32) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	window = max(window, 0)
+	s.timings = make([]FrameTimings, window)
+	s.occupancy = make([]float64, window)
+	s.next = 0
+	s.count = 0
+	s.maxCount = int(window)
+	s.nextDeadline = time.Time{}
+	s.lastBegin = time.Time{}
+}
+
+/*
+begin sleeps for the frame limiter and returns the time the frame begins at along with
+the time since the previous frame and how long it slept. The last value is false for the first frame
+as it has no previous frame to measure from, so it must not be recorded.
+*/
+func (s *frameStats) begin() (time.Time, time.Duration, time.Duration, bool) {
+	now := time.Now()
+	var sleep time.Duration
+	if s.limit > 0 {
+		if s.nextDeadline.IsZero() || now.Sub(s.nextDeadline) > s.limit {
+			// resync instead of running frames back to back to catch up with a deadline that is long gone
+			s.nextDeadline = now
+		} else if now.Before(s.nextDeadline) {
+			time.Sleep(s.nextDeadline.Sub(now))
+			sleep = time.Since(now)
+			now = time.Now()
+		}
+		s.nextDeadline = s.nextDeadline.Add(s.limit)
+	}
+	if s.lastBegin.IsZero() {
+		s.lastBegin = now
+		return now, 0, sleep, false
+	}
+	frameTime := now.Sub(s.lastBegin)
+	s.lastBegin = now
+	return now, frameTime, sleep, true
+}
+
+func (s *frameStats) record(t FrameTimings) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.maxCount == 0 {
+		return
+	}
+	s.timings[s.next] = t
+	s.occupancy[s.next] = float64(t.FramesInFlight) / float64(len(instance.graphics.framesInFlight))
+	s.next = (s.next + 1) % s.maxCount
+	s.count = min(s.count+1, s.maxCount)
+}
+
+func (s *frameStats) stats() FrameStats {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	ret := FrameStats{NumFrames: s.count}
+	if s.count == 0 {
+		return ret
+	}
+	ret.Last = s.timings[(s.next+s.maxCount-1)%s.maxCount]
+
+	window := s.timings[:s.count]
+	ret.Min = window[0]
+	ret.Max = window[0]
+	var sum FrameTimings
+	var sumFramesInFlight int64
+	frameTimes := make([]time.Duration, 0, len(window))
+	for _, t := range window {
+		sum.FrameTime += t.FrameTime
+		sum.CPUTime += t.CPUTime
+		sum.LimiterSleep += t.LimiterSleep
+		sum.GPUWait += t.GPUWait
+		sum.AcquireTime += t.AcquireTime
+		sum.PresentTime += t.PresentTime
+		sumFramesInFlight += int64(t.FramesInFlight)
+
+		ret.Min = FrameTimings{
+			FrameTime:      min(ret.Min.FrameTime, t.FrameTime),
+			CPUTime:        min(ret.Min.CPUTime, t.CPUTime),
+			LimiterSleep:   min(ret.Min.LimiterSleep, t.LimiterSleep),
+			GPUWait:        min(ret.Min.GPUWait, t.GPUWait),
+			AcquireTime:    min(ret.Min.AcquireTime, t.AcquireTime),
+			PresentTime:    min(ret.Min.PresentTime, t.PresentTime),
+			FramesInFlight: min(ret.Min.FramesInFlight, t.FramesInFlight),
+		}
+		ret.Max = FrameTimings{
+			FrameTime:      max(ret.Max.FrameTime, t.FrameTime),
+			CPUTime:        max(ret.Max.CPUTime, t.CPUTime),
+			LimiterSleep:   max(ret.Max.LimiterSleep, t.LimiterSleep),
+			GPUWait:        max(ret.Max.GPUWait, t.GPUWait),
+			AcquireTime:    max(ret.Max.AcquireTime, t.AcquireTime),
+			PresentTime:    max(ret.Max.PresentTime, t.PresentTime),
+			FramesInFlight: max(ret.Max.FramesInFlight, t.FramesInFlight),
+		}
+		frameTimes = append(frameTimes, t.FrameTime)
+	}
+
+	n := time.Duration(len(window))
+	ret.Average = FrameTimings{
+		FrameTime:      sum.FrameTime / n,
+		CPUTime:        sum.CPUTime / n,
+		LimiterSleep:   sum.LimiterSleep / n,
+		GPUWait:        sum.GPUWait / n,
+		AcquireTime:    sum.AcquireTime / n,
+		PresentTime:    sum.PresentTime / n,
+		FramesInFlight: int32(sumFramesInFlight / int64(len(window))),
+	}
+
+	slices.Sort(frameTimes)
+	percentile := func(p int) time.Duration {
+		return frameTimes[min(len(frameTimes)-1, (len(frameTimes)*p)/100)]
+	}
+	ret.FrameTimeP50 = percentile(50)
+	ret.FrameTimeP95 = percentile(95)
+	ret.FrameTimeP99 = percentile(99)
+	if sum.FrameTime > 0 {
+		ret.FPS = float64(len(window)) / sum.FrameTime.Seconds()
+	}
+	for _, o := range s.occupancy[:s.count] {
+		ret.Occupancy += o
+	}
+	ret.Occupancy /= float64(s.count)
+	return ret
+}
+
+// FrameStatistics returns the rolling statistics of the last Config.FrameStatsWindow frames, canceled frames are not recorded.
+func FrameStatistics() FrameStats {
+	return instance.graphics.frameStats.stats()
+}
+
+/*
+SetFrameLimit makes FrameBegin sleep so that frames begin no more often than fps times a second,
+0 disables the limiter. It must not be called while there is an active frame.
+*/
+func SetFrameLimit(fps float64) {
+	if instance.graphics.frameStarted {
+		abort("SetFrameLimit called when there's an active frame")
+	}
+	if fps < 0 {
+		abort("SetFrameLimit called with negative fps: %f", fps)
+	}
+	instance.graphics.frameStats.nextDeadline = time.Time{}
+	if fps == 0 {
+		instance.graphics.frameStats.limit = 0
+		return
+	}
+	instance.graphics.frameStats.limit = time.Duration(float64(time.Second) / fps)
+}
@@ -25,6 +25,7 @@ import "C"
 
 import (
 	"fmt"
+	"math/bits"
 	"runtime"
 	"unsafe"
 
@@ -86,7 +87,6 @@ type VertexInputPipelineCreateInfo struct {
 }
 
 type VertexInputPipeline struct {
-	id         string
 	name       string
 	vkPipeline C.VkPipeline
 	topology   C.VkPrimitiveTopology
@@ -113,11 +113,7 @@ func NewVertexInputPipeline(info VertexInputPipelineCreateInfo) *VertexInputPipe
 			abort("PrimitiveRestart is invalid for topology: %s", info.Topology.String())
 		}
 
-		p.vkPipeline = instance.graphics.pipelineCache.createOrRetrievePipeline(p.name, func() C.VkPipeline {
-			C.vxr_vk_graphics_createVertexInputPipeline(instance.cInstance, C.size_t(len(p.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(p.name))),
-				C.VkPrimitiveTopology(info.Topology), vk.TRUE, &p.vkPipeline)
-			return p.vkPipeline
-		})
+		p.vkPipeline = createOrRetrieveVertexInputPipeline(p.name, info.Topology, vk.TRUE)
 	} else {
 		switch info.Topology {
 		case VertexTopologyPointList:
@@ -131,33 +127,88 @@ func NewVertexInputPipeline(info VertexInputPipelineCreateInfo) *VertexInputPipe
 			p.name = "[vertex_input:patch]"
 		}
 
-		p.vkPipeline = instance.graphics.pipelineCache.createOrRetrievePipeline(p.name, func() C.VkPipeline {
-			C.vxr_vk_graphics_createVertexInputPipeline(instance.cInstance, C.size_t(len(p.name)), (*C.char)(unsafe.Pointer(unsafe.StringData(p.name))),
-				C.VkPrimitiveTopology(info.Topology), vk.FALSE, &p.vkPipeline)
-			return p.vkPipeline
-		})
+		p.vkPipeline = createOrRetrieveVertexInputPipeline(p.name, info.Topology, vk.FALSE)
 	}
-	p.id = genID(p.vkPipeline)
 
 	return p
 }
 
+func createOrRetrieveVertexInputPipeline(name string, topology VertexTopology, restart C.VkBool32) C.VkPipeline {
+	h := newHasher()
+	h.writeString(name)
+	return instance.graphics.pipelineCache.createOrRetrievePipeline(h.sum, pipelineLibraryKey{name: name}, func() cachedPipeline {
+		p := cachedPipeline{name: name}
+		C.vxr_vk_graphics_createVertexInputPipeline(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+			C.VkPrimitiveTopology(topology), restart, &p.vkPipeline)
+		runtime.KeepAlive(name)
+		return p
+	}).vkPipeline
+}
+
 type GraphicsShaderPipeline struct {
-	noCopy util.NoCopy
-	id     string
-	name   string
-	stage  ShaderStage
+	noCopy   util.NoCopy
+	name     string
+	stage    ShaderStage
+	viewMask uint32
 
 	vkPipeline C.VkPipeline
 }
 
 type GraphicsShaderPipelineCreateInfo struct {
+	// SpecConstants nil inherits the PipelineLayoutCreateInfo.SpecConstants of the stage,
+	// see ShaderMetadata.SpecConstantValues to create them by name.
 	SpecConstants []uint32
+
+	// ViewMask must match the RenderParameters.ViewMask of the renderpasses the pipeline is drawn in,
+	// requires Config.Multiview if != 0.
+	ViewMask uint32
+}
+
+func (info *GraphicsShaderPipelineCreateInfo) validate(entryPoint ShaderEntryPointLayout) error {
+	usesViewIndex := false
+	switch l := entryPoint.(type) {
+	case ShaderEntryPointVertexLayout:
+		usesViewIndex = l.UsesViewIndex
+	case ShaderEntryPointFragmentLayout:
+		usesViewIndex = l.UsesViewIndex
+	}
+	if usesViewIndex && !instance.graphics.features.multiview {
+		return debug.Errorf("Entry point %q uses gl_ViewIndex which requires Config.Multiview", entryPoint.EntryPointName())
+	}
+	if info.ViewMask == 0 {
+		return nil
+	}
+	if !instance.graphics.features.multiview {
+		return debug.Errorf("GraphicsShaderPipelineCreateInfo.ViewMask [%#b] requires Config.Multiview", info.ViewMask)
+	}
+	if uint32(bits.Len32(info.ViewMask)) > instance.deviceProperties.Limits.Multiview.MaxViewCount {
+		return debug.Errorf("GraphicsShaderPipelineCreateInfo.ViewMask [%#b] uses views beyond DeviceProperties.Limits.Multiview.MaxViewCount [%d]",
+			info.ViewMask, instance.deviceProperties.Limits.Multiview.MaxViewCount)
+	}
+	return nil
 }
 
 func NewGraphicsShaderPipeline(pipelineLayout *PipelineLayout, s *Shader, entryPoint ShaderEntryPointLayout, info GraphicsShaderPipelineCreateInfo) *GraphicsShaderPipeline {
+	if err := info.validate(entryPoint); err != nil {
+		abort("Failed to validate GraphicsShaderPipelineCreateInfo: %s", err)
+	}
+	specConstants, err := pipelineLayout.resolveSpecConstants(entryPoint.ShaderStage(), info.SpecConstants)
+	if err != nil {
+		abort("Failed to validate GraphicsShaderPipelineCreateInfo.SpecConstants: %s", err)
+	}
+	info.SpecConstants = specConstants
+	p := &GraphicsShaderPipeline{
+		name:     fmt.Sprintf("[%q,%s,%s,%#x]", s.ID, entryPoint.EntryPointName(), jsonString(info.SpecConstants), info.ViewMask),
+		stage:    entryPoint.ShaderStage(),
+		viewMask: info.ViewMask,
+	}
+	p.vkPipeline = createGraphicsShaderPipeline(p.name, pipelineLayout, s, entryPoint, info)
+	p.noCopy.Init()
+	return p
+}
+
+func createGraphicsShaderPipeline(name string, pipelineLayout *PipelineLayout, s *Shader, entryPoint ShaderEntryPointLayout, info GraphicsShaderPipelineCreateInfo) C.VkPipeline {
 	entryPointName := entryPoint.EntryPointName()
-	shaderName := fmt.Sprintf("[%q,%s,%s]", s.ID, entryPointName, jsonString(info.SpecConstants))
 	pipelineInfo := C.vxr_vk_graphics_shaderPipelineCreateInfo{
 		layout:         pipelineLayout.vkPipelinelayout,
 		entryPointSize: C.size_t(len(entryPointName)),
@@ -169,29 +220,27 @@ func NewGraphicsShaderPipeline(pipelineLayout *PipelineLayout, s *Shader, entryP
 		},
 		numSpecConstants: C.uint32_t(len(info.SpecConstants)),
 		specConstants:    (*C.uint32_t)(unsafe.SliceData(info.SpecConstants)),
+
+		viewMask: C.uint32_t(info.ViewMask),
+
+		optionalDynamicStates: instance.graphics.features.cOptionalDynamicStates(),
 	}
 	var vkPipeline C.VkPipeline
 	switch entryPoint.ShaderStage() {
 	case ShaderStageVertex:
-		C.vxr_vk_graphics_createVertexShaderPipeline(instance.cInstance, C.size_t(len(shaderName)), (*C.char)(unsafe.Pointer(unsafe.StringData(shaderName))),
+		C.vxr_vk_graphics_createVertexShaderPipeline(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
 			pipelineInfo, &vkPipeline)
 	case ShaderStageFragment:
-		C.vxr_vk_graphics_createFragmentShaderPipeline(instance.cInstance, C.size_t(len(shaderName)), (*C.char)(unsafe.Pointer(unsafe.StringData(shaderName))),
+		C.vxr_vk_graphics_createFragmentShaderPipeline(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
 			pipelineInfo, &vkPipeline)
 	default:
 		abort("Invalid shader stage: %s", entryPoint.ShaderStage().String())
 	}
+	runtime.KeepAlive(name)
 	runtime.KeepAlive(entryPointName)
 	runtime.KeepAlive(s.SPIRV)
 	runtime.KeepAlive(info.SpecConstants)
-	p := &GraphicsShaderPipeline{
-		id:         genID(vkPipeline),
-		name:       shaderName,
-		stage:      ShaderStage(pipelineInfo.stage),
-		vkPipeline: vkPipeline,
-	}
-	p.noCopy.Init()
-	return p
+	return vkPipeline
 }
 
 func (s *GraphicsShaderPipeline) Destroy() {
@@ -199,7 +248,8 @@ func (s *GraphicsShaderPipeline) Destroy() {
 		return
 	}
 	s.noCopy.Check()
-	instance.graphics.pipelineCache.destroyPipeline(s.id, s.vkPipeline)
+	instance.graphics.pipelineCache.destroyPipeline(s.name, s.vkPipeline)
+	s.vkPipeline = nil
 	s.noCopy.Close()
 }
 
@@ -209,7 +259,7 @@ type GraphicsPipelineLibrary struct {
 	VertexShader, FragmentShader *GraphicsShaderPipeline
 }
 
-func (gp *GraphicsPipelineLibrary) validate() error {
+func (gp *GraphicsPipelineLibrary) validate(viewMask uint32) error {
 	gp.VertexShader.noCopy.Check()
 	gp.FragmentShader.noCopy.Check()
 	if gp.VertexShader.stage != ShaderStageVertex {
@@ -218,5 +268,13 @@ func (gp *GraphicsPipelineLibrary) validate() error {
 	if gp.FragmentShader.stage != ShaderStageFragment {
 		return debug.Errorf("Fragment shader does not contain a fragment shader entry point")
 	}
+	if gp.VertexShader.viewMask != viewMask {
+		return debug.Errorf("Vertex shader was created with ViewMask [%#b] but the renderpass has ViewMask [%#b]",
+			gp.VertexShader.viewMask, viewMask)
+	}
+	if gp.FragmentShader.viewMask != viewMask {
+		return debug.Errorf("Fragment shader was created with ViewMask [%#b] but the renderpass has ViewMask [%#b]",
+			gp.FragmentShader.viewMask, viewMask)
+	}
 	return nil
 }
@@ -0,0 +1,125 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+// benchmarkPipelineKey returns a key of fake handles, they are never dereferenced and only have to be distinct and outside of the go heap.
+func benchmarkPipelineKey(i int) executablePipelineKey {
+	handles := [4]uintptr{}
+	for j := range handles {
+		handles[j] = 0x100000 + uintptr(i*len(handles)+j)*0x10
+	}
+	return *(*executablePipelineKey)(unsafe.Pointer(&handles))
+}
+
+// benchmarkChainIDs is how the string ids of a linked pipeline used to be chained on every draw.
+func benchmarkChainIDs(ids ...string) string {
+	sb := strings.Builder{}
+	for _, id := range ids {
+		sb.WriteString(id)
+	}
+	return strings.Clone(sb.String())
+}
+
+// benchmarkPipeline is what a draw looks a linked pipeline up from, the libraries only hold fake handles.
+type benchmarkPipeline struct {
+	library    GraphicsPipelineLibrary
+	renderPass renderPass
+	// ids are the library and render pass ids the pipelines used to be cached under.
+	vertexInputID, vertexShaderID, fragmentShaderID, renderPassID string
+}
+
+func newBenchmarkPipelines(n int) []benchmarkPipeline {
+	pipelines := make([]benchmarkPipeline, n)
+	for i := range pipelines {
+		k := benchmarkPipelineKey(i)
+		pipelines[i] = benchmarkPipeline{
+			library: GraphicsPipelineLibrary{
+				VertexInput:    &VertexInputPipeline{name: "[vertex_input]", vkPipeline: k.vertexInput},
+				VertexShader:   &GraphicsShaderPipeline{name: "[vertex_shader]", vkPipeline: k.vertexShader},
+				FragmentShader: &GraphicsShaderPipeline{name: "[fragment_shader]", vkPipeline: k.fragmentShader},
+			},
+			renderPass:       renderPass{name: "[R8G8B8A8_SRGB]", fragmentOutputPipeline: k.fragmentOutput},
+			vertexInputID:    toHex(k.vertexInput),
+			vertexShaderID:   toHex(k.vertexShader),
+			fragmentShaderID: toHex(k.fragmentShader),
+			renderPassID:     fmt.Sprintf("[fragment_output:[%s]]", toHex(k.fragmentOutput)),
+		}
+	}
+	return pipelines
+}
+
+/*
+BenchmarkExecutablePipelineLookup measures building the key of a linked pipeline and looking it up in a cache of 1024 pipelines
+the way a draw does, against the string ids chained with benchmarkChainIDs the pipelines used to be cached under.
+A hit must not allocate as it happens on every draw.
+*/
+func BenchmarkExecutablePipelineLookup(b *testing.B) {
+	const numPipelines = 1024
+	pipelines := newBenchmarkPipelines(numPipelines)
+
+	b.Run("chainIDs", func(b *testing.B) {
+		mtx := sync.RWMutex{}
+		cache := map[string]cachedPipeline{}
+		for _, p := range pipelines {
+			id := benchmarkChainIDs(p.vertexInputID, p.vertexShaderID, p.fragmentShaderID, p.renderPassID)
+			cache[id] = cachedPipeline{vkPipeline: p.library.VertexInput.vkPipeline}
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p := &pipelines[i%numPipelines]
+			id := benchmarkChainIDs(p.vertexInputID, p.vertexShaderID, p.fragmentShaderID, p.renderPassID)
+			name := benchmarkChainIDs(p.library.VertexInput.name, p.library.VertexShader.name, p.library.FragmentShader.name, p.renderPass.name)
+			mtx.RLock()
+			_, ok := cache[id]
+			mtx.RUnlock()
+			if !ok {
+				b.Fatalf("Cache miss for: %s", name)
+			}
+		}
+	})
+
+	b.Run("executablePipelineKey", func(b *testing.B) {
+		c := graphicsPipelineCache{
+			executables: map[executablePipelineKey]cachedPipeline{},
+		}
+		for _, p := range pipelines {
+			key := newExecutablePipelineKey(p.library, p.renderPass.fragmentOutputPipeline)
+			c.executables[key] = cachedPipeline{vkPipeline: p.library.VertexInput.vkPipeline}
+		}
+
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			p := &pipelines[i%numPipelines]
+			key := newExecutablePipelineKey(p.library, p.renderPass.fragmentOutputPipeline)
+			c.linkOrRetrieveExecutablePipeline(key, nil, func() string {
+				b.Fatalf("Cache miss for key: %s", key.String())
+				return ""
+			})
+		}
+	})
+}
@@ -0,0 +1,187 @@
+//go:build goarrg_vxr_hazard
+// +build goarrg_vxr_hazard
+
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+import (
+	"strings"
+
+	"goarrg.com/debug"
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+// hazardGraphicsStages is what PipelineStageGraphics expands to out of the stages commands are recorded with.
+const hazardGraphicsStages = PipelineStageIndirect | PipelineStageVertexInput | PipelineStageVertexShader |
+	PipelineStageFragmentShader | PipelineStageFragmentTests | PipelineStageRenderAttachmentWrite | PipelineStageConditionalRendering
+
+// hazardWriteAccess is every write access a barrier can make available, any of them covers the last write.
+const hazardWriteAccess AccessFlags = vk.ACCESS_2_SHADER_WRITE_BIT | vk.ACCESS_2_SHADER_STORAGE_WRITE_BIT |
+	vk.ACCESS_2_COLOR_ATTACHMENT_WRITE_BIT | vk.ACCESS_2_DEPTH_STENCIL_ATTACHMENT_WRITE_BIT | vk.ACCESS_2_TRANSFER_WRITE_BIT |
+	vk.ACCESS_2_HOST_WRITE_BIT | vk.ACCESS_2_MEMORY_WRITE_BIT
+
+type hazardAccess struct {
+	stage   PipelineStage
+	regions string
+	stack   string
+	// descriptor is set for storage descriptors, which may only be read by the shader.
+	descriptor bool
+}
+
+type hazardState struct {
+	// write is the last write to the resource and visible the stages barriers made it visible to since.
+	write   *hazardAccess
+	visible PipelineStage
+}
+
+/*
+hazardTracker records the reads and writes of every command of a command buffer and reports read-after-write
+and write-after-write hazards that have no barrier in between, along with the named regions and Go call stacks
+of both commands. It is only built with the goarrg_vxr_hazard tag.
+
+Only a single command buffer is seen so hazards between submissions are not reported. The shader's actual access
+is not known so storage descriptors count as writes and everything else as reads, the bindless heap is not tracked.
+Only the descriptors a set still holds are considered, array slots that were never bound or were removed with
+DescriptorSet.Unbind e.g. by popping them from a managed array are not reported.
+As storage descriptors are often only read, two of them in a row are not reported as a write-after-write hazard.
+*/
+type hazardTracker struct {
+	regions   []string
+	resources map[any]*hazardState
+}
+
+func (h *hazardTracker) beginRegion(name string) {
+	h.regions = append(h.regions, name)
+}
+
+func (h *hazardTracker) endRegion() {
+	h.regions = h.regions[:len(h.regions)-1]
+}
+
+func (h *hazardTracker) state(r any) *hazardState {
+	if h.resources == nil {
+		h.resources = map[any]*hazardState{}
+	}
+	s, ok := h.resources[r]
+	if !ok {
+		s = &hazardState{}
+		h.resources[r] = s
+	}
+	return s
+}
+
+func (h *hazardTracker) read(r any, stage PipelineStage) {
+	h.access(r, stage, false, false)
+}
+
+func (h *hazardTracker) write(r any, stage PipelineStage) {
+	h.access(r, stage, true, false)
+}
+
+func (h *hazardTracker) access(r any, stage PipelineStage, write, descriptor bool) {
+	s := h.state(r)
+	regions := strings.Join(h.regions, "/")
+	if s.write != nil && !hazardStagesCover(s.visible, stage) && !(write && descriptor && s.write.descriptor) {
+		kind := "read-after-write"
+		if write {
+			kind = "write-after-write"
+		}
+		instance.logger.EPrintf("Synchronization hazard [%s] on %T(%p): access at stage %#x in region [%s] follows the write at stage %#x in region [%s] without a barrier\nwrite at:\n%s\naccess at:\n%s",
+			kind, r, r, stage, regions, s.write.stage, s.write.regions, s.write.stack, debug.StackTrace(2))
+	}
+	if write {
+		s.write = &hazardAccess{stage: stage, regions: regions, stack: debug.StackTrace(2), descriptor: descriptor}
+		s.visible = PipelineStageNone
+	}
+}
+
+// barrier makes the last write to r visible to dst if src covers it, a nil r is a memory barrier covering every resource.
+func (h *hazardTracker) barrier(r any, src PipelineStage, srcAccess AccessFlags, dst PipelineStage) {
+	apply := func(s *hazardState) {
+		if s.write != nil && (srcAccess&hazardWriteAccess) != 0 && hazardStagesCover(src, s.write.stage) {
+			s.visible |= dst
+		}
+	}
+	if r == nil {
+		for _, s := range h.resources {
+			apply(s)
+		}
+		return
+	}
+	if s, ok := h.resources[r]; ok {
+		apply(s)
+	}
+}
+
+// bindings records the accesses of the descriptors bound by a draw or dispatch at the stages of their bindings.
+func (h *hazardTracker) bindings(layout *PipelineLayout, sets []*DescriptorSet, push [][]DescriptorInfo) {
+	for _, s := range sets {
+		if s == nil {
+			continue
+		}
+		// forEachResource skips unbound slots so popped descriptors don't show up as accesses
+		s.forEachResource(func(bindingIndex, _ int, r any) {
+			h.descriptor(s.descriptorSetLayout.bindings[bindingIndex], r)
+		})
+	}
+	if layout.pushDescriptorSet < 0 {
+		return
+	}
+	bindings := layout.descriptorSetLayouts[layout.pushDescriptorSet].bindings
+	for i, descriptors := range push {
+		for _, d := range descriptors {
+			forEachDescriptorResource(d, func(r any) {
+				h.descriptor(bindings[i], r)
+			})
+		}
+	}
+}
+
+func (h *hazardTracker) descriptor(binding descriptorSetBinding, r any) {
+	if _, ok := r.(*Sampler); ok {
+		return
+	}
+	var stage PipelineStage
+	shaderStage := ShaderStage(binding.shaderStage)
+	if shaderStage&ShaderStageVertex != 0 {
+		stage |= PipelineStageVertexShader
+	}
+	if shaderStage&ShaderStageFragment != 0 {
+		stage |= PipelineStageFragmentShader
+	}
+	if shaderStage&ShaderStageCompute != 0 {
+		stage |= PipelineStageCompute
+	}
+	switch binding.descriptorType {
+	case vk.DESCRIPTOR_TYPE_STORAGE_BUFFER, vk.DESCRIPTOR_TYPE_STORAGE_IMAGE, vk.DESCRIPTOR_TYPE_STORAGE_TEXEL_BUFFER:
+		h.access(r, stage, true, true)
+	default:
+		h.read(r, stage)
+	}
+}
+
+// hazardStagesCover reports whether every stage of stage is part of mask once the meta stages of mask are expanded.
+func hazardStagesCover(mask, stage PipelineStage) bool {
+	if (mask & PipelineStageAll) != 0 {
+		return true
+	}
+	if (mask & PipelineStageGraphics) != 0 {
+		mask |= hazardGraphicsStages
+	}
+	return (stage &^ mask) == 0
+}
@@ -0,0 +1,30 @@
+//go:build !goarrg_vxr_hazard
+// +build !goarrg_vxr_hazard
+
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+// hazardTracker does nothing unless built with the goarrg_vxr_hazard tag, see hazard.go.
+type hazardTracker struct{}
+
+func (*hazardTracker) beginRegion(string)                                             {}
+func (*hazardTracker) endRegion()                                                     {}
+func (*hazardTracker) read(any, PipelineStage)                                        {}
+func (*hazardTracker) write(any, PipelineStage)                                       {}
+func (*hazardTracker) barrier(any, PipelineStage, AccessFlags, PipelineStage)         {}
+func (*hazardTracker) bindings(*PipelineLayout, []*DescriptorSet, [][]DescriptorInfo) {}
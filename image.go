@@ -180,6 +180,7 @@ const (
 type Sampler struct {
 	noCopy   util.NoCopy
 	cSampler C.VkSampler
+	tracker  resourceTracker
 }
 
 var _ interface {
@@ -194,10 +195,17 @@ func (s *Sampler) Destroy() {
 		return
 	}
 	s.noCopy.Check()
-	C.vxr_vk_destroySampler(instance.cInstance, s.cSampler)
+	cSampler := s.cSampler
+	s.tracker.release(func() {
+		C.vxr_vk_destroySampler(instance.cInstance, cSampler)
+	})
 	s.noCopy.Close()
 }
 
+func (s *Sampler) resourceTracker() *resourceTracker {
+	return &s.tracker
+}
+
 type SamplerFilter C.VkFilter
 
 const (
@@ -264,6 +272,7 @@ type Image interface {
 	Aspect() ImageAspectFlags
 	Extent() gmath.Extent3i32
 	usage() ImageUsageFlags
+	numArrayLayers() int32
 
 	vkFormat() C.VkFormat
 	vkImage() C.VkImage
@@ -342,25 +351,40 @@ type image struct {
 	noCopy     util.NoCopy
 	usageFlags ImageUsageFlags
 	extent     gmath.Extent3i32
+	numLayers  int32
 
 	cImage C.vxr_vk_image
 
 	cImageViewType C.VkImageViewType
 	cImageView     C.VkImageView
+
+	tracker resourceTracker
 }
 
 func (img *image) Destroy() {
 	img.noCopy.Check()
-	C.vxr_vk_destroyImage(instance.cInstance, img.cImage)
-	C.vxr_vk_destroyImageView(instance.cInstance, img.cImageView)
+	cImage, cImageView := img.cImage, img.cImageView
+	img.tracker.release(func() {
+		C.vxr_vk_destroyImage(instance.cInstance, cImage)
+		C.vxr_vk_destroyImageView(instance.cInstance, cImageView)
+	})
 	img.noCopy.Close()
 }
 
+func (img *image) resourceTracker() *resourceTracker {
+	return &img.tracker
+}
+
 func (img *image) usage() ImageUsageFlags {
 	img.noCopy.Check()
 	return img.usageFlags
 }
 
+func (img *image) numArrayLayers() int32 {
+	img.noCopy.Check()
+	return img.numLayers
+}
+
 func (img *image) vkImage() C.VkImage {
 	img.noCopy.Check()
 	return img.cImage.vkImage
@@ -612,6 +636,7 @@ func newImage(name string, format C.VkFormat, aspect C.VkImageAspectFlags, info
 	return image{
 		usageFlags: info.Usage,
 		extent:     info.Extent,
+		numLayers:  info.NumArrayLayers,
 
 		cImage: vkImage,
 
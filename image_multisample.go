@@ -83,20 +83,33 @@ type imageMultiSampled struct {
 
 	cImage     C.vxr_vk_image
 	cImageView C.VkImageView
+	tracker    resourceTracker
 }
 
 func (img *imageMultiSampled) Destroy() {
 	img.noCopy.Check()
-	C.vxr_vk_destroyImage(instance.cInstance, img.cImage)
-	C.vxr_vk_destroyImageView(instance.cInstance, img.cImageView)
+	cImage, cImageView := img.cImage, img.cImageView
+	img.tracker.release(func() {
+		C.vxr_vk_destroyImage(instance.cInstance, cImage)
+		C.vxr_vk_destroyImageView(instance.cInstance, cImageView)
+	})
 	img.noCopy.Close()
 }
 
+func (img *imageMultiSampled) resourceTracker() *resourceTracker {
+	return &img.tracker
+}
+
 func (img *imageMultiSampled) usage() ImageUsageFlags {
 	img.noCopy.Check()
 	return img.usageFlags
 }
 
+func (img *imageMultiSampled) numArrayLayers() int32 {
+	img.noCopy.Check()
+	return 1
+}
+
 func (img *imageMultiSampled) vkImage() C.VkImage {
 	img.noCopy.Check()
 	return img.cImage.vkImage
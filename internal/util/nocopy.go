@@ -16,10 +16,15 @@ limitations under the License.
 
 package util
 
-import "goarrg.com/debug"
+import (
+	"sync/atomic"
+
+	"goarrg.com/debug"
+)
 
 type NoCopy struct {
 	addr *NoCopy
+	busy atomic.Bool
 }
 
 func (n *NoCopy) Init() {
@@ -45,6 +50,22 @@ func (n *NoCopy) Check() {
 	}
 }
 
+/*
+Acquire is Check that additionally marks the value as in use until Release is called,
+aborting if the value is already in use. It is used to enforce that values which are not
+safe for concurrent use are not shared between goroutines.
+*/
+func (n *NoCopy) Acquire() {
+	n.Check()
+	if !n.busy.CompareAndSwap(false, true) {
+		abort("Illegal concurrent use of value: \n%s", debug.StackTrace(0))
+	}
+}
+
+func (n *NoCopy) Release() {
+	n.busy.Store(false)
+}
+
 func (n *NoCopy) Close() {
 	n.addr = nil
 }
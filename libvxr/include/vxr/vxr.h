@@ -58,9 +58,12 @@ VXR_HANDLE(vxr_vk_buffer);
 
 VXR_HANDLE(vxr_vk_shader_toolchain);
 VXR_HANDLE(vxr_vk_shader_compileResult);
+VXR_HANDLE(vxr_vk_shader_compileError);
 VXR_HANDLE(vxr_vk_shader_reflectResult);
 
 VXR_HANDLE(vxr_vk_graphics_frame);
+VXR_HANDLE(vxr_vk_graphics_swapchain);
+VXR_HANDLE(vxr_vk_graphics_commandRecorder);
 
 typedef struct {
 	float minPointSize;
@@ -120,6 +123,26 @@ typedef struct {
 			uint32_t maxSubgroupCount;
 		} workgroup;
 	} compute;
+
+	struct {
+		uint32_t maxViewCount;
+	} multiview;
+
+	struct {
+		uint32_t maxDrawCount;
+	} indirect;
+
+	struct {
+		float timestampPeriod;
+	} query;
+
+	struct {
+		uint32_t maxSamplerCount;
+		uint32_t maxSampledImageCount;
+		uint32_t maxStorageImageCount;
+		uint32_t maxSBOCount;
+		uint32_t maxResourceCount;
+	} descriptorIndexing;
 } vxr_vk_device_limits;
 
 typedef struct {
@@ -136,6 +159,12 @@ typedef struct {
 	vxr_vk_device_limits limits;
 } vxr_vk_device_properties;
 
+typedef enum {
+	vxr_vk_queueType_graphics,
+	vxr_vk_queueType_compute,
+	vxr_vk_queueType_transfer,
+} vxr_vk_queueType;
+
 typedef struct {
 	VkDeviceSize size;
 	VkBufferUsageFlags usage;
@@ -196,9 +225,19 @@ typedef struct {
 typedef struct {
 	VkFormat format;
 	VkExtent2D extent;
+	VkColorSpaceKHR colorSpace;
 	uint32_t numImages;
+	VkPresentModeKHR presentMode;
 } vxr_vk_surfaceInfo;
 
+typedef struct {
+	uint32_t numImages;
+	uint32_t numPresentModes;
+	const VkPresentModeKHR* presentModes;
+	uint32_t numSurfaceFormats;
+	const VkSurfaceFormatKHR* surfaceFormats;
+} vxr_vk_graphics_swapchainCreateInfo;
+
 typedef struct {
 	vxr_vk_surfaceInfo info;
 	VkImage vkImage;
@@ -262,9 +301,18 @@ typedef struct {
 	const uint32_t* data;
 } vxr_vk_shader_spirv;
 
+typedef enum {
+	vxr_vk_shader_constantType_unknown,
+	vxr_vk_shader_constantType_bool,
+	vxr_vk_shader_constantType_int,
+	vxr_vk_shader_constantType_uint,
+	vxr_vk_shader_constantType_float,
+} vxr_vk_shader_constantType;
+
 typedef struct {
 	const char* name;
 	uint32_t value;
+	vxr_vk_shader_constantType type;
 } vxr_vk_shader_reflectResult_specConstant;
 
 typedef struct {
@@ -314,6 +362,21 @@ typedef struct {
 	uint32_t numSpecConstants;
 	const uint32_t* specConstants;
 } vxr_vk_compute_shaderPipelineCreateInfo;
+// a write into the set at setOffset of the descriptor buffer, bindingDescriptorCount is the descriptor count of the written binding
+typedef struct {
+	VkDeviceSize setOffset;
+	VkDescriptorSetLayout layout;
+	uint32_t bindingDescriptorCount;
+	VkWriteDescriptorSet write;
+} vxr_vk_descriptorBuffer_writeInfo;
+// the set of the layout created with VK_DESCRIPTOR_SET_LAYOUT_CREATE_PUSH_DESCRIPTOR_BIT_KHR,
+// it is skipped when binding descriptor sets and its descriptors are pushed with the writes instead
+typedef struct {
+	VkBool32 enable;
+	uint32_t set;
+	uint32_t numWrites;
+	VkWriteDescriptorSet* writes;
+} vxr_vk_pushDescriptorSet;
 typedef struct {
 	VkPipelineLayout layout;
 	VkPipeline pipeline;
@@ -323,6 +386,9 @@ typedef struct {
 
 	uint32_t numDescriptorSets;
 	VkDescriptorSet* descriptorSets;
+	// when the device uses descriptor buffers the sets are bound with these offsets into it instead
+	VkDeviceSize* descriptorBufferOffsets;
+	vxr_vk_pushDescriptorSet pushDescriptors;
 
 	VkExtent3D groupCount;
 } vxr_vk_compute_dispatchInfo;
@@ -335,11 +401,22 @@ typedef struct {
 
 	uint32_t numDescriptorSets;
 	VkDescriptorSet* descriptorSets;
+	// when the device uses descriptor buffers the sets are bound with these offsets into it instead
+	VkDeviceSize* descriptorBufferOffsets;
+	vxr_vk_pushDescriptorSet pushDescriptors;
 
 	VkBuffer buffer;
 	VkDeviceSize offset;
 } vxr_vk_compute_dispatchIndirectInfo;
 
+// dynamic states that depend on optional features, only the enabled ones are declared or set
+typedef struct {
+	VkBool32 depthClampEnable;
+	VkBool32 logicOpEnable;
+	VkBool32 alphaToCoverageEnable;
+	VkBool32 sampleMask;
+} vxr_vk_graphics_optionalDynamicStates;
+
 typedef struct {
 	VkPipelineLayout layout;
 	size_t entryPointSize;
@@ -349,6 +426,10 @@ typedef struct {
 
 	uint32_t numSpecConstants;
 	const uint32_t* specConstants;
+
+	uint32_t viewMask;
+
+	vxr_vk_graphics_optionalDynamicStates optionalDynamicStates;
 } vxr_vk_graphics_shaderPipelineCreateInfo;
 
 typedef struct {
@@ -356,6 +437,10 @@ typedef struct {
 	const VkFormat* colorAttachmentFormats;
 	VkFormat depthFormat;
 	VkFormat stencilFormat;
+	VkLogicOp logicOp;
+	uint32_t viewMask;
+
+	vxr_vk_graphics_optionalDynamicStates optionalDynamicStates;
 } vxr_vk_graphics_fragmentOutputPipelineCreateInfo;
 
 typedef struct {
@@ -389,11 +474,35 @@ typedef struct {
 	VkStencilOpState stencilTestFrontFace;
 	VkStencilOpState stencilTestBackFace;
 
+	VkBool32 depthBiasEnable;
+	float depthBiasConstantFactor;
+	float depthBiasClamp;
+	float depthBiasSlopeFactor;
+
+	VkBool32 depthBoundsTestEnable;
+	float minDepthBounds;
+	float maxDepthBounds;
+
+	VkBool32 depthClampEnable;
+
+	float blendConstants[4];
+	VkBool32 logicOpEnable;
+	uint32_t numColorWriteMasks;
+	VkColorComponentFlags* colorWriteMasks;
+
+	VkBool32 alphaToCoverageEnable;
+	VkSampleMask sampleMask[2];
+
+	vxr_vk_graphics_optionalDynamicStates optionalDynamicStates;
+
 	VkPushConstantRange pushConstantRange;
 	void* pushConstantData;
 
 	uint32_t numDescriptorSets;
 	VkDescriptorSet* descriptorSets;
+	// when the device uses descriptor buffers the sets are bound with these offsets into it instead
+	VkDeviceSize* descriptorBufferOffsets;
+	vxr_vk_pushDescriptorSet pushDescriptors;
 } vxr_vk_graphics_drawParameters;
 typedef struct {
 	vxr_vk_graphics_drawParameters parameters;
@@ -406,11 +515,21 @@ typedef struct {
 	VkBuffer vkBuffer;
 	VkDeviceSize offset;
 	uint32_t drawCount;
+	uint32_t stride;
 } vxr_vk_graphics_drawIndirectBufferInfo;
 typedef struct {
 	vxr_vk_graphics_drawParameters parameters;
 	vxr_vk_graphics_drawIndirectBufferInfo indirectBuffer;
 } vxr_vk_graphics_drawIndirectInfo;
+typedef struct {
+	VkBuffer vkBuffer;
+	VkDeviceSize offset;
+} vxr_vk_graphics_drawIndirectCountBufferInfo;
+typedef struct {
+	vxr_vk_graphics_drawParameters parameters;
+	vxr_vk_graphics_drawIndirectBufferInfo indirectBuffer;
+	vxr_vk_graphics_drawIndirectCountBufferInfo countBuffer;
+} vxr_vk_graphics_drawIndirectCountInfo;
 
 typedef struct {
 	VkBuffer vkBuffer;
@@ -430,11 +549,18 @@ typedef struct {
 	vxr_vk_graphics_indexBufferInfo indexBuffer;
 	vxr_vk_graphics_drawIndirectBufferInfo indirectBuffer;
 } vxr_vk_graphics_drawIndexedIndirectInfo;
+typedef struct {
+	vxr_vk_graphics_drawParameters parameters;
+	vxr_vk_graphics_indexBufferInfo indexBuffer;
+	vxr_vk_graphics_drawIndirectBufferInfo indirectBuffer;
+	vxr_vk_graphics_drawIndirectCountBufferInfo countBuffer;
+} vxr_vk_graphics_drawIndexedIndirectCountInfo;
 
 extern VXR_FN void vxr_stdlib_init(vxr_loggerCallback, vxr_loggerCallback, vxr_loggerCallback, vxr_loggerCallback,
 								   vxr_loggerCallback, vxr_loggerCallback);
 
 extern VXR_FN void vxr_vk_init(uintptr_t, uintptr_t, PFN_vkDebugUtilsMessengerCallbackEXT, vxr_vk_instance*);
+extern VXR_FN VkBool32 vxr_vk_instanceExtensionSupported(vxr_vk_instance, size_t, const char*);
 extern VXR_FN void vxr_vk_destroy(vxr_vk_instance);
 
 extern VXR_FN VkResult vxr_vk_device_vkPhysicalDeviceFromUUID(vxr_vk_instance, uint8_t (*)[VK_UUID_SIZE], uintptr_t*);
@@ -453,9 +579,17 @@ extern VXR_FN void vxr_vk_device_selector_getEnabledFeatures(vxr_vk_device_selec
 extern VXR_FN void vxr_vk_device_init(vxr_vk_instance, vxr_vk_device_selector);
 extern VXR_FN void vxr_vk_device_destroy(vxr_vk_instance);
 extern VXR_FN void vxr_vk_device_getProperties(vxr_vk_instance, vxr_vk_device_properties*);
+extern VXR_FN void vxr_vk_device_getMaxPushDescriptors(vxr_vk_instance, uint32_t*);
 
 extern VXR_FN void vxr_vk_waitIdle(vxr_vk_instance);
 
+extern VXR_FN vxr_vk_queueType vxr_vk_resolveQueue(vxr_vk_instance, vxr_vk_queueType);
+extern VXR_FN void vxr_vk_getTimestampValidBits(vxr_vk_instance, vxr_vk_queueType, uint32_t*);
+extern VXR_FN void vxr_vk_commandBufferBegin(vxr_vk_instance, vxr_vk_queueType, size_t, const char*, VkCommandPool*, VkCommandBuffer*);
+extern VXR_FN void vxr_vk_commandBufferSubmit(vxr_vk_instance, vxr_vk_queueType, VkCommandBuffer, uint32_t, VkSemaphoreSubmitInfo*, uint32_t,
+											  VkSemaphoreSubmitInfo*);
+extern VXR_FN void vxr_vk_destroyCommandPool(vxr_vk_instance, VkCommandPool);
+
 extern VXR_FN void vxr_vk_commandBuffer_beginNamedRegion(vxr_vk_instance, VkCommandBuffer, size_t, const char*);
 extern VXR_FN void vxr_vk_commandBuffer_endNamedRegion(vxr_vk_instance, VkCommandBuffer);
 extern VXR_FN void vxr_vk_commandBuffer_barrier(vxr_vk_instance, VkCommandBuffer, VkDependencyInfo);
@@ -464,15 +598,30 @@ extern VXR_FN void vxr_vk_commandBuffer_updateBuffer(vxr_vk_instance, VkCommandB
 extern VXR_FN void vxr_vk_commandBuffer_clearColorImage(vxr_vk_instance, VkCommandBuffer, VkImage, VkImageLayout,
 														VkClearColorValue, uint32_t, VkImageSubresourceRange*);
 extern VXR_FN void vxr_vk_commandBuffer_copyBuffer(vxr_vk_instance, VkCommandBuffer, VkBuffer, VkBuffer, uint32_t, VkBufferCopy*);
+extern VXR_FN void vxr_vk_commandBuffer_writeTimestamp(vxr_vk_instance, VkCommandBuffer, VkPipelineStageFlags2, VkQueryPool, uint32_t);
+extern VXR_FN void vxr_vk_commandBuffer_beginQuery(vxr_vk_instance, VkCommandBuffer, VkQueryPool, uint32_t, VkQueryControlFlags);
+extern VXR_FN void vxr_vk_commandBuffer_endQuery(vxr_vk_instance, VkCommandBuffer, VkQueryPool, uint32_t);
+extern VXR_FN void vxr_vk_commandBuffer_copyQueryPoolResults(vxr_vk_instance, VkCommandBuffer, VkQueryPool, uint32_t, uint32_t, VkBuffer,
+															 VkDeviceSize, VkDeviceSize, VkQueryResultFlags);
 extern VXR_FN void vxr_vk_commandBuffer_copyBufferToImage(vxr_vk_instance, VkCommandBuffer, VkBuffer, VkImage,
 														  VkImageLayout, uint32_t, VkBufferImageCopy*);
+extern VXR_FN void vxr_vk_commandBuffer_copyImageToBuffer(vxr_vk_instance, VkCommandBuffer, VkImage, VkImageLayout,
+														  VkBuffer, uint32_t, VkBufferImageCopy*);
 
 extern VXR_FN void vxr_vk_createSemaphore(vxr_vk_instance, size_t, const char*, VkSemaphoreType, VkSemaphore*);
 extern VXR_FN void vxr_vk_signalSemaphore(vxr_vk_instance, VkSemaphore, uint64_t);
 extern VXR_FN void vxr_vk_waitSemaphore(vxr_vk_instance, VkSemaphore, uint64_t);
+extern VXR_FN VkResult vxr_vk_waitSemaphores(vxr_vk_instance, uint32_t, VkSemaphore*, uint64_t*, VkBool32, uint64_t);
 extern VXR_FN uint64_t vxr_vk_getSemaphoreValue(vxr_vk_instance, VkSemaphore);
 extern VXR_FN void vxr_vk_destroySemaphore(vxr_vk_instance, VkSemaphore);
 
+extern VXR_FN void vxr_vk_createQueryPool(vxr_vk_instance, size_t, const char*, VkQueryType, uint32_t, VkQueryPipelineStatisticFlags,
+										  VkQueryPool*);
+extern VXR_FN void vxr_vk_resetQueryPool(vxr_vk_instance, VkQueryPool, uint32_t, uint32_t);
+extern VXR_FN VkResult vxr_vk_getQueryPoolResults(vxr_vk_instance, VkQueryPool, uint32_t, uint32_t, size_t, void*, VkDeviceSize,
+												  VkQueryResultFlags);
+extern VXR_FN void vxr_vk_destroyQueryPool(vxr_vk_instance, VkQueryPool);
+
 extern VXR_FN void vxr_vk_createHostBuffer(vxr_vk_instance, size_t, const char*, vxr_vk_bufferCreateInfo, vxr_vk_hostBuffer*);
 extern VXR_FN void vxr_vk_destroyHostBuffer(vxr_vk_instance, vxr_vk_hostBuffer);
 extern VXR_FN void vxr_vk_hostBuffer_write(vxr_vk_instance, vxr_vk_hostBuffer, size_t, size_t, void*);
@@ -496,7 +645,12 @@ extern VXR_FN void vxr_vk_shader_destroyToolchain(vxr_vk_shader_toolchain);
 
 extern VXR_FN void vxr_vk_shader_compile(vxr_vk_shader_toolchain, vxr_vk_shader_compileInfo,
 										 vxr_vk_shader_compileResult*, vxr_vk_shader_reflectResult*);
+extern VXR_FN VkBool32 vxr_vk_shader_tryCompile(vxr_vk_shader_toolchain, vxr_vk_shader_compileInfo, vxr_vk_shader_compileResult*,
+											  vxr_vk_shader_reflectResult*, vxr_vk_shader_compileError*);
 extern VXR_FN void vxr_vk_shader_destroyCompileResult(vxr_vk_shader_compileResult);
+extern VXR_FN void vxr_vk_shader_destroyCompileError(vxr_vk_shader_compileError);
+
+extern VXR_FN void vxr_vk_shader_compileError_getMessage(vxr_vk_shader_compileError, size_t*, const char**);
 
 extern VXR_FN void vxr_vk_shader_compileResult_getSPIRV(vxr_vk_shader_compileResult, vxr_vk_shader_spirv*);
 
@@ -507,6 +661,7 @@ extern VXR_FN void vxr_vk_shader_reflectResult_getEntryPoints(vxr_vk_shader_refl
 extern VXR_FN void vxr_vk_shader_reflectResult_getSpecConstants(vxr_vk_shader_reflectResult, uint32_t*, vxr_vk_shader_reflectResult_specConstant*);
 extern VXR_FN void vxr_vk_shader_reflectResult_getLocalSize(vxr_vk_shader_reflectResult, vxr_vk_shader_reflectResult_constant (*)[3]);
 extern VXR_FN void vxr_vk_shader_reflectResult_getNumOutputs(vxr_vk_shader_reflectResult, size_t, uint32_t*);
+extern VXR_FN void vxr_vk_shader_reflectResult_getUsesViewIndex(vxr_vk_shader_reflectResult, size_t, VkBool32*);
 extern VXR_FN void vxr_vk_shader_reflectResult_getPushConstantRange(vxr_vk_shader_reflectResult, VkPushConstantRange*);
 extern VXR_FN void vxr_vk_shader_reflectResult_getDescriptorSetSizes(vxr_vk_shader_reflectResult, uint32_t*, uint32_t*);
 extern VXR_FN void vxr_vk_shader_reflectResult_getDescriptorSetBinding(vxr_vk_shader_reflectResult, uint32_t, uint32_t,
@@ -518,17 +673,33 @@ extern VXR_FN void vxr_vk_shader_reflectResult_getSamplerMetadata(vxr_vk_shader_
 extern VXR_FN void vxr_vk_shader_reflectResult_getImageMetadata(vxr_vk_shader_reflectResult, uint32_t, uint32_t,
 																uint32_t, vxr_vk_shader_reflectResult_imageMetadata*);
 
-extern VXR_FN void vxr_vk_shader_createDescriptorSetLayout(vxr_vk_instance, size_t, const char*, uint32_t,
-														   VkDescriptorSetLayoutBinding*, VkDescriptorSetLayout*);
+extern VXR_FN void vxr_vk_shader_createDescriptorSetLayout(vxr_vk_instance, size_t, const char*, VkDescriptorSetLayoutCreateFlags,
+														   uint32_t, VkDescriptorSetLayoutBinding*, VkDescriptorSetLayout*);
 extern VXR_FN void vxr_vk_shader_destroyDescriptorSetLayout(vxr_vk_instance, VkDescriptorSetLayout);
 
 extern VXR_FN void vxr_vk_shader_createDescriptorPool(vxr_vk_instance, size_t, const char*, VkDescriptorPoolCreateInfo, VkDescriptorPool*);
+extern VXR_FN void vxr_vk_shader_resetDescriptorPool(vxr_vk_instance, VkDescriptorPool);
 extern VXR_FN void vxr_vk_shader_destroyDescriptorPool(vxr_vk_instance, VkDescriptorPool);
 
 extern VXR_FN void vxr_vk_shader_createDescriptorSet(vxr_vk_instance, size_t, const char*, VkDescriptorSetAllocateInfo, VkDescriptorSet*);
 extern VXR_FN void vxr_vk_shader_updateDescriptorSet(vxr_vk_instance, VkWriteDescriptorSet);
+extern VXR_FN void vxr_vk_shader_updateDescriptorSets(vxr_vk_instance, uint32_t, const VkWriteDescriptorSet*);
+extern VXR_FN void vxr_vk_shader_createDescriptorUpdateTemplate(vxr_vk_instance, size_t, const char*, VkDescriptorSetLayout, uint32_t,
+																const VkDescriptorUpdateTemplateEntry*, VkDescriptorUpdateTemplate*);
+extern VXR_FN void vxr_vk_shader_destroyDescriptorUpdateTemplate(vxr_vk_instance, VkDescriptorUpdateTemplate);
+extern VXR_FN void vxr_vk_shader_updateDescriptorSetWithTemplate(vxr_vk_instance, VkDescriptorSet, VkDescriptorUpdateTemplate, const void*);
 extern VXR_FN void vxr_vk_shader_destroyDescriptorSet(vxr_vk_instance, VkDescriptorPool, VkDescriptorSet);
 
+extern VXR_FN void vxr_vk_shader_createBindlessHeap(vxr_vk_instance, size_t, const char*, uint32_t, VkDescriptorSetLayoutBinding*,
+													VkDescriptorSetLayout*, VkDescriptorPool*, VkDescriptorSet*);
+
+extern VXR_FN void vxr_vk_descriptorBuffer_init(vxr_vk_instance, VkBool32, VkDeviceSize*, VkDeviceSize*);
+extern VXR_FN void vxr_vk_descriptorBuffer_destroy(vxr_vk_instance);
+extern VXR_FN void vxr_vk_descriptorBuffer_getLayoutSize(vxr_vk_instance, VkDescriptorSetLayout, VkDeviceSize*);
+extern VXR_FN void vxr_vk_descriptorBuffer_write(vxr_vk_instance, VkDeviceSize, VkDescriptorSetLayout, uint32_t, VkWriteDescriptorSet);
+extern VXR_FN void vxr_vk_descriptorBuffer_writes(vxr_vk_instance, uint32_t, const vxr_vk_descriptorBuffer_writeInfo*);
+extern VXR_FN void vxr_vk_descriptorBuffer_bind(vxr_vk_instance, VkCommandBuffer);
+
 extern VXR_FN void vxr_vk_shader_createPipelineLayout(vxr_vk_instance, size_t, const char*,
 													  vxr_vk_shader_pipelineLayoutCreateInfo, VkPipelineLayout*);
 extern VXR_FN void vxr_vk_shader_destroyPipelineLayout(vxr_vk_instance, VkPipelineLayout);
@@ -540,10 +711,15 @@ extern VXR_FN void vxr_vk_compute_createShaderPipeline(vxr_vk_instance, size_t,
 extern VXR_FN void vxr_vk_compute_dispatch(vxr_vk_instance, VkCommandBuffer, vxr_vk_compute_dispatchInfo);
 extern VXR_FN void vxr_vk_compute_dispatchIndirect(vxr_vk_instance, VkCommandBuffer, vxr_vk_compute_dispatchIndirectInfo);
 
-extern VXR_FN VkResult vxr_vk_graphics_init(vxr_vk_instance, uint64_t, uint32_t);
 extern VXR_FN void vxr_vk_graphics_destroy(vxr_vk_instance);
 
-extern VXR_FN void vxr_vk_graphics_getSurfaceInfo(vxr_vk_instance, vxr_vk_surfaceInfo*);
+extern VXR_FN VkResult vxr_vk_graphics_getSupportedSurfaceFormats(vxr_vk_instance, uint64_t, uint32_t*, VkSurfaceFormatKHR*);
+
+extern VXR_FN void vxr_vk_graphics_createSwapchain(vxr_vk_instance, size_t, const char*, uint64_t, vxr_vk_graphics_swapchain*);
+extern VXR_FN void vxr_vk_graphics_destroySwapchain(vxr_vk_instance, vxr_vk_graphics_swapchain);
+extern VXR_FN VkResult vxr_vk_graphics_swapchain_init(vxr_vk_instance, vxr_vk_graphics_swapchain, vxr_vk_graphics_swapchainCreateInfo);
+extern VXR_FN void vxr_vk_graphics_swapchain_getSurfaceInfo(vxr_vk_graphics_swapchain, vxr_vk_surfaceInfo*);
+extern VXR_FN void vxr_vk_graphics_swapchain_setHdrMetadata(vxr_vk_instance, vxr_vk_graphics_swapchain, VkHdrMetadataEXT);
 
 extern VXR_FN void vxr_vk_graphics_createVertexInputPipeline(vxr_vk_instance, size_t, const char*, VkPrimitiveTopology, VkBool32, VkPipeline*);
 extern VXR_FN void vxr_vk_graphics_createVertexShaderPipeline(vxr_vk_instance, size_t, const char*,
@@ -563,8 +739,8 @@ extern VXR_FN void vxr_vk_graphics_destroyFrame(vxr_vk_graphics_frame);
 extern VXR_FN void vxr_vk_graphics_frame_begin(vxr_vk_instance, size_t, const char*, vxr_vk_graphics_frame);
 extern VXR_FN void vxr_vk_graphics_frame_cancel(vxr_vk_instance, vxr_vk_graphics_frame);
 extern VXR_FN void vxr_vk_graphics_frame_end(vxr_vk_instance, vxr_vk_graphics_frame);
-extern VXR_FN VkResult vxr_vk_graphics_frame_acquireSurface(vxr_vk_instance, vxr_vk_graphics_frame, vxr_vk_surface*);
-extern VXR_FN VkResult vxr_vk_graphics_frame_submit(vxr_vk_instance, vxr_vk_graphics_frame);
+extern VXR_FN VkResult vxr_vk_graphics_frame_acquireSurface(vxr_vk_instance, vxr_vk_graphics_frame, vxr_vk_graphics_swapchain, vxr_vk_surface*);
+extern VXR_FN VkResult vxr_vk_graphics_frame_submit(vxr_vk_instance, vxr_vk_graphics_frame, VkResult*);
 extern VXR_FN void vxr_vk_graphics_frame_wait(vxr_vk_instance, vxr_vk_graphics_frame);
 
 extern VXR_FN void vxr_vk_graphics_frame_createHostScratchBuffer(vxr_vk_instance, vxr_vk_graphics_frame, size_t,
@@ -574,16 +750,29 @@ extern VXR_FN void vxr_vk_graphics_frame_commandBufferBegin(vxr_vk_instance, vxr
 extern VXR_FN void vxr_vk_graphics_frame_commandBufferSubmit(vxr_vk_instance, vxr_vk_graphics_frame, VkCommandBuffer, uint32_t,
 															 VkSemaphoreSubmitInfo*, uint32_t, VkSemaphoreSubmitInfo*);
 
+extern VXR_FN void vxr_vk_graphics_frame_createCommandRecorder(vxr_vk_instance, vxr_vk_graphics_frame, size_t, const char*,
+															   vxr_vk_graphics_commandRecorder*);
+extern VXR_FN void vxr_vk_graphics_commandRecorder_commandBufferBegin(vxr_vk_instance, vxr_vk_graphics_commandRecorder, size_t, const char*,
+																	  VkCommandBuffer*);
+extern VXR_FN void vxr_vk_graphics_commandRecorder_commandBufferEnd(vxr_vk_instance, vxr_vk_graphics_commandRecorder, VkCommandBuffer);
+extern VXR_FN void vxr_vk_graphics_frame_commandBuffersSubmit(vxr_vk_instance, vxr_vk_graphics_frame, uint32_t, VkCommandBuffer*, uint32_t,
+															  VkSemaphoreSubmitInfo*, uint32_t, VkSemaphoreSubmitInfo*);
+
 extern VXR_FN void vxr_vk_graphics_renderPassBegin(vxr_vk_instance, VkCommandBuffer, size_t, const char*, vxr_vk_graphics_renderPassInfo);
 extern VXR_FN void vxr_vk_graphics_renderPassSetViewport(vxr_vk_instance, VkCommandBuffer, VkBool32, VkViewport);
 extern VXR_FN void vxr_vk_graphics_renderPassSetScissor(vxr_vk_instance, VkCommandBuffer, VkRect2D);
 extern VXR_FN void vxr_vk_graphics_renderPassSetViewportAndScissor(vxr_vk_instance, VkCommandBuffer, VkBool32, VkViewport, VkRect2D);
 extern VXR_FN void vxr_vk_graphics_renderPassSetColorBlend(vxr_vk_instance, VkCommandBuffer, uint32_t, uint32_t, vxr_vk_graphics_colorBlendInfo);
 extern VXR_FN void vxr_vk_graphics_renderPassSetLineWidth(vxr_vk_instance, VkCommandBuffer, float);
+extern VXR_FN void vxr_vk_graphics_beginConditionalRendering(vxr_vk_instance, VkCommandBuffer, VkBuffer, VkDeviceSize,
+															 VkConditionalRenderingFlagsEXT);
+extern VXR_FN void vxr_vk_graphics_endConditionalRendering(vxr_vk_instance, VkCommandBuffer);
 extern VXR_FN void vxr_vk_graphics_draw(vxr_vk_instance, VkCommandBuffer, vxr_vk_graphics_drawInfo);
 extern VXR_FN void vxr_vk_graphics_drawIndirect(vxr_vk_instance, VkCommandBuffer, vxr_vk_graphics_drawIndirectInfo);
+extern VXR_FN void vxr_vk_graphics_drawIndirectCount(vxr_vk_instance, VkCommandBuffer, vxr_vk_graphics_drawIndirectCountInfo);
 extern VXR_FN void vxr_vk_graphics_drawIndexed(vxr_vk_instance, VkCommandBuffer, vxr_vk_graphics_drawIndexedInfo);
 extern VXR_FN void vxr_vk_graphics_drawIndexedIndirect(vxr_vk_instance, VkCommandBuffer, vxr_vk_graphics_drawIndexedIndirectInfo);
+extern VXR_FN void vxr_vk_graphics_drawIndexedIndirectCount(vxr_vk_instance, VkCommandBuffer, vxr_vk_graphics_drawIndexedIndirectCountInfo);
 extern VXR_FN void vxr_vk_graphics_renderPassEnd(vxr_vk_instance, VkCommandBuffer);
 
 #ifdef __cplusplus
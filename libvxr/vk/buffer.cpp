@@ -36,7 +36,7 @@ VXR_FN void vxr_vk_createHostBuffer(vxr_vk_instance instanceHandle, size_t nameS
 	VkBufferCreateInfo bufferInfo = {};
 	bufferInfo.sType = VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO;
 	bufferInfo.size = info.size;
-	bufferInfo.usage = info.usage;
+	bufferInfo.usage = instance->device.bufferUsage(info.usage);
 	bufferInfo.sharingMode = VK_SHARING_MODE_EXCLUSIVE;
 
 	VmaAllocationCreateInfo allocCreateInfo = {};
@@ -85,7 +85,7 @@ VXR_FN void vxr_vk_createDeviceBuffer(vxr_vk_instance instanceHandle, size_t nam
 	VkBufferCreateInfo bufferInfo = {};
 	bufferInfo.sType = VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO;
 	bufferInfo.size = info.size;
-	bufferInfo.usage = info.usage;
+	bufferInfo.usage = instance->device.bufferUsage(info.usage);
 	bufferInfo.sharingMode = VK_SHARING_MODE_EXCLUSIVE;
 
 	VmaAllocationCreateInfo allocCreateInfo = {};
@@ -20,13 +20,147 @@ limitations under the License.
 #include <stdint.h>
 
 #include "std/stdlib.hpp"
+#include "std/log.hpp"
+#include "std/array.hpp"
 #include "std/string.hpp"
+#include "std/vector.hpp"
 
 #include "vk/vk.hpp"
+#include "vk/vkfns.hpp"
 #include "vk/vklog.hpp"
 #include "vk/device/device.hpp"
 
+static const vxr::vk::device::queue* getQueue(vxr::vk::instance* instance, vxr_vk_queueType queue) {
+	switch (queue) {
+		case vxr_vk_queueType_graphics:
+			return &instance->device.graphicsQueue;
+		case vxr_vk_queueType_compute:
+			return &instance->device.computeQueue;
+		case vxr_vk_queueType_transfer:
+			return &instance->device.transferQueue;
+	}
+	vxr::std::ePrintf("Unknown queue type: %d", queue);
+	vxr::std::abort();
+	return nullptr;
+}
+
 extern "C" {
+// resources are created with exclusive sharing for the graphics queue family,
+// so queues from other families cannot be used without ownership transfers and fall back to the graphics queue
+VXR_FN vxr_vk_queueType vxr_vk_resolveQueue(vxr_vk_instance instanceHandle, vxr_vk_queueType queue) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	if (getQueue(instance, queue)->family != instance->device.graphicsQueue.family) {
+		return vxr_vk_queueType_graphics;
+	}
+	return queue;
+}
+VXR_FN void vxr_vk_getTimestampValidBits(vxr_vk_instance instanceHandle, vxr_vk_queueType queue, uint32_t* timestampValidBits) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	uint32_t numQueueFamilies = 0;
+	VK_PROC(vkGetPhysicalDeviceQueueFamilyProperties)(instance->device.vkPhysicalDevice, &numQueueFamilies, nullptr);
+	vxr::std::vector<VkQueueFamilyProperties> queueFamilies(numQueueFamilies);
+	VK_PROC(vkGetPhysicalDeviceQueueFamilyProperties)(instance->device.vkPhysicalDevice, &numQueueFamilies, queueFamilies.get());
+	*timestampValidBits = queueFamilies[getQueue(instance, queue)->family].timestampValidBits;
+}
+VXR_FN void vxr_vk_commandBufferBegin(vxr_vk_instance instanceHandle, vxr_vk_queueType queue, size_t nameSz, const char* name,
+									  VkCommandPool* pool, VkCommandBuffer* cb) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	{
+		VkCommandPoolCreateInfo poolInfo = {};
+		poolInfo.sType = VK_STRUCTURE_TYPE_COMMAND_POOL_CREATE_INFO;
+		poolInfo.queueFamilyIndex = getQueue(instance, queue)->family;
+		poolInfo.flags = VK_COMMAND_POOL_CREATE_TRANSIENT_BIT;
+
+		const VkResult ret = VK_PROC_DEVICE(vkCreateCommandPool)(instance->device.vkDevice, &poolInfo, nullptr, pool);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to create commandpool: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+		vxr::std::debugRun([=]() {
+			vxr::std::stringbuilder builder;
+			builder.write("cmd_pool_").write(nameSz, name);
+			vxr::vk::debugLabel(instance->device.vkDevice, *pool, builder.cStr());
+		});
+	}
+
+	{
+		VkCommandBufferAllocateInfo allocateInfo = {};
+		allocateInfo.sType = VK_STRUCTURE_TYPE_COMMAND_BUFFER_ALLOCATE_INFO;
+		allocateInfo.commandPool = *pool;
+		allocateInfo.level = VK_COMMAND_BUFFER_LEVEL_PRIMARY;
+		allocateInfo.commandBufferCount = 1;
+
+		const VkResult ret = VK_PROC_DEVICE(vkAllocateCommandBuffers)(instance->device.vkDevice, &allocateInfo, cb);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to create command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+	}
+
+	{
+		VkCommandBufferBeginInfo beginInfo = {};
+		beginInfo.sType = VK_STRUCTURE_TYPE_COMMAND_BUFFER_BEGIN_INFO;
+		beginInfo.flags = VK_COMMAND_BUFFER_USAGE_ONE_TIME_SUBMIT_BIT;
+
+		const VkResult ret = VK_PROC_DEVICE(vkBeginCommandBuffer)(*cb, &beginInfo);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to begin command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+
+		vxr::std::debugRun([=]() {
+			vxr::std::stringbuilder builder;
+			builder.write("cmd_buffer_").write(nameSz, name);
+			vxr::vk::debugLabelBegin(*cb, builder.cStr());
+		});
+	}
+}
+VXR_FN void vxr_vk_commandBufferSubmit(vxr_vk_instance instanceHandle, vxr_vk_queueType queue, VkCommandBuffer cb,
+									   uint32_t numWaitSemaphores, VkSemaphoreSubmitInfo* waitSemaphores, uint32_t numSignalSemaphores,
+									   VkSemaphoreSubmitInfo* signalSemaphores) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	vxr::std::debugRun([=]() { vxr::vk::debugLabelEnd(cb); });
+
+	{
+		const VkResult ret = VK_PROC_DEVICE(vkEndCommandBuffer)(cb);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to end command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+	}
+
+	{
+		VkSubmitInfo2 submitInfo = {};
+		submitInfo.sType = VK_STRUCTURE_TYPE_SUBMIT_INFO_2;
+
+		submitInfo.waitSemaphoreInfoCount = numWaitSemaphores;
+		submitInfo.pWaitSemaphoreInfos = waitSemaphores;
+
+		vxr::std::array commandbuffers = {
+			VkCommandBufferSubmitInfo{
+				.sType = VK_STRUCTURE_TYPE_COMMAND_BUFFER_SUBMIT_INFO,
+				.commandBuffer = cb,
+			},
+		};
+		submitInfo.commandBufferInfoCount = commandbuffers.size();
+		submitInfo.pCommandBufferInfos = commandbuffers.get();
+
+		submitInfo.signalSemaphoreInfoCount = numSignalSemaphores;
+		submitInfo.pSignalSemaphoreInfos = signalSemaphores;
+
+		const VkResult ret = VK_PROC_DEVICE(vkQueueSubmit2)(getQueue(instance, queue)->vkQueue, 1, &submitInfo, VK_NULL_HANDLE);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to submit command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+	}
+}
+VXR_FN void vxr_vk_destroyCommandPool(vxr_vk_instance instanceHandle, VkCommandPool pool) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	VK_PROC_DEVICE(vkDestroyCommandPool)(instance->device.vkDevice, pool, nullptr);
+}
 VXR_FN void vxr_vk_commandBuffer_beginNamedRegion(vxr_vk_instance, VkCommandBuffer cb, size_t nameSz, const char* name) {
 	vxr::std::debugRun([=]() {
 		vxr::std::stringbuilder builder;
@@ -56,8 +190,28 @@ VXR_FN void vxr_vk_commandBuffer_copyBuffer(vxr_vk_instance, VkCommandBuffer cb,
 											uint32_t regionCount, VkBufferCopy* regions) {
 	VK_PROC_DEVICE(vkCmdCopyBuffer)(cb, bIn, bOut, regionCount, regions);
 }
+VXR_FN void vxr_vk_commandBuffer_writeTimestamp(vxr_vk_instance, VkCommandBuffer cb, VkPipelineStageFlags2 stage, VkQueryPool pool,
+												uint32_t query) {
+	VK_PROC_DEVICE(vkCmdWriteTimestamp2)(cb, stage, pool, query);
+}
+VXR_FN void vxr_vk_commandBuffer_beginQuery(vxr_vk_instance, VkCommandBuffer cb, VkQueryPool pool, uint32_t query,
+											VkQueryControlFlags flags) {
+	VK_PROC_DEVICE(vkCmdBeginQuery)(cb, pool, query, flags);
+}
+VXR_FN void vxr_vk_commandBuffer_endQuery(vxr_vk_instance, VkCommandBuffer cb, VkQueryPool pool, uint32_t query) {
+	VK_PROC_DEVICE(vkCmdEndQuery)(cb, pool, query);
+}
+VXR_FN void vxr_vk_commandBuffer_copyQueryPoolResults(vxr_vk_instance, VkCommandBuffer cb, VkQueryPool pool, uint32_t first,
+													  uint32_t count, VkBuffer buffer, VkDeviceSize offset, VkDeviceSize stride,
+													  VkQueryResultFlags flags) {
+	VK_PROC_DEVICE(vkCmdCopyQueryPoolResults)(cb, pool, first, count, buffer, offset, stride, flags);
+}
 VXR_FN void vxr_vk_commandBuffer_copyBufferToImage(vxr_vk_instance, VkCommandBuffer cb, VkBuffer buffer, VkImage image,
 												   VkImageLayout layout, uint32_t regionCount, VkBufferImageCopy* regions) {
 	VK_PROC_DEVICE(vkCmdCopyBufferToImage)(cb, buffer, image, layout, regionCount, regions);
 }
+VXR_FN void vxr_vk_commandBuffer_copyImageToBuffer(vxr_vk_instance, VkCommandBuffer cb, VkImage image, VkImageLayout layout,
+												   VkBuffer buffer, uint32_t regionCount, VkBufferImageCopy* regions) {
+	VK_PROC_DEVICE(vkCmdCopyImageToBuffer)(cb, image, layout, buffer, regionCount, regions);
+}
 }
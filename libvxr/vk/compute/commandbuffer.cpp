@@ -18,6 +18,7 @@ limitations under the License.
 
 #include "vk/vk.hpp"
 #include "vk/device/device.hpp"
+#include "vk/descriptor.hpp"
 
 extern "C" {
 VXR_FN void vxr_vk_compute_dispatch(vxr_vk_instance, VkCommandBuffer cb, vxr_vk_compute_dispatchInfo info) {
@@ -26,10 +27,8 @@ VXR_FN void vxr_vk_compute_dispatch(vxr_vk_instance, VkCommandBuffer cb, vxr_vk_
 		(cb, info.layout, info.pushConstantRange.stageFlags, info.pushConstantRange.offset, info.pushConstantRange.size,
 		 info.pushConstantData);
 	}
-	if (info.numDescriptorSets > 0) {
-		VK_PROC_DEVICE(vkCmdBindDescriptorSets)
-		(cb, VK_PIPELINE_BIND_POINT_COMPUTE, info.layout, 0, info.numDescriptorSets, info.descriptorSets, 0, nullptr);
-	}
+	vxr::vk::descriptor::bindSets(cb, VK_PIPELINE_BIND_POINT_COMPUTE, info.layout, info.numDescriptorSets, info.descriptorSets,
+								  info.descriptorBufferOffsets, info.pushDescriptors);
 	VK_PROC_DEVICE(vkCmdBindPipeline)(cb, VK_PIPELINE_BIND_POINT_COMPUTE, info.pipeline);
 	VK_PROC_DEVICE(vkCmdDispatch)(cb, info.groupCount.width, info.groupCount.height, info.groupCount.depth);
 }
@@ -40,8 +39,8 @@ VXR_FN void vxr_vk_compute_dispatchIndirect(vxr_vk_instance, VkCommandBuffer cb,
 		 info.pushConstantData);
 	}
 
-	VK_PROC_DEVICE(vkCmdBindDescriptorSets)
-	(cb, VK_PIPELINE_BIND_POINT_COMPUTE, info.layout, 0, info.numDescriptorSets, info.descriptorSets, 0, nullptr);
+	vxr::vk::descriptor::bindSets(cb, VK_PIPELINE_BIND_POINT_COMPUTE, info.layout, info.numDescriptorSets, info.descriptorSets,
+								  info.descriptorBufferOffsets, info.pushDescriptors);
 	VK_PROC_DEVICE(vkCmdBindPipeline)(cb, VK_PIPELINE_BIND_POINT_COMPUTE, info.pipeline);
 
 	VK_PROC_DEVICE(vkCmdDispatchIndirect)(cb, info.buffer, info.offset);
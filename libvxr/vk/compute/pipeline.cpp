@@ -78,6 +78,7 @@ VXR_FN void vxr_vk_compute_createShaderPipeline(vxr_vk_instance instanceHandle,
 
 	const VkComputePipelineCreateInfo computePipelineCreateInfo = {
 		.sType = VK_STRUCTURE_TYPE_COMPUTE_PIPELINE_CREATE_INFO,
+		.flags = instance->device.pipelineCreateFlags(),
 		.stage = stageCreateInfo,
 		.layout = shader.layout,
 	};
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+#pragma once
+
+#ifndef __cplusplus
+#error C++ only header
+#endif
+
+#include <stdint.h>
+
+#include "std/stdlib.hpp"
+#include "std/log.hpp"
+#include "std/utility.hpp"
+
+#include "vxr/vxr.h"
+#include "vk/device/device.hpp"
+
+namespace vxr::vk::descriptor {
+// every set lives in the single descriptor buffer bound at index 0, so only the offsets differ between sets
+inline void bindBufferSets(VkCommandBuffer cb, VkPipelineBindPoint bindPoint, VkPipelineLayout layout, uint32_t firstSet,
+						   uint32_t numSets, const VkDeviceSize* offsets) noexcept {
+	static constexpr uint32_t bufferIndices[32] = {};
+	if (numSets > (sizeof(bufferIndices) / sizeof(bufferIndices[0]))) {
+		vxr::std::ePrintf("Trying to bind %u descriptor sets from the descriptor buffer", numSets);
+		vxr::std::abort();
+	}
+	VK_TRY_PROC_DEVICE(vkCmdSetDescriptorBufferOffsetsEXT)(cb, bindPoint, layout, firstSet, numSets, bufferIndices, offsets);
+}
+
+inline void bindSetRange(VkCommandBuffer cb, VkPipelineBindPoint bindPoint, VkPipelineLayout layout, uint32_t firstSet,
+						 uint32_t numSets, const VkDescriptorSet* sets, const VkDeviceSize* offsets) noexcept {
+	if (numSets == 0) {
+		return;
+	}
+	if (offsets != nullptr) {
+		bindBufferSets(cb, bindPoint, layout, firstSet, numSets, offsets + firstSet);
+	} else {
+		VK_PROC_DEVICE(vkCmdBindDescriptorSets)(cb, bindPoint, layout, firstSet, numSets, sets + firstSet, 0, nullptr);
+	}
+}
+
+// the push descriptor set cannot be bound, so the sets on either side of it are bound separately
+// and its descriptors are pushed afterwards
+inline void bindSets(VkCommandBuffer cb, VkPipelineBindPoint bindPoint, VkPipelineLayout layout, uint32_t numSets,
+					 const VkDescriptorSet* sets, const VkDeviceSize* offsets, const vxr_vk_pushDescriptorSet& push) noexcept {
+	if (!push.enable) {
+		bindSetRange(cb, bindPoint, layout, 0, numSets, sets, offsets);
+		return;
+	}
+
+	bindSetRange(cb, bindPoint, layout, 0, vxr::std::min(push.set, numSets), sets, offsets);
+	if (push.set + 1 < numSets) {
+		bindSetRange(cb, bindPoint, layout, push.set + 1, numSets - push.set - 1, sets, offsets);
+	}
+
+	if (push.numWrites == 0) {
+		return;
+	}
+	// the core entry point only exists when the device was created with 1.4
+	if (VK_TRY_PROC_DEVICE(vkCmdPushDescriptorSet) != nullptr) {
+		VK_TRY_PROC_DEVICE(vkCmdPushDescriptorSet)(cb, bindPoint, layout, push.set, push.numWrites, push.writes);
+	} else {
+		VK_TRY_PROC_DEVICE(vkCmdPushDescriptorSetKHR)(cb, bindPoint, layout, push.set, push.numWrites, push.writes);
+	}
+}
+}  // namespace vxr::vk::descriptor
@@ -0,0 +1,238 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+#include "vxr/vxr.h"  // IWYU pragma: associated
+
+#include <stddef.h>
+#include <stdint.h>
+
+#include "std/stdlib.hpp"
+#include "std/log.hpp"
+#include "std/string.hpp"
+
+#include "vk/vk.hpp"
+#include "vk/vkfns.hpp"
+#include "vk/vklog.hpp"
+#include "vk/device/device.hpp"
+#include "vk/device/vma/vma.hpp"
+
+static size_t descriptorSize(const VkPhysicalDeviceDescriptorBufferPropertiesEXT& properties, VkDescriptorType type) {
+	switch (type) {
+		case VK_DESCRIPTOR_TYPE_SAMPLER:
+			return properties.samplerDescriptorSize;
+		case VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER:
+			return properties.combinedImageSamplerDescriptorSize;
+		case VK_DESCRIPTOR_TYPE_SAMPLED_IMAGE:
+			return properties.sampledImageDescriptorSize;
+		case VK_DESCRIPTOR_TYPE_STORAGE_IMAGE:
+			return properties.storageImageDescriptorSize;
+		case VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER:
+			return properties.uniformBufferDescriptorSize;
+		case VK_DESCRIPTOR_TYPE_STORAGE_BUFFER:
+			return properties.storageBufferDescriptorSize;
+		default:
+			vxr::std::ePrintf("Descriptor type %d is not supported by the descriptor buffer backend", type);
+			vxr::std::abort();
+	}
+	return 0;
+}
+
+static void writeDescriptors(vxr::vk::instance* instance, VkDeviceSize setOffset, VkDescriptorSetLayout layout,
+							 uint32_t bindingDescriptorCount, const VkWriteDescriptorSet& write) {
+	const auto& descriptorBuffer = instance->device.descriptorBuffer;
+
+	VkDeviceSize bindingOffset = 0;
+	VK_TRY_PROC_DEVICE(vkGetDescriptorSetLayoutBindingOffsetEXT)(instance->device.vkDevice, layout, write.dstBinding, &bindingOffset);
+	uint8_t* binding = descriptorBuffer.ptr + setOffset + bindingOffset;
+
+	// without single array support a binding of combined image samplers is laid out as all of its images followed by all of its samplers
+	if ((write.descriptorType == VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER)
+		&& (descriptorBuffer.properties.combinedImageSamplerDescriptorSingleArray == VK_FALSE)) {
+		const size_t imageSize = descriptorBuffer.properties.sampledImageDescriptorSize;
+		const size_t samplerSize = descriptorBuffer.properties.samplerDescriptorSize;
+		uint8_t* samplers = binding + (bindingDescriptorCount * imageSize);
+
+		for (uint32_t i = 0; i < write.descriptorCount; i++) {
+			const uint32_t element = write.dstArrayElement + i;
+			VkDescriptorGetInfoEXT info = {.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_GET_INFO_EXT, .type = VK_DESCRIPTOR_TYPE_SAMPLED_IMAGE};
+			info.data.pSampledImage = &write.pImageInfo[i];
+			VK_TRY_PROC_DEVICE(vkGetDescriptorEXT)(instance->device.vkDevice, &info, imageSize, binding + (element * imageSize));
+
+			info.type = VK_DESCRIPTOR_TYPE_SAMPLER;
+			info.data.pSampler = &write.pImageInfo[i].sampler;
+			VK_TRY_PROC_DEVICE(vkGetDescriptorEXT)(instance->device.vkDevice, &info, samplerSize, samplers + (element * samplerSize));
+		}
+		return;
+	}
+
+	const size_t size = descriptorSize(descriptorBuffer.properties, write.descriptorType);
+	for (uint32_t i = 0; i < write.descriptorCount; i++) {
+		VkDescriptorGetInfoEXT info = {.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_GET_INFO_EXT, .type = write.descriptorType};
+		VkDescriptorAddressInfoEXT addressInfo = {.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_ADDRESS_INFO_EXT};
+
+		switch (write.descriptorType) {
+			case VK_DESCRIPTOR_TYPE_SAMPLER:
+				info.data.pSampler = &write.pImageInfo[i].sampler;
+				break;
+			case VK_DESCRIPTOR_TYPE_COMBINED_IMAGE_SAMPLER:
+				info.data.pCombinedImageSampler = &write.pImageInfo[i];
+				break;
+			case VK_DESCRIPTOR_TYPE_SAMPLED_IMAGE:
+				info.data.pSampledImage = &write.pImageInfo[i];
+				break;
+			case VK_DESCRIPTOR_TYPE_STORAGE_IMAGE:
+				info.data.pStorageImage = &write.pImageInfo[i];
+				break;
+			case VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER:
+			case VK_DESCRIPTOR_TYPE_STORAGE_BUFFER: {
+				const VkBufferDeviceAddressInfo bufferAddressInfo = {
+					.sType = VK_STRUCTURE_TYPE_BUFFER_DEVICE_ADDRESS_INFO,
+					.buffer = write.pBufferInfo[i].buffer,
+				};
+				addressInfo.address = VK_PROC_DEVICE(vkGetBufferDeviceAddress)(instance->device.vkDevice, &bufferAddressInfo)
+									  + write.pBufferInfo[i].offset;
+				addressInfo.range = write.pBufferInfo[i].range;
+				if (write.descriptorType == VK_DESCRIPTOR_TYPE_UNIFORM_BUFFER) {
+					info.data.pUniformBuffer = &addressInfo;
+				} else {
+					info.data.pStorageBuffer = &addressInfo;
+				}
+			} break;
+			default:
+				vxr::std::ePrintf("Descriptor type %d is not supported by the descriptor buffer backend", write.descriptorType);
+				vxr::std::abort();
+		}
+
+		VK_TRY_PROC_DEVICE(vkGetDescriptorEXT)(instance->device.vkDevice, &info, size, binding + ((write.dstArrayElement + i) * size));
+	}
+}
+
+extern "C" {
+VXR_FN void vxr_vk_descriptorBuffer_init(vxr_vk_instance instanceHandle, VkBool32 pushDescriptors, VkDeviceSize* size,
+										  VkDeviceSize* offsetAlignment) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	auto* descriptorBuffer = &instance->device.descriptorBuffer;
+
+	descriptorBuffer->properties = {.sType = VK_STRUCTURE_TYPE_PHYSICAL_DEVICE_DESCRIPTOR_BUFFER_PROPERTIES_EXT};
+	VkPhysicalDeviceProperties2 properties = {
+		.sType = VK_STRUCTURE_TYPE_PHYSICAL_DEVICE_PROPERTIES_2,
+		.pNext = &descriptorBuffer->properties,
+	};
+	VK_PROC(vkGetPhysicalDeviceProperties2)(instance->device.vkPhysicalDevice, &properties);
+
+	// samplers and resources share the one buffer, so all of it has to be addressable by both
+	const VkDeviceSize limits[] = {
+		descriptorBuffer->properties.maxSamplerDescriptorBufferRange,
+		descriptorBuffer->properties.maxResourceDescriptorBufferRange,
+		descriptorBuffer->properties.samplerDescriptorBufferAddressSpaceSize,
+		descriptorBuffer->properties.resourceDescriptorBufferAddressSpaceSize,
+		descriptorBuffer->properties.descriptorBufferAddressSpaceSize,
+	};
+	for (const VkDeviceSize limit : limits) {
+		if (*size > limit) {
+			*size = limit;
+		}
+	}
+
+	descriptorBuffer->pushDescriptorBuffer = pushDescriptors && !descriptorBuffer->properties.bufferlessPushDescriptors;
+
+	VkBufferCreateInfo bufferInfo = {
+		.sType = VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO,
+		.size = *size,
+		.usage = VK_BUFFER_USAGE_SAMPLER_DESCRIPTOR_BUFFER_BIT_EXT | VK_BUFFER_USAGE_RESOURCE_DESCRIPTOR_BUFFER_BIT_EXT
+				 | VK_BUFFER_USAGE_SHADER_DEVICE_ADDRESS_BIT,
+		.sharingMode = VK_SHARING_MODE_EXCLUSIVE,
+	};
+	if (descriptorBuffer->pushDescriptorBuffer) {
+		bufferInfo.usage |= VK_BUFFER_USAGE_PUSH_DESCRIPTORS_DESCRIPTOR_BUFFER_BIT_EXT;
+	}
+
+	// descriptors are written by the host and read by every draw, so prefer BAR memory when there is any
+	VmaAllocationCreateInfo allocCreateInfo = {};
+	allocCreateInfo.usage = VMA_MEMORY_USAGE_AUTO;
+	allocCreateInfo.flags = VMA_ALLOCATION_CREATE_HOST_ACCESS_SEQUENTIAL_WRITE_BIT | VMA_ALLOCATION_CREATE_MAPPED_BIT;
+	allocCreateInfo.requiredFlags = VK_MEMORY_PROPERTY_HOST_VISIBLE_BIT | VK_MEMORY_PROPERTY_HOST_COHERENT_BIT;
+	allocCreateInfo.preferredFlags = VK_MEMORY_PROPERTY_DEVICE_LOCAL_BIT;
+
+	VmaAllocationInfo allocInfo = {};
+	const VkResult ret = vmaCreateBuffer(instance->device.vma.allocator, &bufferInfo, &allocCreateInfo, &descriptorBuffer->vkBuffer,
+										 &descriptorBuffer->allocation, &allocInfo);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to create descriptor buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+
+	const VkBufferDeviceAddressInfo addressInfo = {
+		.sType = VK_STRUCTURE_TYPE_BUFFER_DEVICE_ADDRESS_INFO,
+		.buffer = descriptorBuffer->vkBuffer,
+	};
+	descriptorBuffer->ptr = static_cast<uint8_t*>(allocInfo.pMappedData);
+	descriptorBuffer->address = VK_PROC_DEVICE(vkGetBufferDeviceAddress)(instance->device.vkDevice, &addressInfo);
+	descriptorBuffer->size = *size;
+	*offsetAlignment = descriptorBuffer->properties.descriptorBufferOffsetAlignment;
+
+	vxr::std::debugRun([=]() {
+		vxr::std::stringbuilder builder;
+		builder.write("buffer_descriptor");
+		vxr::vk::debugLabel(instance->device.vkDevice, descriptorBuffer->vkBuffer, builder.cStr());
+
+		builder.write("_allocation");
+		vmaSetAllocationName(instance->device.vma.allocator, descriptorBuffer->allocation, builder.cStr());
+	});
+}
+VXR_FN void vxr_vk_descriptorBuffer_destroy(vxr_vk_instance instanceHandle) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	auto* descriptorBuffer = &instance->device.descriptorBuffer;
+
+	if (descriptorBuffer->vkBuffer != VK_NULL_HANDLE) {
+		vmaDestroyBuffer(instance->device.vma.allocator, descriptorBuffer->vkBuffer, descriptorBuffer->allocation);
+	}
+	*descriptorBuffer = {};
+}
+VXR_FN void vxr_vk_descriptorBuffer_getLayoutSize(vxr_vk_instance instanceHandle, VkDescriptorSetLayout layout, VkDeviceSize* size) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	VK_TRY_PROC_DEVICE(vkGetDescriptorSetLayoutSizeEXT)(instance->device.vkDevice, layout, size);
+}
+VXR_FN void vxr_vk_descriptorBuffer_write(vxr_vk_instance instanceHandle, VkDeviceSize setOffset, VkDescriptorSetLayout layout,
+										  uint32_t bindingDescriptorCount, VkWriteDescriptorSet write) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	writeDescriptors(instance, setOffset, layout, bindingDescriptorCount, write);
+}
+VXR_FN void vxr_vk_descriptorBuffer_writes(vxr_vk_instance instanceHandle, uint32_t numWrites, const vxr_vk_descriptorBuffer_writeInfo* writes) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	for (uint32_t i = 0; i < numWrites; i++) {
+		writeDescriptors(instance, writes[i].setOffset, writes[i].layout, writes[i].bindingDescriptorCount, writes[i].write);
+	}
+}
+VXR_FN void vxr_vk_descriptorBuffer_bind(vxr_vk_instance instanceHandle, VkCommandBuffer cb) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	const VkDescriptorBufferBindingPushDescriptorBufferHandleEXT pushInfo = {
+		.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_BUFFER_BINDING_PUSH_DESCRIPTOR_BUFFER_HANDLE_EXT,
+		.buffer = instance->device.descriptorBuffer.vkBuffer,
+	};
+	VkDescriptorBufferBindingInfoEXT info = {
+		.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_BUFFER_BINDING_INFO_EXT,
+		.address = instance->device.descriptorBuffer.address,
+		.usage = VK_BUFFER_USAGE_SAMPLER_DESCRIPTOR_BUFFER_BIT_EXT | VK_BUFFER_USAGE_RESOURCE_DESCRIPTOR_BUFFER_BIT_EXT,
+	};
+	if (instance->device.descriptorBuffer.pushDescriptorBuffer) {
+		info.pNext = &pushInfo;
+		info.usage |= VK_BUFFER_USAGE_PUSH_DESCRIPTORS_DESCRIPTOR_BUFFER_BIT_EXT;
+	}
+	VK_TRY_PROC_DEVICE(vkCmdBindDescriptorBuffersEXT)(cb, 1, &info);
+}
+}
@@ -19,6 +19,7 @@ limitations under the License.
 #include "std/utility.hpp"
 
 #include "vk/vk.hpp"
+#include "vk/vkfns.hpp"
 #include "vk/device/device.hpp"
 #include "vk/device/selector/selector.hpp"
 
@@ -62,4 +63,16 @@ VXR_FN void vxr_vk_device_getProperties(vxr_vk_instance instanceHandle, vxr_vk_d
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 	*properties = instance->device.properties;
 }
+VXR_FN void vxr_vk_device_getMaxPushDescriptors(vxr_vk_instance instanceHandle, uint32_t* maxPushDescriptors) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	VkPhysicalDevicePushDescriptorPropertiesKHR pushDescriptorProperties = {
+		.sType = VK_STRUCTURE_TYPE_PHYSICAL_DEVICE_PUSH_DESCRIPTOR_PROPERTIES_KHR,
+	};
+	VkPhysicalDeviceProperties2 properties = {
+		.sType = VK_STRUCTURE_TYPE_PHYSICAL_DEVICE_PROPERTIES_2,
+		.pNext = &pushDescriptorProperties,
+	};
+	VK_PROC(vkGetPhysicalDeviceProperties2)(instance->device.vkPhysicalDevice, &properties);
+	*maxPushDescriptors = pushDescriptorProperties.maxPushDescriptors;
+}
 }
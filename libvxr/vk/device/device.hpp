@@ -35,6 +35,19 @@ struct queue {
 
 using bindIndexBuffer = void (*)(VkCommandBuffer, vxr_vk_graphics_indexBufferInfo);
 
+// descriptorBuffer is the single host visible buffer all descriptor sets are written into when VK_EXT_descriptor_buffer is in use,
+// vkBuffer is VK_NULL_HANDLE when the device uses descriptor pools instead.
+struct descriptorBuffer {
+	VkBuffer vkBuffer = VK_NULL_HANDLE;
+	VmaAllocation allocation = VK_NULL_HANDLE;
+	uint8_t* ptr = nullptr;
+	VkDeviceAddress address = 0;
+	VkDeviceSize size = 0;
+	VkPhysicalDeviceDescriptorBufferPropertiesEXT properties = {};
+	// push descriptors on devices without bufferlessPushDescriptors are backed by the descriptor buffer
+	bool pushDescriptorBuffer = false;
+};
+
 struct instance {
 	VkPhysicalDevice vkPhysicalDevice;
 	VkDevice vkDevice;
@@ -46,11 +59,25 @@ struct instance {
 	struct queue transferQueue;
 
 	vxr_vk_device_properties properties;
+	struct descriptorBuffer descriptorBuffer;
 	// table of function pointers for functions that vary behaviour depending on features enabled
 	// this is to not pay the cost of ifs
 	struct {
 		::vxr::vk::device::bindIndexBuffer bindIndexBuffer;
 	} fnTable;
+
+	[[nodiscard]] bool usesDescriptorBuffer() const noexcept { return this->descriptorBuffer.vkBuffer != VK_NULL_HANDLE; }
+	// every pipeline must agree with the descriptor set layouts on how descriptors are bound
+	[[nodiscard]] VkPipelineCreateFlags pipelineCreateFlags() const noexcept {
+		return this->usesDescriptorBuffer() ? VK_PIPELINE_CREATE_DESCRIPTOR_BUFFER_BIT_EXT : 0;
+	}
+	// descriptors of uniform and storage buffers are written from their device address when using descriptor buffers
+	[[nodiscard]] VkBufferUsageFlags bufferUsage(VkBufferUsageFlags usage) const noexcept {
+		if (this->usesDescriptorBuffer() && ((usage & (VK_BUFFER_USAGE_UNIFORM_BUFFER_BIT | VK_BUFFER_USAGE_STORAGE_BUFFER_BIT)) != 0)) {
+			usage |= VK_BUFFER_USAGE_SHADER_DEVICE_ADDRESS_BIT;
+		}
+		return usage;
+	}
 };
 
 #define VK_PROC_DEVICE(FN) extern PFN_##FN p##FN;
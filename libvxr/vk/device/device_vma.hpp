@@ -72,7 +72,8 @@ inline static void setupVMA(vxr::vk::instance* instance) {
 	allocatorInfo.pVulkanFunctions = &vkFns;
 	allocatorInfo.instance = instance->vkInstance;
 	allocatorInfo.vulkanApiVersion = instance->device.properties.api;
-	allocatorInfo.flags = VMA_ALLOCATOR_CREATE_EXT_MEMORY_BUDGET_BIT | VMA_ALLOCATOR_CREATE_KHR_MAINTENANCE4_BIT;
+	allocatorInfo.flags = VMA_ALLOCATOR_CREATE_EXT_MEMORY_BUDGET_BIT | VMA_ALLOCATOR_CREATE_KHR_MAINTENANCE4_BIT
+						  | VMA_ALLOCATOR_CREATE_BUFFER_DEVICE_ADDRESS_BIT;
 
 	const VkResult ret = vmaCreateAllocator(&allocatorInfo, &instance->device.vma.allocator);
 	if (ret != VK_SUCCESS) {
@@ -148,6 +148,34 @@ bool vxr::vk::device::selector::selector::findProperties(vxr::vk::instance* inst
 			limits->compute.minSubgroupSize = device13Properties.minSubgroupSize;
 			limits->compute.maxSubgroupSize = device13Properties.maxSubgroupSize;
 		}
+
+		// multiview Limits
+		{
+			limits->multiview.maxViewCount = device11Properties.maxMultiviewViewCount;
+		}
+
+		// indirect Limits
+		{
+			limits->indirect.maxDrawCount = device10Proprties.maxDrawIndirectCount;
+		}
+
+		// query Limits
+		{
+			limits->query.timestampPeriod = device10Proprties.timestampPeriod;
+		}
+
+		// descriptor indexing Limits
+		{
+			limits->descriptorIndexing.maxSamplerCount = vxr::std::min(
+				device12Properties.maxPerStageDescriptorUpdateAfterBindSamplers, device12Properties.maxDescriptorSetUpdateAfterBindSamplers);
+			limits->descriptorIndexing.maxSampledImageCount = vxr::std::min(device12Properties.maxPerStageDescriptorUpdateAfterBindSampledImages,
+																			device12Properties.maxDescriptorSetUpdateAfterBindSampledImages);
+			limits->descriptorIndexing.maxStorageImageCount = vxr::std::min(device12Properties.maxPerStageDescriptorUpdateAfterBindStorageImages,
+																			device12Properties.maxDescriptorSetUpdateAfterBindStorageImages);
+			limits->descriptorIndexing.maxSBOCount = vxr::std::min(device12Properties.maxPerStageDescriptorUpdateAfterBindStorageBuffers,
+																   device12Properties.maxDescriptorSetUpdateAfterBindStorageBuffers);
+			limits->descriptorIndexing.maxResourceCount = device12Properties.maxPerStageUpdateAfterBindResources;
+		}
 	}
 
 	return true;
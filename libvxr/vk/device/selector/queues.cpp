@@ -41,10 +41,13 @@ inline static bool findComputeQueue(vxr::vk::device::instance* device, const aut
 
 inline static bool findGraphicsQueue(vxr::vk::device::instance* device, VkSurfaceKHR vkSurface, const auto& queueFamilies) {
 	for (uint32_t i = 0; i < queueFamilies.size(); i++) {
-		VkBool32 presentSupport = VK_FALSE;
-		const VkResult ret = VK_PROC(vkGetPhysicalDeviceSurfaceSupportKHR)(device->vkPhysicalDevice, i, vkSurface, &presentSupport);
-		if (ret != VK_SUCCESS) {
-			return false;
+		// offscreen rendering has no surface to present to so any graphics queue will do
+		VkBool32 presentSupport = VK_TRUE;
+		if (vkSurface != VK_NULL_HANDLE) {
+			const VkResult ret = VK_PROC(vkGetPhysicalDeviceSurfaceSupportKHR)(device->vkPhysicalDevice, i, vkSurface, &presentSupport);
+			if (ret != VK_SUCCESS) {
+				return false;
+			}
 		}
 
 		if (vxr::std::cmpBitFlagsContains(queueFamilies[i].queueFlags, VkQueueFlags(VK_QUEUE_GRAPHICS_BIT | VK_QUEUE_COMPUTE_BIT)) &&
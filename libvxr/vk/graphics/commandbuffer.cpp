@@ -28,6 +28,7 @@ limitations under the License.
 #include "vk/vk.hpp"
 #include "vk/vklog.hpp"
 #include "vk/device/device.hpp"
+#include "vk/descriptor.hpp"
 #include "vk/graphics/graphics.hpp"
 
 extern "C" {
@@ -117,6 +118,92 @@ VXR_FN void vxr_vk_graphics_frame_commandBufferSubmit(
 
 	frame->pendingCommandBuffers.pushBack(cb);
 }
+VXR_FN void vxr_vk_graphics_commandRecorder_commandBufferBegin(vxr_vk_instance instanceHandle,
+															   vxr_vk_graphics_commandRecorder recorderHandle, size_t nameSz,
+															   const char* name, VkCommandBuffer* cb) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	auto* recorder = vxr::vk::graphics::commandRecorder::fromHandle(recorderHandle);
+
+	if (recorder->freeCommandBuffers.size() > 0u) {
+		*cb = recorder->freeCommandBuffers.popFront();
+	} else {
+		VkCommandBufferAllocateInfo layoutCmdAllocateInfo = {};
+		layoutCmdAllocateInfo.sType = VK_STRUCTURE_TYPE_COMMAND_BUFFER_ALLOCATE_INFO;
+		layoutCmdAllocateInfo.commandPool = recorder->vkCommandPool;
+		layoutCmdAllocateInfo.level = VK_COMMAND_BUFFER_LEVEL_PRIMARY;
+		layoutCmdAllocateInfo.commandBufferCount = 1;
+
+		const VkResult ret = VK_PROC_DEVICE(vkAllocateCommandBuffers)(instance->device.vkDevice, &layoutCmdAllocateInfo, cb);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to create graphics command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+		recorder->allocatedCommandBuffers += 1;
+	}
+
+	{
+		VkCommandBufferBeginInfo beginInfo = {};
+		beginInfo.sType = VK_STRUCTURE_TYPE_COMMAND_BUFFER_BEGIN_INFO;
+		beginInfo.flags = VK_COMMAND_BUFFER_USAGE_ONE_TIME_SUBMIT_BIT;
+
+		const VkResult ret = VK_PROC_DEVICE(vkBeginCommandBuffer)(*cb, &beginInfo);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to begin graphics command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+
+		vxr::std::debugRun([=]() {
+			vxr::std::stringbuilder builder;
+			builder.write("graphics_cmd_buffer_").write(nameSz, name);
+			vxr::vk::debugLabelBegin(*cb, builder.cStr());
+		});
+	}
+}
+VXR_FN void vxr_vk_graphics_commandRecorder_commandBufferEnd(vxr_vk_instance, vxr_vk_graphics_commandRecorder recorderHandle,
+															 VkCommandBuffer cb) {
+	auto* recorder = vxr::vk::graphics::commandRecorder::fromHandle(recorderHandle);
+
+	vxr::std::debugRun([=]() { vxr::vk::debugLabelEnd(cb); });
+
+	const VkResult ret = VK_PROC_DEVICE(vkEndCommandBuffer)(cb);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to end command buffer: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+
+	recorder->pendingCommandBuffers.pushBack(cb);
+}
+VXR_FN void vxr_vk_graphics_frame_commandBuffersSubmit(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame, uint32_t numCommandBuffers,
+													   VkCommandBuffer* cbs, uint32_t numWaitSemaphores, VkSemaphoreSubmitInfo* waitSemaphores,
+													   uint32_t numSignalSemaphores, VkSemaphoreSubmitInfo* signalSemaphores) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	vxr::std::vector<VkCommandBufferSubmitInfo> commandbuffers(numCommandBuffers);
+	for (uint32_t i = 0; i < numCommandBuffers; i++) {
+		commandbuffers[i] = VkCommandBufferSubmitInfo{
+			.sType = VK_STRUCTURE_TYPE_COMMAND_BUFFER_SUBMIT_INFO,
+			.commandBuffer = cbs[i],
+		};
+	}
+
+	VkSubmitInfo2 submitInfo = {};
+	submitInfo.sType = VK_STRUCTURE_TYPE_SUBMIT_INFO_2;
+
+	submitInfo.waitSemaphoreInfoCount = numWaitSemaphores;
+	submitInfo.pWaitSemaphoreInfos = waitSemaphores;
+
+	submitInfo.commandBufferInfoCount = numCommandBuffers;
+	submitInfo.pCommandBufferInfos = commandbuffers.get();
+
+	submitInfo.signalSemaphoreInfoCount = numSignalSemaphores;
+	submitInfo.pSignalSemaphoreInfos = signalSemaphores;
+
+	const VkResult ret = VK_PROC_DEVICE(vkQueueSubmit2)(instance->device.graphicsQueue.vkQueue, 1, &submitInfo, VK_NULL_HANDLE);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to submit frame: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+}
 VXR_FN void vxr_vk_graphics_renderPassBegin(vxr_vk_instance, VkCommandBuffer cb, size_t nameSz, const char* name,
 											vxr_vk_graphics_renderPassInfo info) {
 	vxr::std::debugRun([=]() {
@@ -213,6 +300,19 @@ VXR_FN void vxr_vk_graphics_renderPassSetColorBlend(vxr_vk_instance, VkCommandBu
 VXR_FN void vxr_vk_graphics_renderPassSetLineWidth(vxr_vk_instance, VkCommandBuffer cb, float width) {
 	VK_PROC_DEVICE(vkCmdSetLineWidth)(cb, width);
 }
+VXR_FN void vxr_vk_graphics_beginConditionalRendering(vxr_vk_instance, VkCommandBuffer cb, VkBuffer buffer, VkDeviceSize offset,
+													  VkConditionalRenderingFlagsEXT flags) {
+	const VkConditionalRenderingBeginInfoEXT beginInfo = {
+		.sType = VK_STRUCTURE_TYPE_CONDITIONAL_RENDERING_BEGIN_INFO_EXT,
+		.buffer = buffer,
+		.offset = offset,
+		.flags = flags,
+	};
+	VK_TRY_PROC_DEVICE(vkCmdBeginConditionalRenderingEXT)(cb, &beginInfo);
+}
+VXR_FN void vxr_vk_graphics_endConditionalRendering(vxr_vk_instance, VkCommandBuffer cb) {
+	VK_TRY_PROC_DEVICE(vkCmdEndConditionalRenderingEXT)(cb);
+}
 inline static VXR_FN void setupDraw(vxr_vk_graphics_drawParameters parameters, VkCommandBuffer cb) {
 	VK_PROC_DEVICE(vkCmdBindPipeline)(cb, VK_PIPELINE_BIND_POINT_GRAPHICS, parameters.pipeline);
 
@@ -267,16 +367,42 @@ inline static VXR_FN void setupDraw(vxr_vk_graphics_drawParameters parameters, V
 		}
 	}
 
+	VK_PROC_DEVICE(vkCmdSetDepthBiasEnable)(cb, parameters.depthBiasEnable);
+	if (parameters.depthBiasEnable == VK_TRUE) {
+		VK_PROC_DEVICE(vkCmdSetDepthBias)(
+			cb, parameters.depthBiasConstantFactor, parameters.depthBiasClamp, parameters.depthBiasSlopeFactor);
+	}
+	VK_PROC_DEVICE(vkCmdSetDepthBoundsTestEnable)(cb, parameters.depthBoundsTestEnable);
+	if (parameters.depthBoundsTestEnable == VK_TRUE) {
+		VK_PROC_DEVICE(vkCmdSetDepthBounds)(cb, parameters.minDepthBounds, parameters.maxDepthBounds);
+	}
+	if (parameters.optionalDynamicStates.depthClampEnable == VK_TRUE) {
+		VK_PROC_DEVICE(vkCmdSetDepthClampEnableEXT)(cb, parameters.depthClampEnable);
+	}
+
+	VK_PROC_DEVICE(vkCmdSetBlendConstants)(cb, parameters.blendConstants);
+	if (parameters.optionalDynamicStates.logicOpEnable == VK_TRUE) {
+		VK_PROC_DEVICE(vkCmdSetLogicOpEnableEXT)(cb, parameters.logicOpEnable);
+	}
+	if (parameters.numColorWriteMasks > 0) {
+		VK_PROC_DEVICE(vkCmdSetColorWriteMaskEXT)(cb, 0, parameters.numColorWriteMasks, parameters.colorWriteMasks);
+	}
+
+	if (parameters.optionalDynamicStates.alphaToCoverageEnable == VK_TRUE) {
+		VK_PROC_DEVICE(vkCmdSetAlphaToCoverageEnableEXT)(cb, parameters.alphaToCoverageEnable);
+	}
+	if (parameters.optionalDynamicStates.sampleMask == VK_TRUE) {
+		// sized for the max sample count so it never depends on the current rasterization samples
+		VK_PROC_DEVICE(vkCmdSetSampleMaskEXT)(cb, VK_SAMPLE_COUNT_64_BIT, parameters.sampleMask);
+	}
+
 	if (parameters.pushConstantRange.size > 0) {
 		VK_PROC_DEVICE(vkCmdPushConstants)
 		(cb, parameters.layout, parameters.pushConstantRange.stageFlags, parameters.pushConstantRange.offset,
 		 parameters.pushConstantRange.size, parameters.pushConstantData);
 	}
-	if (parameters.numDescriptorSets > 0) {
-		VK_PROC_DEVICE(vkCmdBindDescriptorSets)
-		(cb, VK_PIPELINE_BIND_POINT_GRAPHICS, parameters.layout, 0, parameters.numDescriptorSets,
-		 parameters.descriptorSets, 0, nullptr);
-	}
+	vxr::vk::descriptor::bindSets(cb, VK_PIPELINE_BIND_POINT_GRAPHICS, parameters.layout, parameters.numDescriptorSets,
+								  parameters.descriptorSets, parameters.descriptorBufferOffsets, parameters.pushDescriptors);
 }
 VXR_FN void vxr_vk_graphics_draw(vxr_vk_instance, VkCommandBuffer cb, vxr_vk_graphics_drawInfo info) {
 	setupDraw(info.parameters, cb);
@@ -285,7 +411,13 @@ VXR_FN void vxr_vk_graphics_draw(vxr_vk_instance, VkCommandBuffer cb, vxr_vk_gra
 VXR_FN void vxr_vk_graphics_drawIndirect(vxr_vk_instance, VkCommandBuffer cb, vxr_vk_graphics_drawIndirectInfo info) {
 	setupDraw(info.parameters, cb);
 	VK_PROC_DEVICE(vkCmdDrawIndirect)(cb, info.indirectBuffer.vkBuffer, info.indirectBuffer.offset,
-									  info.indirectBuffer.drawCount, sizeof(VkDrawIndirectCommand));
+									  info.indirectBuffer.drawCount, info.indirectBuffer.stride);
+}
+VXR_FN void vxr_vk_graphics_drawIndirectCount(vxr_vk_instance, VkCommandBuffer cb, vxr_vk_graphics_drawIndirectCountInfo info) {
+	setupDraw(info.parameters, cb);
+	VK_PROC_DEVICE(vkCmdDrawIndirectCount)(cb, info.indirectBuffer.vkBuffer, info.indirectBuffer.offset,
+										   info.countBuffer.vkBuffer, info.countBuffer.offset,
+										   info.indirectBuffer.drawCount, info.indirectBuffer.stride);
 }
 VXR_FN void vxr_vk_graphics_drawIndexed(vxr_vk_instance instanceHandle, VkCommandBuffer cb, vxr_vk_graphics_drawIndexedInfo info) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
@@ -300,7 +432,17 @@ VXR_FN void vxr_vk_graphics_drawIndexedIndirect(vxr_vk_instance instanceHandle,
 	setupDraw(info.parameters, cb);
 	instance->device.fnTable.bindIndexBuffer(cb, info.indexBuffer);
 	VK_PROC_DEVICE(vkCmdDrawIndexedIndirect)(cb, info.indirectBuffer.vkBuffer, info.indirectBuffer.offset,
-											 info.indirectBuffer.drawCount, sizeof(VkDrawIndexedIndirectCommand));
+											 info.indirectBuffer.drawCount, info.indirectBuffer.stride);
+}
+VXR_FN void vxr_vk_graphics_drawIndexedIndirectCount(vxr_vk_instance instanceHandle, VkCommandBuffer cb,
+													 vxr_vk_graphics_drawIndexedIndirectCountInfo info) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	setupDraw(info.parameters, cb);
+	instance->device.fnTable.bindIndexBuffer(cb, info.indexBuffer);
+	VK_PROC_DEVICE(vkCmdDrawIndexedIndirectCount)(cb, info.indirectBuffer.vkBuffer, info.indirectBuffer.offset,
+												  info.countBuffer.vkBuffer, info.countBuffer.offset,
+												  info.indirectBuffer.drawCount, info.indirectBuffer.stride);
 }
 VXR_FN void vxr_vk_graphics_renderPassEnd(vxr_vk_instance, VkCommandBuffer cb) {
 	VK_PROC_DEVICE(vkCmdEndRendering)(cb);
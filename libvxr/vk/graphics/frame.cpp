@@ -37,8 +37,61 @@ limitations under the License.
 #include "vk/graphics/swapchain/swapchain.hpp"
 
 namespace vxr::vk::graphics {
-frame::frame(vxr::vk::instance* instance, size_t nameSz, const char* name) noexcept
+commandRecorder::commandRecorder(vxr::vk::instance* instance, size_t nameSz, const char* name) noexcept
 	: vkDevice(instance->device.vkDevice) {
+	VkCommandPoolCreateInfo poolInfo = {};
+	poolInfo.sType = VK_STRUCTURE_TYPE_COMMAND_POOL_CREATE_INFO;
+	poolInfo.queueFamilyIndex = instance->device.graphicsQueue.family;
+	poolInfo.flags = VK_COMMAND_POOL_CREATE_TRANSIENT_BIT;
+
+	const VkResult ret = VK_PROC_DEVICE(vkCreateCommandPool)(instance->device.vkDevice, &poolInfo, nullptr, &this->vkCommandPool);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to create graphics commandpool: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+	vxr::std::debugRun([=, this]() {
+		vxr::std::stringbuilder builder;
+		builder.write("graphics_cmd_pool_recorder_").write(nameSz, name);
+		vxr::vk::debugLabel(instance->device.vkDevice, this->vkCommandPool, builder.cStr());
+	});
+	this->allocatedCommandBuffers = 0;
+}
+
+commandRecorder::~commandRecorder() noexcept {
+	while (this->freeCommandBuffers.size() != 0u) {
+		auto* cb = this->freeCommandBuffers.popFront();
+		VK_PROC_DEVICE(vkFreeCommandBuffers)(this->vkDevice, this->vkCommandPool, 1, &cb);
+	}
+	for (auto& cb : this->pendingCommandBuffers) {
+		VK_PROC_DEVICE(vkFreeCommandBuffers)(this->vkDevice, this->vkCommandPool, 1, &cb);
+	}
+
+	this->allocatedCommandBuffers = 0;
+
+	VK_PROC_DEVICE(vkDestroyCommandPool)(this->vkDevice, this->vkCommandPool, nullptr);
+}
+
+void commandRecorder::reset() noexcept {
+	const VkResult ret = VK_PROC_DEVICE(vkResetCommandPool)(this->vkDevice, this->vkCommandPool, 0);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to reset graphics command pool: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+
+	for (auto& cb : this->pendingCommandBuffers) {
+		this->freeCommandBuffers.pushBack(cb);
+	}
+	this->pendingCommandBuffers.resize(0);
+
+	if (this->freeCommandBuffers.size() != this->allocatedCommandBuffers) {
+		vxr::std::ePrintf("Recorder allocated %zu command buffers but ended %zu", this->allocatedCommandBuffers,
+						  this->freeCommandBuffers.size());
+		vxr::std::abort();
+	}
+}
+
+presentSlot::presentSlot(vxr::vk::instance* instance, size_t nameSz, const char* name) noexcept
+	: vkDevice(instance->device.vkDevice), swapchain(nullptr), imageIndex(0) {
 	{
 		VkSemaphoreCreateInfo semaphoreInfo = {};
 		semaphoreInfo.sType = VK_STRUCTURE_TYPE_SEMAPHORE_CREATE_INFO;
@@ -50,7 +103,7 @@ frame::frame(vxr::vk::instance* instance, size_t nameSz, const char* name) noexc
 		}
 		vxr::std::debugRun([=, this]() {
 			vxr::std::stringbuilder builder;
-			builder.write("semaphore_binary_surface_acquire_frame_").write(nameSz, name);
+			builder.write("semaphore_binary_surface_acquire_").write(nameSz, name);
 			vxr::vk::debugLabel(instance->device.vkDevice, this->surfaceAcquireSemaphore, builder.cStr());
 		});
 		ret = VK_PROC_DEVICE(vkCreateSemaphore)(instance->device.vkDevice, &semaphoreInfo, nullptr, &this->surfaceReleaseSemaphore);
@@ -60,7 +113,7 @@ frame::frame(vxr::vk::instance* instance, size_t nameSz, const char* name) noexc
 		}
 		vxr::std::debugRun([=, this]() {
 			vxr::std::stringbuilder builder;
-			builder.write("semaphore_binary_surface_release_frame_").write(nameSz, name);
+			builder.write("semaphore_binary_surface_release_").write(nameSz, name);
 			vxr::vk::debugLabel(instance->device.vkDevice, this->surfaceReleaseSemaphore, builder.cStr());
 		});
 	}
@@ -77,11 +130,20 @@ frame::frame(vxr::vk::instance* instance, size_t nameSz, const char* name) noexc
 		}
 		vxr::std::debugRun([=, this]() {
 			vxr::std::stringbuilder builder;
-			builder.write("graphics_fence_frame_").write(nameSz, name);
+			builder.write("graphics_fence_").write(nameSz, name);
 			vxr::vk::debugLabel(instance->device.vkDevice, this->fence, builder.cStr());
 		});
 	}
+}
+
+presentSlot::~presentSlot() noexcept {
+	VK_PROC_DEVICE(vkDestroySemaphore)(this->vkDevice, this->surfaceAcquireSemaphore, nullptr);
+	VK_PROC_DEVICE(vkDestroySemaphore)(this->vkDevice, this->surfaceReleaseSemaphore, nullptr);
+	VK_PROC_DEVICE(vkDestroyFence)(this->vkDevice, this->fence, nullptr);
+}
 
+frame::frame(vxr::vk::instance* instance, size_t nameSz, const char* name) noexcept
+	: vkDevice(instance->device.vkDevice), name(nameSz, name), activePresentSlots(0) {
 	{
 		this->vmaAllocator = instance->device.vma.allocator;
 
@@ -139,6 +201,7 @@ frame::frame(vxr::vk::instance* instance, size_t nameSz, const char* name) noexc
 			vxr::vk::debugLabel(instance->device.vkDevice, this->vkCommandPool, builder.cStr());
 		});
 		this->allocatedCommandBuffers = 0;
+		this->activeCommandRecorders = 0;
 	}
 }
 
@@ -150,6 +213,12 @@ frame::~frame() noexcept {
 	for (auto& cb : this->pendingCommandBuffers) {
 		VK_PROC_DEVICE(vkFreeCommandBuffers)(this->vkDevice, this->vkCommandPool, 1, &cb);
 	}
+	for (auto* r : this->commandRecorders) {
+		delete r;
+	}
+	this->commandRecorders.resize(0);
+	this->activeCommandRecorders = 0;
+
 	for (auto& b : this->pendingScratchBuffers) {
 		vmaUnmapMemory(this->vmaAllocator, b.second);
 		vmaDestroyBuffer(this->vmaAllocator, b.first, b.second);
@@ -157,9 +226,12 @@ frame::~frame() noexcept {
 
 	this->allocatedCommandBuffers = 0;
 
-	VK_PROC_DEVICE(vkDestroySemaphore)(this->vkDevice, this->surfaceAcquireSemaphore, nullptr);
-	VK_PROC_DEVICE(vkDestroySemaphore)(this->vkDevice, this->surfaceReleaseSemaphore, nullptr);
-	VK_PROC_DEVICE(vkDestroyFence)(this->vkDevice, this->fence, nullptr);
+	for (auto* slot : this->presentSlots) {
+		delete slot;
+	}
+	this->presentSlots.resize(0);
+	this->activePresentSlots = 0;
+
 	VK_PROC_DEVICE(vkDestroyCommandPool)(this->vkDevice, this->vkCommandPool, nullptr);
 
 	vmaDestroyPool(this->vmaAllocator, this->vmaPool);
@@ -199,6 +271,12 @@ VXR_FN void vxr_vk_graphics_frame_begin(vxr_vk_instance instanceHandle, size_t n
 							  frame->freeCommandBuffers.size());
 			vxr::std::abort();
 		}
+
+		for (auto* r : frame->commandRecorders) {
+			r->reset();
+		}
+		frame->activeCommandRecorders = 0;
+		frame->activePresentSlots = 0;
 	}
 
 	{
@@ -217,26 +295,30 @@ VXR_FN void vxr_vk_graphics_frame_begin(vxr_vk_instance instanceHandle, size_t n
 }
 VXR_FN void vxr_vk_graphics_frame_cancel(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
-	auto* graphics = &instance->graphics;
+	// auto* graphics = &instance->graphics;
 	auto* frame = vxr::vk::graphics::frame::fromHandle(frameHandle);
 
 	vxr::std::abort("DO NOT USE");
 
-	const vxr::std::array indices = {
-		frame->imageIndex,
-	};
-	const VkReleaseSwapchainImagesInfoEXT releaseInfo = {
-		.sType = VK_STRUCTURE_TYPE_RELEASE_SWAPCHAIN_IMAGES_INFO_EXT,
-		.swapchain = graphics->swapchain.vkSwapchain,
-		.imageIndexCount = indices.size(),
-		.pImageIndices = indices.get(),
-	};
-
-	const VkResult ret = VK_TRY_PROC_DEVICE(vkReleaseSwapchainImagesEXT)(instance->device.vkDevice, &releaseInfo);
-	if (ret != VK_SUCCESS) {
-		vxr::std::ePrintf("Failed to release image: %s", vxr::vk::vkResultStr(ret).cStr());
-		vxr::std::abort();
+	for (size_t i = 0; i < frame->activePresentSlots; i++) {
+		auto* slot = frame->presentSlots[i];
+		const vxr::std::array indices = {
+			slot->imageIndex,
+		};
+		const VkReleaseSwapchainImagesInfoEXT releaseInfo = {
+			.sType = VK_STRUCTURE_TYPE_RELEASE_SWAPCHAIN_IMAGES_INFO_EXT,
+			.swapchain = slot->swapchain->vkSwapchain,
+			.imageIndexCount = indices.size(),
+			.pImageIndices = indices.get(),
+		};
+
+		const VkResult ret = VK_TRY_PROC_DEVICE(vkReleaseSwapchainImagesEXT)(instance->device.vkDevice, &releaseInfo);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to release image: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
 	}
+	frame->activePresentSlots = 0;
 	vxr::vk::debugLabelEnd(instance->device.graphicsQueue.vkQueue);
 }
 VXR_FN void vxr_vk_graphics_frame_end(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame) {
@@ -246,19 +328,29 @@ VXR_FN void vxr_vk_graphics_frame_end(vxr_vk_instance instanceHandle, vxr_vk_gra
 
 	vxr::vk::debugLabelEnd(instance->device.graphicsQueue.vkQueue);
 }
-VXR_FN VkResult vxr_vk_graphics_frame_acquireSurface(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle, vxr_vk_surface* surface) {
+VXR_FN VkResult vxr_vk_graphics_frame_acquireSurface(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle,
+													 vxr_vk_graphics_swapchain swapchainHandle, vxr_vk_surface* surface) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
-	auto* graphics = &instance->graphics;
+	// auto* graphics = &instance->graphics;
 	auto* frame = vxr::vk::graphics::frame::fromHandle(frameHandle);
+	auto* swapchain = vxr::vk::graphics::swapchain::fromHandle(swapchainHandle);
 
-	if (graphics->swapchain.vkSwapchain == VK_NULL_HANDLE) {
+	if (swapchain->vkSwapchain == VK_NULL_HANDLE) {
 		return VK_ERROR_SURFACE_LOST_KHR;
 	}
 
+	if (frame->activePresentSlots == frame->presentSlots.size()) {
+		vxr::std::stringbuilder builder;
+		builder.write("frame_").write(frame->name.size(), frame->name.cStr()).writef("_%zu", frame->presentSlots.size());
+		const auto slotName = builder.str();
+		frame->presentSlots.pushBack(new (::std::nothrow) vxr::vk::graphics::presentSlot(instance, slotName.size(), slotName.cStr()));
+	}
+	auto* slot = frame->presentSlots[frame->activePresentSlots];
+
 	{
-		const VkResult ret = VK_PROC_DEVICE(vkAcquireNextImageKHR)(
-			instance->device.vkDevice, graphics->swapchain.vkSwapchain, vxr::std::time::second,
-			frame->surfaceAcquireSemaphore, VK_NULL_HANDLE, &frame->imageIndex);
+		const VkResult ret = VK_TRY_PROC_DEVICE(vkAcquireNextImageKHR)(
+			instance->device.vkDevice, swapchain->vkSwapchain, vxr::std::time::second,
+			slot->surfaceAcquireSemaphore, VK_NULL_HANDLE, &slot->imageIndex);
 		switch (ret) {
 			case VK_SUCCESS:
 			case VK_SUBOPTIMAL_KHR:
@@ -276,15 +368,39 @@ VXR_FN VkResult vxr_vk_graphics_frame_acquireSurface(vxr_vk_instance instanceHan
 	}
 
 	{
-		vxr_vk_graphics_getSurfaceInfo(instanceHandle, &surface->info);
-		surface->vkImage = graphics->swapchain.images[frame->imageIndex].first;
-		surface->vkImageView = graphics->swapchain.images[frame->imageIndex].second;
-		surface->acquireSemaphore = frame->surfaceAcquireSemaphore;
-		surface->releaseSemaphore = frame->surfaceReleaseSemaphore;
+		slot->swapchain = swapchain;
+		frame->activePresentSlots++;
+
+		vxr_vk_graphics_swapchain_getSurfaceInfo(swapchainHandle, &surface->info);
+		surface->vkImage = swapchain->images[slot->imageIndex].first;
+		surface->vkImageView = swapchain->images[slot->imageIndex].second;
+		surface->acquireSemaphore = slot->surfaceAcquireSemaphore;
+		surface->releaseSemaphore = slot->surfaceReleaseSemaphore;
 	}
 
 	return VK_SUCCESS;
 }
+VXR_FN void vxr_vk_graphics_frame_createCommandRecorder(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle, size_t nameSz,
+													   const char* name, vxr_vk_graphics_commandRecorder* recorderHandle) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	// auto* graphics = &instance->graphics;
+	auto* frame = vxr::vk::graphics::frame::fromHandle(frameHandle);
+
+	if (frame->activeCommandRecorders < frame->commandRecorders.size()) {
+		auto* recorder = frame->commandRecorders[frame->activeCommandRecorders];
+		vxr::std::debugRun([=]() {
+			vxr::std::stringbuilder builder;
+			builder.write("graphics_cmd_pool_recorder_").write(nameSz, name);
+			vxr::vk::debugLabel(instance->device.vkDevice, recorder->vkCommandPool, builder.cStr());
+		});
+		*recorderHandle = recorder->handle();
+	} else {
+		auto* recorder = new (::std::nothrow) vxr::vk::graphics::commandRecorder(instance, nameSz, name);
+		frame->commandRecorders.pushBack(recorder);
+		*recorderHandle = recorder->handle();
+	}
+	frame->activeCommandRecorders++;
+}
 VXR_FN void vxr_vk_graphics_frame_createHostScratchBuffer(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle, size_t nameSz,
 														  const char* name, vxr_vk_bufferCreateInfo info, vxr_vk_hostBuffer* b) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
@@ -294,7 +410,7 @@ VXR_FN void vxr_vk_graphics_frame_createHostScratchBuffer(vxr_vk_instance instan
 	VkBufferCreateInfo bufferInfo = {};
 	bufferInfo.sType = VK_STRUCTURE_TYPE_BUFFER_CREATE_INFO;
 	bufferInfo.size = info.size;
-	bufferInfo.usage = info.usage;
+	bufferInfo.usage = instance->device.bufferUsage(info.usage);
 	bufferInfo.sharingMode = VK_SHARING_MODE_EXCLUSIVE;
 
 	VmaAllocationCreateInfo allocCreateInfo = {};
@@ -327,9 +443,9 @@ VXR_FN void vxr_vk_graphics_frame_createHostScratchBuffer(vxr_vk_instance instan
 		vmaSetAllocationName(instance->device.vma.allocator, reinterpret_cast<VmaAllocation>(b->allocation), builder.cStr());
 	});
 }
-VXR_FN VkResult vxr_vk_graphics_frame_submit(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle) {
+VXR_FN VkResult vxr_vk_graphics_frame_submit(vxr_vk_instance instanceHandle, vxr_vk_graphics_frame frameHandle, VkResult* results) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
-	auto* graphics = &instance->graphics;
+	// auto* graphics = &instance->graphics;
 	auto* frame = vxr::vk::graphics::frame::fromHandle(frameHandle);
 
 	VkResult status = VK_SUCCESS;
@@ -341,24 +457,25 @@ VXR_FN VkResult vxr_vk_graphics_frame_submit(vxr_vk_instance instanceHandle, vxr
 		presentInfo.sType = VK_STRUCTURE_TYPE_PRESENT_INFO_KHR;
 		presentInfo.pNext = &presentFenceInfo;
 
-		const vxr::std::array waitSemaphores = {
-			frame->surfaceReleaseSemaphore,
-		};
+		const size_t numSlots = frame->activePresentSlots;
+		vxr::std::vector<VkSemaphore> waitSemaphores(numSlots);
+		vxr::std::vector<VkSwapchainKHR> swapchains(numSlots);
+		vxr::std::vector<uint32_t> indices(numSlots);
+		vxr::std::vector<VkFence> fences(numSlots);
+		for (size_t i = 0; i < numSlots; i++) {
+			auto* slot = frame->presentSlots[i];
+			waitSemaphores[i] = slot->surfaceReleaseSemaphore;
+			swapchains[i] = slot->swapchain->vkSwapchain;
+			indices[i] = slot->imageIndex;
+			fences[i] = slot->fence;
+		}
+
 		presentInfo.waitSemaphoreCount = waitSemaphores.size();
 		presentInfo.pWaitSemaphores = waitSemaphores.get();
-
-		const vxr::std::array swapchains = {
-			graphics->swapchain.vkSwapchain,
-		};
-		const vxr::std::array<uint32_t, swapchains.size()> indices = {
-			frame->imageIndex,
-		};
-		const vxr::std::array<VkFence, swapchains.size()> fences = {
-			frame->fence,
-		};
 		presentFenceInfo.swapchainCount = presentInfo.swapchainCount = swapchains.size();
 		presentInfo.pSwapchains = swapchains.get();
 		presentInfo.pImageIndices = indices.get();
+		presentInfo.pResults = results;
 		presentFenceInfo.pFences = fences.get();
 
 		{
@@ -371,7 +488,7 @@ VXR_FN VkResult vxr_vk_graphics_frame_submit(vxr_vk_instance instanceHandle, vxr
 		}
 
 		{
-			const VkResult ret = VK_PROC_DEVICE(vkQueuePresentKHR)(instance->device.graphicsQueue.vkQueue, &presentInfo);
+			const VkResult ret = VK_TRY_PROC_DEVICE(vkQueuePresentKHR)(instance->device.graphicsQueue.vkQueue, &presentInfo);
 			switch (ret) {
 				case VK_SUCCESS:
 				case VK_SUBOPTIMAL_KHR:
@@ -395,9 +512,17 @@ VXR_FN void vxr_vk_graphics_frame_wait(vxr_vk_instance instanceHandle, vxr_vk_gr
 	// auto* graphics = &instance->graphics;
 	auto* frame = vxr::vk::graphics::frame::fromHandle(frameHandle);
 
+	if (frame->presentSlots.size() == 0) {
+		return;
+	}
+
 	{
+		vxr::std::vector<VkFence> fences(frame->presentSlots.size());
+		for (size_t i = 0; i < frame->presentSlots.size(); i++) {
+			fences[i] = frame->presentSlots[i]->fence;
+		}
 		const VkResult ret = VK_PROC_DEVICE(vkWaitForFences)(
-			instance->device.vkDevice, 1, &frame->fence, VK_TRUE, vxr::std::time::second);
+			instance->device.vkDevice, fences.size(), fences.get(), VK_TRUE, vxr::std::time::second);
 		if (ret != VK_SUCCESS) {
 			vxr::std::ePrintf("Failed to wait on frame: %s", vxr::vk::vkResultStr(ret).cStr());
 			vxr::std::abort();
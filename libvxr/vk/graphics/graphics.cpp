@@ -17,38 +17,83 @@ limitations under the License.
 #include "vxr/vxr.h"  // IWYU pragma: associated
 
 #include <stdint.h>
+#include <stddef.h>
+#include <new>
 
 #include "vk/vk.hpp"
+#include "vk/vkfns.hpp"
+#include "vk/device/device.hpp"
 #include "vk/graphics/graphics.hpp"
 #include "vk/graphics/swapchain/swapchain.hpp"
 
 extern "C" {
-VXR_FN VkResult vxr_vk_graphics_init(vxr_vk_instance instanceHandle, uint64_t vkSurface, uint32_t wantNumImages) {
+VXR_FN VkResult vxr_vk_graphics_getSupportedSurfaceFormats(vxr_vk_instance instanceHandle, uint64_t vkSurface, uint32_t* numFormats,
+													   VkSurfaceFormatKHR* formats) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
-	// auto* graphics = &instance->graphics;
 
-	{
-		const VkResult ret = vxr::vk::graphics::initSwapchain(
-			instance, reinterpret_cast<VkSurfaceKHR>(vkSurface), wantNumImages);  // NOLINT(performance-no-int-to-ptr)
-		if (ret != VK_SUCCESS) {
-			return ret;
+	return VK_PROC(vkGetPhysicalDeviceSurfaceFormatsKHR)(
+		instance->device.vkPhysicalDevice, reinterpret_cast<VkSurfaceKHR>(vkSurface), numFormats, formats);  // NOLINT(performance-no-int-to-ptr)
+}
+VXR_FN void vxr_vk_graphics_createSwapchain(vxr_vk_instance instanceHandle, size_t nameSz, const char* name, uint64_t vkSurface,
+											vxr_vk_graphics_swapchain* swapchainHandle) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	auto* graphics = &instance->graphics;
+
+	auto* swapchain = new (::std::nothrow) vxr::vk::graphics::swapchain();
+	swapchain->name = vxr::std::string(nameSz, name);
+	swapchain->surface = reinterpret_cast<VkSurfaceKHR>(vkSurface);  // NOLINT(performance-no-int-to-ptr)
+	graphics->swapchains.pushBack(swapchain);
+	*swapchainHandle = swapchain->handle();
+}
+VXR_FN void vxr_vk_graphics_destroySwapchain(vxr_vk_instance instanceHandle, vxr_vk_graphics_swapchain swapchainHandle) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	auto* graphics = &instance->graphics;
+	auto* swapchain = vxr::vk::graphics::swapchain::fromHandle(swapchainHandle);
+
+	for (size_t i = 0; i < graphics->swapchains.size(); i++) {
+		if (graphics->swapchains[i] == swapchain) {
+			graphics->swapchains[i] = graphics->swapchains[graphics->swapchains.size() - 1];
+			graphics->swapchains.resize(graphics->swapchains.size() - 1);
+			break;
 		}
 	}
+	vxr::vk::graphics::destroySwapchain(instance, swapchain);
+	delete swapchain;
+}
+VXR_FN VkResult vxr_vk_graphics_swapchain_init(vxr_vk_instance instanceHandle, vxr_vk_graphics_swapchain swapchainHandle,
+											   vxr_vk_graphics_swapchainCreateInfo info) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	auto* swapchain = vxr::vk::graphics::swapchain::fromHandle(swapchainHandle);
+
+	return vxr::vk::graphics::initSwapchain(instance, swapchain, info);
+}
+VXR_FN void vxr_vk_graphics_swapchain_getSurfaceInfo(vxr_vk_graphics_swapchain swapchainHandle, vxr_vk_surfaceInfo* info) {
+	auto* swapchain = vxr::vk::graphics::swapchain::fromHandle(swapchainHandle);
 
-	return VK_SUCCESS;
+	info->format = swapchain->surfaceFormat.format;
+	info->colorSpace = swapchain->surfaceFormat.colorSpace;
+	info->extent = swapchain->extent;
+	info->numImages = swapchain->size();
+	info->presentMode = swapchain->presentMode;
 }
-VXR_FN void vxr_vk_graphics_getSurfaceInfo(vxr_vk_instance instanceHandle, vxr_vk_surfaceInfo* info) {
+VXR_FN void vxr_vk_graphics_swapchain_setHdrMetadata(vxr_vk_instance instanceHandle, vxr_vk_graphics_swapchain swapchainHandle,
+													 VkHdrMetadataEXT metadata) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
-	auto* graphics = &instance->graphics;
+	auto* swapchain = vxr::vk::graphics::swapchain::fromHandle(swapchainHandle);
 
-	info->format = graphics->swapchain.surfaceFormat.format;
-	info->extent = graphics->swapchain.extent;
-	info->numImages = graphics->swapchain.size();
+	if (swapchain->vkSwapchain == VK_NULL_HANDLE) {
+		return;
+	}
+	VK_TRY_PROC_DEVICE(vkSetHdrMetadataEXT)(instance->device.vkDevice, 1, &swapchain->vkSwapchain, &metadata);
 }
 VXR_FN void vxr_vk_graphics_destroy(vxr_vk_instance instanceHandle) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
-	// auto* graphics = &instance->graphics;
+	auto* graphics = &instance->graphics;
 
-	vxr::vk::graphics::destroySwapchain(instance);
+	for (auto* swapchain : graphics->swapchains) {
+		vxr::vk::graphics::destroySwapchain(instance, swapchain);
+		delete swapchain;
+	}
+	graphics->swapchains.resize(0);
 }
 }
@@ -26,6 +26,7 @@ limitations under the License.
 #include "std/utility.hpp"
 #include "std/vector.hpp"
 #include "std/ringbuffer.hpp"
+#include "std/string.hpp"
 
 #include "vxr/vxr.h"
 #include "vk/device/vma/vma.hpp"
@@ -34,6 +35,52 @@ limitations under the License.
 namespace vxr::vk {
 struct instance;
 namespace graphics {
+// commandRecorder is a command pool that is only ever touched by a single thread at a time,
+// frames keep a list of them so that command buffers can be recorded in parallel.
+struct commandRecorder {
+	commandRecorder() noexcept = delete;
+	commandRecorder(commandRecorder&) = delete;
+	commandRecorder& operator=(const commandRecorder&) = delete;
+
+	commandRecorder(vxr::vk::instance*, size_t, const char*) noexcept;
+	~commandRecorder() noexcept;
+
+	VkDevice vkDevice;
+
+	VkCommandPool vkCommandPool;
+	size_t allocatedCommandBuffers;
+	vxr::std::ringbuffer<VkCommandBuffer> freeCommandBuffers;
+	vxr::std::vector<VkCommandBuffer> pendingCommandBuffers;
+
+	void reset() noexcept;
+
+	[[nodiscard]] vxr_vk_graphics_commandRecorder handle() noexcept {
+		return reinterpret_cast<vxr_vk_graphics_commandRecorder>(this);
+	}
+	[[nodiscard]] static commandRecorder* fromHandle(vxr_vk_graphics_commandRecorder handle) noexcept {
+		return reinterpret_cast<commandRecorder*>(handle);
+	}
+};
+
+// presentSlot holds what a frame needs to acquire and present one swapchain image,
+// frames keep a list of them so that multiple swapchains can be presented together.
+struct presentSlot {
+	presentSlot() noexcept = delete;
+	presentSlot(presentSlot&) = delete;
+	presentSlot& operator=(const presentSlot&) = delete;
+
+	presentSlot(vxr::vk::instance*, size_t, const char*) noexcept;
+	~presentSlot() noexcept;
+
+	VkDevice vkDevice;
+
+	struct swapchain* swapchain;
+	uint32_t imageIndex;
+	VkSemaphore surfaceAcquireSemaphore;
+	VkSemaphore surfaceReleaseSemaphore;
+	VkFence fence;
+};
+
 struct frame {
 	frame() noexcept = delete;
 	frame(frame&) = delete;
@@ -43,11 +90,10 @@ struct frame {
 	~frame() noexcept;
 
 	VkDevice vkDevice;
+	vxr::std::string name;
 
-	uint32_t imageIndex;
-	VkSemaphore surfaceAcquireSemaphore;
-	VkSemaphore surfaceReleaseSemaphore;
-	VkFence fence;
+	size_t activePresentSlots;
+	vxr::std::vector<presentSlot*> presentSlots;
 
 	VmaAllocator vmaAllocator;
 	VmaPool vmaPool;
@@ -58,6 +104,9 @@ struct frame {
 	vxr::std::ringbuffer<VkCommandBuffer> freeCommandBuffers;
 	vxr::std::vector<VkCommandBuffer> pendingCommandBuffers;
 
+	size_t activeCommandRecorders;
+	vxr::std::vector<commandRecorder*> commandRecorders;
+
 	[[nodiscard]] vxr_vk_graphics_frame handle() noexcept { return reinterpret_cast<vxr_vk_graphics_frame>(this); }
 	[[nodiscard]] static frame* fromHandle(vxr_vk_graphics_frame handle) noexcept {
 		return reinterpret_cast<frame*>(handle);
@@ -65,7 +114,7 @@ struct frame {
 };
 
 struct system {
-	struct swapchain swapchain;
+	vxr::std::vector<struct swapchain*> swapchains;
 };
 }  // namespace graphics
 }  // namespace vxr::vk
@@ -88,11 +88,20 @@ inline static void createShaderPipeline(vxr::vk::instance* instance, size_t name
 		stageCreateInfo.pSpecializationInfo = &specializationInfo;
 	}
 
-	VkGraphicsPipelineLibraryCreateInfoEXT libraryInfo = {.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_LIBRARY_CREATE_INFO_EXT};
+	// only the view mask is used by the shader libraries, it must match the one of the fragment output library
+	const VkPipelineRenderingCreateInfo renderingInfo = {
+		.sType = VK_STRUCTURE_TYPE_PIPELINE_RENDERING_CREATE_INFO,
+		.viewMask = info.shader.viewMask,
+	};
+	VkGraphicsPipelineLibraryCreateInfoEXT libraryInfo = {
+		.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_LIBRARY_CREATE_INFO_EXT,
+		.pNext = &renderingInfo,
+	};
 	VkGraphicsPipelineCreateInfo pipelineCreateInfo = {
 		.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
 		.pNext = &libraryInfo,
-		.flags = VK_PIPELINE_CREATE_LIBRARY_BIT_KHR | VK_PIPELINE_CREATE_RETAIN_LINK_TIME_OPTIMIZATION_INFO_BIT_EXT,
+		.flags = VK_PIPELINE_CREATE_LIBRARY_BIT_KHR | VK_PIPELINE_CREATE_RETAIN_LINK_TIME_OPTIMIZATION_INFO_BIT_EXT
+				 | instance->device.pipelineCreateFlags(),
 		.stageCount = 1,
 		.pStages = &stageCreateInfo,
 		.layout = info.shader.layout,
@@ -161,7 +170,8 @@ VXR_FN void vxr_vk_graphics_createVertexInputPipeline(vxr_vk_instance instanceHa
 	const VkGraphicsPipelineCreateInfo pipelineCreateInfo = {
 		.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
 		.pNext = &libraryInfo,
-		.flags = VK_PIPELINE_CREATE_LIBRARY_BIT_KHR | VK_PIPELINE_CREATE_RETAIN_LINK_TIME_OPTIMIZATION_INFO_BIT_EXT,
+		.flags = VK_PIPELINE_CREATE_LIBRARY_BIT_KHR | VK_PIPELINE_CREATE_RETAIN_LINK_TIME_OPTIMIZATION_INFO_BIT_EXT
+				 | instance->device.pipelineCreateFlags(),
 		.pVertexInputState = &inputStateInfo,
 		.pInputAssemblyState = &inputAssemblyInfo,
 		.pDynamicState = &dynamicInfo,
@@ -184,13 +194,22 @@ VXR_FN void vxr_vk_graphics_createVertexShaderPipeline(vxr_vk_instance instanceH
 													   vxr_vk_graphics_shaderPipelineCreateInfo shader, VkPipeline* pipeline) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 
-	static constexpr vxr::std::array vertexDynamicStates = {
-		VK_DYNAMIC_STATE_VIEWPORT_WITH_COUNT, VK_DYNAMIC_STATE_SCISSOR_WITH_COUNT, VK_DYNAMIC_STATE_CULL_MODE,
-		VK_DYNAMIC_STATE_FRONT_FACE,		  VK_DYNAMIC_STATE_LINE_WIDTH,		   VK_DYNAMIC_STATE_POLYGON_MODE_EXT,
-	};
-	static constexpr VkPipelineDynamicStateCreateInfo vertexDynamicInfo{
+	vxr::std::vector<VkDynamicState> vertexDynamicStates(vxr::std::array{
+		VK_DYNAMIC_STATE_VIEWPORT_WITH_COUNT,
+		VK_DYNAMIC_STATE_SCISSOR_WITH_COUNT,
+		VK_DYNAMIC_STATE_CULL_MODE,
+		VK_DYNAMIC_STATE_FRONT_FACE,
+		VK_DYNAMIC_STATE_LINE_WIDTH,
+		VK_DYNAMIC_STATE_POLYGON_MODE_EXT,
+		VK_DYNAMIC_STATE_DEPTH_BIAS_ENABLE,
+		VK_DYNAMIC_STATE_DEPTH_BIAS,
+	});
+	if (shader.optionalDynamicStates.depthClampEnable == VK_TRUE) {
+		vertexDynamicStates.pushBack(VK_DYNAMIC_STATE_DEPTH_CLAMP_ENABLE_EXT);
+	}
+	const VkPipelineDynamicStateCreateInfo vertexDynamicInfo{
 		.sType = VK_STRUCTURE_TYPE_PIPELINE_DYNAMIC_STATE_CREATE_INFO,
-		.dynamicStateCount = vertexDynamicStates.size(),
+		.dynamicStateCount = static_cast<uint32_t>(vertexDynamicStates.size()),
 		.pDynamicStates = vertexDynamicStates.get(),
 	};
 	static constexpr VkPipelineViewportStateCreateInfo viewportState = {
@@ -231,20 +250,28 @@ VXR_FN void vxr_vk_graphics_createFragmentShaderPipeline(vxr_vk_instance instanc
 														 vxr_vk_graphics_shaderPipelineCreateInfo shader, VkPipeline* pipeline) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 
-	static constexpr vxr::std::array fragmentDynamicStates = {
+	vxr::std::vector<VkDynamicState> fragmentDynamicStates(vxr::std::array{
 		VK_DYNAMIC_STATE_DEPTH_TEST_ENABLE,
 		VK_DYNAMIC_STATE_DEPTH_WRITE_ENABLE,
 		VK_DYNAMIC_STATE_DEPTH_COMPARE_OP,
+		VK_DYNAMIC_STATE_DEPTH_BOUNDS_TEST_ENABLE,
+		VK_DYNAMIC_STATE_DEPTH_BOUNDS,
 		VK_DYNAMIC_STATE_STENCIL_TEST_ENABLE,
 		VK_DYNAMIC_STATE_STENCIL_OP,
 		VK_DYNAMIC_STATE_STENCIL_COMPARE_MASK,
 		VK_DYNAMIC_STATE_STENCIL_WRITE_MASK,
 		VK_DYNAMIC_STATE_STENCIL_REFERENCE,
 		VK_DYNAMIC_STATE_RASTERIZATION_SAMPLES_EXT,
-	};
-	static constexpr VkPipelineDynamicStateCreateInfo fragmentDynamicInfo{
+	});
+	if (shader.optionalDynamicStates.alphaToCoverageEnable == VK_TRUE) {
+		fragmentDynamicStates.pushBack(VK_DYNAMIC_STATE_ALPHA_TO_COVERAGE_ENABLE_EXT);
+	}
+	if (shader.optionalDynamicStates.sampleMask == VK_TRUE) {
+		fragmentDynamicStates.pushBack(VK_DYNAMIC_STATE_SAMPLE_MASK_EXT);
+	}
+	const VkPipelineDynamicStateCreateInfo fragmentDynamicInfo{
 		.sType = VK_STRUCTURE_TYPE_PIPELINE_DYNAMIC_STATE_CREATE_INFO,
-		.dynamicStateCount = fragmentDynamicStates.size(),
+		.dynamicStateCount = static_cast<uint32_t>(fragmentDynamicStates.size()),
 		.pDynamicStates = fragmentDynamicStates.get(),
 	};
 	static constexpr VkPipelineMultisampleStateCreateInfo multisampleInfo = {
@@ -282,15 +309,25 @@ VXR_FN void vxr_vk_graphics_createFragmentOutputPipeline(vxr_vk_instance instanc
 														 vxr_vk_graphics_fragmentOutputPipelineCreateInfo info, VkPipeline* pipeline) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 
-	static constexpr vxr::std::array dynamicStates = {
+	vxr::std::vector<VkDynamicState> dynamicStates(vxr::std::array{
 		VK_DYNAMIC_STATE_COLOR_BLEND_ENABLE_EXT,
 		VK_DYNAMIC_STATE_COLOR_BLEND_EQUATION_EXT,
 		VK_DYNAMIC_STATE_COLOR_WRITE_MASK_EXT,
+		VK_DYNAMIC_STATE_BLEND_CONSTANTS,
 		VK_DYNAMIC_STATE_RASTERIZATION_SAMPLES_EXT,
-	};
-	static constexpr VkPipelineDynamicStateCreateInfo dynamicInfo{
+	});
+	if (info.optionalDynamicStates.logicOpEnable == VK_TRUE) {
+		dynamicStates.pushBack(VK_DYNAMIC_STATE_LOGIC_OP_ENABLE_EXT);
+	}
+	if (info.optionalDynamicStates.alphaToCoverageEnable == VK_TRUE) {
+		dynamicStates.pushBack(VK_DYNAMIC_STATE_ALPHA_TO_COVERAGE_ENABLE_EXT);
+	}
+	if (info.optionalDynamicStates.sampleMask == VK_TRUE) {
+		dynamicStates.pushBack(VK_DYNAMIC_STATE_SAMPLE_MASK_EXT);
+	}
+	const VkPipelineDynamicStateCreateInfo dynamicInfo{
 		.sType = VK_STRUCTURE_TYPE_PIPELINE_DYNAMIC_STATE_CREATE_INFO,
-		.dynamicStateCount = dynamicStates.size(),
+		.dynamicStateCount = static_cast<uint32_t>(dynamicStates.size()),
 		.pDynamicStates = dynamicStates.get(),
 	};
 	static constexpr VkPipelineMultisampleStateCreateInfo multisampleInfo = {
@@ -298,12 +335,15 @@ VXR_FN void vxr_vk_graphics_createFragmentOutputPipeline(vxr_vk_instance instanc
 		//.rasterizationSamples = VK_SAMPLE_COUNT_1_BIT,
 		.sampleShadingEnable = VK_FALSE,
 	};
-	static constexpr VkPipelineColorBlendStateCreateInfo colorBlendInfo = {
+	// logicOpEnable is dynamic when supported, the op itself is baked in
+	const VkPipelineColorBlendStateCreateInfo colorBlendInfo = {
 		.sType = VK_STRUCTURE_TYPE_PIPELINE_COLOR_BLEND_STATE_CREATE_INFO,
+		.logicOp = info.logicOp,
 	};
 
 	const VkPipelineRenderingCreateInfo renderingInfo = {
 		.sType = VK_STRUCTURE_TYPE_PIPELINE_RENDERING_CREATE_INFO,
+		.viewMask = info.viewMask,
 		.colorAttachmentCount = info.numColorAttachments,
 		.pColorAttachmentFormats = info.colorAttachmentFormats,
 		.depthAttachmentFormat = info.depthFormat,
@@ -319,7 +359,8 @@ VXR_FN void vxr_vk_graphics_createFragmentOutputPipeline(vxr_vk_instance instanc
 	const VkGraphicsPipelineCreateInfo pipelineCreateInfo = {
 		.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
 		.pNext = &libraryInfo,
-		.flags = VK_PIPELINE_CREATE_LIBRARY_BIT_KHR | VK_PIPELINE_CREATE_RETAIN_LINK_TIME_OPTIMIZATION_INFO_BIT_EXT,
+		.flags = VK_PIPELINE_CREATE_LIBRARY_BIT_KHR | VK_PIPELINE_CREATE_RETAIN_LINK_TIME_OPTIMIZATION_INFO_BIT_EXT
+				 | instance->device.pipelineCreateFlags(),
 		.pMultisampleState = &multisampleInfo,
 		.pColorBlendState = &colorBlendInfo,
 		.pDynamicState = info.numColorAttachments > 0 ? &dynamicInfo : nullptr,
@@ -352,7 +393,8 @@ VXR_FN VkBool32 vxr_vk_graphics_linkPipelines(vxr_vk_instance instanceHandle, si
 		.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
 		.pNext = &linkingInfo,
 		.flags = static_cast<VkPipelineCreateFlags>(
-			VK_PIPELINE_CREATE_LINK_TIME_OPTIMIZATION_BIT_EXT | VK_PIPELINE_CREATE_FAIL_ON_PIPELINE_COMPILE_REQUIRED_BIT),
+			VK_PIPELINE_CREATE_LINK_TIME_OPTIMIZATION_BIT_EXT | VK_PIPELINE_CREATE_FAIL_ON_PIPELINE_COMPILE_REQUIRED_BIT)
+				 | instance->device.pipelineCreateFlags(),
 		.layout = layout,
 	};
 	VkResult ret = VK_PROC_DEVICE(vkCreateGraphicsPipelines)(
@@ -369,7 +411,7 @@ VXR_FN VkBool32 vxr_vk_graphics_linkPipelines(vxr_vk_instance instanceHandle, si
 
 	if (ret == VK_PIPELINE_COMPILE_REQUIRED) {
 		vxr::std::vPrintf("Executable pipeline not cached, fast linking pipeline");
-		executablePipelineCreateInfo.flags = VK_PIPELINE_CREATE_DISABLE_OPTIMIZATION_BIT;
+		executablePipelineCreateInfo.flags = VK_PIPELINE_CREATE_DISABLE_OPTIMIZATION_BIT | instance->device.pipelineCreateFlags();
 		ret = VK_PROC_DEVICE(vkCreateGraphicsPipelines)(
 			instance->device.vkDevice, nullptr, 1, &executablePipelineCreateInfo, nullptr, executable);
 	}
@@ -400,7 +442,7 @@ VXR_FN void vxr_vk_graphics_linkOptimizePipelines(vxr_vk_instance instanceHandle
 	const VkGraphicsPipelineCreateInfo executablePipelineCreateInfo = {
 		.sType = VK_STRUCTURE_TYPE_GRAPHICS_PIPELINE_CREATE_INFO,
 		.pNext = &linkingInfo,
-		.flags = static_cast<VkPipelineCreateFlags>(VK_PIPELINE_CREATE_LINK_TIME_OPTIMIZATION_BIT_EXT),
+		.flags = static_cast<VkPipelineCreateFlags>(VK_PIPELINE_CREATE_LINK_TIME_OPTIMIZATION_BIT_EXT) | instance->device.pipelineCreateFlags(),
 		.layout = layout,
 	};
 
@@ -32,16 +32,13 @@ limitations under the License.
 #include "vk/vklog.hpp"
 #include "vk/device/device.hpp"
 
-static constexpr vxr::std::array wantFormats = {
-	vxr::std::pair(VK_FORMAT_B8G8R8A8_SRGB, VK_COLOR_SPACE_SRGB_NONLINEAR_KHR),
-	vxr::std::pair(VK_FORMAT_R8G8B8A8_SRGB, VK_COLOR_SPACE_SRGB_NONLINEAR_KHR),
-};
-
-inline static bool findFormat(vxr::vk::graphics::swapchain* swapchain, vxr::std::vector<VkSurfaceFormatKHR>& surfaceFormats) {
-	for (auto want : wantFormats) {
+inline static bool findFormat(vxr::vk::graphics::swapchain* swapchain, const vxr_vk_graphics_swapchainCreateInfo& info,
+							  vxr::std::vector<VkSurfaceFormatKHR>& surfaceFormats) {
+	for (uint32_t j = 0; j < info.numSurfaceFormats; j++) {
+		const auto& want = info.surfaceFormats[j];
 		for (size_t i = 0; i < surfaceFormats.size(); i++) {
 			auto& surfaceFormat = surfaceFormats[i];
-			if (surfaceFormat.format == want.first && surfaceFormat.colorSpace == want.second) {
+			if (surfaceFormat.format == want.format && surfaceFormat.colorSpace == want.colorSpace) {
 				swapchain->surfaceFormat = surfaceFormat;
 				vxr::std::iPrintf("Selected format: [%d]", i);
 				return true;
@@ -78,8 +75,8 @@ inline static void printFormats(vxr::std::vector<VkSurfaceFormatKHR>& surfaceFor
 	}
 
 namespace vxr::vk::graphics {
-VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNumImages) {
-	auto* swapchain = &instance->graphics.swapchain;
+VkResult initSwapchain(instance* instance, swapchain* swapchain, const vxr_vk_graphics_swapchainCreateInfo& info) {
+	VkSurfaceKHR surface = swapchain->surface;
 
 	{
 		for (auto& image : swapchain->images) {
@@ -112,7 +109,7 @@ VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNu
 		HANDLE_SURFACE_ERROR(ret, "Failed to get surface formats: %s", vxr::vk::vkResultStr(ret).cStr());
 
 		printFormats(surfaceFormats);
-		if (!findFormat(swapchain, surfaceFormats)) {
+		if (!findFormat(swapchain, info, surfaceFormats)) {
 			swapchain->surfaceFormat = surfaceFormats[0];
 			vxr::std::wPrintf("No known surface formats found");
 			vxr::std::iPrintf("Selected format: [0]");
@@ -120,6 +117,7 @@ VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNu
 	}
 
 	VkSurfaceCapabilitiesKHR surfaceCapabilities;
+	// FIFO is the only mode the spec guarantees, so it is the fallback when none of the wanted modes are supported
 	VkPresentModeKHR presentMode = VK_PRESENT_MODE_FIFO_KHR;
 	vxr::std::vector<VkPresentModeKHR> compatiblePresentModes;
 
@@ -134,12 +132,21 @@ VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNu
 			instance->device.vkPhysicalDevice, surface, &numPresentModes, presentModes.get());
 		HANDLE_SURFACE_ERROR(ret, "Failed to get surface present modes: %s", vxr::vk::vkResultStr(ret).cStr());
 
-		for (uint32_t i = 0; i < numPresentModes; i++) {
-			if (presentModes[i] == VK_PRESENT_MODE_FIFO_RELAXED_KHR) {
-				presentMode = presentModes[i];
-				break;
+		bool found = false;
+		for (uint32_t j = 0; j < info.numPresentModes && !found; j++) {
+			for (uint32_t i = 0; i < numPresentModes; i++) {
+				if (presentModes[i] == info.presentModes[j]) {
+					presentMode = presentModes[i];
+					found = true;
+					break;
+				}
 			}
 		}
+		if (!found) {
+			vxr::std::wPrintf("No wanted present modes found, falling back to FIFO");
+		}
+		swapchain->presentMode = presentMode;
+		vxr::std::iPrintf("Selected present mode: %d", presentMode);
 	}
 
 	{
@@ -199,7 +206,7 @@ VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNu
 			.sType = VK_STRUCTURE_TYPE_SWAPCHAIN_CREATE_INFO_KHR,
 			.pNext = &presentModesCreateInfo,
 			.surface = surface,
-			.minImageCount = vxr::std::max(surfaceCapabilities.minImageCount, wantNumImages),
+			.minImageCount = vxr::std::max(surfaceCapabilities.minImageCount, info.numImages),
 			.imageFormat = swapchain->surfaceFormat.format,
 			.imageColorSpace = swapchain->surfaceFormat.colorSpace,
 			.imageExtent = surfaceCapabilities.currentExtent,
@@ -216,20 +223,20 @@ VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNu
 			createInfo.minImageCount = surfaceCapabilities.maxImageCount;
 		}
 
-		const VkResult ret = VK_PROC_DEVICE(vkCreateSwapchainKHR)(
+		const VkResult ret = VK_TRY_PROC_DEVICE(vkCreateSwapchainKHR)(
 			instance->device.vkDevice, &createInfo, nullptr, &swapchain->vkSwapchain);
 		HANDLE_SURFACE_ERROR(ret, "Failed to create swapchain: %s", vxr::vk::vkResultStr(ret).cStr());
-		VK_PROC_DEVICE(vkDestroySwapchainKHR)(instance->device.vkDevice, createInfo.oldSwapchain, nullptr);
+		VK_TRY_PROC_DEVICE(vkDestroySwapchainKHR)(instance->device.vkDevice, createInfo.oldSwapchain, nullptr);
 	}
 
 	{
 		uint32_t numImages = 0;
-		VkResult ret = VK_PROC_DEVICE(vkGetSwapchainImagesKHR)(instance->device.vkDevice, swapchain->vkSwapchain, &numImages, nullptr);
+		VkResult ret = VK_TRY_PROC_DEVICE(vkGetSwapchainImagesKHR)(instance->device.vkDevice, swapchain->vkSwapchain, &numImages, nullptr);
 		HANDLE_SURFACE_ERROR(ret, "Failed to get swapchain images: %s", vxr::vk::vkResultStr(ret).cStr());
 
 		vxr::std::vector<VkImage> swapChainImages(numImages);
 		swapchain->images.resize(numImages);
-		ret = VK_PROC_DEVICE(vkGetSwapchainImagesKHR)(
+		ret = VK_TRY_PROC_DEVICE(vkGetSwapchainImagesKHR)(
 			instance->device.vkDevice, swapchain->vkSwapchain, &numImages, swapChainImages.get());
 		HANDLE_SURFACE_ERROR(ret, "Failed to get swapchain images: %s", vxr::vk::vkResultStr(ret).cStr());
 
@@ -259,23 +266,21 @@ VkResult initSwapchain(instance* instance, VkSurfaceKHR surface, uint32_t wantNu
 			ret = VK_PROC_DEVICE(vkCreateImageView)(
 				instance->device.vkDevice, &createInfo, nullptr, &swapchain->images[i].second);
 			HANDLE_SURFACE_ERROR(ret, "Failed to create swapchain image view: %s", vxr::vk::vkResultStr(ret).cStr());
-			vxr::vk::debugLabel(instance->device.vkDevice, swapchain->images[i].first, "swapchain_image_%d", i);
-			vxr::vk::debugLabel(instance->device.vkDevice, swapchain->images[i].second, "swapchain_image_view_%d", i);
+			vxr::vk::debugLabel(instance->device.vkDevice, swapchain->images[i].first, "swapchain_%s_image_%d", swapchain->name.cStr(), i);
+			vxr::vk::debugLabel(instance->device.vkDevice, swapchain->images[i].second, "swapchain_%s_image_view_%d", swapchain->name.cStr(), i);
 		}
 	}
 
 	return VK_SUCCESS;
 }
 
-void destroySwapchain(instance* instance) {
-	auto* swapchain = &instance->graphics.swapchain;
-
+void destroySwapchain(instance* instance, swapchain* swapchain) {
 	for (auto& image : swapchain->images) {
 		VK_PROC_DEVICE(vkDestroyImageView)(instance->device.vkDevice, image.second, nullptr);
 	}
 	swapchain->images.resize(0);
 
-	VK_PROC_DEVICE(vkDestroySwapchainKHR)(instance->device.vkDevice, swapchain->vkSwapchain, nullptr);
+	VK_TRY_PROC_DEVICE(vkDestroySwapchainKHR)(instance->device.vkDevice, swapchain->vkSwapchain, nullptr);
 	swapchain->vkSwapchain = VK_NULL_HANDLE;
 }
 }  // namespace vxr::vk::graphics
@@ -27,22 +27,32 @@ limitations under the License.
 
 #include "std/vector.hpp"
 #include "std/utility.hpp"
+#include "std/string.hpp"
 
 namespace vxr::vk {
 
 struct instance;
 
 namespace graphics {
+// swapchain is created for every surface the user presents to, the vkSwapchain is only created on the first init.
 struct swapchain {
+	vxr::std::string name;
+	VkSurfaceKHR surface = VK_NULL_HANDLE;
 	VkExtent2D extent = {};
 	VkSurfaceFormatKHR surfaceFormat = {};
+	VkPresentModeKHR presentMode = VK_PRESENT_MODE_FIFO_KHR;
 	VkSwapchainKHR vkSwapchain = VK_NULL_HANDLE;
 	vxr::std::vector<vxr::std::pair<VkImage, VkImageView>> images;
 
 	[[nodiscard]] size_t size() const noexcept { return this->images.size(); }
+
+	[[nodiscard]] vxr_vk_graphics_swapchain handle() noexcept { return reinterpret_cast<vxr_vk_graphics_swapchain>(this); }
+	[[nodiscard]] static swapchain* fromHandle(vxr_vk_graphics_swapchain handle) noexcept {
+		return reinterpret_cast<swapchain*>(handle);
+	}
 };
 
-VkResult initSwapchain(instance*, VkSurfaceKHR, uint32_t);
-void destroySwapchain(instance*);
+VkResult initSwapchain(instance*, swapchain*, const vxr_vk_graphics_swapchainCreateInfo&);
+void destroySwapchain(instance*, swapchain*);
 }  // namespace graphics
 }  // namespace vxr::vk
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+
+#include "vxr/vxr.h"  // IWYU pragma: associated
+
+#include <stddef.h>
+#include <stdint.h>
+
+#include "std/stdlib.hpp"
+#include "std/log.hpp"
+#include "std/string.hpp"
+
+#include "vk/vk.hpp"
+#include "vk/vklog.hpp"
+#include "vk/device/device.hpp"
+
+extern "C" {
+VXR_FN void vxr_vk_createQueryPool(vxr_vk_instance instanceHandle, size_t nameSz, const char* name, VkQueryType type, uint32_t count,
+								   VkQueryPipelineStatisticFlags statistics, VkQueryPool* pool) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	VkQueryPoolCreateInfo poolInfo = {};
+	poolInfo.sType = VK_STRUCTURE_TYPE_QUERY_POOL_CREATE_INFO;
+	poolInfo.queryType = type;
+	poolInfo.queryCount = count;
+	poolInfo.pipelineStatistics = statistics;
+
+	const VkResult ret = VK_PROC_DEVICE(vkCreateQueryPool)(instance->device.vkDevice, &poolInfo, nullptr, pool);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to create query pool: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+
+	vxr::std::debugRun([=]() {
+		vxr::std::stringbuilder builder;
+		builder.write("query_pool_").write(nameSz, name);
+		vxr::vk::debugLabel(instance->device.vkDevice, *pool, builder.cStr());
+	});
+
+	VK_PROC_DEVICE(vkResetQueryPool)(instance->device.vkDevice, *pool, 0, count);
+}
+VXR_FN void vxr_vk_resetQueryPool(vxr_vk_instance instanceHandle, VkQueryPool pool, uint32_t first, uint32_t count) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	VK_PROC_DEVICE(vkResetQueryPool)(instance->device.vkDevice, pool, first, count);
+}
+VXR_FN VkResult vxr_vk_getQueryPoolResults(vxr_vk_instance instanceHandle, VkQueryPool pool, uint32_t first, uint32_t count, size_t dataSize,
+										   void* data, VkDeviceSize stride, VkQueryResultFlags flags) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	const VkResult ret = VK_PROC_DEVICE(vkGetQueryPoolResults)(instance->device.vkDevice, pool, first, count, dataSize, data, stride, flags);
+	switch (ret) {
+		case VK_SUCCESS:
+		case VK_NOT_READY:
+			return ret;
+
+		default:
+			vxr::std::ePrintf("Failed to get query pool results: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+			return ret;
+	}
+}
+VXR_FN void vxr_vk_destroyQueryPool(vxr_vk_instance instanceHandle, VkQueryPool pool) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+	VK_PROC_DEVICE(vkDestroyQueryPool)(instance->device.vkDevice, pool, nullptr);
+}
+}
@@ -106,6 +106,26 @@ VXR_FN void vxr_vk_waitSemaphore(vxr_vk_instance instanceHandle, VkSemaphore sem
 		vxr::std::abort();
 	}
 }
+VXR_FN VkResult vxr_vk_waitSemaphores(vxr_vk_instance instanceHandle, uint32_t numSemaphores, VkSemaphore* semaphores,
+									  uint64_t* values, VkBool32 waitAny, uint64_t timeout) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	VkSemaphoreWaitInfo waitInfo = {};
+	waitInfo.sType = VK_STRUCTURE_TYPE_SEMAPHORE_WAIT_INFO;
+	if (waitAny) {
+		waitInfo.flags = VK_SEMAPHORE_WAIT_ANY_BIT;
+	}
+	waitInfo.semaphoreCount = numSemaphores;
+	waitInfo.pSemaphores = semaphores;
+	waitInfo.pValues = values;
+
+	const VkResult ret = VK_PROC_DEVICE(vkWaitSemaphores)(instance->device.vkDevice, &waitInfo, timeout);
+	if (ret != VK_SUCCESS && ret != VK_TIMEOUT) {
+		vxr::std::ePrintf("Failed waiting on semaphores: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+	return ret;
+}
 VXR_FN uint64_t vxr_vk_getSemaphoreValue(vxr_vk_instance instanceHandle, VkSemaphore semaphore) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 	uint64_t value = 0;
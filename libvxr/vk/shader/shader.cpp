@@ -29,14 +29,21 @@ limitations under the License.
 #include "vk/device/device.hpp"
 
 extern "C" {
-VXR_FN void vxr_vk_shader_createDescriptorSetLayout(vxr_vk_instance instanceHandle, size_t nameSz, const char* name, uint32_t bindingsCount,
+VXR_FN void vxr_vk_shader_createDescriptorSetLayout(vxr_vk_instance instanceHandle, size_t nameSz, const char* name,
+													VkDescriptorSetLayoutCreateFlags flags, uint32_t bindingsCount,
 													VkDescriptorSetLayoutBinding* bindings, VkDescriptorSetLayout* descriptorSetLayout) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 
+	// descriptor buffers are plain memory and push descriptors are recorded into the command buffer,
+	// neither has pending state to opt out of
+	const VkDescriptorBindingFlags arrayBindingFlags =
+		(instance->device.usesDescriptorBuffer() || (flags & VK_DESCRIPTOR_SET_LAYOUT_CREATE_PUSH_DESCRIPTOR_BIT_KHR) != 0)
+			? VkDescriptorBindingFlags(VK_DESCRIPTOR_BINDING_PARTIALLY_BOUND_BIT)
+			: VkDescriptorBindingFlags(VK_DESCRIPTOR_BINDING_UPDATE_UNUSED_WHILE_PENDING_BIT | VK_DESCRIPTOR_BINDING_PARTIALLY_BOUND_BIT);
 	vxr::std::vector<VkDescriptorBindingFlags> descriptorLayoutBindingFlags(bindingsCount);
 	for (uint32_t i = 0; i < bindingsCount; i++) {
 		if (bindings[i].descriptorCount > 1) {
-			descriptorLayoutBindingFlags[i] = VK_DESCRIPTOR_BINDING_UPDATE_UNUSED_WHILE_PENDING_BIT | VK_DESCRIPTOR_BINDING_PARTIALLY_BOUND_BIT;
+			descriptorLayoutBindingFlags[i] = arrayBindingFlags;
 		}
 	}
 
@@ -48,6 +55,10 @@ VXR_FN void vxr_vk_shader_createDescriptorSetLayout(vxr_vk_instance instanceHand
 	VkDescriptorSetLayoutCreateInfo descriptorLayoutInfo = {};
 	descriptorLayoutInfo.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_SET_LAYOUT_CREATE_INFO;
 	descriptorLayoutInfo.pNext = &descriptorLayoutBindingFlagsInfo;
+	descriptorLayoutInfo.flags = flags;
+	if (instance->device.usesDescriptorBuffer()) {
+		descriptorLayoutInfo.flags |= VK_DESCRIPTOR_SET_LAYOUT_CREATE_DESCRIPTOR_BUFFER_BIT_EXT;
+	}
 	descriptorLayoutInfo.pBindings = bindings;
 	descriptorLayoutInfo.bindingCount = bindingsCount;
 
@@ -85,6 +96,11 @@ VXR_FN void vxr_vk_shader_createDescriptorPool(vxr_vk_instance instanceHandle, s
 		vxr::vk::debugLabel(instance->device.vkDevice, *descriptorPool, sb.cStr());
 	});
 }
+VXR_FN void vxr_vk_shader_resetDescriptorPool(vxr_vk_instance instanceHandle, VkDescriptorPool descriptorPool) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	VK_PROC_DEVICE(vkResetDescriptorPool)(instance->device.vkDevice, descriptorPool, 0);
+}
 VXR_FN void vxr_vk_shader_destroyDescriptorPool(vxr_vk_instance instanceHandle, VkDescriptorPool descriptorPool) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 
@@ -111,11 +127,145 @@ VXR_FN void vxr_vk_shader_updateDescriptorSet(vxr_vk_instance instanceHandle, Vk
 
 	VK_PROC_DEVICE(vkUpdateDescriptorSets)(instance->device.vkDevice, 1, &descriptorWrites, 0, nullptr);
 }
+VXR_FN void vxr_vk_shader_updateDescriptorSets(vxr_vk_instance instanceHandle, uint32_t numWrites, const VkWriteDescriptorSet* descriptorWrites) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	VK_PROC_DEVICE(vkUpdateDescriptorSets)(instance->device.vkDevice, numWrites, descriptorWrites, 0, nullptr);
+}
+VXR_FN void vxr_vk_shader_createDescriptorUpdateTemplate(vxr_vk_instance instanceHandle, size_t nameSz, const char* name,
+														 VkDescriptorSetLayout descriptorSetLayout, uint32_t numEntries,
+														 const VkDescriptorUpdateTemplateEntry* entries,
+														 VkDescriptorUpdateTemplate* descriptorUpdateTemplate) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	const VkDescriptorUpdateTemplateCreateInfo info = {
+		.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_UPDATE_TEMPLATE_CREATE_INFO,
+		.descriptorUpdateEntryCount = numEntries,
+		.pDescriptorUpdateEntries = entries,
+		.templateType = VK_DESCRIPTOR_UPDATE_TEMPLATE_TYPE_DESCRIPTOR_SET,
+		.descriptorSetLayout = descriptorSetLayout,
+	};
+	const VkResult ret =
+		VK_PROC_DEVICE(vkCreateDescriptorUpdateTemplate)(instance->device.vkDevice, &info, nullptr, descriptorUpdateTemplate);
+	if (ret != VK_SUCCESS) {
+		vxr::std::ePrintf("Failed to create descriptor update template: %s", vxr::vk::vkResultStr(ret).cStr());
+		vxr::std::abort();
+	}
+
+	vxr::std::debugRun([=]() {
+		vxr::std::stringbuilder sb;
+		sb.write("descriptor_update_template_").write(nameSz, name);
+		vxr::vk::debugLabel(instance->device.vkDevice, *descriptorUpdateTemplate, sb.cStr());
+	});
+}
+VXR_FN void vxr_vk_shader_destroyDescriptorUpdateTemplate(vxr_vk_instance instanceHandle, VkDescriptorUpdateTemplate descriptorUpdateTemplate) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	VK_PROC_DEVICE(vkDestroyDescriptorUpdateTemplate)(instance->device.vkDevice, descriptorUpdateTemplate, nullptr);
+}
+VXR_FN void vxr_vk_shader_updateDescriptorSetWithTemplate(vxr_vk_instance instanceHandle, VkDescriptorSet descriptorSet,
+														  VkDescriptorUpdateTemplate descriptorUpdateTemplate, const void* data) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	VK_PROC_DEVICE(vkUpdateDescriptorSetWithTemplate)(instance->device.vkDevice, descriptorSet, descriptorUpdateTemplate, data);
+}
 VXR_FN void vxr_vk_shader_destroyDescriptorSet(vxr_vk_instance instanceHandle, VkDescriptorPool descriptorPool, VkDescriptorSet descriptorSet) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 
 	VK_PROC_DEVICE(vkFreeDescriptorSets)(instance->device.vkDevice, descriptorPool, 1, &descriptorSet);
 }
+VXR_FN void vxr_vk_shader_createBindlessHeap(vxr_vk_instance instanceHandle, size_t nameSz, const char* name, uint32_t bindingsCount,
+											 VkDescriptorSetLayoutBinding* bindings, VkDescriptorSetLayout* descriptorSetLayout,
+											 VkDescriptorPool* descriptorPool, VkDescriptorSet* descriptorSet) {
+	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
+
+	// the heap is updated while bound, slots are only rewritten after the frames using them have finished.
+	// with descriptor buffers only the layout is created, the heap is a range of the descriptor buffer.
+	const bool descriptorBuffer = instance->device.usesDescriptorBuffer();
+	vxr::std::vector<VkDescriptorBindingFlags> descriptorLayoutBindingFlags(bindingsCount);
+	vxr::std::vector<VkDescriptorPoolSize> poolSizes(bindingsCount);
+	for (uint32_t i = 0; i < bindingsCount; i++) {
+		descriptorLayoutBindingFlags[i] = descriptorBuffer ? VkDescriptorBindingFlags(VK_DESCRIPTOR_BINDING_PARTIALLY_BOUND_BIT)
+														   : VkDescriptorBindingFlags(VK_DESCRIPTOR_BINDING_UPDATE_AFTER_BIND_BIT
+																					  | VK_DESCRIPTOR_BINDING_UPDATE_UNUSED_WHILE_PENDING_BIT
+																					  | VK_DESCRIPTOR_BINDING_PARTIALLY_BOUND_BIT);
+		poolSizes[i] = VkDescriptorPoolSize{
+			.type = bindings[i].descriptorType,
+			.descriptorCount = bindings[i].descriptorCount,
+		};
+	}
+
+	{
+		const VkDescriptorSetLayoutBindingFlagsCreateInfo descriptorLayoutBindingFlagsInfo = {
+			.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_SET_LAYOUT_BINDING_FLAGS_CREATE_INFO,
+			.bindingCount = bindingsCount,
+			.pBindingFlags = descriptorLayoutBindingFlags.get(),
+		};
+		const VkDescriptorSetLayoutCreateInfo descriptorLayoutInfo = {
+			.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_SET_LAYOUT_CREATE_INFO,
+			.pNext = &descriptorLayoutBindingFlagsInfo,
+			.flags = descriptorBuffer ? VkDescriptorSetLayoutCreateFlags(VK_DESCRIPTOR_SET_LAYOUT_CREATE_DESCRIPTOR_BUFFER_BIT_EXT)
+									  : VkDescriptorSetLayoutCreateFlags(VK_DESCRIPTOR_SET_LAYOUT_CREATE_UPDATE_AFTER_BIND_POOL_BIT),
+			.bindingCount = bindingsCount,
+			.pBindings = bindings,
+		};
+		const VkResult ret = VK_PROC_DEVICE(vkCreateDescriptorSetLayout)(
+			instance->device.vkDevice, &descriptorLayoutInfo, nullptr, descriptorSetLayout);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to create bindless heap descriptor set layout: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+	}
+	vxr::std::debugRun([=]() {
+		vxr::std::stringbuilder sb;
+		sb.write("descriptor_set_layout_").write(nameSz, name);
+		vxr::vk::debugLabel(instance->device.vkDevice, *descriptorSetLayout, sb.cStr());
+	});
+	if (descriptorBuffer) {
+		*descriptorPool = VK_NULL_HANDLE;
+		*descriptorSet = VK_NULL_HANDLE;
+		return;
+	}
+
+	{
+		const VkDescriptorPoolCreateInfo poolInfo = {
+			.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_POOL_CREATE_INFO,
+			.flags = VK_DESCRIPTOR_POOL_CREATE_UPDATE_AFTER_BIND_BIT,
+			.maxSets = 1,
+			.poolSizeCount = bindingsCount,
+			.pPoolSizes = poolSizes.get(),
+		};
+		const VkResult ret = VK_PROC_DEVICE(vkCreateDescriptorPool)(instance->device.vkDevice, &poolInfo, nullptr, descriptorPool);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to create bindless heap descriptor pool: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+	}
+	{
+		const VkDescriptorSetAllocateInfo allocateInfo = {
+			.sType = VK_STRUCTURE_TYPE_DESCRIPTOR_SET_ALLOCATE_INFO,
+			.descriptorPool = *descriptorPool,
+			.descriptorSetCount = 1,
+			.pSetLayouts = descriptorSetLayout,
+		};
+		const VkResult ret = VK_PROC_DEVICE(vkAllocateDescriptorSets)(instance->device.vkDevice, &allocateInfo, descriptorSet);
+		if (ret != VK_SUCCESS) {
+			vxr::std::ePrintf("Failed to create bindless heap descriptor set: %s", vxr::vk::vkResultStr(ret).cStr());
+			vxr::std::abort();
+		}
+	}
+
+	vxr::std::debugRun([=]() {
+		vxr::std::stringbuilder sb;
+		sb.write("descriptor_pool_").write(nameSz, name);
+		vxr::vk::debugLabel(instance->device.vkDevice, *descriptorPool, sb.cStr());
+	});
+	vxr::std::debugRun([=]() {
+		vxr::std::stringbuilder sb;
+		sb.write("descriptor_set_").write(nameSz, name);
+		vxr::vk::debugLabel(instance->device.vkDevice, *descriptorSet, sb.cStr());
+	});
+}
 VXR_FN void vxr_vk_shader_createPipelineLayout(vxr_vk_instance instanceHandle, size_t nameSz, const char* name,
 											   vxr_vk_shader_pipelineLayoutCreateInfo info, VkPipelineLayout* layout) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
@@ -82,15 +82,26 @@ compiler::result compiler::compile(vxr_vk_shader_compileInfo info) const noexcep
 		const auto& m = info.macros[i];
 		shaderc_compile_options_add_macro_definition(options, m.name, m.nameSize, m.value, m.valueSize);
 	}
-	shaderc_compilation_result_t result = shaderc_compile_into_spv(
+	return shaderc_compile_into_spv(
 		// NOLINTNEXTLINE(performance-no-int-to-ptr)
 		this->shadercCompiler, reinterpret_cast<const char*>(info.content), info.contentSize,
 		shaderc_glsl_infer_from_source, info.name, "main", options);
-	const shaderc_compilation_status status = shaderc_result_get_compilation_status(result);
+}
+
+compiler::result::~result() noexcept {
+	shaderc_result_release(this->shadercResult);
+}
+
+bool compiler::result::success() const noexcept {
+	return shaderc_result_get_compilation_status(this->shadercResult) == shaderc_compilation_status_success;
+}
+
+vxr::std::string<char> compiler::result::error() const noexcept {
+	const shaderc_compilation_status status = shaderc_result_get_compilation_status(this->shadercResult);
 	const char* err = "unknown_error";
 	switch (status) {
 		case shaderc_compilation_status_success:
-			return result;
+			return {};
 
 		case shaderc_compilation_status_invalid_stage:
 			err = "invalid_stage";
@@ -117,13 +128,10 @@ compiler::result compiler::compile(vxr_vk_shader_compileInfo info) const noexcep
 			err = "configuration_error";
 			break;
 	}
-	vxr::std::ePrintf("Failed to compile shader: (%d: %s) %s", status, err, shaderc_result_get_error_message(result));
-	vxr::std::abort();
-	return result;
-}
 
-compiler::result::~result() noexcept {
-	shaderc_result_release(this->shadercResult);
+	vxr::std::stringbuilder sb;
+	sb.writef("(%d: %s) %s", status, err, shaderc_result_get_error_message(this->shadercResult));
+	return sb.str();
 }
 
 size_t compiler::result::len() const noexcept {
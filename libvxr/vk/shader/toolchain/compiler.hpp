@@ -23,6 +23,8 @@ limitations under the License.
 #include <stddef.h>
 #include <stdint.h>
 
+#include "std/string.hpp"
+
 #include "vk/vk.hpp"
 
 using shadercCompilationResult = struct shaderc_compilation_result*;
@@ -49,6 +51,8 @@ class compiler {
 		}
 		~result() noexcept;
 
+		[[nodiscard]] bool success() const noexcept;
+		[[nodiscard]] vxr::std::string<char> error() const noexcept;
 		[[nodiscard]] size_t len() const noexcept;
 		[[nodiscard]] const uint32_t* get() const noexcept;
 	};
@@ -30,6 +30,25 @@ limitations under the License.
 #include "vxr/vxr.h"
 #include "vk/shader/toolchain/reflector.hpp"
 
+inline static vxr_vk_shader_constantType spvcBaseTypeToConstantType(spvc_basetype type) {
+	switch (type) {
+		case SPVC_BASETYPE_BOOLEAN:
+			return vxr_vk_shader_constantType_bool;
+
+		case SPVC_BASETYPE_INT32:
+			return vxr_vk_shader_constantType_int;
+
+		case SPVC_BASETYPE_UINT32:
+			return vxr_vk_shader_constantType_uint;
+
+		case SPVC_BASETYPE_FP32:
+			return vxr_vk_shader_constantType_float;
+
+		default:
+			return vxr_vk_shader_constantType_unknown;
+	}
+}
+
 inline static VkDescriptorType spvcResrouceToVkDescriptor(spvc_resource_type tid, spvc_compiler compiler, spvc_variable_id vid) {
 	switch (tid) {
 		case SPVC_RESOURCE_TYPE_UNIFORM_BUFFER:
@@ -232,9 +251,11 @@ reflector::~reflector() noexcept {
 		for (size_t i = 0; i < sz; i++) {
 			this->specConstants.resize(vxr::std::max<size_t>(this->specConstants.size(), spvConstants[i].constant_id + 1));
 			const char* name = spvc_compiler_get_name(this->spvcCompiler, spvConstants[i].id);
+			spvc_constant constant = spvc_compiler_get_constant_handle(this->spvcCompiler, spvConstants[i].id);
+			spvc_type type = spvc_compiler_get_type_handle(this->spvcCompiler, spvc_constant_get_type(constant));
 			this->specConstants[spvConstants[i].constant_id].name = name;
-			this->specConstants[spvConstants[i].constant_id].value = spvc_constant_get_scalar_u32(
-				spvc_compiler_get_constant_handle(this->spvcCompiler, spvConstants[i].id), 0, 0);
+			this->specConstants[spvConstants[i].constant_id].value = spvc_constant_get_scalar_u32(constant, 0, 0);
+			this->specConstants[spvConstants[i].constant_id].type = spvcBaseTypeToConstantType(spvc_type_get_basetype(type));
 		}
 
 		spvc_specialization_constant x, y, z;
@@ -242,12 +263,15 @@ reflector::~reflector() noexcept {
 
 		if (x.id != 0) {
 			this->specConstants[x.constant_id].name = "local_size_x";
+			this->specConstants[x.constant_id].type = vxr_vk_shader_constantType_uint;
 		}
 		if (y.id != 0) {
 			this->specConstants[y.constant_id].name = "local_size_y";
+			this->specConstants[y.constant_id].type = vxr_vk_shader_constantType_uint;
 		}
 		if (z.id != 0) {
 			this->specConstants[z.constant_id].name = "local_size_z";
+			this->specConstants[z.constant_id].type = vxr_vk_shader_constantType_uint;
 		}
 	}
 
@@ -282,26 +306,33 @@ reflector::~reflector() noexcept {
 	return localSize;
 }
 
-[[nodiscard]] uint32_t reflector::getNumOutputs(size_t i) noexcept {
+reflector::entryPoint& reflector::setEntryPoint(size_t i) noexcept {
 	auto& entryPoint = this->entryPoints[i];
+	SpvExecutionModel model = SpvExecutionModelMax;
 	switch (entryPoint.stage) {
-		case VK_SHADER_STAGE_FRAGMENT_BIT: {
-			auto ret = spvc_compiler_set_entry_point(this->spvcCompiler, entryPoint.name.cStr(), SpvExecutionModelFragment);
-			if (ret != SPVC_SUCCESS) {
-				vxr::std::ePrintf("Failed to set entry point: %s", spvc_context_get_last_error_string(this->spvcContext));
-				vxr::std::abort();
-			}
-		} break;
+		case VK_SHADER_STAGE_VERTEX_BIT:
+			model = SpvExecutionModelVertex;
+			break;
+
+		case VK_SHADER_STAGE_FRAGMENT_BIT:
+			model = SpvExecutionModelFragment;
+			break;
 
 		default:
-			vxr::std::ePrintf("Failed to get stage outputs: unknown/unimplemented shader stage: %d", entryPoint.stage);
+			vxr::std::ePrintf("Failed to set entry point: unknown/unimplemented shader stage: %d", entryPoint.stage);
 			vxr::std::abort();
 			break;
 	}
 
+	auto ret = spvc_compiler_set_entry_point(this->spvcCompiler, entryPoint.name.cStr(), model);
+	if (ret != SPVC_SUCCESS) {
+		vxr::std::ePrintf("Failed to set entry point: %s", spvc_context_get_last_error_string(this->spvcContext));
+		vxr::std::abort();
+	}
+
 	if (entryPoint.spvcResources == nullptr) {
 		spvc_set set;
-		auto ret = spvc_compiler_get_active_interface_variables(this->spvcCompiler, &set);
+		ret = spvc_compiler_get_active_interface_variables(this->spvcCompiler, &set);
 		if (ret != SPVC_SUCCESS) {
 			vxr::std::ePrintf("Failed to create spvc resources: %s", spvc_context_get_last_error_string(this->spvcContext));
 			vxr::std::abort();
@@ -314,6 +345,16 @@ reflector::~reflector() noexcept {
 		}
 	}
 
+	return entryPoint;
+}
+
+[[nodiscard]] uint32_t reflector::getNumOutputs(size_t i) noexcept {
+	if (this->entryPoints[i].stage != VK_SHADER_STAGE_FRAGMENT_BIT) {
+		vxr::std::ePrintf("Failed to get stage outputs: unknown/unimplemented shader stage: %d", this->entryPoints[i].stage);
+		vxr::std::abort();
+	}
+	auto& entryPoint = this->setEntryPoint(i);
+
 	const spvc_reflected_resource* resource;
 	size_t count;
 	auto ret = spvc_resources_get_resource_list_for_type(
@@ -331,6 +372,26 @@ reflector::~reflector() noexcept {
 	return maxLocation;
 }
 
+[[nodiscard]] bool reflector::usesViewIndex(size_t i) noexcept {
+	auto& entryPoint = this->setEntryPoint(i);
+
+	const spvc_reflected_builtin_resource* resource;
+	size_t count;
+	auto ret = spvc_resources_get_builtin_resource_list_for_type(
+		entryPoint.spvcResources, SPVC_BUILTIN_RESOURCE_TYPE_STAGE_INPUT, &resource, &count);
+	if (ret != SPVC_SUCCESS) {
+		vxr::std::ePrintf("Failed to get stage builtin inputs: %s", spvc_context_get_last_error_string(this->spvcContext));
+		vxr::std::abort();
+	}
+
+	for (size_t i = 0; i < count; i++) {
+		if (resource[i].builtin == SpvBuiltInViewIndex) {
+			return true;
+		}
+	}
+	return false;
+}
+
 [[nodiscard]] VkPushConstantRange reflector::getPushConstantRange() noexcept {
 	if (this->spvcResources == nullptr) {
 		auto ret = spvc_compiler_create_shader_resources(this->spvcCompiler, &this->spvcResources);
@@ -420,10 +481,7 @@ reflector::~reflector() noexcept {
 						const auto currentValue = this->descriptorSets[set][binding].count.value;
 						const auto count = spvc_type_get_array_dimension(t, 0);
 						if (spvc_type_array_dimension_is_literal(t, 0) == SPVC_TRUE) {
-							if (count == 0) {
-								vxr::std::ePrintf("Variable descriptor count bindings are not implemented");
-								vxr::std::abort();
-							}
+							// a count of 0 is a runtime array, which only the bindless heap supports so leave it to the layout to reject
 							if ((currentValue != 0)
 								&& ((currentValue != count) || (this->descriptorSets[set][binding].count.isSpecConstant != VK_FALSE))) {
 								vxr::std::ePrintf(
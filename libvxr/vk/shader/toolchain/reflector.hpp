@@ -49,6 +49,7 @@ class reflector {
 	struct specConstant {
 		vxr::std::string<char> name;
 		uint32_t value;
+		vxr_vk_shader_constantType type;
 	};
 	struct binding {
 		VkDescriptorType type;
@@ -73,6 +74,8 @@ class reflector {
 	vxr::std::vector<specConstant> specConstants;
 	vxr::std::vector<vxr::std::vector<binding>> descriptorSets;
 
+	entryPoint& setEntryPoint(size_t) noexcept;
+
    public:
 	reflector() noexcept = delete;
 	reflector(reflector&&) noexcept = delete;
@@ -84,6 +87,7 @@ class reflector {
 	[[nodiscard]] const vxr::std::vector<specConstant>& getSpecConstants() noexcept;
 	[[nodiscard]] vxr::std::array<vxr_vk_shader_reflectResult_constant, 3> getLocalSize() const noexcept;
 	[[nodiscard]] uint32_t getNumOutputs(size_t) noexcept;
+	[[nodiscard]] bool usesViewIndex(size_t) noexcept;
 	[[nodiscard]] VkPushConstantRange getPushConstantRange() noexcept;
 	[[nodiscard]] const vxr::std::vector<vxr::std::vector<binding>>& getDescriptorSets() noexcept;
 
@@ -43,10 +43,36 @@ VXR_FN void vxr_vk_shader_compile(vxr_vk_shader_toolchain toolchainHandle, vxr_v
 	(*resultHandle) = result->handle();
 	(*reflectionHandle) = result->reflection.handle();
 }
+VXR_FN VkBool32 vxr_vk_shader_tryCompile(vxr_vk_shader_toolchain toolchainHandle, vxr_vk_shader_compileInfo info,
+										 vxr_vk_shader_compileResult* resultHandle, vxr_vk_shader_reflectResult* reflectionHandle,
+										 vxr_vk_shader_compileError* errorHandle) {
+	auto* toolchain = vxr::vk::shader::toolchain::fromHandle(toolchainHandle);
+	vxr::vk::shader::toolchain::compileError* err = nullptr;
+	auto* result = toolchain->tryCompile(info, &err);
+	if (result == nullptr) {
+		(*resultHandle) = nullptr;
+		(*reflectionHandle) = nullptr;
+		(*errorHandle) = err->handle();
+		return VK_FALSE;
+	}
+	(*resultHandle) = result->handle();
+	(*reflectionHandle) = result->reflection.handle();
+	(*errorHandle) = nullptr;
+	return VK_TRUE;
+}
 VXR_FN void vxr_vk_shader_destroyCompileResult(vxr_vk_shader_compileResult resultHandle) {
 	auto* result = vxr::vk::shader::toolchain::compileResult::fromHandle(resultHandle);
 	delete result;
 }
+VXR_FN void vxr_vk_shader_destroyCompileError(vxr_vk_shader_compileError errorHandle) {
+	auto* err = vxr::vk::shader::toolchain::compileError::fromHandle(errorHandle);
+	delete err;
+}
+VXR_FN void vxr_vk_shader_compileError_getMessage(vxr_vk_shader_compileError errorHandle, size_t* messageSize, const char** message) {
+	auto* err = vxr::vk::shader::toolchain::compileError::fromHandle(errorHandle);
+	*messageSize = err->message.size();
+	*message = err->message.cStr();
+}
 VXR_FN void vxr_vk_shader_compileResult_getSPIRV(vxr_vk_shader_compileResult resultHandle, vxr_vk_shader_spirv* spirv) {
 	auto* result = vxr::vk::shader::toolchain::compileResult::fromHandle(resultHandle);
 
@@ -86,6 +112,7 @@ VXR_FN void vxr_vk_shader_reflectResult_getSpecConstants(vxr_vk_shader_reflectRe
 			specConstants[i] = vxr_vk_shader_reflectResult_specConstant{
 				result->getSpecConstants()[i].name.cStr(),
 				result->getSpecConstants()[i].value,
+				result->getSpecConstants()[i].type,
 			};
 		}
 	} else {
@@ -102,6 +129,10 @@ VXR_FN void vxr_vk_shader_reflectResult_getNumOutputs(vxr_vk_shader_reflectResul
 	auto* result = vxr::vk::shader::reflector::fromHandle(resultHandle);
 	*numOutputs = result->getNumOutputs(e);
 }
+VXR_FN void vxr_vk_shader_reflectResult_getUsesViewIndex(vxr_vk_shader_reflectResult resultHandle, size_t e, VkBool32* usesViewIndex) {
+	auto* result = vxr::vk::shader::reflector::fromHandle(resultHandle);
+	*usesViewIndex = result->usesViewIndex(e) ? VK_TRUE : VK_FALSE;
+}
 VXR_FN void vxr_vk_shader_reflectResult_getPushConstantRange(vxr_vk_shader_reflectResult resultHandle, VkPushConstantRange* range) {
 	auto* result = vxr::vk::shader::reflector::fromHandle(resultHandle);
 	*range = result->getPushConstantRange();
@@ -23,6 +23,9 @@ limitations under the License.
 #include <stdint.h>
 #include <new>	// IWYU pragma: keep
 
+#include "std/log.hpp"
+#include "std/stdlib.hpp"
+#include "std/string.hpp"
 #include "std/utility.hpp"
 
 #include "vk/vk.hpp"
@@ -52,11 +55,38 @@ class toolchain {
 		}
 	};
 
+	struct compileError {
+		vxr::std::string<char> message;
+
+		[[nodiscard]] vxr_vk_shader_compileError handle() noexcept {
+			return reinterpret_cast<vxr_vk_shader_compileError>(this);
+		}
+		[[nodiscard]] static compileError* fromHandle(vxr_vk_shader_compileError handle) noexcept {
+			return reinterpret_cast<compileError*>(handle);
+		}
+	};
+
 	toolchain(vxr_vk_shader_toolchainOptions options) noexcept : compiler(options), optimizer(options) {}
 	~toolchain() noexcept = default;
 
 	[[nodiscard]] compileResult* compile(vxr_vk_shader_compileInfo info) const noexcept {
+		compileError* err = nullptr;
+		auto* result = this->tryCompile(info, &err);
+		if (result == nullptr) {
+			vxr::std::ePrintf("Failed to compile shader: %s", err->message.cStr());
+			vxr::std::abort();
+		}
+		return result;
+	}
+
+	// tryCompile returns nullptr and sets err instead of aborting if the source fails to compile,
+	// errors found during optimization or reflection are still fatal.
+	[[nodiscard]] compileResult* tryCompile(vxr_vk_shader_compileInfo info, compileError** err) const noexcept {
 		auto src = this->compiler.compile(info);
+		if (!src.success()) {
+			*err = new (::std::nothrow) compileError{.message = src.error()};
+			return nullptr;
+		}
 
 		return new (::std::nothrow) compileResult(
 			vxr_vk_shader_spirv{
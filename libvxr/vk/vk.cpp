@@ -21,6 +21,8 @@ limitations under the License.
 
 #include "std/stdlib.hpp"
 #include "std/log.hpp"
+#include "std/string.hpp"
+#include "std/vector.hpp"
 
 #include "vk/vk.hpp"
 #include "vk/vkfns.hpp"
@@ -43,6 +45,30 @@ VXR_FN void vxr_vk_init(uintptr_t vkInstance, uintptr_t vkProcAddr,
 	vxr::std::vPrintf("vkInitMessenger");
 	vxr::vk::initMessenger(instance, vkMessengerCallback);
 }
+VXR_FN VkBool32 vxr_vk_instanceExtensionSupported(vxr_vk_instance, size_t sz, const char* extension) {
+	const vxr::std::string<char> name(sz, extension);
+	// enumerate through the loader the instance was created with, global functions take a null instance
+	auto enumerateInstanceExtensionProperties = reinterpret_cast<PFN_vkEnumerateInstanceExtensionProperties>(
+		VK_PROC(vkGetInstanceProcAddr)(VK_NULL_HANDLE, "vkEnumerateInstanceExtensionProperties"));
+	if (!enumerateInstanceExtensionProperties) {
+		vxr::std::iPrintf("Failed to find vkEnumerateInstanceExtensionProperties to check for instance extension: %s", name.cStr());
+		return VK_FALSE;
+	}
+	uint32_t count = 0;
+	if (enumerateInstanceExtensionProperties(nullptr, &count, nullptr) != VK_SUCCESS) {
+		return VK_FALSE;
+	}
+	vxr::std::vector<VkExtensionProperties> properties(count);
+	if (enumerateInstanceExtensionProperties(nullptr, &count, properties.get()) < VK_SUCCESS) {
+		return VK_FALSE;
+	}
+	for (uint32_t i = 0; i < count; i++) {
+		if (name == properties[i].extensionName) {
+			return VK_TRUE;
+		}
+	}
+	return VK_FALSE;
+}
 VXR_FN void vxr_vk_waitIdle(vxr_vk_instance instanceHandle) {
 	auto* instance = vxr::vk::instance::fromHandle(instanceHandle);
 	const VkResult ret = VK_PROC_DEVICE(vkDeviceWaitIdle)(instance->device.vkDevice);
@@ -286,4 +286,9 @@ template <typename... Args>
 inline static void debugLabel(VkDevice vkDevice, VkPipeline pipeline, const char* fmt, Args... args) {
 	debugLabel(vkDevice, VK_OBJECT_TYPE_PIPELINE, reinterpret_cast<uint64_t>(pipeline), fmt, args...);
 }
+
+template <typename... Args>
+inline static void debugLabel(VkDevice vkDevice, VkQueryPool pool, const char* fmt, Args... args) {
+	debugLabel(vkDevice, VK_OBJECT_TYPE_QUERY_POOL, reinterpret_cast<uint64_t>(pool), fmt, args...);
+}
 }  // namespace vxr::vk
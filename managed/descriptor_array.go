@@ -22,49 +22,146 @@ import (
 	"goarrg.com/rhi/vxr/internal/util"
 )
 
+/*
+DescriptorArrayGrowFunc is called when a descriptor array is full, it must return a new set whose binding of the array
+holds at least minDescriptorCount descriptors, typically a set of a PipelineLayout created with a larger spec constant.
+The live descriptors of the array are rebound into the new set at the same indices, every other binding of the new set
+is up to the function as is the lifetime of the old set which may still be in use by frames in flight.
+*/
+type DescriptorArrayGrowFunc func(old *vxr.DescriptorSet, minDescriptorCount int) *vxr.DescriptorSet
+
+// DescriptorArrayStats reports the occupancy of a descriptor array.
+type DescriptorArrayStats struct {
+	// Len is the number of descriptors in the array including popped ones waiting for their frame to come around.
+	Len int
+	// Cap is the number of descriptors the current set can hold.
+	Cap int
+	// HighWaterMark is the highest Len the array has reached.
+	HighWaterMark int
+	// Grown is the number of times the array migrated to a larger set.
+	Grown int
+}
+
+type descriptorArrayEntry struct {
+	index int
+	info  vxr.DescriptorInfo
+	// popped entries are waiting for their frame to come around, their resources may already be destroyed.
+	popped bool
+}
+
 type descriptorArray[DescriptorType comparable] struct {
 	noCopy             util.NoCopy
 	set                *vxr.DescriptorSet
 	binding            int
 	index              int
 	freeStack          container.Stack[int]
-	managedDescriptors map[DescriptorType]int
+	managedDescriptors map[DescriptorType]descriptorArrayEntry
+	grow               DescriptorArrayGrowFunc
+	highWaterMark      int
+	grown              int
+}
+
+func newDescriptorArray[DescriptorType comparable](set *vxr.DescriptorSet, binding int) descriptorArray[DescriptorType] {
+	return descriptorArray[DescriptorType]{
+		set:                set,
+		binding:            binding,
+		managedDescriptors: map[DescriptorType]descriptorArrayEntry{},
+	}
 }
 
 func (d *descriptorArray[DescriptorType]) push(key DescriptorType, info vxr.DescriptorInfo) int {
 	d.noCopy.Check()
-	if i, found := d.managedDescriptors[key]; found {
-		return i
+	if e, found := d.managedDescriptors[key]; found && !e.popped {
+		return e.index
 	}
 	var i int
 	if d.freeStack.Empty() {
 		if d.index >= d.set.MaxDescriptorCount(d.binding) {
-			abort("Trying to push descriptor into a full set")
+			if d.grow == nil {
+				abort("Trying to push descriptor into a full set")
+			}
+			d.migrate(d.index + 1)
 		}
 		i = d.index
 		d.index++
 	} else {
 		i = d.freeStack.Pop()
 	}
-	d.managedDescriptors[key] = i
+	d.managedDescriptors[key] = descriptorArrayEntry{index: i, info: info}
+	d.highWaterMark = max(d.highWaterMark, len(d.managedDescriptors))
 	d.set.Bind(d.binding, i, info)
 	return i
 }
 
+// migrate moves every live descriptor into a set from d.grow that holds at least minDescriptorCount descriptors.
+func (d *descriptorArray[DescriptorType]) migrate(minDescriptorCount int) {
+	// grow geometrically so pushing one at a time does not migrate every push
+	minDescriptorCount = max(minDescriptorCount, d.set.MaxDescriptorCount(d.binding)*2)
+	set := d.grow(d.set, minDescriptorCount)
+	if set == nil || set.MaxDescriptorCount(d.binding) < minDescriptorCount {
+		abort("DescriptorArrayGrowFunc returned a set that cannot hold %d descriptors", minDescriptorCount)
+	}
+
+	batch := vxr.DescriptorWriteBatch{}
+	for _, e := range d.managedDescriptors {
+		if e.popped {
+			continue
+		}
+		batch.Bind(set, d.binding, e.index, e.info)
+	}
+	batch.Flush()
+
+	d.set = set
+	d.grown++
+}
+
+/*
+SetGrowFunc makes the array migrate to the set returned by f when it is full instead of aborting,
+see DescriptorArrayGrowFunc. Set returns the set to bind after a migration.
+*/
+func (d *descriptorArray[DescriptorType]) SetGrowFunc(f DescriptorArrayGrowFunc) {
+	d.noCopy.Check()
+	d.grow = f
+}
+
+// Set returns the set the array currently binds into, it changes when the array grows.
+func (d *descriptorArray[DescriptorType]) Set() *vxr.DescriptorSet {
+	d.noCopy.Check()
+	return d.set
+}
+
+// Stats returns the occupancy of the array.
+func (d *descriptorArray[DescriptorType]) Stats() DescriptorArrayStats {
+	d.noCopy.Check()
+	return DescriptorArrayStats{
+		Len:           len(d.managedDescriptors),
+		Cap:           d.set.MaxDescriptorCount(d.binding),
+		HighWaterMark: d.highWaterMark,
+		Grown:         d.grown,
+	}
+}
+
 /*
 Pop marks the descriptor index containing target as unused, which will become available for reuse
-the next time f.Index() has the same value.
+the next time f.Index() has the same value. The set stops tracking target right away so it may be destroyed
+after Pop even if the set is still used, as long as shaders no longer access its index.
 */
 func (d *descriptorArray[DescriptorType]) Pop(f *vxr.Frame, target DescriptorType) {
 	d.noCopy.Check()
-	i, found := d.managedDescriptors[target]
-	if !found {
+	e, found := d.managedDescriptors[target]
+	if !found || e.popped {
 		return
 	}
+	d.set.Unbind(d.binding, e.index, 1)
+	e.popped = true
+	d.managedDescriptors[target] = e
 	f.QueueDestory(destroyFunc{
 		func() {
-			delete(d.managedDescriptors, target)
-			d.freeStack.Push(i)
+			// target may have been pushed again at a new index in the meantime
+			if current := d.managedDescriptors[target]; current.popped && current.index == e.index {
+				delete(d.managedDescriptors, target)
+			}
+			d.freeStack.Push(e.index)
 		},
 	})
 }
@@ -79,11 +176,7 @@ type DescriptorArrayBuffer struct {
 
 func NewDescriptorArrayBuffer(set *vxr.DescriptorSet, binding int) *DescriptorArrayBuffer {
 	ret := DescriptorArrayBuffer{
-		descriptorArray: descriptorArray[vxr.Buffer]{
-			set:                set,
-			binding:            binding,
-			managedDescriptors: map[vxr.Buffer]int{},
-		},
+		descriptorArray: newDescriptorArray[vxr.Buffer](set, binding),
 	}
 	ret.noCopy.Init()
 	return &ret
@@ -103,11 +196,7 @@ type DescriptorArrayImage struct {
 
 func NewDescriptorArrayImage(set *vxr.DescriptorSet, binding int) *DescriptorArrayImage {
 	ret := DescriptorArrayImage{
-		descriptorArray: descriptorArray[vxr.Image]{
-			set:                set,
-			binding:            binding,
-			managedDescriptors: map[vxr.Image]int{},
-		},
+		descriptorArray: newDescriptorArray[vxr.Image](set, binding),
 	}
 	ret.noCopy.Init()
 	return &ret
@@ -117,6 +206,27 @@ func (d *DescriptorArrayImage) Push(info vxr.DescriptorImageInfo) int {
 	return d.push(info.Image, info)
 }
 
+/*
+DescriptorArrayStorageImage manages inserting and removing Images from a storage image descriptor array,
+images are bound in ImageLayoutGeneral as storage images require, it is the user's responsibility to handle sync
+and to transition the images.
+*/
+type DescriptorArrayStorageImage struct {
+	descriptorArray[vxr.Image]
+}
+
+func NewDescriptorArrayStorageImage(set *vxr.DescriptorSet, binding int) *DescriptorArrayStorageImage {
+	ret := DescriptorArrayStorageImage{
+		descriptorArray: newDescriptorArray[vxr.Image](set, binding),
+	}
+	ret.noCopy.Init()
+	return &ret
+}
+
+func (d *DescriptorArrayStorageImage) Push(image vxr.Image) int {
+	return d.push(image, vxr.DescriptorImageInfo{Image: image, Layout: vxr.ImageLayoutGeneral})
+}
+
 /*
 DescriptorArrayCombinedImageSampler manages inserting and removing CombinedImageSamplers from a descriptor array,
 it is the user's responsibility to handle sync and layout changes
@@ -127,11 +237,7 @@ type DescriptorArrayCombinedImageSampler struct {
 
 func NewDescriptorArrayCombinedImageSampler(set *vxr.DescriptorSet, binding int) *DescriptorArrayCombinedImageSampler {
 	ret := DescriptorArrayCombinedImageSampler{
-		descriptorArray: descriptorArray[vxr.Image]{
-			set:                set,
-			binding:            binding,
-			managedDescriptors: map[vxr.Image]int{},
-		},
+		descriptorArray: newDescriptorArray[vxr.Image](set, binding),
 	}
 	ret.noCopy.Init()
 	return &ret
@@ -140,3 +246,20 @@ func NewDescriptorArrayCombinedImageSampler(set *vxr.DescriptorSet, binding int)
 func (d *DescriptorArrayCombinedImageSampler) Push(info vxr.DescriptorCombinedImageSamplerInfo) int {
 	return d.push(info.Image, info)
 }
+
+// DescriptorArraySampler manages inserting and removing Samplers from a descriptor array.
+type DescriptorArraySampler struct {
+	descriptorArray[*vxr.Sampler]
+}
+
+func NewDescriptorArraySampler(set *vxr.DescriptorSet, binding int) *DescriptorArraySampler {
+	ret := DescriptorArraySampler{
+		descriptorArray: newDescriptorArray[*vxr.Sampler](set, binding),
+	}
+	ret.noCopy.Init()
+	return &ret
+}
+
+func (d *DescriptorArraySampler) Push(sampler *vxr.Sampler) int {
+	return d.push(sampler, sampler)
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	goimage "image"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"goarrg.com/gmath"
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+/*
+OffscreenConfig replaces the swapchain created by InitDevice with a ring of offscreen images of a fixed size and format,
+no surface is created so the device does not need to support presentation. Frame.Surface returns the next image of the ring
+and is used exactly like a swapchain image, transitioning it to ImageLayoutPresent hands it back for readback.
+Every frame that acquired the surface is read back once the GPU is done with it and passed to OnFrame and OutputDir
+in the order the frames were rendered. Resize and the other swapchain functions are ignored as the surface never changes.
+*/
+type OffscreenConfig struct {
+	Extent gmath.Extent2i32
+	// Format defaults to R8G8B8A8_SRGB, it must support being a color attachment and a transfer source.
+	Format Format
+	// OnFrame is called from the goroutine calling FrameBegin, Frame.Surface, FlushOffscreenFrames or Destroy,
+	// OffscreenFrame.Data is reused and only valid until it returns.
+	OnFrame func(OffscreenFrame)
+	// OutputDir has every frame written to it as frame_%06d.png when not empty,
+	// which requires Format to be one of the 8 bit RGBA or BGRA formats.
+	OutputDir string
+}
+
+func (c *OffscreenConfig) validate() {
+	if min(c.Extent.X, c.Extent.Y) < 1 {
+		abort("Config.Offscreen.Extent [%+v] must be >= 1", c.Extent)
+	}
+	if c.Format == 0 {
+		c.Format = FORMAT_R8G8B8A8_SRGB
+	}
+	if c.OutputDir != "" && !offscreenFormatIsRGBA8(c.Format) {
+		abort("Config.Offscreen.OutputDir requires an 8 bit RGBA or BGRA format, have: %s", c.Format)
+	}
+}
+
+func (c *OffscreenConfig) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf("{\"Extent\": {\"X\": %d, \"Y\": %d}, \"Format\": %q, \"OnFrame\": %t, \"OutputDir\": %q}",
+		c.Extent.X, c.Extent.Y, c.Format.String(), c.OnFrame != nil, c.OutputDir)), nil
+}
+
+func offscreenFormatIsRGBA8(format Format) bool {
+	switch format {
+	case FORMAT_R8G8B8A8_UNORM, FORMAT_R8G8B8A8_SRGB, FORMAT_B8G8R8A8_UNORM, FORMAT_B8G8R8A8_SRGB:
+		return true
+	default:
+		return false
+	}
+}
+
+// OffscreenFrame is a frame read back from the offscreen surface, Data holds its texels tightly packed row by row.
+type OffscreenFrame struct {
+	// Index counts the frames that acquired the surface starting from 0, it does not skip frames so it can name outputs.
+	Index  uint64
+	Extent gmath.Extent2i32
+	Format Format
+	Data   []byte
+}
+
+// Image copies the frame into an image.NRGBA, swizzling BGRA formats, it requires Format to be one of the 8 bit RGBA or BGRA formats.
+func (f OffscreenFrame) Image() *goimage.NRGBA {
+	if !offscreenFormatIsRGBA8(f.Format) {
+		abort("OffscreenFrame.Image requires an 8 bit RGBA or BGRA format, have: %s", f.Format)
+	}
+	img := goimage.NewNRGBA(goimage.Rect(0, 0, int(f.Extent.X), int(f.Extent.Y)))
+	copy(img.Pix, f.Data)
+	if f.Format == FORMAT_B8G8R8A8_UNORM || f.Format == FORMAT_B8G8R8A8_SRGB {
+		for i := 0; i < len(img.Pix); i += 4 {
+			img.Pix[i], img.Pix[i+2] = img.Pix[i+2], img.Pix[i]
+		}
+	}
+	return img
+}
+
+func (f OffscreenFrame) writePNG(dir string) {
+	path := filepath.Join(dir, fmt.Sprintf("frame_%06d.png", f.Index))
+	file, err := os.Create(path)
+	if err != nil {
+		abort("Failed to create %q: %v", path, err)
+	}
+	if err := png.Encode(file, f.Image()); err != nil {
+		abort("Failed to encode %q: %v", path, err)
+	}
+	if err := file.Close(); err != nil {
+		abort("Failed to close %q: %v", path, err)
+	}
+}
+
+type offscreenImage struct {
+	image   *DeviceColorImage
+	buffer  *HostBuffer
+	acquire binarySemaphore
+	release binarySemaphore
+
+	// index and waiter are those of the frame being read back, waiter is nil once it has been delivered.
+	index  uint64
+	waiter *TimelineSemaphoreWaiter
+}
+
+type offscreen struct {
+	config   OffscreenConfig
+	images   []offscreenImage
+	next     int
+	numFrame uint64
+
+	readback *TimelineSemaphore
+	// pending are the images being read back in the order they were rendered.
+	pending []*offscreenImage
+	data    []byte
+}
+
+func newOffscreen(config OffscreenConfig) *offscreen {
+	if config.OutputDir != "" {
+		if err := os.MkdirAll(config.OutputDir, 0o755); err != nil {
+			abort("Failed to create Config.Offscreen.OutputDir %q: %v", config.OutputDir, err)
+		}
+	}
+	o := &offscreen{
+		config:   config,
+		images:   make([]offscreenImage, instance.config.maxFramesInFlight+instance.config.swapchainImageCountPadding),
+		readback: NewTimelineSemaphore("offscreen_readback"),
+	}
+	for i := range o.images {
+		img := &o.images[i]
+		img.image = NewColorImage(fmt.Sprintf("offscreen_%d", i), config.Format, ImageCreateInfo{
+			Usage:          ImageUsageColorAttachment | ImageUsageTransferSrc,
+			ImageType:      ImageType2D,
+			Extent:         gmath.Extent3i32{X: config.Extent.X, Y: config.Extent.Y, Z: 1},
+			NumMipLevels:   1,
+			NumArrayLayers: 1,
+		})
+		img.buffer = NewHostBuffer(fmt.Sprintf("offscreen_%d", i), img.image.BufferSize(), BufferUsageTransferDst)
+		img.acquire.init(fmt.Sprintf("offscreen_%d_acquire", i))
+		img.release.init(fmt.Sprintf("offscreen_%d_release", i))
+	}
+	o.data = make([]byte, o.images[0].image.BufferSize())
+	return o
+}
+
+func (o *offscreen) info() SurfaceInfo {
+	return SurfaceInfo{
+		Extent:            gmath.Extent3i32{X: o.config.Extent.X, Y: o.config.Extent.Y, Z: 1},
+		Format:            o.config.Format,
+		ColorSpace:        ColorSpaceSRGBNonlinear,
+		PresentMode:       PresentModeFIFO,
+		NumFramesInFlight: instance.config.maxFramesInFlight,
+	}
+}
+
+func (o *offscreen) deliver() {
+	img := o.pending[0]
+	o.pending = o.pending[1:]
+	img.waiter.Wait()
+	img.waiter = nil
+	img.buffer.HostRead(0, o.data)
+
+	frame := OffscreenFrame{Index: img.index, Extent: o.config.Extent, Format: o.config.Format, Data: o.data}
+	if o.config.OutputDir != "" {
+		frame.writePNG(o.config.OutputDir)
+	}
+	if o.config.OnFrame != nil {
+		o.config.OnFrame(frame)
+	}
+}
+
+// poll delivers the frames that finished reading back without blocking, stopping at the first one still in flight to keep them in order.
+func (o *offscreen) poll() {
+	for len(o.pending) > 0 && o.pending[0].waiter.Poll() {
+		o.deliver()
+	}
+}
+
+// flush blocks until every frame up to and including img has been delivered, or every frame if img is nil.
+func (o *offscreen) flush(img *offscreenImage) {
+	if img != nil && img.waiter == nil {
+		return
+	}
+	for len(o.pending) > 0 {
+		done := o.pending[0] == img
+		o.deliver()
+		if done {
+			return
+		}
+	}
+}
+
+func (o *offscreen) acquire(f *Frame) *Surface {
+	img := &o.images[o.next]
+	o.next = (o.next + 1) % len(o.images)
+	o.flush(img)
+	img.index = o.numFrame
+	o.numFrame++
+
+	// there is no presentation engine to signal the acquire semaphore so signal it with an empty batch
+	signal := img.acquire.vkSignalInfo(PipelineStageAll)
+	q := QueueGraphics.state()
+	q.mtx.Lock()
+	C.vxr_vk_graphics_frame_commandBuffersSubmit(instance.cInstance, f.frame.cFrame, 0, nil, 0, nil, 1, &signal)
+	q.mtx.Unlock()
+
+	surface := Surface{offscreen: img}
+	surface.noCopy.Init()
+	surface.cSurface = C.vxr_vk_surface{
+		info: C.vxr_vk_surfaceInfo{
+			format:      C.VkFormat(o.config.Format),
+			extent:      C.VkExtent2D{width: C.uint32_t(o.config.Extent.X), height: C.uint32_t(o.config.Extent.Y)},
+			colorSpace:  C.VkColorSpaceKHR(ColorSpaceSRGBNonlinear),
+			numImages:   C.uint32_t(len(o.images)),
+			presentMode: C.VkPresentModeKHR(PresentModeFIFO),
+		},
+		vkImage:          img.image.vkImage(),
+		vkImageView:      img.image.vkImageView(),
+		acquireSemaphore: img.acquire.vkSemaphore,
+		releaseSemaphore: img.release.vkSemaphore,
+	}
+	return &surface
+}
+
+// submitReadback copies the image of the surface into its host buffer once the commands rendering to it are done.
+func (o *offscreen) submitReadback(f *Frame, s *Surface) {
+	img := s.offscreen
+	cb := f.newSingleUseCommandBuffer(fmt.Sprintf("offscreen_readback_%d", img.index))
+	cb.CopyImageToBuffer(img.image, ImageLayoutTransferSrc, img.buffer, []BufferImageCopyRegion{{
+		ImageSubresource: ImageSubresourceLayers{NumArrayLayers: 1},
+		ImageExtent:      img.image.Extent(),
+	}})
+	cb.BufferBarrier(BufferBarrier{
+		Buffer: img.buffer,
+		Src:    BufferBarrierInfo{Stage: PipelineStageTransfer, Access: vk.ACCESS_2_TRANSFER_WRITE_BIT},
+		Dst:    BufferBarrierInfo{Stage: vk.PIPELINE_STAGE_2_HOST_BIT, Access: vk.ACCESS_2_HOST_READ_BIT},
+	})
+	cb.Submit(
+		[]SemaphoreWaitInfo{{Semaphore: &img.release, Stage: PipelineStageTransfer}},
+		[]SemaphoreSignalInfo{{Semaphore: o.readback, Stage: PipelineStageTransfer}},
+	)
+	img.waiter = o.readback.WaiterForPendingValue()
+	f.frame.readbackWaiter = img.waiter
+	o.pending = append(o.pending, img)
+}
+
+func (o *offscreen) destroy() {
+	o.flush(nil)
+	for i := range o.images {
+		img := &o.images[i]
+		img.image.Destroy()
+		img.buffer.Destroy()
+		img.acquire.destroy()
+		img.release.destroy()
+	}
+	o.readback.Destroy()
+}
+
+/*
+FlushOffscreenFrames blocks until every frame rendered to the offscreen surface has been passed to Config.Offscreen,
+it must be called from the goroutine that calls FrameBegin. It does nothing when Config.Offscreen is nil.
+*/
+func FlushOffscreenFrames() {
+	if instance.offscreen == nil {
+		return
+	}
+	instance.offscreen.flush(nil)
+}
@@ -26,7 +26,10 @@ import "C"
 import (
 	"bytes"
 	"fmt"
+	"runtime"
+	"slices"
 	"strings"
+	"unsafe"
 
 	"goarrg.com/debug"
 	"goarrg.com/rhi/vxr/internal/vk"
@@ -52,25 +55,56 @@ const (
 	PipelineStageGraphics              PipelineStage = vk.PIPELINE_STAGE_2_ALL_GRAPHICS_BIT
 	PipelineStageTransfer              PipelineStage = vk.PIPELINE_STAGE_2_ALL_TRANSFER_BIT
 	PipelineStageAll                   PipelineStage = vk.PIPELINE_STAGE_2_ALL_COMMANDS_BIT
+	// PipelineStageConditionalRendering is the stage predicates are read in, requires the conditionalRendering feature.
+	PipelineStageConditionalRendering PipelineStage = vk.PIPELINE_STAGE_2_CONDITIONAL_RENDERING_BIT_EXT
 )
 
 type PipelineLayout struct {
-	id               string
+	key              hashKey
 	name             string
 	vkPipelinelayout C.VkPipelineLayout
 
 	pushConstantRange    C.VkPushConstantRange
 	descriptorSetLayouts []descriptorSetLayout
+
+	// pushDescriptorSet is -1 when the layout has no push descriptor set,
+	// pushDescriptorMetadata is indexed by its bindings and holds the metadata of every shader that uses them.
+	pushDescriptorSet      int
+	pushDescriptorMetadata [][]ShaderBindingMetadata
+
+	specializations []stageSpecialization
+}
+
+// stageSpecialization is what a PipelineLayout remembers of the SpecConstants it was created with
+// so pipelines can default to them and be checked against them.
+type stageSpecialization struct {
+	stage         ShaderStage
+	specConstants []uint32
+	// descriptorCounts are the ids of the spec constants that sized a descriptor binding,
+	// a pipeline using a different value for any of them would not match the layout.
+	descriptorCounts []uint32
 }
 
 type PipelineLayoutCreateInfo struct {
-	ShaderLayout  *ShaderLayout
-	ShaderStage   ShaderStage
+	ShaderLayout *ShaderLayout
+	ShaderStage  ShaderStage
+	// SpecConstants are used to resolve descriptor counts that are spec constants,
+	// pipelines created with a nil SpecConstants inherit the ones given here for their stage.
+	// See ShaderMetadata.SpecConstantValues to create them by name.
 	SpecConstants []uint32
+	// UsePushDescriptors makes PushDescriptorSet a push descriptor set, instead of being bound with a DescriptorSet
+	// its descriptors are given with every draw or dispatch. Infos using push descriptors must agree on the set.
+	// Requires VK_KHR_push_descriptor or Vulkan 1.4 and VkPhysicalDeviceDescriptorBufferFeaturesEXT.DescriptorBufferPushDescriptors
+	// when descriptor buffers are in use.
+	UsePushDescriptors bool
+	PushDescriptorSet  int
+	// ShaderMetadata is optional, pushed descriptors are always checked against the type of their binding
+	// and when it is given they are also checked with ShaderBindingMetadata.ValidateDescriptor.
+	ShaderMetadata *ShaderMetadata
 }
 
 func NewPipelineLayout(infos ...PipelineLayoutCreateInfo) *PipelineLayout {
-	var layout PipelineLayout
+	layout := PipelineLayout{pushDescriptorSet: -1}
 
 	for i, stageInfo := range infos {
 		{
@@ -79,6 +113,25 @@ func NewPipelineLayout(infos ...PipelineLayoutCreateInfo) *PipelineLayout {
 			}
 		}
 
+		if stageInfo.UsePushDescriptors {
+			if !instance.graphics.features.pushDescriptor {
+				abort("Failed creating PipelineLayout: push descriptors are not supported by the device")
+			}
+			if stageInfo.PushDescriptorSet < 0 || (instance.config.bindlessHeap && stageInfo.PushDescriptorSet == BindlessHeapSet) {
+				abort("Failed creating PipelineLayout: set [%d] cannot be a push descriptor set", stageInfo.PushDescriptorSet)
+			}
+			if layout.pushDescriptorSet >= 0 && layout.pushDescriptorSet != stageInfo.PushDescriptorSet {
+				abort("Failed creating PipelineLayout: infos disagree on the push descriptor set: [%d] and [%d]",
+					layout.pushDescriptorSet, stageInfo.PushDescriptorSet)
+			}
+			layout.pushDescriptorSet = stageInfo.PushDescriptorSet
+		}
+
+		specialization := stageSpecialization{
+			stage:         stageInfo.ShaderStage,
+			specConstants: slices.Clone(stageInfo.SpecConstants),
+		}
+
 		{
 			if stageInfo.ShaderLayout.PushConstants.Size > 0 {
 				newRange := C.VkPushConstantRange{
@@ -103,6 +156,11 @@ func NewPipelineLayout(infos ...PipelineLayoutCreateInfo) *PipelineLayout {
 					len(stageInfo.ShaderLayout.DescriptorSetLayouts[set]))
 				for binding := C.uint32_t(0); binding < C.uint32_t(len(stageInfo.ShaderLayout.DescriptorSetLayouts[set])); binding++ {
 					shaderBindingInfo := stageInfo.ShaderLayout.DescriptorSetLayouts[set][binding]
+					if isHeap, err := validateBindlessShaderBinding(int(set), int(binding), shaderBindingInfo.DescriptorType, shaderBindingInfo.DescriptorCount); err != nil {
+						abort("Failed to create PipelineLayout: %s", err)
+					} else if isHeap {
+						continue
+					}
 					currentBindingInfo := layout.descriptorSetLayouts[set].bindings[binding]
 					currentBindingInfo.shaderStage |= C.VkShaderStageFlags(stageInfo.ShaderStage)
 					newBindingInfo := descriptorSetBinding{
@@ -111,7 +169,12 @@ func NewPipelineLayout(infos ...PipelineLayoutCreateInfo) *PipelineLayout {
 						descriptorCount: C.uint32_t(shaderBindingInfo.DescriptorCount.Value),
 					}
 					if shaderBindingInfo.DescriptorCount.IsSpecConstant {
+						if int(shaderBindingInfo.DescriptorCount.Value) >= len(stageInfo.SpecConstants) {
+							abort("Failed to create PipelineLayout: set[%d] binding[%d] descriptor count is spec constant [%d] but only %d spec constants were given for stage %s",
+								set, binding, shaderBindingInfo.DescriptorCount.Value, len(stageInfo.SpecConstants), stageInfo.ShaderStage.String())
+						}
 						newBindingInfo.descriptorCount = C.uint32_t(stageInfo.SpecConstants[shaderBindingInfo.DescriptorCount.Value])
+						specialization.descriptorCounts = append(specialization.descriptorCounts, shaderBindingInfo.DescriptorCount.Value)
 					}
 					if newBindingInfo.descriptorCount == 0 {
 						continue
@@ -125,31 +188,73 @@ func NewPipelineLayout(infos ...PipelineLayoutCreateInfo) *PipelineLayout {
 				}
 			}
 		}
+
+		layout.specializations = append(layout.specializations, specialization)
+	}
+
+	if layout.pushDescriptorSet >= 0 {
+		layout.descriptorSetLayouts = growSlice(layout.descriptorSetLayouts, layout.pushDescriptorSet+1)
+		set := &layout.descriptorSetLayouts[layout.pushDescriptorSet]
+		set.push = true
+		numDescriptors := uint32(0)
+		for _, b := range set.bindings {
+			numDescriptors += uint32(b.descriptorCount)
+		}
+		if numDescriptors > instance.graphics.features.maxPushDescriptors {
+			abort("Failed creating PipelineLayout: push descriptor set [%d] has %d descriptors while the device's maxPushDescriptors is %d",
+				layout.pushDescriptorSet, numDescriptors, instance.graphics.features.maxPushDescriptors)
+		}
+		layout.pushDescriptorMetadata = make([][]ShaderBindingMetadata, len(set.bindings))
+		for _, stageInfo := range infos {
+			if stageInfo.ShaderMetadata == nil {
+				continue
+			}
+			for _, m := range stageInfo.ShaderMetadata.DescriptorSetBindings {
+				if info := m.Info(); info.Set == layout.pushDescriptorSet && info.Binding < len(set.bindings) {
+					layout.pushDescriptorMetadata[info.Binding] = append(layout.pushDescriptorMetadata[info.Binding], m)
+				}
+			}
+		}
+	}
+
+	if instance.config.bindlessHeap {
+		layout.descriptorSetLayouts = growSlice(layout.descriptorSetLayouts, BindlessHeapSet+1)
+		layout.descriptorSetLayouts[BindlessHeapSet] = instance.bindlessHeap.layout
+	}
+	if err := layout.validateDescriptorLimits(); err != nil {
+		abort("Failed to create PipelineLayout: %s", err)
 	}
 
 	{
+		h := newHasher()
+		h.writeUint32(uint32(layout.pushConstantRange.stageFlags))
+		h.writeUint32(uint32(layout.pushConstantRange.offset))
+		h.writeUint32(uint32(layout.pushConstantRange.size))
 		if layout.pushConstantRange.stageFlags == 0 {
-			layout.id = genID(layout.pushConstantRange.stageFlags, layout.pushConstantRange.offset, layout.pushConstantRange.size)
 			layout.name = "[\"\",0,0]"
 		} else {
-			layout.id = genID(layout.pushConstantRange.stageFlags, layout.pushConstantRange.offset, layout.pushConstantRange.size)
 			layout.name = fmt.Sprintf("[%s,%d,%d]",
 				ShaderStage(layout.pushConstantRange.stageFlags).String(),
 				layout.pushConstantRange.offset,
 				layout.pushConstantRange.size,
 			)
 		}
+		h.writeUint32(uint32(len(layout.descriptorSetLayouts)))
 		for i := range layout.descriptorSetLayouts {
 			set := &layout.descriptorSetLayouts[i]
+			if instance.config.bindlessHeap && i == BindlessHeapSet {
+				h.writeUint64(uint64(set.key))
+				layout.name += set.name
+				continue
+			}
+			set.key = set.genKey()
+			h.writeUint64(uint64(set.key))
 			if len(set.bindings) > 0 {
 				cDescriptorSetBindings := make([]C.VkDescriptorSetLayoutBinding, 0, len(set.bindings))
 				for j, binding := range set.bindings {
 					if binding.descriptorCount == 0 {
-						set.id += fmt.Sprintf("null,")
-						set.name += fmt.Sprintf("null,")
+						set.name += "null,"
 					} else {
-						set.id += fmt.Sprintf("%s:%s:%d,",
-							toHex(binding.shaderStage), toHex(binding.descriptorType), binding.descriptorCount)
 						set.name += fmt.Sprintf("%s:%s:%d,",
 							ShaderStage(binding.shaderStage).String(), DescriptorType(binding.descriptorType).String(), binding.descriptorCount)
 						cDescriptorSetBindings = append(cDescriptorSetBindings, C.VkDescriptorSetLayoutBinding{
@@ -160,30 +265,132 @@ func NewPipelineLayout(infos ...PipelineLayoutCreateInfo) *PipelineLayout {
 						})
 					}
 				}
-				set.id = fmt.Sprintf("[%s]", strings.TrimSuffix(set.id, ","))
 				set.name = fmt.Sprintf("[%s]", strings.TrimSuffix(set.name, ","))
-				layout.id += fmt.Sprintf("%s", set.id)
-				layout.name += fmt.Sprintf("%s", set.name)
+				if set.push {
+					set.name = "push" + set.name
+				}
+				layout.name += set.name
 				instance.descriptorSetLayoutCache.createOrRetrieveDescriptorSetLayout(set, cDescriptorSetBindings)
 			} else {
-				set.id = "[null]"
 				set.name = "[null]"
-				layout.id += fmt.Sprintf("[null]")
-				layout.name += fmt.Sprintf("[null]")
+				if set.push {
+					set.name = "push" + set.name
+				}
+				layout.name += set.name
 				instance.descriptorSetLayoutCache.createOrRetrieveDescriptorSetLayout(set, nil)
 			}
 		}
+		layout.key = h.sum
 		instance.pipelineLayoutCache.createOrRetrievePipelineLayout(&layout)
 	}
 
 	return &layout
 }
 
+type descriptorStageCounts struct {
+	samplers      uint32
+	sampledImages uint32
+	storageImages uint32
+	ubos          uint32
+	sbos          uint32
+	resources     uint32
+}
+
+// add counts n descriptors of type t the way the maxPerStage* limits do.
+func (c *descriptorStageCounts) add(t C.VkDescriptorType, n uint32) {
+	switch DescriptorType(t) {
+	case DescriptorTypeSampler:
+		c.samplers += n
+		return
+	case DescriptorTypeCombinedImageSampler:
+		c.samplers += n
+		c.sampledImages += n
+	case DescriptorTypeSampledImage, DescriptorTypeUniformTexelBuffer:
+		c.sampledImages += n
+	case DescriptorTypeStorageImage, DescriptorTypeStorageTexelBuffer:
+		c.storageImages += n
+	case DescriptorTypeUniformBuffer, vk.DESCRIPTOR_TYPE_UNIFORM_BUFFER_DYNAMIC:
+		c.ubos += n
+	case DescriptorTypeStorageBuffer, vk.DESCRIPTOR_TYPE_STORAGE_BUFFER_DYNAMIC:
+		c.sbos += n
+	}
+	c.resources += n
+}
+
+/*
+validateDescriptorLimits checks the descriptors every stage of the layout can access against the device's per stage limits.
+With the bindless heap the user sets must also fit in the update after bind limits together with the heap,
+as those limits count every descriptor of a layout that has an update after bind set.
+*/
+func (l *PipelineLayout) validateDescriptorLimits() error {
+	stages := C.VkShaderStageFlags(0)
+	for i, set := range l.descriptorSetLayouts {
+		if instance.config.bindlessHeap && i == BindlessHeapSet {
+			continue
+		}
+		for _, b := range set.bindings {
+			stages |= b.shaderStage
+		}
+	}
+
+	type limit struct {
+		name  string
+		count uint32
+		max   uint32
+	}
+	for bit := C.VkShaderStageFlags(1); bit != 0 && bit <= stages; bit <<= 1 {
+		if stages&bit == 0 {
+			continue
+		}
+		stage := ShaderStage(bit)
+		counts := descriptorStageCounts{}
+		for i, set := range l.descriptorSetLayouts {
+			if instance.config.bindlessHeap && i == BindlessHeapSet {
+				continue
+			}
+			for _, b := range set.bindings {
+				if b.shaderStage&bit != 0 {
+					counts.add(b.descriptorType, uint32(b.descriptorCount))
+				}
+			}
+		}
+
+		perStage := instance.deviceProperties.Limits.PerStage
+		limits := []limit{
+			{"samplers", counts.samplers, perStage.MaxSamplerCount},
+			{"sampled images", counts.sampledImages, perStage.MaxSampledImageCount},
+			{"storage images", counts.storageImages, perStage.MaxStorageImageCount},
+			{"uniform buffers", counts.ubos, perStage.MaxUBOCount},
+			{"storage buffers", counts.sbos, perStage.MaxSBOCount},
+			{"resources", counts.resources, perStage.MaxResourceCount},
+		}
+		if instance.config.bindlessHeap {
+			for _, b := range instance.bindlessHeap.layout.bindings {
+				counts.add(b.descriptorType, uint32(b.descriptorCount))
+			}
+			indexing := instance.deviceProperties.Limits.DescriptorIndexing
+			limits = append(limits,
+				limit{"samplers with the bindless heap", counts.samplers, indexing.MaxSamplerCount},
+				limit{"sampled images with the bindless heap", counts.sampledImages, indexing.MaxSampledImageCount},
+				limit{"storage images with the bindless heap", counts.storageImages, indexing.MaxStorageImageCount},
+				limit{"storage buffers with the bindless heap", counts.sbos, indexing.MaxSBOCount},
+				limit{"resources with the bindless heap", counts.resources, indexing.MaxResourceCount},
+			)
+		}
+		for _, lim := range limits {
+			if lim.count > lim.max {
+				return debug.Errorf("stage %s uses %d %s which is more than the device's per stage limit of %d", stage.String(), lim.count, lim.name, lim.max)
+			}
+		}
+	}
+	return nil
+}
+
 func (l *PipelineLayout) MarshalJSON() ([]byte, error) {
 	buff := bytes.Buffer{}
 	buff.WriteString("{")
 
-	buff.WriteString(fmt.Sprintf("\"id\": %q,", l.id))
+	buff.WriteString(fmt.Sprintf("\"key\": %q,", l.key.String()))
 	buff.WriteString(fmt.Sprintf("\"name\": %q,", l.name))
 	buff.WriteString(fmt.Sprintf("\"vkPipelinelayout\": %q,", toHex(l.vkPipelinelayout)))
 
@@ -200,24 +407,258 @@ func (l *PipelineLayout) MarshalJSON() ([]byte, error) {
 		}
 		buff.Truncate(buff.Len() - 1)
 	}
-	buff.WriteString("]")
+	buff.WriteString("],")
+
+	buff.WriteString(fmt.Sprintf("\"pushDescriptorSet\": %d", l.pushDescriptorSet))
 
 	buff.WriteString("}")
 	return buff.Bytes(), nil
 }
 
 func (l *PipelineLayout) NewDescriptorSet(set int) *DescriptorSet {
-	return instance.descriptorSetCache.createOrRetrieveDescriptorSet(l.descriptorSetLayouts[set])
+	if instance.config.bindlessHeap && set == BindlessHeapSet {
+		abort("Set [%d] is reserved for the bindless heap", set)
+	}
+	if set == l.pushDescriptorSet {
+		abort("Set [%d] is a push descriptor set, its descriptors are given with the draw or dispatch", set)
+	}
+	descriptorSet := instance.descriptorSetCache.createOrRetrieveDescriptorSet(l.descriptorSetLayouts[set])
+	descriptorSet.set = set
+	return descriptorSet
 }
 
-func (l *PipelineLayout) cmdValidate(pushConstants []byte, descriptorSets []*DescriptorSet) error {
+func (l *PipelineLayout) cmdValidate(pushConstants []byte, descriptorSets []*DescriptorSet, pushDescriptors [][]DescriptorInfo) error {
 	if len(pushConstants) != int(l.pushConstantRange.size) {
 		return debug.Errorf("Pushconstants size mismatch between given data and pipeline layout: expecting: %d bytes given: %d bytes",
 			l.pushConstantRange.size, len(pushConstants))
 	}
-	if len(descriptorSets) != len(l.descriptorSetLayouts) {
+	numSets := len(l.descriptorSetLayouts)
+	// the bindless heap is bound implicitly so it may be left out when it is the last set
+	if instance.config.bindlessHeap && numSets == BindlessHeapSet+1 && len(descriptorSets) == BindlessHeapSet {
+		numSets = BindlessHeapSet
+	}
+	if len(descriptorSets) != numSets {
 		return debug.Errorf("DescriptorSet count mismatch between given sets and pipeline layout: expecting %d sets given %d sets",
-			len(l.descriptorSetLayouts), len(descriptorSets))
+			numSets, len(descriptorSets))
+	}
+	for i := range l.descriptorSetLayouts {
+		var set *DescriptorSet
+		if i < len(descriptorSets) {
+			set = descriptorSets[i]
+		}
+		if instance.config.bindlessHeap && i == BindlessHeapSet {
+			if set != nil {
+				return debug.Errorf("DescriptorSets[%d] must be nil as it is bound to the bindless heap", i)
+			}
+			continue
+		}
+		if i == l.pushDescriptorSet {
+			if set != nil {
+				return debug.Errorf("DescriptorSets[%d] must be nil as it is the push descriptor set", i)
+			}
+			continue
+		}
+		if set == nil && !l.descriptorSetLayouts[i].empty() {
+			return debug.Errorf("DescriptorSet count mismatch between given sets and pipeline layout: set [%d] %s was not given",
+				i, l.descriptorSetLayouts[i].name)
+		}
+	}
+	return l.validatePushDescriptors(pushDescriptors)
+}
+
+func (l *PipelineLayout) validatePushDescriptors(pushDescriptors [][]DescriptorInfo) error {
+	if l.pushDescriptorSet < 0 {
+		if len(pushDescriptors) > 0 {
+			return debug.Errorf("PushDescriptors were given but pipeline layout has no push descriptor set")
+		}
+		return nil
+	}
+
+	set := &l.descriptorSetLayouts[l.pushDescriptorSet]
+	if len(pushDescriptors) > len(set.bindings) {
+		return debug.Errorf("PushDescriptors count mismatch between given bindings and pipeline layout: expecting %d bindings given %d bindings",
+			len(set.bindings), len(pushDescriptors))
+	}
+	for i, binding := range set.bindings {
+		var descriptors []DescriptorInfo
+		if i < len(pushDescriptors) {
+			descriptors = pushDescriptors[i]
+		}
+		if len(descriptors) == 0 {
+			if binding.descriptorCount > 0 {
+				return debug.Errorf("PushDescriptors[%d] was not given for binding %s:%s:%d", i,
+					ShaderStage(binding.shaderStage).String(), DescriptorType(binding.descriptorType).String(), binding.descriptorCount)
+			}
+			continue
+		}
+		if len(descriptors) > int(binding.descriptorCount) {
+			return debug.Errorf("PushDescriptors[%d] has %d descriptors while layout's max is %d", i, len(descriptors), binding.descriptorCount)
+		}
+		for j, d := range descriptors {
+			if !descriptorInfoMatchesType(d, DescriptorType(binding.descriptorType)) {
+				return debug.Errorf("PushDescriptors[%d][%d] %#v cannot be written to a %s binding",
+					i, j, d, DescriptorType(binding.descriptorType).String())
+			}
+			if err := validateDescriptorUsage(d, DescriptorType(binding.descriptorType)); err != nil {
+				return debug.ErrorWrapf(err, "PushDescriptors[%d][%d] is invalid", i, j)
+			}
+			for _, m := range l.pushDescriptorMetadata[i] {
+				if err := m.ValidateDescriptor(d); err != nil {
+					return debug.ErrorWrapf(err, "PushDescriptors[%d][%d] is invalid", i, j)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+/*
+vkPushDescriptorSet returns the writes of the push descriptor set, they and everything they point to are pinned
+with pinner. The descriptors must have been validated with cmdValidate.
+*/
+func (l *PipelineLayout) vkPushDescriptorSet(pinner *runtime.Pinner, pushDescriptors [][]DescriptorInfo) C.vxr_vk_pushDescriptorSet {
+	if l.pushDescriptorSet < 0 {
+		return C.vxr_vk_pushDescriptorSet{}
+	}
+
+	set := &l.descriptorSetLayouts[l.pushDescriptorSet]
+	writes := make([]C.VkWriteDescriptorSet, 0, len(pushDescriptors))
+	for i, descriptors := range pushDescriptors {
+		if len(descriptors) > 0 {
+			writes = append(writes, vkWriteDescriptorSet(pinner, set.bindings[i], i, 0, descriptors))
+		}
+	}
+	if len(writes) > 0 {
+		pinner.Pin(unsafe.SliceData(writes))
+	}
+	return C.vxr_vk_pushDescriptorSet{
+		enable:    vk.TRUE,
+		set:       C.uint32_t(l.pushDescriptorSet),
+		numWrites: C.uint32_t(len(writes)),
+		writes:    unsafe.SliceData(writes),
+	}
+}
+
+/*
+vkDescriptorSets returns the sets to bind for the layout, sets of the layout without bindings may be nil
+and the bindless heap is filled in at BindlessHeapSet. The sets must have been validated with cmdValidate.
+With descriptor buffers the offsets of the sets are returned as well, otherwise they are nil.
+*/
+func (l *PipelineLayout) vkDescriptorSets(descriptorSets []*DescriptorSet) ([]C.VkDescriptorSet, []C.VkDeviceSize) {
+	allocations := make([]descriptorSetAllocation, len(l.descriptorSetLayouts))
+	for i, s := range descriptorSets {
+		if s != nil {
+			s.noCopy.Check()
+			allocations[i] = s.descriptorSetAllocation
+		}
+	}
+	if instance.config.bindlessHeap {
+		allocations[BindlessHeapSet] = instance.bindlessHeap.set
+	}
+
+	sets := make([]C.VkDescriptorSet, len(allocations))
+	if instance.descriptorBuffer.enabled() {
+		offsets := make([]C.VkDeviceSize, len(allocations))
+		for i, a := range allocations {
+			offsets[i] = a.descriptorBufferOffset
+		}
+		return sets, offsets
+	}
+	for i, a := range allocations {
+		sets[i] = a.cDescriptorSet
+	}
+	return sets, nil
+}
+
+/*
+resolveSpecConstants returns the SpecConstants a pipeline of the given stage should be created with,
+nil inherits the ones given to the layout for that stage while anything else must agree with the layout
+on every spec constant that sized a descriptor binding.
+*/
+func (l *PipelineLayout) resolveSpecConstants(stage ShaderStage, specConstants []uint32) ([]uint32, error) {
+	var stageSpecializations []stageSpecialization
+	for _, s := range l.specializations {
+		if s.stage == stage {
+			stageSpecializations = append(stageSpecializations, s)
+		}
+	}
+
+	if specConstants == nil {
+		if len(stageSpecializations) == 0 {
+			return nil, nil
+		}
+		for _, s := range stageSpecializations[1:] {
+			if !slices.Equal(s.specConstants, stageSpecializations[0].specConstants) {
+				return nil, debug.Errorf("PipelineLayout was created with different SpecConstants for multiple %s shaders, SpecConstants must be given explicitly",
+					stage.String())
+			}
+		}
+		return slices.Clone(stageSpecializations[0].specConstants), nil
+	}
+
+	// with multiple shaders of the same stage there is no telling which one the pipeline is for
+	if len(stageSpecializations) == 1 {
+		s := stageSpecializations[0]
+		for _, id := range s.descriptorCounts {
+			if int(id) >= len(specConstants) {
+				return nil, debug.Errorf("Spec constant [%d] sizes a descriptor binding in the PipelineLayout but only %d spec constants were given",
+					id, len(specConstants))
+			}
+			if specConstants[id] != s.specConstants[id] {
+				return nil, debug.Errorf("Spec constant [%d] is [%d] but the PipelineLayout was created with [%d]",
+					id, specConstants[id], s.specConstants[id])
+			}
+		}
+	}
+	return specConstants, nil
+}
+
+// validateShaderLayout checks that a shader layout for the given stage is compatible with the pipeline layout,
+// this is how recompiled shaders are checked before they are allowed to replace pipelines built with the layout.
+func (l *PipelineLayout) validateShaderLayout(shaderLayout *ShaderLayout, stage ShaderStage, specConstants []uint32) error {
+	if err := shaderLayout.Validate(); err != nil {
+		return err
+	}
+	if shaderLayout.PushConstants.Size > 0 {
+		if !hasBits(ShaderStage(l.pushConstantRange.stageFlags), stage) ||
+			shaderLayout.PushConstants.Offset != uint32(l.pushConstantRange.offset) ||
+			shaderLayout.PushConstants.Size != uint32(l.pushConstantRange.size) {
+			return debug.Errorf("Shader's push constants [%s,%d,%d] do not match pipeline layout's [%s,%d,%d]",
+				stage.String(), shaderLayout.PushConstants.Offset, shaderLayout.PushConstants.Size,
+				ShaderStage(l.pushConstantRange.stageFlags).String(), l.pushConstantRange.offset, l.pushConstantRange.size)
+		}
+	}
+	for set, bindings := range shaderLayout.DescriptorSetLayouts {
+		for binding, shaderBindingInfo := range bindings {
+			descriptorCount := shaderBindingInfo.DescriptorCount.Value
+			if shaderBindingInfo.DescriptorCount.IsSpecConstant {
+				if int(descriptorCount) >= len(specConstants) {
+					return debug.Errorf("set[%d] binding[%d] descriptor count is spec constant [%d] but only %d spec constants were given",
+						set, binding, descriptorCount, len(specConstants))
+				}
+				descriptorCount = specConstants[descriptorCount]
+			}
+			if isHeap, err := validateBindlessShaderBinding(set, binding, shaderBindingInfo.DescriptorType, shaderBindingInfo.DescriptorCount); err != nil {
+				return err
+			} else if isHeap {
+				continue
+			}
+			if descriptorCount == 0 {
+				continue
+			}
+			if set >= len(l.descriptorSetLayouts) || binding >= len(l.descriptorSetLayouts[set].bindings) {
+				return debug.Errorf("set[%d] binding[%d] does not exist in pipeline layout", set, binding)
+			}
+			layoutBindingInfo := l.descriptorSetLayouts[set].bindings[binding]
+			if !hasBits(ShaderStage(layoutBindingInfo.shaderStage), stage) ||
+				DescriptorType(layoutBindingInfo.descriptorType) != shaderBindingInfo.DescriptorType ||
+				uint32(layoutBindingInfo.descriptorCount) != descriptorCount {
+				return debug.Errorf("set[%d] binding[%d] [%s:%s:%d] does not match pipeline layout's [%s:%s:%d]",
+					set, binding, stage.String(), shaderBindingInfo.DescriptorType.String(), descriptorCount,
+					ShaderStage(layoutBindingInfo.shaderStage).String(), DescriptorType(layoutBindingInfo.descriptorType).String(),
+					layoutBindingInfo.descriptorCount)
+			}
+		}
 	}
 	return nil
 }
@@ -0,0 +1,452 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/bits"
+	"runtime"
+	"sync"
+	"time"
+	"unsafe"
+
+	"goarrg.com/rhi/vxr/internal/util"
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+type PipelineStatisticFlags C.VkQueryPipelineStatisticFlags
+
+const (
+	PipelineStatisticInputAssemblyVertices     PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_INPUT_ASSEMBLY_VERTICES_BIT
+	PipelineStatisticInputAssemblyPrimitives   PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_INPUT_ASSEMBLY_PRIMITIVES_BIT
+	PipelineStatisticVertexShaderInvocations   PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_VERTEX_SHADER_INVOCATIONS_BIT
+	PipelineStatisticClippingInvocations       PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_CLIPPING_INVOCATIONS_BIT
+	PipelineStatisticClippingPrimitives        PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_CLIPPING_PRIMITIVES_BIT
+	PipelineStatisticFragmentShaderInvocations PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_FRAGMENT_SHADER_INVOCATIONS_BIT
+	PipelineStatisticComputeShaderInvocations  PipelineStatisticFlags = vk.QUERY_PIPELINE_STATISTIC_COMPUTE_SHADER_INVOCATIONS_BIT
+
+	pipelineStatisticAll = PipelineStatisticInputAssemblyVertices | PipelineStatisticInputAssemblyPrimitives |
+		PipelineStatisticVertexShaderInvocations | PipelineStatisticClippingInvocations | PipelineStatisticClippingPrimitives |
+		PipelineStatisticFragmentShaderInvocations | PipelineStatisticComputeShaderInvocations
+)
+
+// PipelineStatistics holds the results of a pipeline statistics query, statistics that were not requested are 0.
+type PipelineStatistics struct {
+	InputAssemblyVertices     uint64 `json:"inputAssemblyVertices,omitempty"`
+	InputAssemblyPrimitives   uint64 `json:"inputAssemblyPrimitives,omitempty"`
+	VertexShaderInvocations   uint64 `json:"vertexShaderInvocations,omitempty"`
+	ClippingInvocations       uint64 `json:"clippingInvocations,omitempty"`
+	ClippingPrimitives        uint64 `json:"clippingPrimitives,omitempty"`
+	FragmentShaderInvocations uint64 `json:"fragmentShaderInvocations,omitempty"`
+	ComputeShaderInvocations  uint64 `json:"computeShaderInvocations,omitempty"`
+}
+
+// set assigns values in the order vulkan writes them, which is the bit order of the flags.
+func (s *PipelineStatistics) set(flags PipelineStatisticFlags, values []uint64) {
+	for i := 0; flags != 0; i++ {
+		flag := PipelineStatisticFlags(1) << bits.TrailingZeros32(uint32(flags))
+		flags &^= flag
+
+		switch flag {
+		case PipelineStatisticInputAssemblyVertices:
+			s.InputAssemblyVertices = values[i]
+		case PipelineStatisticInputAssemblyPrimitives:
+			s.InputAssemblyPrimitives = values[i]
+		case PipelineStatisticVertexShaderInvocations:
+			s.VertexShaderInvocations = values[i]
+		case PipelineStatisticClippingInvocations:
+			s.ClippingInvocations = values[i]
+		case PipelineStatisticClippingPrimitives:
+			s.ClippingPrimitives = values[i]
+		case PipelineStatisticFragmentShaderInvocations:
+			s.FragmentShaderInvocations = values[i]
+		case PipelineStatisticComputeShaderInvocations:
+			s.ComputeShaderInvocations = values[i]
+		}
+	}
+}
+
+type ProfilerCreateInfo struct {
+	// MaxRegions is the max number of named regions a single ProfilerFrame can record.
+	MaxRegions uint32
+	// PipelineStatistics are collected for every top level named region, requires the pipelineStatisticsQuery feature.
+	// Nested regions only record timestamps as only one pipeline statistics query can be active at a time.
+	PipelineStatistics PipelineStatisticFlags
+}
+
+type profilerPools struct {
+	vkTimestampPool  C.VkQueryPool
+	vkStatisticsPool C.VkQueryPool
+	tracker          resourceTracker
+}
+
+func (pools *profilerPools) resourceTracker() *resourceTracker {
+	return &pools.tracker
+}
+
+/*
+Profiler records GPU timestamps at named region boundaries, see CommandBuffer.AttachProfilerFrame.
+Results are resolved without blocking by Results once the waiter of a ProfilerFrame signals.
+Profiler is safe for concurrent use.
+*/
+type Profiler struct {
+	noCopy util.NoCopy
+	name   string
+	info   ProfilerCreateInfo
+
+	mtx       sync.Mutex
+	numPools  int
+	freePools []*profilerPools
+	recording int
+	pending   []*ProfilerFrame
+}
+
+var _ Destroyer = (*Profiler)(nil)
+
+func NewProfiler(name string, info ProfilerCreateInfo) *Profiler {
+	if !instance.graphics.features.hostQueryReset {
+		abort("NewProfiler requires the hostQueryReset feature")
+	}
+	if info.MaxRegions == 0 {
+		abort("ProfilerCreateInfo.MaxRegions must be > 0")
+	}
+	if info.PipelineStatistics != 0 {
+		if !instance.graphics.features.pipelineStatisticsQuery {
+			abort("ProfilerCreateInfo.PipelineStatistics requires the pipelineStatisticsQuery feature")
+		}
+		if (info.PipelineStatistics &^ pipelineStatisticAll) != 0 {
+			abort("ProfilerCreateInfo.PipelineStatistics has unknown flags: %#x", info.PipelineStatistics&^pipelineStatisticAll)
+		}
+	}
+	p := Profiler{name: name, info: info}
+	p.noCopy.Init()
+	return &p
+}
+
+func (p *Profiler) Destroy() {
+	p.noCopy.Check()
+	p.mtx.Lock()
+	if p.recording != 0 {
+		p.mtx.Unlock()
+		abort("Profiler [%s] destroyed with %d ProfilerFrames that have not ended", p.name, p.recording)
+	}
+	pending := p.pending
+	p.pending = nil
+	p.mtx.Unlock()
+
+	// waiting while holding mtx would block every other goroutine using the profiler until the GPU is done
+	for _, f := range pending {
+		f.waiter.Wait()
+	}
+
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	for _, f := range pending {
+		p.freePools = append(p.freePools, f.pools)
+		f.noCopy.Close()
+	}
+	for _, pools := range p.freePools {
+		vkTimestampPool, vkStatisticsPool := pools.vkTimestampPool, pools.vkStatisticsPool
+		pools.tracker.release(func() {
+			C.vxr_vk_destroyQueryPool(instance.cInstance, vkTimestampPool)
+			if vkStatisticsPool != nil {
+				C.vxr_vk_destroyQueryPool(instance.cInstance, vkStatisticsPool)
+			}
+		})
+	}
+	p.freePools = nil
+	p.noCopy.Close()
+}
+
+func (p *Profiler) newPools() *profilerPools {
+	pools := &profilerPools{}
+	name := fmt.Sprintf("%s_timestamps_%d", p.name, p.numPools)
+	C.vxr_vk_createQueryPool(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+		vk.QUERY_TYPE_TIMESTAMP, C.uint32_t(p.info.MaxRegions*2), 0, &pools.vkTimestampPool)
+	runtime.KeepAlive(name)
+	if p.info.PipelineStatistics != 0 {
+		name := fmt.Sprintf("%s_statistics_%d", p.name, p.numPools)
+		C.vxr_vk_createQueryPool(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+			vk.QUERY_TYPE_PIPELINE_STATISTICS, C.uint32_t(p.info.MaxRegions), C.VkQueryPipelineStatisticFlags(p.info.PipelineStatistics),
+			&pools.vkStatisticsPool)
+		runtime.KeepAlive(name)
+	}
+	p.numPools++
+	return pools
+}
+
+type profilerRegion struct {
+	name       string
+	parent     int
+	statistics int
+	renderPass bool
+	// timestampMask keeps the valid bits of the timestamps of the queue the region was recorded on.
+	timestampMask uint64
+}
+
+/*
+ProfilerFrame collects the named regions of every command buffer it is attached to.
+Regions may be recorded from multiple goroutines, and are ordered by when they began recording.
+*/
+type ProfilerFrame struct {
+	noCopy   util.NoCopy
+	profiler *Profiler
+	name     string
+	pools    *profilerPools
+
+	mtx           sync.Mutex
+	regions       []profilerRegion
+	numStatistics int
+	open          int
+	waiter        *TimelineSemaphoreWaiter
+}
+
+func (p *Profiler) NewFrame(name string) *ProfilerFrame {
+	p.noCopy.Check()
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	f := ProfilerFrame{profiler: p, name: name}
+	f.noCopy.Init()
+	if len(p.freePools) > 0 {
+		f.pools = p.freePools[len(p.freePools)-1]
+		p.freePools = p.freePools[:len(p.freePools)-1]
+	} else {
+		f.pools = p.newPools()
+	}
+	p.recording++
+	return &f
+}
+
+func (f *ProfilerFrame) beginRegion(cb *commandBuffer, name string, parent int) int {
+	f.noCopy.Check()
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	if f.waiter != nil {
+		abort("ProfilerFrame [%s] has already ended", f.name)
+	}
+	if len(f.regions) >= int(f.profiler.info.MaxRegions) {
+		abort("ProfilerFrame [%s] exceeded ProfilerCreateInfo.MaxRegions: %d", f.name, f.profiler.info.MaxRegions)
+	}
+	validBits := instance.queues[cb.queue].timestampValidBits
+	if validBits == 0 {
+		abort("Named region [%s] cannot be recorded on %s as it does not support timestamps", name, cb.queue)
+	}
+	region := profilerRegion{
+		name: name, parent: parent, statistics: -1, renderPass: cb.renderPassViews != 0,
+		timestampMask: ^uint64(0) >> (64 - validBits),
+	}
+	index := len(f.regions)
+	C.vxr_vk_commandBuffer_writeTimestamp(instance.cInstance, cb.vkCommandBuffer, vk.PIPELINE_STAGE_2_ALL_COMMANDS_BIT,
+		f.pools.vkTimestampPool, C.uint32_t(index*2))
+	cb.track(f.pools)
+	if parent < 0 && f.pools.vkStatisticsPool != nil && cb.queue != QueueTransfer {
+		region.statistics = f.numStatistics
+		f.numStatistics++
+		C.vxr_vk_commandBuffer_beginQuery(instance.cInstance, cb.vkCommandBuffer, f.pools.vkStatisticsPool,
+			C.uint32_t(region.statistics), 0)
+	}
+	f.regions = append(f.regions, region)
+	f.open++
+	return index
+}
+
+func (f *ProfilerFrame) endRegion(cb *commandBuffer, index int) {
+	f.noCopy.Check()
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+	region := f.regions[index]
+	if region.statistics >= 0 {
+		if region.renderPass != (cb.renderPassViews != 0) {
+			abort("Named region [%s] must begin and end in the same render pass when collecting pipeline statistics", region.name)
+		}
+		C.vxr_vk_commandBuffer_endQuery(instance.cInstance, cb.vkCommandBuffer, f.pools.vkStatisticsPool, C.uint32_t(region.statistics))
+	}
+	C.vxr_vk_commandBuffer_writeTimestamp(instance.cInstance, cb.vkCommandBuffer, vk.PIPELINE_STAGE_2_ALL_COMMANDS_BIT,
+		f.pools.vkTimestampPool, C.uint32_t(index*2+1))
+	cb.track(f.pools)
+	f.open--
+}
+
+/*
+End marks the frame as submitted, waiter must be signaled once every command buffer
+the frame is attached to has finished execution.
+*/
+func (f *ProfilerFrame) End(waiter *TimelineSemaphoreWaiter) {
+	f.noCopy.Check()
+	if waiter == nil {
+		abort("Cannot end ProfilerFrame without a TimelineSemaphoreWaiter")
+	}
+	f.mtx.Lock()
+	if f.waiter != nil {
+		f.mtx.Unlock()
+		abort("ProfilerFrame [%s] has already ended", f.name)
+	}
+	if f.open != 0 {
+		f.mtx.Unlock()
+		abort("ProfilerFrame [%s] ended with %d named regions that have not ended", f.name, f.open)
+	}
+	f.waiter = waiter
+	f.mtx.Unlock()
+
+	p := f.profiler
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	p.recording--
+	p.pending = append(p.pending, f)
+}
+
+type ProfilerRegion struct {
+	Name string
+	// Start is the GPU time at which the region began, it is only meaningful relative to other regions.
+	Start    time.Duration
+	Duration time.Duration
+	// Statistics is nil unless the region is a top level region and ProfilerCreateInfo.PipelineStatistics is non zero.
+	Statistics *PipelineStatistics
+	Children   []*ProfilerRegion
+}
+
+type ProfilerResult struct {
+	Name    string
+	Regions []*ProfilerRegion
+}
+
+/*
+Results returns the results of every ended ProfilerFrame whose waiter has signaled, in the order they ended.
+It does not block, frames that are still executing are returned by a later call.
+*/
+func (p *Profiler) Results() []ProfilerResult {
+	p.noCopy.Check()
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	var results []ProfilerResult
+	n := 0
+	for _, f := range p.pending {
+		if !f.waiter.Poll() {
+			p.pending[n] = f
+			n++
+			continue
+		}
+		result, ok := f.resolve()
+		if !ok {
+			p.pending[n] = f
+			n++
+			continue
+		}
+		results = append(results, result)
+		C.vxr_vk_resetQueryPool(instance.cInstance, f.pools.vkTimestampPool, 0, C.uint32_t(p.info.MaxRegions*2))
+		if f.pools.vkStatisticsPool != nil {
+			C.vxr_vk_resetQueryPool(instance.cInstance, f.pools.vkStatisticsPool, 0, C.uint32_t(p.info.MaxRegions))
+		}
+		p.freePools = append(p.freePools, f.pools)
+		f.noCopy.Close()
+	}
+	clear(p.pending[n:])
+	p.pending = p.pending[:n]
+	return results
+}
+
+func (f *ProfilerFrame) resolve() (ProfilerResult, bool) {
+	result := ProfilerResult{Name: f.name}
+	if len(f.regions) == 0 {
+		return result, true
+	}
+
+	timestamps := make([]uint64, len(f.regions)*2)
+	if ret := C.vxr_vk_getQueryPoolResults(instance.cInstance, f.pools.vkTimestampPool, 0, C.uint32_t(len(timestamps)),
+		C.size_t(len(timestamps)*8), unsafe.Pointer(unsafe.SliceData(timestamps)), 8, vk.QUERY_RESULT_64_BIT); ret != vk.SUCCESS {
+		return result, false
+	}
+
+	numValues := bits.OnesCount32(uint32(f.profiler.info.PipelineStatistics))
+	statistics := make([]uint64, f.numStatistics*numValues)
+	if f.numStatistics > 0 {
+		if ret := C.vxr_vk_getQueryPoolResults(instance.cInstance, f.pools.vkStatisticsPool, 0, C.uint32_t(f.numStatistics),
+			C.size_t(len(statistics)*8), unsafe.Pointer(unsafe.SliceData(statistics)), C.VkDeviceSize(numValues*8),
+			vk.QUERY_RESULT_64_BIT); ret != vk.SUCCESS {
+			return result, false
+		}
+	}
+
+	period := float64(instance.deviceProperties.Limits.Query.TimestampPeriod)
+	regions := make([]ProfilerRegion, len(f.regions))
+	for i, r := range f.regions {
+		regions[i] = ProfilerRegion{
+			Name:     r.name,
+			Start:    time.Duration(float64(timestamps[i*2]&r.timestampMask) * period),
+			Duration: time.Duration(float64((timestamps[i*2+1]-timestamps[i*2])&r.timestampMask) * period),
+		}
+		if r.statistics >= 0 {
+			regions[i].Statistics = &PipelineStatistics{}
+			regions[i].Statistics.set(f.profiler.info.PipelineStatistics, statistics[r.statistics*numValues:])
+		}
+		if r.parent < 0 {
+			result.Regions = append(result.Regions, &regions[i])
+		} else {
+			regions[r.parent].Children = append(regions[r.parent].Children, &regions[i])
+		}
+	}
+	return result, true
+}
+
+type chromeTraceEvent struct {
+	Name  string              `json:"name"`
+	Cat   string              `json:"cat"`
+	Phase string              `json:"ph"`
+	Ts    float64             `json:"ts"`
+	Dur   float64             `json:"dur"`
+	Pid   int                 `json:"pid"`
+	Tid   int                 `json:"tid"`
+	Args  *PipelineStatistics `json:"args,omitempty"`
+}
+
+// WriteChromeTrace writes the results as Chrome trace event JSON, viewable with chrome://tracing or Perfetto.
+func WriteChromeTrace(w io.Writer, results ...ProfilerResult) error {
+	var events []chromeTraceEvent
+	var walk func(frame string, r *ProfilerRegion)
+	walk = func(frame string, r *ProfilerRegion) {
+		events = append(events, chromeTraceEvent{
+			Name:  r.Name,
+			Cat:   frame,
+			Phase: "X",
+			Ts:    float64(r.Start) / float64(time.Microsecond),
+			Dur:   float64(r.Duration) / float64(time.Microsecond),
+			Args:  r.Statistics,
+		})
+		for _, c := range r.Children {
+			walk(frame, c)
+		}
+	}
+	for _, result := range results {
+		for _, r := range result.Regions {
+			walk(result.Name, r)
+		}
+	}
+	return json.NewEncoder(w).Encode(struct {
+		TraceEvents     []chromeTraceEvent `json:"traceEvents"`
+		DisplayTimeUnit string             `json:"displayTimeUnit"`
+	}{events, "ns"})
+}
@@ -127,6 +127,24 @@ type (
 				MaxSubgroupCount uint32
 			}
 		}
+		Multiview struct {
+			MaxViewCount uint32
+		}
+		Indirect struct {
+			MaxDrawCount uint32
+		}
+		Query struct {
+			// TimestampPeriod is the number of nanoseconds it takes for a timestamp query to be incremented by 1.
+			TimestampPeriod float32
+		}
+		// DescriptorIndexing limits apply to bindings that can be updated while bound e.g. the bindless heap.
+		DescriptorIndexing struct {
+			MaxSamplerCount      uint32
+			MaxSampledImageCount uint32
+			MaxStorageImageCount uint32
+			MaxSBOCount          uint32
+			MaxResourceCount     uint32
+		}
 	}
 	Properties struct {
 		UUID          UUID
@@ -0,0 +1,285 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"runtime"
+	"unsafe"
+
+	"goarrg.com/rhi/vxr/internal/util"
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+/*
+OcclusionQueryPool holds queries that count the samples passing the depth and stencil tests
+of the draws recorded between GraphicsCommandBuffer.BeginOcclusionQuery and EndOcclusionQuery.
+Queries start out reset and must be reset with Reset before they can be reused.
+*/
+type OcclusionQueryPool struct {
+	noCopy      util.NoCopy
+	name        string
+	numQueries  uint32
+	vkQueryPool C.VkQueryPool
+	tracker     resourceTracker
+}
+
+var _ Destroyer = (*OcclusionQueryPool)(nil)
+
+func NewOcclusionQueryPool(name string, numQueries uint32) *OcclusionQueryPool {
+	if !instance.graphics.features.hostQueryReset {
+		abort("NewOcclusionQueryPool requires the hostQueryReset feature")
+	}
+	if numQueries == 0 {
+		abort("OcclusionQueryPool [%s] must have at least 1 query", name)
+	}
+	p := OcclusionQueryPool{name: name, numQueries: numQueries}
+	p.noCopy.Init()
+	C.vxr_vk_createQueryPool(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+		vk.QUERY_TYPE_OCCLUSION, C.uint32_t(numQueries), 0, &p.vkQueryPool)
+	runtime.KeepAlive(name)
+	return &p
+}
+
+func (p *OcclusionQueryPool) Destroy() {
+	p.noCopy.Check()
+	vkQueryPool := p.vkQueryPool
+	p.tracker.release(func() {
+		C.vxr_vk_destroyQueryPool(instance.cInstance, vkQueryPool)
+	})
+	p.noCopy.Close()
+}
+
+func (p *OcclusionQueryPool) resourceTracker() *resourceTracker {
+	return &p.tracker
+}
+
+func (p *OcclusionQueryPool) NumQueries() uint32 {
+	p.noCopy.Check()
+	return p.numQueries
+}
+
+func (p *OcclusionQueryPool) checkRange(first, count uint32) {
+	if (uint64(first) + uint64(count)) > uint64(p.numQueries) {
+		abort("Query range [%d, %d) is out of bounds of OcclusionQueryPool [%s] with %d queries", first, uint64(first)+uint64(count),
+			p.name, p.numQueries)
+	}
+}
+
+// Reset resets the queries on the host, the queries must not be used by any pending command buffer.
+func (p *OcclusionQueryPool) Reset(first, count uint32) {
+	p.noCopy.Check()
+	p.checkRange(first, count)
+	C.vxr_vk_resetQueryPool(instance.cInstance, p.vkQueryPool, C.uint32_t(first), C.uint32_t(count))
+}
+
+/*
+Results reads len(results) queries starting at first into results without blocking,
+it returns false if any of them are not yet available e.g. the command buffer using them has not finished execution.
+*/
+func (p *OcclusionQueryPool) Results(first uint32, results []uint64) bool {
+	p.noCopy.Check()
+	p.checkRange(first, uint32(len(results)))
+	if len(results) == 0 {
+		return true
+	}
+	ret := C.vxr_vk_getQueryPoolResults(instance.cInstance, p.vkQueryPool, C.uint32_t(first), C.uint32_t(len(results)),
+		C.size_t(len(results)*8), unsafe.Pointer(unsafe.SliceData(results)), 8, vk.QUERY_RESULT_64_BIT)
+	runtime.KeepAlive(results)
+	return ret == vk.SUCCESS
+}
+
+type occlusionQuery struct {
+	pool  *OcclusionQueryPool
+	query uint32
+}
+
+/*
+BeginOcclusionQuery begins query in p, it must be called inside a render pass and ended before the render pass ends.
+Precise queries count the exact number of samples and require the occlusionQueryPrecise feature,
+otherwise the result is only guaranteed to be non zero if any sample passed.
+With multiview, the query uses one query per view starting at query.
+*/
+func (cb *GraphicsCommandBuffer) BeginOcclusionQuery(p *OcclusionQueryPool, query uint32, precise bool) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	p.noCopy.Check()
+	if cb.currentRenderPass == (renderPass{}) {
+		abort("BeginOcclusionQuery called when there's no active renderpass")
+	}
+	if cb.occlusionQuery != (occlusionQuery{}) {
+		abort("BeginOcclusionQuery called when there's an active occlusion query")
+	}
+	if precise && !instance.graphics.features.occlusionQueryPrecise {
+		abort("Precise occlusion queries require the occlusionQueryPrecise feature")
+	}
+	p.checkRange(query, cb.renderPassViews)
+
+	var flags C.VkQueryControlFlags
+	if precise {
+		flags = vk.QUERY_CONTROL_PRECISE_BIT
+	}
+	C.vxr_vk_commandBuffer_beginQuery(instance.cInstance, cb.vkCommandBuffer, p.vkQueryPool, C.uint32_t(query), flags)
+	cb.track(p)
+	cb.occlusionQuery = occlusionQuery{pool: p, query: query}
+}
+
+func (cb *GraphicsCommandBuffer) EndOcclusionQuery() {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if cb.occlusionQuery == (occlusionQuery{}) {
+		abort("EndOcclusionQuery called when there's no active occlusion query")
+	}
+	C.vxr_vk_commandBuffer_endQuery(instance.cInstance, cb.vkCommandBuffer, cb.occlusionQuery.pool.vkQueryPool,
+		C.uint32_t(cb.occlusionQuery.query))
+	cb.occlusionQuery = occlusionQuery{}
+}
+
+type OcclusionQueryCopyInfo struct {
+	Pool       *OcclusionQueryPool
+	FirstQuery uint32
+	NumQueries uint32
+
+	// Buffer must have been created with BufferUsageTransferDst.
+	Buffer Buffer
+	Offset uint64
+	// Stride is the distance between results, 0 means tightly packed.
+	Stride uint64
+	// Use64Bit writes results as uint64 instead of uint32,
+	// conditional rendering reads uint32 values so leave it false for predicates.
+	Use64Bit bool
+}
+
+/*
+CopyOcclusionQueryResults copies query results into a buffer for use on the GPU, waiting for the queries to be available.
+It must be called outside of a render pass, the copy is a transfer write for the purposes of barriers.
+*/
+func (cb *GraphicsCommandBuffer) CopyOcclusionQueryResults(info OcclusionQueryCopyInfo) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	info.Pool.noCopy.Check()
+	if cb.currentRenderPass != (renderPass{}) {
+		abort("CopyOcclusionQueryResults called when there's an active renderpass")
+	}
+	info.Pool.checkRange(info.FirstQuery, info.NumQueries)
+	if !info.Buffer.Usage().HasBits(BufferUsageTransferDst) {
+		abort("OcclusionQueryCopyInfo.Buffer was not created with BufferUsageTransferDst")
+	}
+
+	resultSize := uint64(4)
+	flags := C.VkQueryResultFlags(vk.QUERY_RESULT_WAIT_BIT)
+	if info.Use64Bit {
+		resultSize = 8
+		flags |= vk.QUERY_RESULT_64_BIT
+	}
+	if info.Stride == 0 {
+		info.Stride = resultSize
+	}
+	if (info.Offset%resultSize) != 0 || (info.Stride%resultSize) != 0 {
+		abort("OcclusionQueryCopyInfo.Offset: %d and Stride: %d must be multiples of %d", info.Offset, info.Stride, resultSize)
+	}
+	if info.NumQueries == 0 {
+		return
+	}
+	if size := info.Offset + info.Stride*uint64(info.NumQueries-1) + resultSize; size > info.Buffer.Size() {
+		abort("Copying %d queries requires %d bytes which will overflow buffer of size %d", info.NumQueries, size, info.Buffer.Size())
+	}
+
+	C.vxr_vk_commandBuffer_copyQueryPoolResults(instance.cInstance, cb.vkCommandBuffer, info.Pool.vkQueryPool,
+		C.uint32_t(info.FirstQuery), C.uint32_t(info.NumQueries), info.Buffer.vkBuffer(),
+		C.VkDeviceSize(info.Offset), C.VkDeviceSize(info.Stride), flags)
+	cb.track(info.Pool)
+	cb.track(info.Buffer)
+	cb.hazards.write(info.Buffer, PipelineStageTransfer)
+}
+
+type ConditionalRenderingInfo struct {
+	// Buffer must have been created with BufferUsageConditionalRendering, the predicate is the uint32 at Offset.
+	Buffer Buffer
+	Offset uint64
+	// Inverted discards commands when the predicate is non zero instead of when it is zero.
+	Inverted bool
+}
+
+/*
+BeginConditionalRendering discards the draws recorded until EndConditionalRendering if the predicate is 0,
+it requires the conditionalRendering feature. If begun inside a render pass, it must be ended before the render pass ends.
+Writes to the predicate must be made visible with PipelineStageConditionalRendering and AccessFlagMemoryRead.
+*/
+func (cb *GraphicsCommandBuffer) BeginConditionalRendering(info ConditionalRenderingInfo) {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	if !instance.graphics.features.conditionalRendering {
+		abort("BeginConditionalRendering requires the conditionalRendering feature")
+	}
+	if cb.conditionalRendering != conditionalRenderingNone {
+		abort("BeginConditionalRendering called when conditional rendering is already active")
+	}
+	if !info.Buffer.Usage().HasBits(BufferUsageConditionalRendering) {
+		abort("ConditionalRenderingInfo.Buffer was not created with BufferUsageConditionalRendering")
+	}
+	if (info.Offset % 4) != 0 {
+		abort("ConditionalRenderingInfo.Offset must be a multiple of 4: %d", info.Offset)
+	}
+	if (info.Offset + 4) > info.Buffer.Size() {
+		abort("ConditionalRenderingInfo.Offset: %d will overflow buffer of size %d", info.Offset, info.Buffer.Size())
+	}
+
+	var flags C.VkConditionalRenderingFlagsEXT
+	if info.Inverted {
+		flags = vk.CONDITIONAL_RENDERING_INVERTED_BIT_EXT
+	}
+	C.vxr_vk_graphics_beginConditionalRendering(instance.cInstance, cb.vkCommandBuffer, info.Buffer.vkBuffer(),
+		C.VkDeviceSize(info.Offset), flags)
+	cb.track(info.Buffer)
+	cb.hazards.read(info.Buffer, PipelineStageConditionalRendering)
+	if cb.currentRenderPass != (renderPass{}) {
+		cb.conditionalRendering = conditionalRenderingRenderPass
+	} else {
+		cb.conditionalRendering = conditionalRenderingActive
+	}
+}
+
+func (cb *GraphicsCommandBuffer) EndConditionalRendering() {
+	cb.noCopy.Acquire()
+	defer cb.noCopy.Release()
+	switch cb.conditionalRendering {
+	case conditionalRenderingNone:
+		abort("EndConditionalRendering called when conditional rendering is not active")
+	case conditionalRenderingActive:
+		if cb.currentRenderPass != (renderPass{}) {
+			abort("EndConditionalRendering called inside a renderpass when conditional rendering began outside of it")
+		}
+	}
+	C.vxr_vk_graphics_endConditionalRendering(instance.cInstance, cb.vkCommandBuffer)
+	cb.conditionalRendering = conditionalRenderingNone
+}
+
+type conditionalRenderingState uint8
+
+const (
+	conditionalRenderingNone conditionalRenderingState = iota
+	conditionalRenderingActive
+	// conditionalRenderingRenderPass is conditional rendering that began inside the current render pass
+	conditionalRenderingRenderPass
+)
@@ -0,0 +1,403 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"goarrg.com/gmath"
+)
+
+type Queue C.vxr_vk_queueType
+
+const (
+	QueueGraphics Queue = C.vxr_vk_queueType_graphics
+	QueueCompute  Queue = C.vxr_vk_queueType_compute
+	QueueTransfer Queue = C.vxr_vk_queueType_transfer
+	numQueues           = 3
+)
+
+func (q Queue) String() string {
+	switch q {
+	case QueueGraphics:
+		return "QueueGraphics"
+	case QueueCompute:
+		return "QueueCompute"
+	case QueueTransfer:
+		return "QueueTransfer"
+	default:
+		return fmt.Sprintf("Queue(%d)", q)
+	}
+}
+
+type pendingCommandBuffer struct {
+	value         C.uint64_t
+	vkCommandPool C.VkCommandPool
+	destroyers    []Destroyer
+}
+
+/*
+queueState serializes access to a VkQueue, every submission to a queue must hold mtx.
+Queues that do not share the graphics queue family resolve to the graphics queue,
+in which case they share its queueState.
+*/
+type queueState struct {
+	mtx       sync.Mutex
+	resolved  Queue
+	semaphore *TimelineSemaphore
+	pending   []pendingCommandBuffer
+	// timestampValidBits is the number of meaningful bits in timestamps written on the queue, 0 if it does not support them.
+	timestampValidBits uint32
+}
+
+func initQueues() {
+	for i := 0; i < numQueues; i++ {
+		instance.queues[i].resolved = Queue(C.vxr_vk_resolveQueue(instance.cInstance, C.vxr_vk_queueType(i)))
+		var timestampValidBits C.uint32_t
+		C.vxr_vk_getTimestampValidBits(instance.cInstance, C.vxr_vk_queueType(instance.queues[i].resolved), &timestampValidBits)
+		instance.queues[i].timestampValidBits = uint32(timestampValidBits)
+	}
+	for i := 0; i < numQueues; i++ {
+		if instance.queues[i].resolved == Queue(i) {
+			instance.queues[i].semaphore = NewTimelineSemaphore(fmt.Sprintf("queue_%s", Queue(i)))
+		}
+	}
+}
+
+func destroyQueues() {
+	for i := 0; i < numQueues; i++ {
+		q := &instance.queues[i]
+		if q.semaphore == nil {
+			continue
+		}
+		q.mtx.Lock()
+		q.reclaim(true)
+		q.semaphore.Destroy()
+		q.semaphore = nil
+		q.mtx.Unlock()
+	}
+}
+
+func (q Queue) state() *queueState {
+	if q >= numQueues {
+		abort("Invalid queue: %s", q)
+	}
+	return &instance.queues[instance.queues[q].resolved]
+}
+
+// reclaim must be called while holding mtx, it releases the resources of every command buffer that has finished execution.
+func (q *queueState) reclaim(all bool) {
+	if len(q.pending) == 0 {
+		return
+	}
+	value := C.vxr_vk_getSemaphoreValue(instance.cInstance, q.semaphore.vkSemaphore)
+	n := 0
+	for _, p := range q.pending {
+		if !all && p.value > value {
+			q.pending[n] = p
+			n++
+			continue
+		}
+		C.vxr_vk_destroyCommandPool(instance.cInstance, p.vkCommandPool)
+		for _, d := range p.destroyers {
+			d.Destroy()
+		}
+	}
+	clear(q.pending[n:])
+	q.pending = q.pending[:n]
+}
+
+func reclaimQueues() {
+	for i := 0; i < numQueues; i++ {
+		q := &instance.queues[i]
+		if q.semaphore == nil {
+			continue
+		}
+		q.mtx.Lock()
+		q.reclaim(false)
+		q.mtx.Unlock()
+	}
+	instance.deferredDestroys.reclaim(false)
+}
+
+/*
+CommandBuffer is a command buffer that is independent of the frame loop, it can be used before
+the first frame or from any goroutine e.g. for uploads at load time.
+A CommandBuffer is not safe for concurrent use, but different CommandBuffers may be recorded and
+submitted concurrently.
+
+Resources are created for exclusive use by the graphics queue family, so QueueCompute and QueueTransfer
+only run on their own queue when it belongs to the graphics queue family, otherwise they run on the graphics queue.
+Commands not supported by the requested queue abort regardless of which queue is used.
+*/
+type CommandBuffer struct {
+	// graphics is not embedded so that the frame only methods e.g. End are not exported.
+	graphics      GraphicsCommandBuffer
+	vkCommandPool C.VkCommandPool
+}
+
+func NewCommandBuffer(name string, queue Queue) *CommandBuffer {
+	q := queue.state()
+	q.mtx.Lock()
+	q.reclaim(false)
+	q.mtx.Unlock()
+
+	cb := CommandBuffer{}
+	cb.graphics.queue = queue
+	cb.graphics.noCopy.Init()
+	C.vxr_vk_commandBufferBegin(instance.cInstance, C.vxr_vk_queueType(q.resolved),
+		C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))), &cb.vkCommandPool, &cb.graphics.vkCommandBuffer)
+	runtime.KeepAlive(name)
+	return &cb
+}
+
+/*
+Submit submits the command buffer and returns a waiter that is signaled when it has finished execution,
+the command buffer's resources and the destroyers are released once the waiter is signaled.
+*/
+func (cb *CommandBuffer) Submit(waitSemaphores []SemaphoreWaitInfo, signalSemaphores []SemaphoreSignalInfo,
+	destroyers ...Destroyer,
+) *TimelineSemaphoreWaiter {
+	cb.graphics.noCopy.Acquire()
+	defer cb.graphics.noCopy.Release()
+	if cb.graphics.currentRenderPass != (renderPass{}) {
+		abort("Submit called when there's an active renderpass")
+	}
+	if cb.graphics.conditionalRendering != conditionalRenderingNone {
+		abort("Submit called when there's active conditional rendering")
+	}
+	if instance.config.validateResourceUse {
+		cb.graphics.validateResources()
+	}
+
+	q := cb.graphics.queue.state()
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	waitSemaphoreInfos := make([]C.VkSemaphoreSubmitInfo, 0, len(waitSemaphores))
+	signalSemaphoreInfos := make([]C.VkSemaphoreSubmitInfo, 0, len(signalSemaphores)+1)
+
+	for _, info := range waitSemaphores {
+		waitSemaphoreInfos = append(waitSemaphoreInfos, info.Semaphore.vkWaitInfo(info.Stage))
+	}
+	for _, info := range signalSemaphores {
+		signalSemaphoreInfos = append(signalSemaphoreInfos, info.Semaphore.vkSignalInfo(info.Stage))
+	}
+	signalSemaphoreInfos = append(signalSemaphoreInfos, q.semaphore.vkSignalInfo(PipelineStageAll))
+
+	C.vxr_vk_commandBufferSubmit(
+		instance.cInstance,
+		C.vxr_vk_queueType(q.resolved),
+		cb.graphics.vkCommandBuffer,
+		C.uint32_t(len(waitSemaphoreInfos)), unsafe.SliceData(waitSemaphoreInfos),
+		C.uint32_t(len(signalSemaphoreInfos)), unsafe.SliceData(signalSemaphoreInfos),
+	)
+	runtime.KeepAlive(waitSemaphores)
+	runtime.KeepAlive(signalSemaphores)
+
+	waiter := q.semaphore.WaiterForPendingValue()
+	q.pending = append(q.pending, pendingCommandBuffer{
+		value:         waiter.value,
+		vkCommandPool: cb.vkCommandPool,
+		destroyers:    destroyers,
+	})
+	cb.graphics.submitResources(q.resolved, waiter.value)
+	cb.graphics.noCopy.Close()
+
+	// reclaim as soon as the waiter is signaled instead of waiting for the next NewCommandBuffer or FrameBegin,
+	// Done is shared with the caller so this doesn't cost an extra watch.
+	done := waiter.Done()
+	go func() {
+		<-done
+		q.mtx.Lock()
+		// the queue may have been destroyed in which case everything has already been reclaimed
+		if q.semaphore != nil {
+			q.reclaim(false)
+		}
+		q.mtx.Unlock()
+		instance.deferredDestroys.reclaim(false)
+	}()
+	return waiter
+}
+
+/*
+Discard ends the command buffer without submitting it. A CommandBuffer that is not going to be submitted
+must be discarded, otherwise its command pool leaks and the resources it recorded are never destroyed.
+*/
+func (cb *CommandBuffer) Discard() {
+	cb.graphics.noCopy.Acquire()
+	defer cb.graphics.noCopy.Release()
+	cb.graphics.discardResources()
+	C.vxr_vk_destroyCommandPool(instance.cInstance, cb.vkCommandPool)
+	cb.graphics.noCopy.Close()
+}
+
+// The recording API forwards to the GraphicsCommandBuffer, see its methods for documentation.
+
+func (cb *CommandBuffer) AttachProfilerFrame(f *ProfilerFrame) {
+	cb.graphics.AttachProfilerFrame(f)
+}
+
+func (cb *CommandBuffer) BeginNamedRegion(name string) {
+	cb.graphics.BeginNamedRegion(name)
+}
+
+func (cb *CommandBuffer) EndNamedRegion() {
+	cb.graphics.EndNamedRegion()
+}
+
+func (cb *CommandBuffer) CompoundBarrier(memoryBarriers []MemoryBarrier, bufferBarriers []BufferBarrier, imageBarriers []ImageBarrier) {
+	cb.graphics.CompoundBarrier(memoryBarriers, bufferBarriers, imageBarriers)
+}
+
+func (cb *CommandBuffer) ExecutionBarrier(src, dst PipelineStage) {
+	cb.graphics.ExecutionBarrier(src, dst)
+}
+
+func (cb *CommandBuffer) MemoryBarrier(barriers ...MemoryBarrier) {
+	cb.graphics.MemoryBarrier(barriers...)
+}
+
+func (cb *CommandBuffer) BufferBarrier(barriers ...BufferBarrier) {
+	cb.graphics.BufferBarrier(barriers...)
+}
+
+func (cb *CommandBuffer) ImageBarrier(barriers ...ImageBarrier) {
+	cb.graphics.ImageBarrier(barriers...)
+}
+
+func (cb *CommandBuffer) FillBuffer(buffer Buffer, offset, size uint64, value uint32) {
+	cb.graphics.FillBuffer(buffer, offset, size, value)
+}
+
+func (cb *CommandBuffer) UpdateBuffer(buffer Buffer, offset uint64, data []byte) {
+	cb.graphics.UpdateBuffer(buffer, offset, data)
+}
+
+func (cb *CommandBuffer) ClearColorImage(img ColorImage, layout ImageLayout, value ColorImageClearValue, imgRange ImageSubresourceRange) {
+	cb.graphics.ClearColorImage(img, layout, value, imgRange)
+}
+
+func (cb *CommandBuffer) CopyBuffer(bIn, bOut Buffer, regions []BufferCopyRegion) {
+	cb.graphics.CopyBuffer(bIn, bOut, regions)
+}
+
+func (cb *CommandBuffer) CopyBufferToImageAspect(buffer Buffer, image ImageBufferCopyable, layout ImageLayout, aspect ImageAspectFlags, regions []BufferImageCopyRegion) {
+	cb.graphics.CopyBufferToImageAspect(buffer, image, layout, aspect, regions)
+}
+
+func (cb *CommandBuffer) CopyBufferToImage(buffer Buffer, image ImageBufferCopyable, layout ImageLayout, regions []BufferImageCopyRegion) {
+	cb.graphics.CopyBufferToImage(buffer, image, layout, regions)
+}
+
+func (cb *CommandBuffer) CopyImageToBufferAspect(image ImageBufferCopyable, layout ImageLayout, aspect ImageAspectFlags, buffer Buffer, regions []BufferImageCopyRegion) {
+	cb.graphics.CopyImageToBufferAspect(image, layout, aspect, buffer, regions)
+}
+
+func (cb *CommandBuffer) CopyImageToBuffer(image ImageBufferCopyable, layout ImageLayout, buffer Buffer, regions []BufferImageCopyRegion) {
+	cb.graphics.CopyImageToBuffer(image, layout, buffer, regions)
+}
+
+func (cb *CommandBuffer) Dispatch(p *ComputePipeline, info DispatchInfo) {
+	cb.graphics.Dispatch(p, info)
+}
+
+func (cb *CommandBuffer) DispatchIndirect(p *ComputePipeline, info DispatchIndirectInfo) {
+	cb.graphics.DispatchIndirect(p, info)
+}
+
+func (cb *CommandBuffer) RenderPassBegin(name string, area gmath.Recti32, parameters RenderParameters, attachments RenderAttachments) {
+	cb.graphics.RenderPassBegin(name, area, parameters, attachments)
+}
+
+func (cb *CommandBuffer) RenderPassSetViewport(flip bool, viewport gmath.Recti32) {
+	cb.graphics.RenderPassSetViewport(flip, viewport)
+}
+
+func (cb *CommandBuffer) RenderPassSetScissor(rect gmath.Recti32) {
+	cb.graphics.RenderPassSetScissor(rect)
+}
+
+func (cb *CommandBuffer) RenderPassSetViewportAndScissor(flip bool, viewport gmath.Recti32, rect gmath.Recti32) {
+	cb.graphics.RenderPassSetViewportAndScissor(flip, viewport, rect)
+}
+
+func (cb *CommandBuffer) RenderPassSetColorBlendParameters(firstAttachment int, infos []RenderColorBlendParameters) {
+	cb.graphics.RenderPassSetColorBlendParameters(firstAttachment, infos)
+}
+
+func (cb *CommandBuffer) RenderPassSetLineWidth(width float32) {
+	cb.graphics.RenderPassSetLineWidth(width)
+}
+
+func (cb *CommandBuffer) RenderPassEnd() {
+	cb.graphics.RenderPassEnd()
+}
+
+func (cb *CommandBuffer) Draw(p GraphicsPipelineLibrary, info DrawInfo) {
+	cb.graphics.Draw(p, info)
+}
+
+func (cb *CommandBuffer) DrawIndirect(p GraphicsPipelineLibrary, info DrawIndirectInfo) {
+	cb.graphics.DrawIndirect(p, info)
+}
+
+func (cb *CommandBuffer) DrawIndirectCount(p GraphicsPipelineLibrary, info DrawIndirectCountInfo) {
+	cb.graphics.DrawIndirectCount(p, info)
+}
+
+func (cb *CommandBuffer) DrawIndexed(p GraphicsPipelineLibrary, info DrawIndexedInfo) {
+	cb.graphics.DrawIndexed(p, info)
+}
+
+func (cb *CommandBuffer) DrawIndexedIndirect(p GraphicsPipelineLibrary, info DrawIndexedIndirectInfo) {
+	cb.graphics.DrawIndexedIndirect(p, info)
+}
+
+func (cb *CommandBuffer) DrawIndexedIndirectCount(p GraphicsPipelineLibrary, info DrawIndexedIndirectCountInfo) {
+	cb.graphics.DrawIndexedIndirectCount(p, info)
+}
+
+func (cb *CommandBuffer) BeginOcclusionQuery(p *OcclusionQueryPool, query uint32, precise bool) {
+	cb.graphics.BeginOcclusionQuery(p, query, precise)
+}
+
+func (cb *CommandBuffer) EndOcclusionQuery() {
+	cb.graphics.EndOcclusionQuery()
+}
+
+func (cb *CommandBuffer) CopyOcclusionQueryResults(info OcclusionQueryCopyInfo) {
+	cb.graphics.CopyOcclusionQueryResults(info)
+}
+
+func (cb *CommandBuffer) BeginConditionalRendering(info ConditionalRenderingInfo) {
+	cb.graphics.BeginConditionalRendering(info)
+}
+
+func (cb *CommandBuffer) EndConditionalRendering() {
+	cb.graphics.EndConditionalRendering()
+}
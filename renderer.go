@@ -33,7 +33,6 @@ import (
 	"maps"
 	"slices"
 	"sync"
-	"time"
 	"unsafe"
 
 	"goarrg.com"
@@ -47,6 +46,11 @@ const (
 	MaxAPI uint32 = C.VXR_VK_MAX_API
 )
 
+/*
+Destroyer is implemented by everything that has to be destroyed explicitly. Buffers, images, samplers and
+DescriptorSets defer the actual destruction until the GPU is done with every submission that used them,
+so they may be destroyed as soon as their last use has been submitted.
+*/
 type Destroyer interface {
 	Destroy()
 }
@@ -63,22 +67,29 @@ type state struct {
 	logger          *debug.Logger
 	vkInstance      goarrg.VkInstance
 	config          config
-	cSurface        uint64
+	swapchain       *Swapchain
+	swapchains      map[*Swapchain]struct{}
+	offscreen       *offscreen
 	cInstance       C.vxr_vk_instance
 	cShaderCompiler C.vxr_vk_shader_toolchain
 
+	// swapchainColorSpace is whether the instance was created with the optional VK_EXT_swapchain_colorspace.
+	swapchainColorSpace bool
+
 	deviceProperties Properties
 	formatProperties formatProperties
 
-	descriptorSetLayoutCache descriptorSetLayoutCache
-	pipelineLayoutCache      pipelineLayoutCache
-	descriptorSetCache       descriptorSetCache
-
-	graphics graphicsState
-
-	sleep bool
-	sizeX float64
-	sizeY float64
+	descriptorSetLayoutCache      descriptorSetLayoutCache
+	pipelineLayoutCache           pipelineLayoutCache
+	descriptorSetCache            descriptorSetCache
+	descriptorUpdateTemplateCache descriptorUpdateTemplateCache
+	bindlessHeap                  BindlessHeap
+	descriptorBuffer              descriptorBuffer
+
+	graphics         graphicsState
+	queues           [numQueues]queueState
+	deferredDestroys deferredDestroys
+	semaphoreWatcher semaphoreWatcher
 }
 
 var instanceInitOnce sync.Once
@@ -95,13 +106,16 @@ var instance = state{
 		},
 	},
 
-	descriptorSetLayoutCache: descriptorSetLayoutCache{cache: map[string]C.VkDescriptorSetLayout{}},
-	pipelineLayoutCache:      pipelineLayoutCache{cache: map[string]C.VkPipelineLayout{}},
-	descriptorSetCache:       descriptorSetCache{descriptorPools: map[string]*descriptorPool{}},
+	descriptorSetLayoutCache:      descriptorSetLayoutCache{cache: map[hashKey]hashBucket[descriptorSetLayoutKey, C.VkDescriptorSetLayout]{}},
+	pipelineLayoutCache:           pipelineLayoutCache{cache: map[hashKey]hashBucket[pipelineLayoutKey, C.VkPipelineLayout]{}},
+	descriptorSetCache:            descriptorSetCache{descriptorPools: map[hashKey]hashBucket[descriptorSetLayoutKey, *descriptorPool]{}},
+	descriptorUpdateTemplateCache: descriptorUpdateTemplateCache{cache: map[hashKey]hashBucket[descriptorSetLayoutKey, descriptorUpdateTemplate]{}},
 
 	graphics: graphicsState{
 		pipelineCache: graphicsPipelineCache{
-			cache:       map[string]C.VkPipeline{},
+			libraries:   map[hashKey]hashBucket[pipelineLibraryKey, cachedPipeline]{},
+			executables: map[executablePipelineKey]cachedPipeline{},
+			dependents:  map[C.VkPipeline]map[executablePipelineKey]struct{}{},
 			cacheShader: map[string]*GraphicsShaderPipeline{},
 		},
 		destroyerChan: make(chan Destroyer),
@@ -109,23 +123,20 @@ var instance = state{
 }
 
 func VkConfig() goarrg.VkConfig {
+	extensions := []string{
+		C.VK_KHR_GET_SURFACE_CAPABILITIES_2_EXTENSION_NAME,
+		C.VK_EXT_SURFACE_MAINTENANCE_1_EXTENSION_NAME,
+	}
 	if C.VXR_DEBUG == 1 {
-		return goarrg.VkConfig{
-			API:    C.VXR_VK_MIN_API,
-			Layers: []string{},
-			Extensions: []string{
-				C.VK_EXT_DEBUG_UTILS_EXTENSION_NAME,
-				C.VK_KHR_GET_SURFACE_CAPABILITIES_2_EXTENSION_NAME,
-				C.VK_EXT_SURFACE_MAINTENANCE_1_EXTENSION_NAME,
-			},
-		}
+		extensions = append(extensions, C.VK_EXT_DEBUG_UTILS_EXTENSION_NAME)
 	}
 	return goarrg.VkConfig{
-		API:    C.VXR_VK_MIN_API,
-		Layers: []string{},
-		Extensions: []string{
-			C.VK_KHR_GET_SURFACE_CAPABILITIES_2_EXTENSION_NAME,
-			C.VK_EXT_SURFACE_MAINTENANCE_1_EXTENSION_NAME,
+		API:        C.VXR_VK_MIN_API,
+		Layers:     []string{},
+		Extensions: extensions,
+		// HDR and wide gamut color spaces are optional so instance creation does not fail without them
+		OptionalExtensions: []string{
+			C.VK_EXT_SWAPCHAIN_COLOR_SPACE_EXTENSION_NAME,
 		},
 	}
 }
@@ -154,6 +165,10 @@ func InitInstance(platform goarrg.PlatformInterface, vkInstance goarrg.VkInstanc
 		instance.logger.IPrintf("vxr_vk_init")
 		C.vxr_vk_init(C.uintptr_t(instance.vkInstance.Uintptr()), C.uintptr_t(instance.vkInstance.ProcAddr()),
 			cGoVkLog, &instance.cInstance)
+		name := C.VK_EXT_SWAPCHAIN_COLOR_SPACE_EXTENSION_NAME
+		instance.swapchainColorSpace = C.vxr_vk_instanceExtensionSupported(instance.cInstance,
+			C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name)))) == vk.TRUE
+		instance.logger.VPrintf("%s: %t", name, instance.swapchainColorSpace)
 	})
 }
 
@@ -190,14 +205,18 @@ func InitDevice(config Config) {
 	config.validate()
 	instance.logger.IPrintf("User requested config: %s", prettyString(&config))
 
-	var err error
-	instance.logger.IPrintf("CreateSurface")
-	if instance.cSurface, err = instance.vkInstance.CreateSurface(); err != nil {
-		abort("Failed to create surface: %v", err)
+	var surface uint64
+	if config.Offscreen == nil {
+		instance.logger.IPrintf("CreateSurface")
+		var err error
+		surface, err = instance.vkInstance.CreateSurface()
+		if err != nil {
+			abort("Failed to create surface: %v", err)
+		}
 	}
 
 	instance.logger.IPrintf("vxr_vk_device_init")
-	selector := config.createDeviceSelector(instance.cSurface)
+	selector := config.createDeviceSelector(surface)
 	defer C.vxr_vk_device_destroySelector(selector)
 	C.vxr_vk_device_init(instance.cInstance, selector)
 
@@ -223,13 +242,24 @@ func InitDevice(config Config) {
 			if err != nil {
 				abort("Failed to get enabled features: %v", err)
 			}
+			instance.graphics.features.init(instance.deviceProperties.EnabledFeatures, instance.deviceProperties.EnabledExtensions)
 		}
 		instance.logger.IPrintf("%s", prettyString(&instance.deviceProperties))
 	}
 
 	instance.logger.IPrintf("Initializing Configuration")
 	instance.config.use(config)
-	initFramesInFlight(1)
+	initDescriptorBuffer()
+	initQueues()
+	initBindlessHeap()
+	instance.graphics.frameStats.init(instance.config.frameStatsWindow)
+	if instance.config.offscreen != nil {
+		initFramesInFlight(instance.config.maxFramesInFlight)
+		instance.offscreen = newOffscreen(*instance.config.offscreen)
+	} else {
+		initFramesInFlight(1)
+		instance.swapchain = newSwapchain("main", surface)
+	}
 	instance.logger.IPrintf("Initialization Completed")
 }
 
@@ -241,34 +271,21 @@ func DeviceProperties() Properties {
 	return ret
 }
 
+// Resize is Resize of the swapchain created by InitDevice, it is ignored when Config.Offscreen is set as the extent is fixed.
 func Resize(w int, h int) {
-	instance.sleep = true
-	instance.sizeX = float64(w)
-	instance.sizeY = float64(h)
-
-	if w != 0 && h != 0 {
-		instance.sleep = false
-		start := time.Now()
-
-		for i := 0; i < len(instance.graphics.framesInFlight); i++ {
-			instance.graphics.framesInFlight[i].waitSurface()
-		}
-
-		instance.logger.IPrintf("vxr_vk_graphics_init")
-		ret := C.vxr_vk_graphics_init(instance.cInstance, C.uint64_t(instance.cSurface),
-			C.uint32_t(instance.config.maxFramesInFlight+instance.config.swapchainImageCountPadding))
-		if ret != vk.SUCCESS {
-			abort("Failed to initialize graphics system: %s", vkResultStr(ret))
-		}
-
-		info := CurrentSurfaceInfo()
-		initFramesInFlight(info.NumFramesInFlight)
-		instance.logger.IPrintf("Resize took: %v", time.Since(start))
+	if instance.offscreen != nil {
+		return
 	}
+	instance.swapchain.Resize(w, h)
 }
 
 func Destroy() {
 	C.vxr_vk_waitIdle(instance.cInstance)
+	instance.graphics.completedFrame.Store(instance.graphics.frameSerial)
+	if instance.offscreen != nil {
+		instance.offscreen.destroy()
+		instance.offscreen = nil
+	}
 
 loop:
 	for {
@@ -279,12 +296,18 @@ loop:
 			break loop
 		}
 	}
+	instance.deferredDestroys.reclaim(true)
 
 	instance.logger.VPrintf("formatProperties: %s", prettyString(&instance.formatProperties))
 
 	instance.logger.VPrintf("pipelineCache: %s", prettyString(&instance.graphics.pipelineCache))
-	for _, p := range instance.graphics.pipelineCache.cache {
-		C.vxr_vk_shader_destroyPipeline(instance.cInstance, p)
+	for _, p := range instance.graphics.pipelineCache.executables {
+		C.vxr_vk_shader_destroyPipeline(instance.cInstance, p.vkPipeline)
+	}
+	for _, b := range instance.graphics.pipelineCache.libraries {
+		for _, e := range b {
+			C.vxr_vk_shader_destroyPipeline(instance.cInstance, e.value.vkPipeline)
+		}
 	}
 	for _, s := range instance.graphics.pipelineCache.cacheShader {
 		C.vxr_vk_shader_destroyPipeline(instance.cInstance, s.vkPipeline)
@@ -293,27 +316,45 @@ loop:
 	instance.logger.VPrintf("descriptorSetLayoutCache: %s", prettyString(&instance.descriptorSetLayoutCache))
 	instance.logger.VPrintf("pipelineLayoutCache: %s", prettyString(&instance.pipelineLayoutCache))
 	instance.logger.VPrintf("descriptorSetCache: %s", prettyString(&instance.descriptorSetCache))
+	instance.logger.VPrintf("descriptorUpdateTemplateCache: %s", prettyString(&instance.descriptorUpdateTemplateCache))
 
-	for _, l := range instance.descriptorSetLayoutCache.cache {
-		C.vxr_vk_shader_destroyDescriptorSetLayout(instance.cInstance, l)
+	instance.descriptorUpdateTemplateCache.destroy()
+
+	for _, b := range instance.descriptorSetLayoutCache.cache {
+		for _, e := range b {
+			C.vxr_vk_shader_destroyDescriptorSetLayout(instance.cInstance, e.value)
+		}
 	}
-	for _, l := range instance.pipelineLayoutCache.cache {
-		C.vxr_vk_shader_destroyPipelineLayout(instance.cInstance, l)
+	for _, b := range instance.pipelineLayoutCache.cache {
+		for _, e := range b {
+			C.vxr_vk_shader_destroyPipelineLayout(instance.cInstance, e.value)
+		}
 	}
-	for _, p := range instance.descriptorSetCache.descriptorPools {
-		p.destroy()
+	for _, b := range instance.descriptorSetCache.descriptorPools {
+		for _, e := range b {
+			e.value.destroy()
+		}
 	}
+	destroyBindlessHeap()
+	destroyDescriptorBuffer()
 
 	for _, f := range instance.graphics.framesInFlight {
 		f.destroy()
 	}
+	destroyQueues()
 
 	instance.logger.IPrintf("vxr_vk_graphics_destroy")
 	C.vxr_vk_graphics_destroy(instance.cInstance)
+	// vxr_vk_graphics_destroy frees the swapchains created by NewSwapchain as well, close them so that using them aborts
+	for s := range instance.swapchains {
+		s.noCopy.Close()
+	}
+	instance.swapchains = nil
 	instance.logger.IPrintf("vxr_vk_device_destroy")
 	C.vxr_vk_device_destroy(instance.cInstance)
 	instance.logger.IPrintf("vxr_vk_destroy")
 	C.vxr_vk_destroy(instance.cInstance)
 
+	instance.swapchain = nil
 	instance.cInstance = nil
 }
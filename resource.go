@@ -0,0 +1,251 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"sync"
+
+	"goarrg.com/debug"
+)
+
+/*
+resourceTracker tracks the submissions a resource was recorded in so that Destroy can defer the actual
+destruction until the GPU is done with it. Command buffers of a frame stamp every resource they record with the frame's serial
+as the frame's command pools are only reset once it is done, whether the command buffer was submitted or not.
+A CommandBuffer counts the resource as recording until it is submitted, where it stamps it with the queue's timeline value, or discarded.
+*/
+type resourceTracker struct {
+	mtx sync.Mutex
+	// values is the last timeline value of each queue the resource was submitted with.
+	values [numQueues]C.uint64_t
+	// frame is the serial of the last frame the resource was submitted in.
+	frame uint64
+	// recording is the number of CommandBuffers that recorded the resource and have been neither submitted nor discarded.
+	recording int
+
+	destroy func()
+	// destroyedAt is the stack trace of Destroy, it is only kept with Config.ValidateResourceUse.
+	destroyedAt string
+}
+
+type trackedResource interface {
+	resourceTracker() *resourceTracker
+}
+
+// release calls destroy once the GPU is done with the resource, right away if it already is.
+func (t *resourceTracker) release(destroy func()) {
+	t.mtx.Lock()
+	t.destroy = destroy
+	if instance.config.validateResourceUse {
+		t.destroyedAt = debug.StackTrace(1)
+	}
+	idle := t.idle(completedQueueValues())
+	t.mtx.Unlock()
+
+	if idle {
+		destroy()
+		return
+	}
+	instance.deferredDestroys.push(t)
+}
+
+// idle must be called while holding mtx.
+func (t *resourceTracker) idle(completed [numQueues]C.uint64_t) bool {
+	if t.recording > 0 || t.frame > instance.graphics.completedFrame.Load() {
+		return false
+	}
+	for i, v := range t.values {
+		if v > completed[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// checkNotDestroyed aborts with where the resource was destroyed if Destroy has been called.
+func (t *resourceTracker) checkNotDestroyed(what string) {
+	t.mtx.Lock()
+	destroyed, destroyedAt := t.destroy != nil, t.destroyedAt
+	t.mtx.Unlock()
+	if destroyed {
+		abort("%s uses a resource that has been destroyed at: \n%s", what, destroyedAt)
+	}
+}
+
+func completedQueueValues() [numQueues]C.uint64_t {
+	var completed [numQueues]C.uint64_t
+	for i := 0; i < numQueues; i++ {
+		if s := instance.queues[i].semaphore; s != nil {
+			completed[i] = C.vxr_vk_getSemaphoreValue(instance.cInstance, s.vkSemaphore)
+		}
+	}
+	return completed
+}
+
+// deferredDestroys holds the resources that were destroyed while the GPU may still be using them.
+type deferredDestroys struct {
+	mtx     sync.Mutex
+	pending []*resourceTracker
+}
+
+func (d *deferredDestroys) push(t *resourceTracker) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	d.pending = append(d.pending, t)
+}
+
+// reclaim destroys every pending resource the GPU is done with, or all of them which requires the device to be idle.
+func (d *deferredDestroys) reclaim(all bool) {
+	d.mtx.Lock()
+	if len(d.pending) == 0 {
+		d.mtx.Unlock()
+		return
+	}
+	completed := completedQueueValues()
+	var ready []func()
+	n := 0
+	for _, t := range d.pending {
+		t.mtx.Lock()
+		idle := all || t.idle(completed)
+		t.mtx.Unlock()
+		if !idle {
+			d.pending[n] = t
+			n++
+			continue
+		}
+		ready = append(ready, t.destroy)
+	}
+	clear(d.pending[n:])
+	d.pending = d.pending[:n]
+	d.mtx.Unlock()
+
+	for _, destroy := range ready {
+		destroy()
+	}
+}
+
+/*
+track records that the command buffer uses r, r is ignored if it is not a resource with a tracked lifetime
+e.g. the frame's HostScratchBuffers and Surface. Tracking a DescriptorSet also tracks the resources bound to it.
+Every resource is only tracked once per command buffer, later uses are a map lookup.
+*/
+func (cb *commandBuffer) track(r any) {
+	tr, ok := r.(trackedResource)
+	if !ok {
+		return
+	}
+	t := tr.resourceTracker()
+	if _, ok := cb.resources[t]; ok {
+		return
+	}
+	if cb.resources == nil {
+		cb.resources = map[*resourceTracker]struct{}{}
+	}
+	cb.resources[t] = struct{}{}
+
+	if instance.config.validateResourceUse {
+		t.checkNotDestroyed("Command buffer")
+	}
+	t.mtx.Lock()
+	if cb.frameSerial != 0 {
+		t.frame = max(t.frame, cb.frameSerial)
+	} else {
+		t.recording++
+	}
+	t.mtx.Unlock()
+
+	if s, ok := r.(*DescriptorSet); ok {
+		s.forEachResource(func(binding, index int, r any) {
+			if tr, ok := r.(trackedResource); ok && instance.config.validateResourceUse {
+				tr.resourceTracker().checkNotDestroyed(fmt.Sprintf("DescriptorSet binding [%d] descriptor [%d]", binding, index))
+			}
+			cb.track(r)
+		})
+	}
+}
+
+// trackBindings tracks the descriptor sets and push descriptors bound by a draw or dispatch.
+func (cb *commandBuffer) trackBindings(descriptorSets []*DescriptorSet, pushDescriptors [][]DescriptorInfo) {
+	for _, s := range descriptorSets {
+		if s != nil {
+			cb.track(s)
+		}
+	}
+	for _, descriptors := range pushDescriptors {
+		for _, d := range descriptors {
+			forEachDescriptorResource(d, cb.track)
+		}
+	}
+}
+
+/*
+validateResources aborts if any of the tracked resources was destroyed before the command buffer was submitted,
+it is only called with Config.ValidateResourceUse as the destruction would otherwise be deferred until the submission is done.
+*/
+func (cb *commandBuffer) validateResources() {
+	for t := range cb.resources {
+		t.checkNotDestroyed("Submitted command buffer")
+	}
+}
+
+// submitResources stamps every tracked resource of a CommandBuffer with the submission, those of a frame were stamped by track.
+func (cb *commandBuffer) submitResources(queue Queue, value C.uint64_t) {
+	if cb.frameSerial == 0 {
+		for t := range cb.resources {
+			t.mtx.Lock()
+			t.values[queue] = max(t.values[queue], value)
+			t.recording--
+			t.mtx.Unlock()
+		}
+	}
+	cb.resources = nil
+}
+
+// discardResources releases the tracked resources of a command buffer that will never be submitted.
+func (cb *commandBuffer) discardResources() {
+	if cb.frameSerial == 0 {
+		for t := range cb.resources {
+			t.mtx.Lock()
+			t.recording--
+			t.mtx.Unlock()
+		}
+	}
+	cb.resources = nil
+}
+
+// forEachDescriptorResource calls f with each resource referenced by d.
+func forEachDescriptorResource(d DescriptorInfo, f func(any)) {
+	switch d := d.(type) {
+	case DescriptorBufferInfo:
+		f(d.Buffer)
+	case DescriptorImageInfo:
+		f(d.Image)
+	case DescriptorCombinedImageSamplerInfo:
+		f(d.Sampler)
+		f(d.Image)
+	case *Sampler:
+		f(d)
+	}
+}
@@ -24,7 +24,11 @@ package vxr
 import "C"
 
 import (
+	"context"
+	"math"
 	"runtime"
+	"sync"
+	"time"
 	"unsafe"
 
 	"goarrg.com/rhi/vxr/internal/util"
@@ -59,6 +63,19 @@ var (
 	_ SemaphoreSignaler = (*binarySemaphore)(nil)
 )
 
+func (s *binarySemaphore) init(name string) {
+	s.noCopy.Init()
+	C.vxr_vk_createSemaphore(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+		vk.SEMAPHORE_TYPE_BINARY, &s.vkSemaphore)
+	runtime.KeepAlive(name)
+}
+
+func (s *binarySemaphore) destroy() {
+	s.noCopy.Check()
+	C.vxr_vk_destroySemaphore(instance.cInstance, s.vkSemaphore)
+	s.noCopy.Close()
+}
+
 func (s *binarySemaphore) vkSignalInfo(stage PipelineStage) C.VkSemaphoreSubmitInfo {
 	s.noCopy.Check()
 	return C.VkSemaphoreSubmitInfo{
@@ -83,7 +100,10 @@ type TimelineSemaphore struct {
 	gpuPending    C.uint64_t
 	cpuPending    C.uint64_t
 	pendingSignal C.uint64_t
-	value         C.uint64_t
+
+	// mtx guards value so that waiters may wait from any goroutine
+	mtx   sync.Mutex
+	value C.uint64_t
 }
 
 var _ interface {
@@ -104,14 +124,28 @@ func NewTimelineSemaphore(name string) *TimelineSemaphore {
 func (s *TimelineSemaphore) Destroy() {
 	s.noCopy.Check()
 	s.Wait()
+	instance.semaphoreWatcher.forget(s)
 	C.vxr_vk_destroySemaphore(instance.cInstance, s.vkSemaphore)
 	s.noCopy.Close()
 }
 
 func (s *TimelineSemaphore) Value() uint64 {
 	s.noCopy.Check()
-	s.value = C.vxr_vk_getSemaphoreValue(instance.cInstance, s.vkSemaphore)
-	return uint64(s.value)
+	value := C.vxr_vk_getSemaphoreValue(instance.cInstance, s.vkSemaphore)
+	s.storeValue(value)
+	return uint64(value)
+}
+
+func (s *TimelineSemaphore) loadValue() C.uint64_t {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.value
+}
+
+func (s *TimelineSemaphore) storeValue(value C.uint64_t) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.value = max(s.value, value)
 }
 
 func (s *TimelineSemaphore) vkSignalInfo(stage PipelineStage) C.VkSemaphoreSubmitInfo {
@@ -128,11 +162,11 @@ func (s *TimelineSemaphore) vkSignalInfo(stage PipelineStage) C.VkSemaphoreSubmi
 
 func (s *TimelineSemaphore) sendSignal(signal C.uint64_t) {
 	s.noCopy.Check()
-	if s.value >= s.cpuPending {
+	if s.loadValue() >= s.cpuPending {
 		abort("No pending CPU signal promise")
 	}
 	C.vxr_vk_signalSemaphore(instance.cInstance, s.vkSemaphore, signal)
-	s.value = signal
+	s.storeValue(signal)
 }
 
 type TimelineSemaphorePromise struct {
@@ -174,12 +208,12 @@ func (s *TimelineSemaphore) vkWaitInfo(stage PipelineStage) C.VkSemaphoreSubmitI
 }
 
 func (s *TimelineSemaphore) waitForSignal(signal C.uint64_t) {
-	if s.value >= signal {
+	if s.loadValue() >= signal {
 		return
 	}
 	s.noCopy.Check()
 	C.vxr_vk_waitSemaphore(instance.cInstance, s.vkSemaphore, signal)
-	s.value = signal
+	s.storeValue(signal)
 }
 
 func (s *TimelineSemaphore) Wait() {
@@ -190,6 +224,8 @@ type TimelineSemaphoreWaiter struct {
 	noCopy    util.NoCopy
 	semaphore *TimelineSemaphore
 	value     C.uint64_t
+	// done is created by the first call to Done and guarded by the semaphoreWatcher's mtx.
+	done chan struct{}
 }
 
 var _ SemaphoreWaiter = (*TimelineSemaphoreWaiter)(nil)
@@ -203,8 +239,9 @@ func (s *TimelineSemaphore) WaiterForPendingValue() *TimelineSemaphoreWaiter {
 
 func (s *TimelineSemaphore) WaiterForCurrentValue() *TimelineSemaphoreWaiter {
 	s.noCopy.Check()
-	s.value = C.vxr_vk_getSemaphoreValue(instance.cInstance, s.vkSemaphore)
-	f := TimelineSemaphoreWaiter{semaphore: s, value: s.value}
+	value := C.vxr_vk_getSemaphoreValue(instance.cInstance, s.vkSemaphore)
+	s.storeValue(value)
+	f := TimelineSemaphoreWaiter{semaphore: s, value: value}
 	f.noCopy.Init()
 	return &f
 }
@@ -235,3 +272,194 @@ func (w *TimelineSemaphoreWaiter) Value() uint64 {
 	w.noCopy.Check()
 	return uint64(w.value)
 }
+
+/*
+WaitContext is Wait that returns ctx.Err() if ctx is done before the value is reached,
+a waiter that is already signaled returns nil even if ctx is done.
+*/
+func (w *TimelineSemaphoreWaiter) WaitContext(ctx context.Context) error {
+	w.noCopy.Check()
+	_, err := waitSemaphores(ctx, []*TimelineSemaphoreWaiter{w}, false)
+	return err
+}
+
+/*
+Done returns a channel that is closed once the value is reached, allowing the waiter to be used in a select.
+The channels of every waiter are serviced by a single goroutine that only runs while there are channels to close.
+*/
+func (w *TimelineSemaphoreWaiter) Done() <-chan struct{} {
+	w.noCopy.Check()
+	return instance.semaphoreWatcher.watch(w)
+}
+
+// WaitAll waits until every waiter is signaled, returning ctx.Err() if ctx is done first.
+func WaitAll(ctx context.Context, waiters ...*TimelineSemaphoreWaiter) error {
+	_, err := waitSemaphores(ctx, waiters, false)
+	return err
+}
+
+/*
+WaitAny waits until at least one of the waiters is signaled and returns the index of a signaled waiter,
+returning -1 and ctx.Err() if ctx is done first.
+*/
+func WaitAny(ctx context.Context, waiters ...*TimelineSemaphoreWaiter) (int, error) {
+	return waitSemaphores(ctx, waiters, true)
+}
+
+// waitSliceTimeout bounds each vkWaitSemaphores call so that a cancelled context is noticed in time.
+const waitSliceTimeout = 5 * time.Millisecond
+
+/*
+waitSemaphores waits on the waiters through vkWaitSemaphores, with waitAny it returns the index of a signaled waiter.
+The wait is split into slices no longer than waitSliceTimeout with ctx checked in between,
+a ctx that can never be done waits in a single call until the waiters are signaled.
+*/
+func waitSemaphores(ctx context.Context, waiters []*TimelineSemaphoreWaiter, waitAny bool) (int, error) {
+	if len(waiters) == 0 {
+		if waitAny {
+			abort("WaitAny called without waiters")
+		}
+		return -1, nil
+	}
+	semaphores := make([]C.VkSemaphore, len(waiters))
+	values := make([]C.uint64_t, len(waiters))
+	for i, w := range waiters {
+		w.noCopy.Check()
+		w.semaphore.noCopy.Check()
+		semaphores[i] = w.semaphore.vkSemaphore
+		values[i] = w.value
+	}
+	cWaitAny := C.VkBool32(vk.FALSE)
+	if waitAny {
+		cWaitAny = vk.TRUE
+	}
+
+	_, hasDeadline := ctx.Deadline()
+	forever := !hasDeadline && ctx.Done() == nil
+	for {
+		timeout := C.uint64_t(math.MaxUint64)
+		if !forever {
+			slice := waitSliceTimeout
+			if deadline, ok := ctx.Deadline(); ok {
+				slice = max(0, min(slice, time.Until(deadline)))
+			}
+			timeout = C.uint64_t(slice.Nanoseconds())
+		}
+		ret := C.vxr_vk_waitSemaphores(instance.cInstance, C.uint32_t(len(semaphores)), unsafe.SliceData(semaphores),
+			unsafe.SliceData(values), cWaitAny, timeout)
+		if ret == vk.SUCCESS {
+			break
+		}
+		if ret != vk.TIMEOUT {
+			abort("Failed waiting on semaphores: %s", vkResultStr(ret))
+		}
+		if err := ctx.Err(); err != nil {
+			return -1, err
+		}
+	}
+	runtime.KeepAlive(semaphores)
+	runtime.KeepAlive(values)
+
+	if !waitAny {
+		for _, w := range waiters {
+			w.semaphore.storeValue(w.value)
+		}
+		return -1, nil
+	}
+	for i, w := range waiters {
+		if w.Poll() {
+			w.semaphore.storeValue(w.value)
+			return i, nil
+		}
+	}
+	abort("vkWaitSemaphores returned without any of the waiters being signaled")
+	return -1, nil
+}
+
+/*
+semaphoreWatcher closes the channels returned by TimelineSemaphoreWaiter.Done, a single goroutine waits on
+every watched waiter at once and exits when there are none left.
+*/
+type semaphoreWatcher struct {
+	// waitMtx is held for the duration of every vkWaitSemaphores call so semaphores are not destroyed while in use.
+	waitMtx sync.Mutex
+	mtx     sync.Mutex
+	waiters []*TimelineSemaphoreWaiter
+	running bool
+}
+
+func (sw *semaphoreWatcher) watch(w *TimelineSemaphoreWaiter) <-chan struct{} {
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	if w.done != nil {
+		return w.done
+	}
+	w.done = make(chan struct{})
+	if w.Poll() {
+		close(w.done)
+		return w.done
+	}
+	sw.waiters = append(sw.waiters, w)
+	if !sw.running {
+		sw.running = true
+		go sw.run()
+	}
+	return w.done
+}
+
+func (sw *semaphoreWatcher) run() {
+	for {
+		sw.waitMtx.Lock()
+		sw.mtx.Lock()
+		n := 0
+		for _, w := range sw.waiters {
+			if C.vxr_vk_getSemaphoreValue(instance.cInstance, w.semaphore.vkSemaphore) >= w.value {
+				close(w.done)
+				continue
+			}
+			sw.waiters[n] = w
+			n++
+		}
+		clear(sw.waiters[n:])
+		sw.waiters = sw.waiters[:n]
+		if n == 0 {
+			sw.running = false
+			sw.mtx.Unlock()
+			sw.waitMtx.Unlock()
+			return
+		}
+		semaphores := make([]C.VkSemaphore, n)
+		values := make([]C.uint64_t, n)
+		for i, w := range sw.waiters {
+			semaphores[i] = w.semaphore.vkSemaphore
+			values[i] = w.value
+		}
+		sw.mtx.Unlock()
+
+		// new waiters are picked up after at most waitSliceTimeout
+		C.vxr_vk_waitSemaphores(instance.cInstance, C.uint32_t(n), unsafe.SliceData(semaphores),
+			unsafe.SliceData(values), vk.TRUE, C.uint64_t(waitSliceTimeout.Nanoseconds()))
+		runtime.KeepAlive(semaphores)
+		runtime.KeepAlive(values)
+		sw.waitMtx.Unlock()
+	}
+}
+
+// forget closes the channels of the waiters of s, s must have reached every value it will be waited on for.
+func (sw *semaphoreWatcher) forget(s *TimelineSemaphore) {
+	sw.waitMtx.Lock()
+	defer sw.waitMtx.Unlock()
+	sw.mtx.Lock()
+	defer sw.mtx.Unlock()
+	n := 0
+	for _, w := range sw.waiters {
+		if w.semaphore == s {
+			close(w.done)
+			continue
+		}
+		sw.waiters[n] = w
+		n++
+	}
+	clear(sw.waiters[n:])
+	sw.waiters = sw.waiters[:n]
+}
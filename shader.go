@@ -24,6 +24,8 @@ package vxr
 import "C"
 
 import (
+	"math"
+	"reflect"
 	"strings"
 
 	"goarrg.com/debug"
@@ -76,7 +78,10 @@ func (l ShaderEntryPointComputeLayout) EntryPointName() string  { return l.Name
 func (ShaderEntryPointComputeLayout) ShaderStage() ShaderStage  { return ShaderStageCompute }
 func (ShaderEntryPointComputeLayout) isShaderEntryPointLayout() {}
 
-type ShaderEntryPointVertexLayout struct{ Name string }
+type ShaderEntryPointVertexLayout struct {
+	Name          string
+	UsesViewIndex bool
+}
 
 var _ ShaderEntryPointLayout = ShaderEntryPointVertexLayout{}
 
@@ -87,6 +92,7 @@ func (ShaderEntryPointVertexLayout) isShaderEntryPointLayout() {}
 type ShaderEntryPointFragmentLayout struct {
 	Name                      string
 	NumRenderColorAttachments uint32
+	UsesViewIndex             bool
 }
 
 var _ ShaderEntryPointLayout = ShaderEntryPointFragmentLayout{}
@@ -261,10 +267,129 @@ func (l *ShaderLayout) Validate() error {
 	return nil
 }
 
+type ShaderConstantType C.vxr_vk_shader_constantType
+
+const (
+	ShaderConstantTypeUnknown ShaderConstantType = C.vxr_vk_shader_constantType_unknown
+	ShaderConstantTypeBool    ShaderConstantType = C.vxr_vk_shader_constantType_bool
+	ShaderConstantTypeInt     ShaderConstantType = C.vxr_vk_shader_constantType_int
+	ShaderConstantTypeUint    ShaderConstantType = C.vxr_vk_shader_constantType_uint
+	ShaderConstantTypeFloat   ShaderConstantType = C.vxr_vk_shader_constantType_float
+)
+
+func (t ShaderConstantType) String() string {
+	switch t {
+	case ShaderConstantTypeBool:
+		return "bool"
+	case ShaderConstantTypeInt:
+		return "int"
+	case ShaderConstantTypeUint:
+		return "uint"
+	case ShaderConstantTypeFloat:
+		return "float"
+	default:
+		return "unknown"
+	}
+}
+
 type ShaderMetadata struct {
 	SpecConstants []struct {
 		Name    string
 		Default uint32
 	}
+	// SpecConstantTypes is indexed the same as SpecConstants,
+	// it may be shorter for metadata generated before types were reflected in which case the type is unknown.
+	SpecConstantTypes     []ShaderConstantType
 	DescriptorSetBindings map[string]ShaderBindingMetadata
 }
+
+/*
+SpecConstantValues returns the positional SpecConstants to be used in PipelineLayoutCreateInfo, ComputePipelineCreateInfo
+and GraphicsShaderPipelineCreateInfo, every constant not named in values is set to its default.
+Names must exist in the shader and values must match the type of the constant:
+bool constants take a bool, int and uint constants take any integer that fits and float constants take a float32 or float64.
+Constants of unknown type take any of the above.
+*/
+func (m *ShaderMetadata) SpecConstantValues(values map[string]any) ([]uint32, error) {
+	specConstants := make([]uint32, len(m.SpecConstants))
+	ids := make(map[string]int, len(m.SpecConstants))
+	for i, c := range m.SpecConstants {
+		specConstants[i] = c.Default
+		if c.Name != "" {
+			ids[c.Name] = i
+		}
+	}
+
+	for name, value := range values {
+		id, ok := ids[name]
+		if !ok {
+			return nil, debug.Errorf("Shader has no spec constant named %q", name)
+		}
+		t := ShaderConstantTypeUnknown
+		if id < len(m.SpecConstantTypes) {
+			t = m.SpecConstantTypes[id]
+		}
+		v, err := specConstantValue(t, value)
+		if err != nil {
+			return nil, debug.ErrorWrapf(err, "Invalid value for spec constant %q", name)
+		}
+		specConstants[id] = v
+	}
+
+	return specConstants, nil
+}
+
+func specConstantValue(t ShaderConstantType, value any) (uint32, error) {
+	switch v := value.(type) {
+	case bool:
+		if t != ShaderConstantTypeBool && t != ShaderConstantTypeUnknown {
+			return 0, debug.Errorf("%T is not assignable to a %s constant", value, t.String())
+		}
+		if v {
+			return vk.TRUE, nil
+		}
+		return vk.FALSE, nil
+
+	case float32, float64:
+		if t != ShaderConstantTypeFloat && t != ShaderConstantTypeUnknown {
+			return 0, debug.Errorf("%T is not assignable to a %s constant", value, t.String())
+		}
+		f, _ := v.(float32)
+		if d, ok := v.(float64); ok {
+			f = float32(d)
+		}
+		return math.Float32bits(f), nil
+
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		i := reflect.ValueOf(v)
+		switch t {
+		case ShaderConstantTypeInt:
+			if i.CanInt() && i.Int() >= math.MinInt32 && i.Int() <= math.MaxInt32 {
+				return uint32(int32(i.Int())), nil
+			}
+			if i.CanUint() && i.Uint() <= math.MaxInt32 {
+				return uint32(i.Uint()), nil
+			}
+		case ShaderConstantTypeUint:
+			if i.CanInt() && i.Int() >= 0 && i.Int() <= math.MaxUint32 {
+				return uint32(i.Int()), nil
+			}
+			if i.CanUint() && i.Uint() <= math.MaxUint32 {
+				return uint32(i.Uint()), nil
+			}
+		case ShaderConstantTypeUnknown:
+			if i.CanInt() && i.Int() >= math.MinInt32 && i.Int() <= math.MaxUint32 {
+				return uint32(i.Int()), nil
+			}
+			if i.CanUint() && i.Uint() <= math.MaxUint32 {
+				return uint32(i.Uint()), nil
+			}
+		default:
+			return 0, debug.Errorf("%T is not assignable to a %s constant", value, t.String())
+		}
+		return 0, debug.Errorf("%v overflows a %s constant", value, t.String())
+
+	default:
+		return 0, debug.Errorf("%T is not a valid spec constant type", value)
+	}
+}
@@ -33,25 +33,54 @@ package vxr
 import "C"
 
 import (
+	"embed"
+	"io"
 	"path"
 	"runtime"
 	"runtime/cgo"
 	"slices"
+	"strings"
 	"unsafe"
 
 	"goarrg.com/asset"
+	"goarrg.com/debug"
 	"goarrg.com/rhi/vxr/internal/vk"
 )
 
 type shaderCompileState struct {
 	fs    *asset.FileSystem
 	files []*asset.File
+	// sources maps every file opened during compilation to a hash of its contents,
+	// files that failed to open are recorded with a zero hash.
+	sources map[string]hashKey
+	cErrors []*C.char
+	// cBuiltins holds the contents of the builtin includes given to the compiler
+	cBuiltins []unsafe.Pointer
 }
 
+// builtinIncludes are the files served for #include <vxr/...>, e.g. <vxr/bindless.glsl>.
+//
+//go:embed glsl/vxr
+var builtinIncludes embed.FS
+
 func (s *shaderCompileState) destroy() {
 	for _, f := range s.files {
 		f.Close()
 	}
+	for _, e := range s.cErrors {
+		C.free(unsafe.Pointer(e))
+	}
+	for _, b := range s.cBuiltins {
+		C.free(b)
+	}
+}
+
+func hashShaderSource(f io.Reader) hashKey {
+	h := newHasher()
+	if _, err := io.Copy(&h, f); err != nil {
+		return 0
+	}
+	return h.sum
 }
 
 //export goShaderIncludeResolver
@@ -65,14 +94,38 @@ func goShaderIncludeResolver(data C.uintptr_t, cTarget *C.char, t C.vxr_vk_shade
 		target = C.GoString(cTarget)
 	}
 
-	cTarget = C.CString(target)
 	s := cgo.Handle(data).Value().(*shaderCompileState)
+
+	// builtin includes never change so they are not recorded in sources
+	if t == C.vxr_vk_shader_includeType_system && strings.HasPrefix(target, "vxr/") {
+		if content, err := builtinIncludes.ReadFile(path.Join("glsl", target)); err == nil {
+			cContent := C.CBytes(content)
+			s.cBuiltins = append(s.cBuiltins, cContent)
+			return C.vxr_vk_shader_includeResult{
+				nameSize:    C.size_t(len(target)),
+				name:        C.CString(target),
+				contentSize: C.size_t(len(content)),
+				content:     C.uintptr_t(uintptr(cContent)),
+			}
+		}
+	}
+
 	f, err := s.fs.Open(target)
 	if err != nil {
-		abort("%s", err)
+		// an empty name tells shaderc the include failed and the content is the error message
+		s.sources[target] = 0
+		cErr := C.CString(err.Error())
+		s.cErrors = append(s.cErrors, cErr)
+		return C.vxr_vk_shader_includeResult{
+			name:        C.CString(""),
+			contentSize: C.size_t(len(err.Error())),
+			content:     C.uintptr_t(uintptr(unsafe.Pointer(cErr))),
+		}
 	}
+	cTarget = C.CString(target)
 	a := f.(*asset.File)
 	s.files = append(s.files, a)
+	s.sources[target] = hashShaderSource(f)
 	return C.vxr_vk_shader_includeResult{
 		nameSize:    C.size_t(len(target)),
 		name:        cTarget,
@@ -123,19 +176,30 @@ type ShaderMacro struct {
 }
 
 func CompileShader(fs *asset.FileSystem, name string, macros ...ShaderMacro) (*Shader, *ShaderLayout, *ShaderMetadata) {
+	shader, layout, reflection, _, err := compileShader(fs, name, macros)
+	if err != nil {
+		abort("%s", err)
+	}
+	return shader, layout, reflection
+}
+
+// compileShader is CompileShader that returns errors instead of aborting,
+// it also returns a hash of every source file the shader was built from.
+func compileShader(fs *asset.FileSystem, name string, macros []ShaderMacro) (*Shader, *ShaderLayout, *ShaderMetadata, map[string]hashKey, error) {
 	instance.logger.VPrintf("Compiling shader: %q", name)
 
 	f, err := fs.Open(name)
 	if err != nil {
-		abort("%s", err)
+		return nil, nil, nil, map[string]hashKey{name: 0}, debug.ErrorWrapf(err, "Failed to open shader %q", name)
 	}
 	a := f.(*asset.File)
 	cName := C.CString(name)
 	defer C.free(unsafe.Pointer(cName))
 
 	s := shaderCompileState{
-		fs:    fs,
-		files: []*asset.File{a},
+		fs:      fs,
+		files:   []*asset.File{a},
+		sources: map[string]hashKey{name: hashShaderSource(f)},
 	}
 	defer s.destroy()
 	h := cgo.NewHandle(&s)
@@ -174,7 +238,17 @@ func CompileShader(fs *asset.FileSystem, name string, macros ...ShaderMacro) (*S
 		resultReleaser:  C.vxr_vk_shaderIncludeResultReleaser(C.goShaderIncludeResultRelease),
 		userdata:        C.uintptr_t(h),
 	}
-	C.vxr_vk_shader_compile(instance.cShaderCompiler, info, &cResult, &cReflection)
+	{
+		var cError C.vxr_vk_shader_compileError
+		if C.vxr_vk_shader_tryCompile(instance.cShaderCompiler, info, &cResult, &cReflection, &cError) != vk.TRUE {
+			var messageSize C.size_t
+			var message *C.char
+			C.vxr_vk_shader_compileError_getMessage(cError, &messageSize, &message)
+			err := debug.Errorf("Failed to compile shader %q: %s", name, C.GoStringN(message, C.int(messageSize)))
+			C.vxr_vk_shader_destroyCompileError(cError)
+			return nil, nil, nil, s.sources, err
+		}
+	}
 	defer C.vxr_vk_shader_destroyCompileResult(cResult)
 
 	shader := Shader{
@@ -225,13 +299,21 @@ func CompileShader(fs *asset.FileSystem, name string, macros ...ShaderMacro) (*S
 					},
 				}
 			case ShaderStageVertex:
-				layout.EntryPoints[entryPointName] = ShaderEntryPointVertexLayout{Name: entryPointName}
+				var cUsesViewIndex C.VkBool32
+				C.vxr_vk_shader_reflectResult_getUsesViewIndex(cReflection, C.size_t(i), &cUsesViewIndex)
+				layout.EntryPoints[entryPointName] = ShaderEntryPointVertexLayout{
+					Name:          entryPointName,
+					UsesViewIndex: cUsesViewIndex == vk.TRUE,
+				}
 			case ShaderStageFragment:
 				var cNumOutput C.uint32_t
+				var cUsesViewIndex C.VkBool32
 				C.vxr_vk_shader_reflectResult_getNumOutputs(cReflection, C.size_t(i), &cNumOutput)
+				C.vxr_vk_shader_reflectResult_getUsesViewIndex(cReflection, C.size_t(i), &cUsesViewIndex)
 				layout.EntryPoints[entryPointName] = ShaderEntryPointFragmentLayout{
 					Name:                      entryPointName,
 					NumRenderColorAttachments: uint32(cNumOutput),
+					UsesViewIndex:             cUsesViewIndex == vk.TRUE,
 				}
 			default:
 				layout.EntryPoints[entryPointName] = ShaderEntryPointUnknownLayout{
@@ -257,6 +339,7 @@ func CompileShader(fs *asset.FileSystem, name string, macros ...ShaderMacro) (*S
 				Name:    C.GoString(c.name),
 				Default: uint32(c.value),
 			})
+			reflection.SpecConstantTypes = append(reflection.SpecConstantTypes, ShaderConstantType(c._type))
 		}
 	}
 
@@ -328,16 +411,16 @@ func CompileShader(fs *asset.FileSystem, name string, macros ...ShaderMacro) (*S
 
 					case vk.DESCRIPTOR_TYPE_MAX_ENUM:
 						if info.count.value != 0 || info.count.isSpecConstant == vk.TRUE {
-							abort("Unknown DescriptorType at set [%d] binding [%d]", set, binding)
+							return nil, nil, nil, s.sources, debug.Errorf("Unknown DescriptorType at set [%d] binding [%d]", set, binding)
 						}
 
 					default:
-						abort("Descriptor type: %s is unimplemented", DescriptorType(info._type))
+						return nil, nil, nil, s.sources, debug.Errorf("Descriptor type: %s is unimplemented", toHex(info._type))
 					}
 				}
 			}
 		}
 	}
 
-	return &shader, &layout, &reflection
+	return &shader, &layout, &reflection, s.sources, nil
 }
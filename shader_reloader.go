@@ -0,0 +1,309 @@
+//go:build !goarrg_vxr_disable_shadercompiler
+// +build !goarrg_vxr_disable_shadercompiler
+
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"errors"
+	iofs "io/fs"
+	"slices"
+	"time"
+
+	"goarrg.com/asset"
+	"goarrg.com/debug"
+	"goarrg.com/gmath"
+	"goarrg.com/rhi/vxr/internal/util"
+)
+
+type reloadableGraphicsPipeline struct {
+	pipeline       *GraphicsShaderPipeline
+	layout         *PipelineLayout
+	entryPointName string
+	info           GraphicsShaderPipelineCreateInfo
+}
+
+type reloadableComputePipeline struct {
+	pipeline       *ComputePipeline
+	entryPointName string
+	info           ComputePipelineCreateInfo
+}
+
+// sourceStat is what Poll compares to tell if a source may have changed without reading it.
+type sourceStat struct {
+	modTime time.Time
+	size    int64
+}
+
+type reloadableShader struct {
+	name    string
+	macros  []ShaderMacro
+	sources map[string]hashKey
+	// stats are the stats of the sources when they were last hashed, only sources whose stat changed are hashed again.
+	stats map[string]sourceStat
+
+	shader     *Shader
+	layout     *ShaderLayout
+	reflection *ShaderMetadata
+
+	graphicsPipelines []reloadableGraphicsPipeline
+	computePipelines  []reloadableComputePipeline
+}
+
+/*
+ShaderReloader watches the sources of the shaders compiled through it, including every file pulled in with #include,
+and rebuilds the pipelines created from them through it when any of those sources change.
+
+Reloading happens during Poll, which must be called from the goroutine that records commands while no command buffer is
+recording, e.g. before FrameBegin. A shader that fails to compile or whose new layout is incompatible with the
+PipelineLayout of any of its pipelines is reported and leaves every one of its pipelines untouched, otherwise all of
+its pipelines are replaced together and the old ones are destroyed once the frame they were last used in has finished.
+*/
+type ShaderReloader struct {
+	noCopy  util.NoCopy
+	fs      *asset.FileSystem
+	shaders []*reloadableShader
+}
+
+func NewShaderReloader(fs *asset.FileSystem) *ShaderReloader {
+	r := &ShaderReloader{fs: fs}
+	r.noCopy.Init()
+	return r
+}
+
+/*
+CompileShader is eq to the global CompileShader except the shader is watched for changes,
+the returned values are updated in place every time the shader is reloaded.
+*/
+func (r *ShaderReloader) CompileShader(name string, macros ...ShaderMacro) (*Shader, *ShaderLayout, *ShaderMetadata) {
+	r.noCopy.Check()
+	shader, layout, reflection, sources, err := compileShader(r.fs, name, macros)
+	if err != nil {
+		abort("%s", err)
+	}
+	r.shaders = append(r.shaders, &reloadableShader{
+		name:    name,
+		macros:  slices.Clone(macros),
+		sources: sources,
+
+		shader:     shader,
+		layout:     layout,
+		reflection: reflection,
+	})
+	return shader, layout, reflection
+}
+
+func (r *ShaderReloader) findShader(s *Shader) *reloadableShader {
+	for _, shader := range r.shaders {
+		if shader.shader == s {
+			return shader
+		}
+	}
+	abort("Shader %q was not compiled by this ShaderReloader", s.ID)
+	return nil
+}
+
+func (r *ShaderReloader) NewGraphicsShaderPipeline(pipelineLayout *PipelineLayout, s *Shader, entryPoint ShaderEntryPointLayout, info GraphicsShaderPipelineCreateInfo) *GraphicsShaderPipeline {
+	r.noCopy.Check()
+	shader := r.findShader(s)
+	specConstants, err := pipelineLayout.resolveSpecConstants(entryPoint.ShaderStage(), info.SpecConstants)
+	if err != nil {
+		abort("Failed to validate GraphicsShaderPipelineCreateInfo.SpecConstants: %s", err)
+	}
+	info.SpecConstants = slices.Clone(specConstants)
+	p := NewGraphicsShaderPipeline(pipelineLayout, s, entryPoint, info)
+	shader.graphicsPipelines = append(shader.graphicsPipelines, reloadableGraphicsPipeline{
+		pipeline:       p,
+		layout:         pipelineLayout,
+		entryPointName: entryPoint.EntryPointName(),
+		info:           info,
+	})
+	return p
+}
+
+func (r *ShaderReloader) NewComputePipeline(pipelineLayout *PipelineLayout, s *Shader, entryPoint ShaderEntryPointLayout, info ComputePipelineCreateInfo) *ComputePipeline {
+	r.noCopy.Check()
+	shader := r.findShader(s)
+	specConstants, err := pipelineLayout.resolveSpecConstants(ShaderStageCompute, info.SpecConstants)
+	if err != nil {
+		abort("Failed to validate ComputePipelineCreateInfo.SpecConstants: %s", err)
+	}
+	info.SpecConstants = slices.Clone(specConstants)
+	p := NewComputePipeline(pipelineLayout, s, entryPoint, info)
+	shader.computePipelines = append(shader.computePipelines, reloadableComputePipeline{
+		pipeline:       p,
+		entryPointName: entryPoint.EntryPointName(),
+		info:           info,
+	})
+	return p
+}
+
+/*
+Poll checks every watched shader for changes and reloads the ones that changed,
+the returned error contains every shader that failed to reload and is nil if none failed.
+Sources are only read and hashed again when their modification time or size changed.
+*/
+func (r *ShaderReloader) Poll() error {
+	r.noCopy.Check()
+	var errs []error
+	for _, s := range r.shaders {
+		s.prune()
+		if !s.changed(r.fs) {
+			continue
+		}
+		if err := s.reload(r.fs); err != nil {
+			instance.logger.EPrintf("Failed to reload shader %q: %v", s.name, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+/*
+Destroy stops watching all shaders, pipelines created through the ShaderReloader remain valid
+and must still be destroyed by the caller.
+*/
+func (r *ShaderReloader) Destroy() {
+	if r == nil {
+		return
+	}
+	r.noCopy.Check()
+	r.shaders = nil
+	r.noCopy.Close()
+}
+
+// prune forgets pipelines that have been destroyed since the last poll.
+func (s *reloadableShader) prune() {
+	s.graphicsPipelines = slices.DeleteFunc(s.graphicsPipelines, func(p reloadableGraphicsPipeline) bool {
+		return p.pipeline.vkPipeline == nil
+	})
+	s.computePipelines = slices.DeleteFunc(s.computePipelines, func(p reloadableComputePipeline) bool {
+		return p.pipeline.vkPipeline == nil
+	})
+}
+
+func (s *reloadableShader) changed(fs *asset.FileSystem) bool {
+	if s.stats == nil {
+		s.stats = map[string]sourceStat{}
+	}
+	for name, sum := range s.sources {
+		info, err := iofs.Stat(fs, name)
+		if err != nil {
+			delete(s.stats, name)
+			if sum != 0 {
+				return true
+			}
+			continue
+		}
+		stat := sourceStat{modTime: info.ModTime(), size: info.Size()}
+		if old, ok := s.stats[name]; ok && old.modTime.Equal(stat.modTime) && old.size == stat.size {
+			continue
+		}
+		// recorded before hashing so an edit racing with the hash still changes the stat for the next poll
+		s.stats[name] = stat
+
+		f, err := fs.Open(name)
+		if err != nil {
+			if sum != 0 {
+				return true
+			}
+			continue
+		}
+		newSum := hashShaderSource(f)
+		f.Close()
+		if newSum != sum {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *reloadableShader) reload(fs *asset.FileSystem) error {
+	instance.logger.IPrintf("Reloading shader: %q", s.name)
+
+	shader, layout, reflection, sources, err := compileShader(fs, s.name, s.macros)
+	// remember what was compiled even on failure so a broken edit is only reported once
+	s.sources = sources
+	s.stats = nil
+	if err != nil {
+		return err
+	}
+
+	// validate everything before touching any pipeline so a bad edit leaves all of them intact
+	graphicsEntryPoints := make([]ShaderEntryPointLayout, len(s.graphicsPipelines))
+	for i, p := range s.graphicsPipelines {
+		entryPoint, ok := layout.EntryPoints[p.entryPointName]
+		if !ok || entryPoint.ShaderStage() != p.pipeline.stage {
+			return debug.Errorf("Shader %q no longer has a %s entry point named %q", s.name, p.pipeline.stage.String(), p.entryPointName)
+		}
+		if err := p.layout.validateShaderLayout(layout, entryPoint.ShaderStage(), p.info.SpecConstants); err != nil {
+			return debug.ErrorWrapf(err, "Shader %q is incompatible with pipeline %s", s.name, p.pipeline.name)
+		}
+		graphicsEntryPoints[i] = entryPoint
+	}
+	computeEntryPoints := make([]ShaderEntryPointLayout, len(s.computePipelines))
+	computeLocalSizes := make([]gmath.Extent3u32, len(s.computePipelines))
+	for i, p := range s.computePipelines {
+		entryPoint, ok := layout.EntryPoints[p.entryPointName]
+		if !ok || entryPoint.ShaderStage() != ShaderStageCompute {
+			return debug.Errorf("Shader %q no longer has a %s entry point named %q", s.name, ShaderStageCompute.String(), p.entryPointName)
+		}
+		if err := p.pipeline.layout.validateShaderLayout(layout, ShaderStageCompute, p.info.SpecConstants); err != nil {
+			return debug.ErrorWrapf(err, "Shader %q is incompatible with pipeline %s", s.name, p.pipeline.name)
+		}
+		localSize, err := computeLocalSize(entryPoint, p.info)
+		if err != nil {
+			return debug.ErrorWrapf(err, "Shader %q is incompatible with pipeline %s", s.name, p.pipeline.name)
+		}
+		computeEntryPoints[i] = entryPoint
+		computeLocalSizes[i] = localSize
+	}
+
+	for i, p := range s.graphicsPipelines {
+		old := p.pipeline.vkPipeline
+		p.pipeline.vkPipeline = createGraphicsShaderPipeline(p.pipeline.name, p.layout, shader, graphicsEntryPoints[i], p.info)
+		// also takes care of every executable pipeline linked with the old library
+		instance.graphics.pipelineCache.destroyPipeline(p.pipeline.name, old)
+	}
+	for i, p := range s.computePipelines {
+		old := p.pipeline.vkPipeline
+		name := p.pipeline.name
+		p.pipeline.vkPipeline = createComputePipeline(name, p.pipeline.layout, shader, computeEntryPoints[i], p.info)
+		p.pipeline.localSize = computeLocalSizes[i]
+		go func() {
+			instance.graphics.destroyerChan <- destroyFunc{
+				func() {
+					instance.logger.VPrintf("Destroying pipeline: %s", name)
+					C.vxr_vk_shader_destroyPipeline(instance.cInstance, old)
+				},
+			}
+		}()
+	}
+
+	*s.shader = *shader
+	*s.layout = *layout
+	*s.reflection = *reflection
+	return nil
+}
@@ -78,8 +78,11 @@ type CommandBuffer2D struct {
 func (cb *CommandBuffer2D) Begin() {
 	if cb.noCopy.InitLazy() {
 		cb.descriptorSetTextures = instance.solid2DPipeline.Layout.NewDescriptorSet(1)
-		cb.descriptorSetTextures.Bind(0, 0, instance.linearSampler)
-		cb.managedTextureBindings = managed.NewDescriptorArrayImage(cb.descriptorSetTextures, 1)
+		if err := cb.descriptorSetTextures.BindByName(instance.solid2DFragmentMetadata, "textureSampler", 0, instance.linearSampler); err != nil {
+			abort("Failed to bind texture sampler: %s", err)
+		}
+		cb.managedTextureBindings = managed.NewDescriptorArrayImage(cb.descriptorSetTextures,
+			instance.solid2DFragmentMetadata.DescriptorSetBindings["textures"].Info().Binding)
 	}
 	if cb.cbState == cbRecording {
 		abort("Begin() called while CommandBuffer2D is not idle")
@@ -158,12 +161,16 @@ func (cb *CommandBuffer2D) ExecutePrePass(frame *vxr.Frame, vcb *vxr.GraphicsCom
 				vxr.BufferUsageStorageBuffer|vxr.BufferUsageIndirectBuffer|vxr.BufferUsageTransferDst)
 		}
 
-		cb.descriptorSetDrawInfo.Bind(0, 0, vxr.DescriptorBufferInfo{
+		if err := cb.descriptorSetDrawInfo.BindByName(instance.solid2DMetadata, "Objects", 0, vxr.DescriptorBufferInfo{
 			Buffer: cb.objectBuffer,
-		})
-		cb.descriptorSetDrawInfo.Bind(1, 0, vxr.DescriptorBufferInfo{
+		}); err != nil {
+			abort("Failed to bind object buffer: %s", err)
+		}
+		if err := cb.descriptorSetDrawInfo.BindByName(instance.solid2DMetadata, "Triangles", 0, vxr.DescriptorBufferInfo{
 			Buffer: cb.triangleBuffer,
-		})
+		}); err != nil {
+			abort("Failed to bind triangle buffer: %s", err)
+		}
 
 		vcb.BufferBarrier(vxr.BufferBarrier{
 			Buffer: cb.objectBuffer,
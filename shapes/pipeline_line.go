@@ -73,9 +73,11 @@ func (p *Pipeline2DLine) Draw(f *vxr.Frame, cb *vxr.GraphicsCommandBuffer, frag
 				(instance.line2DVertexShaderObjectMetadata.RuntimeArrayStride*uint64(len(instances))),
 			vxr.BufferUsageStorageBuffer,
 		)
-		ds.Bind(0, 0, vxr.DescriptorBufferInfo{
+		if err := ds.BindByName(instance.line2DVertexShaderMetadata, "Objects", 0, vxr.DescriptorBufferInfo{
 			Buffer: b,
-		})
+		}); err != nil {
+			abort("Failed to bind object buffer: %s", err)
+		}
 
 		var off uintptr
 		s := gmath.Vector2f32{X: 2 / float32(viewport.X), Y: 2 / float32(viewport.Y)}
@@ -149,9 +151,11 @@ func (p *Pipeline2DLineStrip) Draw(f *vxr.Frame, cb *vxr.GraphicsCommandBuffer,
 				(instance.lineStrip2DVertexShaderObjectMetadata.RuntimeArrayStride*uint64(len(points))),
 			vxr.BufferUsageStorageBuffer,
 		)
-		ds.Bind(0, 0, vxr.DescriptorBufferInfo{
+		if err := ds.BindByName(instance.lineStrip2DVertexShaderMetadata, "Objects", 0, vxr.DescriptorBufferInfo{
 			Buffer: b,
-		})
+		}); err != nil {
+			abort("Failed to bind object buffer: %s", err)
+		}
 
 		var off uintptr
 		s := gmath.Vector2f32{X: 2 / float32(viewport.X), Y: 2 / float32(viewport.Y)}
@@ -208,9 +208,11 @@ func (p *Pipeline2D) Draw(f *vxr.Frame, cb *vxr.GraphicsCommandBuffer, frag *vxr
 				(instance.poly2DVertexShaderObjectMetadata.RuntimeArrayStride*uint64(len(instances))),
 			vxr.BufferUsageStorageBuffer,
 		)
-		ds.Bind(0, 0, vxr.DescriptorBufferInfo{
+		if err := ds.BindByName(instance.poly2DVertexShaderMetadata, "Objects", 0, vxr.DescriptorBufferInfo{
 			Buffer: b,
-		})
+		}); err != nil {
+			abort("Failed to bind object buffer: %s", err)
+		}
 		var off uintptr
 		for _, i := range instances {
 			m := i.Transform.modelMatrix(p.mode, p.triangleCount)
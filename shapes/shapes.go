@@ -1,6 +1,6 @@
 //go:generate go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go  -O -Os -strip main.comp
 //go:generate go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -skip-metadata -O -Os -strip main.vert
-//go:generate go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -skip-metadata -O -Os -strip main.frag
+//go:generate go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -O -Os -strip main.frag
 
 //go:generate go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -O -Os -strip pipeline_poly.vert
 //go:generate go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -O -Os -strip pipeline_line.vert
@@ -60,22 +60,27 @@ var instance = struct {
 
 	dispatcher                    *vxr.ComputePipeline
 	solid2DPipeline               vxr.GraphicsPipelineLibrary
+	solid2DMetadata               *vxr.ShaderMetadata
 	solid2DObjectBufferMetadata   vxr.ShaderBindingTypeBufferMetadata
 	solid2DTriangleBufferMetadata vxr.ShaderBindingTypeBufferMetadata
+	solid2DFragmentMetadata       *vxr.ShaderMetadata
 
 	poly2DVertexInputPipeline        *vxr.VertexInputPipeline
 	poly2DVertexShader               *vxr.Shader
 	poly2DVertexShaderLayout         *vxr.ShaderLayout
+	poly2DVertexShaderMetadata       *vxr.ShaderMetadata
 	poly2DVertexShaderObjectMetadata vxr.ShaderBindingTypeBufferMetadata
 
 	line2DVertexInputPipeline        *vxr.VertexInputPipeline
 	line2DVertexShader               *vxr.Shader
 	line2DVertexShaderLayout         *vxr.ShaderLayout
+	line2DVertexShaderMetadata       *vxr.ShaderMetadata
 	line2DVertexShaderObjectMetadata vxr.ShaderBindingTypeBufferMetadata
 
 	lineStrip2DVertexInputPipeline        *vxr.VertexInputPipeline
 	lineStrip2DVertexShader               *vxr.Shader
 	lineStrip2DVertexShaderLayout         *vxr.ShaderLayout
+	lineStrip2DVertexShaderMetadata       *vxr.ShaderMetadata
 	lineStrip2DVertexShaderObjectMetadata vxr.ShaderBindingTypeBufferMetadata
 }{
 	logger: debug.NewLogger("vxr", "shapes"),
@@ -115,7 +120,9 @@ func Init(c Config) {
 	{
 		cs, cl, m := vxrcLoad_main_comp()
 		vs, vl := vxrcLoad_main_vert()
-		fs, fl := vxrcLoad_main_frag()
+		fs, fl, fm := vxrcLoad_main_frag()
+		instance.solid2DMetadata = m
+		instance.solid2DFragmentMetadata = fm
 		instance.solid2DObjectBufferMetadata = m.DescriptorSetBindings["Objects"].(vxr.ShaderBindingTypeBufferMetadata)
 		instance.solid2DTriangleBufferMetadata = m.DescriptorSetBindings["Triangles"].(vxr.ShaderBindingTypeBufferMetadata)
 
@@ -131,18 +138,21 @@ func Init(c Config) {
 				SpecConstants: []uint32{uint32(c.Limits.PerCommandBuffer2D.MaxTextures)},
 			},
 		)
+		dispatcherSpecConstants, err := m.SpecConstantValues(map[string]any{"local_size_x": properties.Compute.SubgroupSize})
+		if err != nil {
+			abort("Failed to specialize dispatcher: %s", err)
+		}
 		instance.dispatcher = vxr.NewComputePipeline(instance.solid2DPipeline.Layout, cs, cl.EntryPoints["main"], vxr.ComputePipelineCreateInfo{
-			SpecConstants: []uint32{properties.Compute.SubgroupSize, 1, 1},
+			SpecConstants: dispatcherSpecConstants,
 		})
 		instance.solid2DPipeline.VertexInput = vxr.NewVertexInputPipeline(vxr.VertexInputPipelineCreateInfo{
 			Topology: vxr.VertexTopologyTriangleList,
 		})
 		instance.solid2DPipeline.VertexShader = vxr.NewGraphicsShaderPipeline(instance.solid2DPipeline.Layout,
 			vs, vl.EntryPoints["main"], vxr.GraphicsShaderPipelineCreateInfo{})
+		// inherits the texture count from the layout
 		instance.solid2DPipeline.FragmentShader = vxr.NewGraphicsShaderPipeline(instance.solid2DPipeline.Layout,
-			fs, fl.EntryPoints["main"], vxr.GraphicsShaderPipelineCreateInfo{
-				SpecConstants: []uint32{uint32(c.Limits.PerCommandBuffer2D.MaxTextures)},
-			})
+			fs, fl.EntryPoints["main"], vxr.GraphicsShaderPipelineCreateInfo{})
 	}
 
 	// poly2DPipeline
@@ -152,6 +162,7 @@ func Init(c Config) {
 		})
 		var m *vxr.ShaderMetadata
 		instance.poly2DVertexShader, instance.poly2DVertexShaderLayout, m = vxrcLoad_pipeline_poly_vert()
+		instance.poly2DVertexShaderMetadata = m
 		instance.poly2DVertexShaderObjectMetadata = m.DescriptorSetBindings["Objects"].(vxr.ShaderBindingTypeBufferMetadata)
 	}
 
@@ -162,6 +173,7 @@ func Init(c Config) {
 		})
 		var m *vxr.ShaderMetadata
 		instance.line2DVertexShader, instance.line2DVertexShaderLayout, m = vxrcLoad_pipeline_line_vert()
+		instance.line2DVertexShaderMetadata = m
 		instance.line2DVertexShaderObjectMetadata = m.DescriptorSetBindings["Objects"].(vxr.ShaderBindingTypeBufferMetadata)
 	}
 
@@ -172,6 +184,7 @@ func Init(c Config) {
 		})
 		var m *vxr.ShaderMetadata
 		instance.lineStrip2DVertexShader, instance.lineStrip2DVertexShaderLayout, m = vxrcLoad_pipeline_linestrip_vert()
+		instance.lineStrip2DVertexShaderMetadata = m
 		instance.lineStrip2DVertexShaderObjectMetadata = m.DescriptorSetBindings["Objects"].(vxr.ShaderBindingTypeBufferMetadata)
 	}
 }
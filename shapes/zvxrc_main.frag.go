@@ -1,4 +1,4 @@
-// go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -skip-metadata -O -Os -strip main.frag 
+// go run goarrg.com/rhi/vxr/cmd/vxrc -id-prefix=vxr/shapes/ -dir=./ -generator=go -O -Os -strip main.frag 
 // Code generated by the command above; DO NOT EDIT.
 
 package shapes
@@ -7,8 +7,9 @@ import(
 	"goarrg.com/rhi/vxr"
 )
 
-func vxrcLoad_main_frag() (spv *vxr.Shader, layout *vxr.ShaderLayout) {
+func vxrcLoad_main_frag() (spv *vxr.Shader, layout *vxr.ShaderLayout, meta *vxr.ShaderMetadata) {
 	spv = &vxr.Shader{ID:"vxr/shapes/main.frag", SPIRV:[]uint32{0x7230203, 0x10600, 0xd000b, 0x5d, 0x0, 0x20011, 0x1, 0x20011, 0x14b5, 0x20011, 0x14bb, 0x6000b, 0x1, 0x4c534c47, 0x6474732e, 0x3035342e, 0x0, 0x3000e, 0x0, 0x1, 0xb000f, 0x4, 0x4, 0x6e69616d, 0x0, 0x11, 0x15, 0x2c, 0x36, 0x3b, 0x43, 0x30010, 0x4, 0x7, 0x50048, 0xd, 0x0, 0x23, 0x0, 0x50048, 0xd, 0x1, 0x23, 0x4, 0x50048, 0xd, 0x2, 0x23, 0x8, 0x50048, 0xd, 0x3, 0x23, 0xc, 0x50048, 0xd, 0x4, 0x23, 0x10, 0x50048, 0xd, 0x5, 0x23, 0x14, 0x40048, 0xd, 0x6, 0x4, 0x50048, 0xd, 0x6, 0x7, 0xc, 0x50048, 0xd, 0x6, 0x23, 0x18, 0x40047, 0xe, 0x6, 0x30, 0x30047, 0xf, 0x2, 0x40048, 0xf, 0x0, 0x13, 0x40048, 0xf, 0x0, 0x18, 0x50048, 0xf, 0x0, 0x23, 0x0, 0x40048, 0xf, 0x1, 0x13, 0x40048, 0xf, 0x1, 0x18, 0x50048, 0xf, 0x1, 0x23, 0x4, 0x30047, 0x11, 0x13, 0x30047, 0x11, 0x18, 0x40047, 0x11, 0x21, 0x0, 0x40047, 0x11, 0x22, 0x0, 0x30047, 0x15, 0xe, 0x40047, 0x15, 0x1e, 0x0, 0x40047, 0x29, 0x1, 0x0, 0x40047, 0x2c, 0x21, 0x1, 0x40047, 0x2c, 0x22, 0x1, 0x30047, 0x30, 0x14b4, 0x30047, 0x32, 0x14b4, 0x30047, 0x33, 0x14b4, 0x40047, 0x36, 0x21, 0x0, 0x40047, 0x36, 0x22, 0x1, 0x40047, 0x3b, 0x1e, 0x1, 0x40047, 0x43, 0x1e, 0x0, 0x20013, 0x2, 0x30021, 0x3, 0x2, 0x40015, 0x6, 0x20, 0x0, 0x30016, 0x7, 0x20, 0x40017, 0x8, 0x7, 0x2, 0x40018, 0x9, 0x8, 0x3, 0x9001e, 0xa, 0x6, 0x6, 0x6, 0x6, 0x7, 0x6, 0x9, 0x9001e, 0xd, 0x6, 0x6, 0x6, 0x6, 0x7, 0x6, 0x9, 0x3001d, 0xe, 0xd, 0x4001e, 0xf, 0x6, 0xe, 0x40020, 0x10, 0xc, 0xf, 0x4003b, 0x10, 0x11, 0xc, 0x40015, 0x12, 0x20, 0x1, 0x4002b, 0x12, 0x13, 0x1, 0x40020, 0x14, 0x1, 0x6, 0x4003b, 0x14, 0x15, 0x1, 0x40020, 0x17, 0xc, 0xd, 0x4002b, 0x6, 0x1f, 0x80000000, 0x20014, 0x21, 0x40017, 0x25, 0x7, 0x4, 0x90019, 0x28, 0x7, 0x1, 0x0, 0x0, 0x0, 0x1, 0x0, 0x40032, 0x12, 0x29, 0x1, 0x4001c, 0x2a, 0x28, 0x29, 0x40020, 0x2b, 0x0, 0x2a, 0x4003b, 0x2b, 0x2c, 0x0, 0x40020, 0x31, 0x0, 0x28, 0x2001a, 0x34, 0x40020, 0x35, 0x0, 0x34, 0x4003b, 0x35, 0x36, 0x0, 0x3001b, 0x38, 0x28, 0x40020, 0x3a, 0x1, 0x8, 0x4003b, 0x3a, 0x3b, 0x1, 0x40020, 0x42, 0x3, 0x25, 0x4003b, 0x42, 0x43, 0x3, 0x40017, 0x44, 0x7, 0x3, 0x50036, 0x2, 0x4, 0x0, 0x3, 0x200f8, 0x5, 0x4003d, 0x6, 0x16, 0x15, 0x60041, 0x17, 0x18, 0x11, 0x13, 0x16, 0x4003d, 0xd, 0x19, 0x18, 0x40190, 0xa, 0x1a, 0x19, 0x50051, 0x6, 0x58, 0x1a, 0x0, 0x50051, 0x6, 0x59, 0x1a, 0x5, 0x500c7, 0x6, 0x20, 0x58, 0x1f, 0x500aa, 0x21, 0x22, 0x20, 0x1f, 0x300f7, 0x24, 0x0, 0x400fa, 0x22, 0x23, 0x3e, 0x200f8, 0x23, 0x40053, 0x6, 0x30, 0x59, 0x50041, 0x31, 0x32, 0x2c, 0x30, 0x4003d, 0x28, 0x33, 0x32, 0x4003d, 0x34, 0x37, 0x36, 0x50056, 0x38, 0x39, 0x33, 0x37, 0x4003d, 0x8, 0x3c, 0x3b, 0x50057, 0x25, 0x3d, 0x39, 0x3c, 0x200f9, 0x24, 0x200f8, 0x3e, 0x6000c, 0x25, 0x41, 0x1, 0x40, 0x59, 0x200f9, 0x24, 0x200f8, 0x24, 0x700f5, 0x25, 0x5c, 0x3d, 0x23, 0x41, 0x3e, 0x8004f, 0x44, 0x46, 0x5c, 0x5c, 0x0, 0x1, 0x2, 0x50051, 0x7, 0x4a, 0x5c, 0x3, 0x60050, 0x44, 0x4b, 0x4a, 0x4a, 0x4a, 0x50088, 0x44, 0x4c, 0x46, 0x4b, 0x50051, 0x7, 0x4f, 0x4c, 0x0, 0x50051, 0x7, 0x50, 0x4c, 0x1, 0x50051, 0x7, 0x51, 0x4c, 0x2, 0x70050, 0x25, 0x52, 0x4f, 0x50, 0x51, 0x4a, 0x3003e, 0x43, 0x52, 0x100fd, 0x10038}}
-	layout = &vxr.ShaderLayout{EntryPoints:map[string]vxr.ShaderEntryPointLayout{"main":vxr.ShaderEntryPointFragmentLayout{Name:"main", NumRenderColorAttachments:0x1}}, PushConstants:struct { Offset uint32; Size uint32 }{Offset:0x0, Size:0x0}, DescriptorSetLayouts:[][]struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{[]struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{DescriptorType:0x7, DescriptorCount:vxr.ShaderConstant{Value:0x1, IsSpecConstant:false}}}, []struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{DescriptorType:0x0, DescriptorCount:vxr.ShaderConstant{Value:0x1, IsSpecConstant:false}}, struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{DescriptorType:0x2, DescriptorCount:vxr.ShaderConstant{Value:0x0, IsSpecConstant:true}}}}}
+	layout = &vxr.ShaderLayout{EntryPoints:map[string]vxr.ShaderEntryPointLayout{"main":vxr.ShaderEntryPointFragmentLayout{Name:"main", NumRenderColorAttachments:0x1, UsesViewIndex:false}}, PushConstants:struct { Offset uint32; Size uint32 }{Offset:0x0, Size:0x0}, DescriptorSetLayouts:[][]struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{[]struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{DescriptorType:0x7, DescriptorCount:vxr.ShaderConstant{Value:0x1, IsSpecConstant:false}}}, []struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{DescriptorType:0x0, DescriptorCount:vxr.ShaderConstant{Value:0x1, IsSpecConstant:false}}, struct { DescriptorType vxr.DescriptorType; DescriptorCount vxr.ShaderConstant }{DescriptorType:0x2, DescriptorCount:vxr.ShaderConstant{Value:0x0, IsSpecConstant:true}}}}}
+	meta = &vxr.ShaderMetadata{SpecConstants:[]struct { Name string; Default uint32 }{struct { Name string; Default uint32 }{Name:"maxTextureCount", Default:0x1}}, SpecConstantTypes:[]vxr.ShaderConstantType{0x2}, DescriptorSetBindings:map[string]vxr.ShaderBindingMetadata{"Objects":vxr.ShaderBindingTypeBufferMetadata{ShaderBindingInfo:vxr.ShaderBindingInfo{DescriptorType:0x7, Set:0, Binding:0}, Size:0x4, RuntimeArrayStride:0x30}, "textureSampler":vxr.ShaderBindingTypeSamplerMetadata{ShaderBindingInfo:vxr.ShaderBindingInfo{DescriptorType:0x0, Set:1, Binding:0}}, "textures":vxr.ShaderBindingTypeImageMetadata{ShaderBindingInfo:vxr.ShaderBindingInfo{DescriptorType:0x2, Set:1, Binding:1}, ViewType:0x1}}}
 	return
 }
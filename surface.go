@@ -24,42 +24,218 @@ package vxr
 import "C"
 
 import (
+	"fmt"
+	"slices"
+
 	"goarrg.com/gmath"
 	"goarrg.com/rhi/vxr/internal/util"
 	"goarrg.com/rhi/vxr/internal/vk"
 )
 
+type PresentMode C.VkPresentModeKHR
+
+const (
+	// PresentModeImmediate does not wait for vblank and may tear, useful for benchmarking.
+	PresentModeImmediate PresentMode = vk.PRESENT_MODE_IMMEDIATE_KHR
+	// PresentModeMailbox does not tear but replaces the queued image instead of blocking.
+	PresentModeMailbox PresentMode = vk.PRESENT_MODE_MAILBOX_KHR
+	// PresentModeFIFO is vsync and is the only mode every surface supports, it is the fallback when none of the wanted modes are.
+	PresentModeFIFO PresentMode = vk.PRESENT_MODE_FIFO_KHR
+	// PresentModeFIFORelaxed is vsync that may tear when a frame misses vblank.
+	PresentModeFIFORelaxed PresentMode = vk.PRESENT_MODE_FIFO_RELAXED_KHR
+)
+
+func (m PresentMode) String() string {
+	switch m {
+	case PresentModeImmediate:
+		return "Immediate"
+	case PresentModeMailbox:
+		return "Mailbox"
+	case PresentModeFIFO:
+		return "FIFO"
+	case PresentModeFIFORelaxed:
+		return "FIFORelaxed"
+	default:
+		return fmt.Sprintf("Unknown: %d", uint32(m))
+	}
+}
+
+type ColorSpace C.VkColorSpaceKHR
+
+/*
+Every color space other than ColorSpaceSRGBNonlinear requires VK_EXT_swapchain_colorspace, which VkConfig requests as an
+optional extension so it is only enabled when the loader supports it, and the display to support it, see SupportedSurfaceFormats.
+*/
+const (
+	ColorSpaceSRGBNonlinear ColorSpace = vk.COLOR_SPACE_SRGB_NONLINEAR_KHR
+	// ColorSpaceExtendedSRGBLinear is scRGB, usually paired with FORMAT_R16G16B16A16_SFLOAT.
+	ColorSpaceExtendedSRGBLinear    ColorSpace = vk.COLOR_SPACE_EXTENDED_SRGB_LINEAR_EXT
+	ColorSpaceExtendedSRGBNonlinear ColorSpace = vk.COLOR_SPACE_EXTENDED_SRGB_NONLINEAR_EXT
+	ColorSpaceDisplayP3Nonlinear    ColorSpace = vk.COLOR_SPACE_DISPLAY_P3_NONLINEAR_EXT
+	ColorSpaceDisplayP3Linear       ColorSpace = vk.COLOR_SPACE_DISPLAY_P3_LINEAR_EXT
+	ColorSpaceBT709Linear           ColorSpace = vk.COLOR_SPACE_BT709_LINEAR_EXT
+	ColorSpaceBT2020Linear          ColorSpace = vk.COLOR_SPACE_BT2020_LINEAR_EXT
+	// ColorSpaceHDR10ST2084 is HDR10, BT2020 primaries with the PQ transfer function,
+	// usually paired with FORMAT_A2B10G10R10_UNORM_PACK32.
+	ColorSpaceHDR10ST2084 ColorSpace = vk.COLOR_SPACE_HDR10_ST2084_EXT
+	ColorSpaceHDR10HLG    ColorSpace = vk.COLOR_SPACE_HDR10_HLG_EXT
+	ColorSpacePassThrough ColorSpace = vk.COLOR_SPACE_PASS_THROUGH_EXT
+)
+
+func (c ColorSpace) String() string {
+	switch c {
+	case ColorSpaceSRGBNonlinear:
+		return "SRGBNonlinear"
+	case ColorSpaceExtendedSRGBLinear:
+		return "ExtendedSRGBLinear"
+	case ColorSpaceExtendedSRGBNonlinear:
+		return "ExtendedSRGBNonlinear"
+	case ColorSpaceDisplayP3Nonlinear:
+		return "DisplayP3Nonlinear"
+	case ColorSpaceDisplayP3Linear:
+		return "DisplayP3Linear"
+	case ColorSpaceBT709Linear:
+		return "BT709Linear"
+	case ColorSpaceBT2020Linear:
+		return "BT2020Linear"
+	case ColorSpaceHDR10ST2084:
+		return "HDR10ST2084"
+	case ColorSpaceHDR10HLG:
+		return "HDR10HLG"
+	case ColorSpacePassThrough:
+		return "PassThrough"
+	default:
+		return fmt.Sprintf("Unknown: %d", uint32(c))
+	}
+}
+
+type SurfaceFormat struct {
+	Format     Format
+	ColorSpace ColorSpace
+}
+
+func (f SurfaceFormat) String() string {
+	return fmt.Sprintf("%s/%s", f.Format.String(), f.ColorSpace.String())
+}
+
 type SurfaceInfo struct {
 	Extent            gmath.Extent3i32
 	Format            Format
+	ColorSpace        ColorSpace
+	PresentMode       PresentMode
 	NumFramesInFlight int32
 }
 
+// CurrentSurfaceInfo is Info of the swapchain created by InitDevice, or that of the offscreen surface when Config.Offscreen is set.
 func CurrentSurfaceInfo() SurfaceInfo {
-	var cInfo C.vxr_vk_surfaceInfo
-	C.vxr_vk_graphics_getSurfaceInfo(instance.cInstance, &cInfo)
+	if instance.offscreen != nil {
+		return instance.offscreen.info()
+	}
+	return instance.swapchain.Info()
+}
+
+// SetPresentModes is SetPresentModes of the swapchain created by InitDevice, it is ignored when Config.Offscreen is set.
+func SetPresentModes(modes ...PresentMode) {
+	if instance.offscreen != nil {
+		return
+	}
+	instance.swapchain.SetPresentModes(modes...)
+}
 
-	if cInfo == (C.vxr_vk_surfaceInfo{}) {
-		return SurfaceInfo{}
+// SetSurfaceFormats is SetSurfaceFormats of the swapchain created by InitDevice, it is ignored when Config.Offscreen is set.
+func SetSurfaceFormats(formats ...SurfaceFormat) {
+	if instance.offscreen != nil {
+		return
 	}
+	instance.swapchain.SetSurfaceFormats(formats...)
+}
+
+// SupportedSurfaceFormats is SupportedSurfaceFormats of the swapchain created by InitDevice,
+// or only the format of the offscreen surface when Config.Offscreen is set.
+func SupportedSurfaceFormats() []SurfaceFormat {
+	if instance.offscreen != nil {
+		return []SurfaceFormat{{Format: instance.offscreen.config.Format, ColorSpace: ColorSpaceSRGBNonlinear}}
+	}
+	return instance.swapchain.SupportedSurfaceFormats()
+}
+
+/*
+HDRMetadata describes the mastering display and content light levels as in VkHdrMetadataEXT,
+chromaticities are CIE 1931 xy coordinates and luminances are in nits.
+*/
+type HDRMetadata struct {
+	DisplayPrimaryRed         gmath.Point2f32
+	DisplayPrimaryGreen       gmath.Point2f32
+	DisplayPrimaryBlue        gmath.Point2f32
+	WhitePoint                gmath.Point2f32
+	MaxLuminance              float32
+	MinLuminance              float32
+	MaxContentLightLevel      float32
+	MaxFrameAverageLightLevel float32
+}
+
+func (m *HDRMetadata) vkHdrMetadata() C.VkHdrMetadataEXT {
+	xy := func(p gmath.Point2f32) C.VkXYColorEXT {
+		return C.VkXYColorEXT{x: C.float(p.X), y: C.float(p.Y)}
+	}
+	return C.VkHdrMetadataEXT{
+		sType:                     vk.STRUCTURE_TYPE_HDR_METADATA_EXT,
+		displayPrimaryRed:         xy(m.DisplayPrimaryRed),
+		displayPrimaryGreen:       xy(m.DisplayPrimaryGreen),
+		displayPrimaryBlue:        xy(m.DisplayPrimaryBlue),
+		whitePoint:                xy(m.WhitePoint),
+		maxLuminance:              C.float(m.MaxLuminance),
+		minLuminance:              C.float(m.MinLuminance),
+		maxContentLightLevel:      C.float(m.MaxContentLightLevel),
+		maxFrameAverageLightLevel: C.float(m.MaxFrameAverageLightLevel),
+	}
+}
 
-	numFrames := gmath.Clamp(int32(cInfo.numImages)-instance.config.swapchainImageCountPadding, 1, instance.config.maxFramesInFlight)
+// SetHDRMetadata is SetHDRMetadata of the swapchain created by InitDevice, it is ignored when Config.Offscreen is set.
+func SetHDRMetadata(metadata HDRMetadata) {
+	if instance.offscreen != nil {
+		return
+	}
+	instance.swapchain.SetHDRMetadata(metadata)
+}
 
-	if instance.sleep {
-		return SurfaceInfo{Format: Format(cInfo.format), NumFramesInFlight: numFrames}
+func validatePresentModes(modes []PresentMode) []PresentMode {
+	if len(modes) == 0 {
+		return []PresentMode{PresentModeFIFORelaxed, PresentModeFIFO}
+	}
+	for i, m := range modes {
+		switch m {
+		case PresentModeImmediate, PresentModeMailbox, PresentModeFIFO, PresentModeFIFORelaxed:
+		default:
+			abort("PresentModes[%d] is not a valid PresentMode: %s", i, m)
+		}
 	}
+	return slices.Clone(modes)
+}
 
-	return SurfaceInfo{
-		Extent:            gmath.Extent3i32{X: int32(cInfo.extent.width), Y: int32(cInfo.extent.height), Z: 1},
-		Format:            Format(cInfo.format),
-		NumFramesInFlight: numFrames,
+func validateSurfaceFormats(formats []SurfaceFormat) []SurfaceFormat {
+	if len(formats) == 0 {
+		return []SurfaceFormat{
+			{Format: FORMAT_B8G8R8A8_SRGB, ColorSpace: ColorSpaceSRGBNonlinear},
+			{Format: FORMAT_R8G8B8A8_SRGB, ColorSpace: ColorSpaceSRGBNonlinear},
+		}
+	}
+	if !instance.swapchainColorSpace {
+		for i, f := range formats {
+			if f.ColorSpace != ColorSpaceSRGBNonlinear {
+				abort("SurfaceFormats[%d] color space %s requires VK_EXT_swapchain_colorspace which the instance was created without", i, f.ColorSpace)
+			}
+		}
 	}
+	return slices.Clone(formats)
 }
 
 type Surface struct {
-	noCopy   util.NoCopy
-	waited   bool
-	cSurface C.vxr_vk_surface
+	noCopy    util.NoCopy
+	waited    bool
+	swapchain *Swapchain
+	offscreen *offscreenImage
+	cSurface  C.vxr_vk_surface
 }
 
 var _ Image = (*Surface)(nil)
@@ -88,6 +264,11 @@ func (s *Surface) usage() ImageUsageFlags {
 	return ImageUsageColorAttachment
 }
 
+func (s *Surface) numArrayLayers() int32 {
+	s.noCopy.Check()
+	return 1
+}
+
 func (s *Surface) vkFormat() C.VkFormat {
 	s.noCopy.Check()
 	return s.cSurface.info.format
@@ -108,6 +289,15 @@ func (s *Surface) vkImageView() C.VkImageView {
 	return s.cSurface.vkImageView
 }
 
+// vkImageLayout replaces ImageLayoutPresent for offscreen surfaces with the layout they are read back in, as there is nothing to present them to.
+func (s *Surface) vkImageLayout(layout ImageLayout) C.VkImageLayout {
+	s.noCopy.Check()
+	if s.offscreen != nil && layout == ImageLayoutPresent {
+		return C.VkImageLayout(ImageLayoutTransferSrc)
+	}
+	return C.VkImageLayout(layout)
+}
+
 func (s *Surface) vkSignalInfo(stage PipelineStage) C.VkSemaphoreSubmitInfo {
 	s.noCopy.Check()
 	defer s.noCopy.Close()
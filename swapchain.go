@@ -0,0 +1,257 @@
+/*
+Copyright 2025 The goARRG Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package vxr
+
+/*
+	#cgo pkg-config: vxr
+
+	#include "vxr/vxr.h"
+*/
+import "C"
+
+import (
+	"runtime"
+	"time"
+	"unsafe"
+
+	"goarrg.com"
+	"goarrg.com/gmath"
+	"goarrg.com/rhi/vxr/internal/util"
+	"goarrg.com/rhi/vxr/internal/vk"
+)
+
+/*
+Swapchain presents to a single surface. The one created by InitDevice backs Resize, CurrentSurfaceInfo and
+Frame.Surface, additional ones are created with NewSwapchain for other windows. Every swapchain is resized on its own,
+their images are acquired with Frame.AcquireSurface and all surfaces acquired within a frame are presented together
+when the frame ends. Swapchains start out asleep until the first call to Resize with a non zero size.
+*/
+type Swapchain struct {
+	noCopy     util.NoCopy
+	name       string
+	cSurface   uint64
+	cSwapchain C.vxr_vk_graphics_swapchain
+
+	presentModes   []PresentMode
+	surfaceFormats []SurfaceFormat
+	hdrMetadata    *HDRMetadata
+
+	sleep bool
+	sizeX float64
+	sizeY float64
+}
+
+func newSwapchain(name string, surface uint64) *Swapchain {
+	s := &Swapchain{
+		name:           name,
+		cSurface:       surface,
+		presentModes:   instance.config.presentModes,
+		surfaceFormats: instance.config.surfaceFormats,
+		sleep:          true,
+	}
+	s.noCopy.Init()
+	C.vxr_vk_graphics_createSwapchain(instance.cInstance, C.size_t(len(name)), (*C.char)(unsafe.Pointer(unsafe.StringData(name))),
+		C.uint64_t(surface), &s.cSwapchain)
+	runtime.KeepAlive(name)
+	return s
+}
+
+/*
+NewSwapchain creates a surface from vkInstance, which must share the VkInstance passed to InitInstance,
+and a swapchain for it using Config.PresentModes and Config.SurfaceFormats. The device must be able to present to it,
+otherwise the first Resize aborts. The surface itself is owned by vkInstance and is not destroyed with the swapchain.
+*/
+func NewSwapchain(name string, vkInstance goarrg.VkInstance) *Swapchain {
+	if instance.offscreen != nil {
+		abort("NewSwapchain called with Config.Offscreen set, the device may not be able to present")
+	}
+	surface, err := vkInstance.CreateSurface()
+	if err != nil {
+		abort("Failed to create surface: %v", err)
+	}
+	s := newSwapchain(name, surface)
+	if instance.swapchains == nil {
+		instance.swapchains = map[*Swapchain]struct{}{}
+	}
+	instance.swapchains[s] = struct{}{}
+	return s
+}
+
+func (s *Swapchain) Info() SurfaceInfo {
+	s.noCopy.Check()
+	var cInfo C.vxr_vk_surfaceInfo
+	C.vxr_vk_graphics_swapchain_getSurfaceInfo(s.cSwapchain, &cInfo)
+
+	if cInfo == (C.vxr_vk_surfaceInfo{}) {
+		return SurfaceInfo{}
+	}
+
+	numFrames := gmath.Clamp(int32(cInfo.numImages)-instance.config.swapchainImageCountPadding, 1, instance.config.maxFramesInFlight)
+
+	if s.sleep {
+		return SurfaceInfo{
+			Format: Format(cInfo.format), ColorSpace: ColorSpace(cInfo.colorSpace),
+			PresentMode: PresentMode(cInfo.presentMode), NumFramesInFlight: numFrames,
+		}
+	}
+
+	return SurfaceInfo{
+		Extent:            gmath.Extent3i32{X: int32(cInfo.extent.width), Y: int32(cInfo.extent.height), Z: 1},
+		Format:            Format(cInfo.format),
+		ColorSpace:        ColorSpace(cInfo.colorSpace),
+		PresentMode:       PresentMode(cInfo.presentMode),
+		NumFramesInFlight: numFrames,
+	}
+}
+
+/*
+Resize recreates the swapchain, a size of 0 puts it to sleep and Frame.AcquireSurface returns nil until it is resized again.
+Only the swapchain created by InitDevice decides the number of frames in flight.
+*/
+func (s *Swapchain) Resize(w int, h int) {
+	s.noCopy.Check()
+	s.sleep = true
+	s.sizeX = float64(w)
+	s.sizeY = float64(h)
+
+	if w != 0 && h != 0 {
+		s.sleep = false
+		start := time.Now()
+
+		for i := 0; i < len(instance.graphics.framesInFlight); i++ {
+			instance.graphics.framesInFlight[i].waitSurface()
+		}
+
+		instance.logger.IPrintf("vxr_vk_graphics_swapchain_init: %s", s.name)
+		pinner := runtime.Pinner{}
+		presentModes := make([]C.VkPresentModeKHR, 0, len(s.presentModes))
+		for _, m := range s.presentModes {
+			presentModes = append(presentModes, C.VkPresentModeKHR(m))
+		}
+		surfaceFormats := make([]C.VkSurfaceFormatKHR, 0, len(s.surfaceFormats))
+		for _, f := range s.surfaceFormats {
+			surfaceFormats = append(surfaceFormats, C.VkSurfaceFormatKHR{format: C.VkFormat(f.Format), colorSpace: C.VkColorSpaceKHR(f.ColorSpace)})
+		}
+		pinner.Pin(unsafe.SliceData(presentModes))
+		pinner.Pin(unsafe.SliceData(surfaceFormats))
+		ret := C.vxr_vk_graphics_swapchain_init(instance.cInstance, s.cSwapchain, C.vxr_vk_graphics_swapchainCreateInfo{
+			numImages:         C.uint32_t(instance.config.maxFramesInFlight + instance.config.swapchainImageCountPadding),
+			numPresentModes:   C.uint32_t(len(presentModes)),
+			presentModes:      unsafe.SliceData(presentModes),
+			numSurfaceFormats: C.uint32_t(len(surfaceFormats)),
+			surfaceFormats:    unsafe.SliceData(surfaceFormats),
+		})
+		pinner.Unpin()
+		if ret != vk.SUCCESS {
+			abort("Failed to initialize swapchain %q: %s", s.name, vkResultStr(ret))
+		}
+		if s.hdrMetadata != nil {
+			C.vxr_vk_graphics_swapchain_setHdrMetadata(instance.cInstance, s.cSwapchain, s.hdrMetadata.vkHdrMetadata())
+		}
+
+		if s == instance.swapchain {
+			info := s.Info()
+			initFramesInFlight(info.NumFramesInFlight)
+		}
+		instance.logger.IPrintf("Resize %q took: %v", s.name, time.Since(start))
+	}
+}
+
+/*
+SetPresentModes replaces the list of present modes and recreates the swapchain the same way Resize does,
+it must not be called while there is an active frame.
+*/
+func (s *Swapchain) SetPresentModes(modes ...PresentMode) {
+	s.noCopy.Check()
+	if instance.graphics.frameStarted {
+		abort("SetPresentModes called when there's an active frame")
+	}
+	s.presentModes = validatePresentModes(modes)
+	s.Resize(int(s.sizeX), int(s.sizeY))
+}
+
+/*
+SetSurfaceFormats replaces the list of surface formats and recreates the swapchain the same way Resize does,
+it must not be called while there is an active frame.
+*/
+func (s *Swapchain) SetSurfaceFormats(formats ...SurfaceFormat) {
+	s.noCopy.Check()
+	if instance.graphics.frameStarted {
+		abort("SetSurfaceFormats called when there's an active frame")
+	}
+	s.surfaceFormats = validateSurfaceFormats(formats)
+	s.Resize(int(s.sizeX), int(s.sizeY))
+}
+
+/*
+SupportedSurfaceFormats returns every format and color space pair the surface can present, in the order the driver reports them.
+Color spaces other than ColorSpaceSRGBNonlinear are left out when the instance was created without VK_EXT_swapchain_colorspace.
+*/
+func (s *Swapchain) SupportedSurfaceFormats() []SurfaceFormat {
+	s.noCopy.Check()
+	var numFormats C.uint32_t
+	if ret := C.vxr_vk_graphics_getSupportedSurfaceFormats(instance.cInstance, C.uint64_t(s.cSurface), &numFormats, nil); ret != vk.SUCCESS {
+		abort("Failed to get surface formats: %s", vkResultStr(ret))
+	}
+	cFormats := make([]C.VkSurfaceFormatKHR, numFormats)
+	if ret := C.vxr_vk_graphics_getSupportedSurfaceFormats(instance.cInstance, C.uint64_t(s.cSurface), &numFormats, unsafe.SliceData(cFormats)); ret != vk.SUCCESS {
+		abort("Failed to get surface formats: %s", vkResultStr(ret))
+	}
+	formats := make([]SurfaceFormat, 0, numFormats)
+	for _, f := range cFormats[:numFormats] {
+		if !instance.swapchainColorSpace && ColorSpace(f.colorSpace) != ColorSpaceSRGBNonlinear {
+			continue
+		}
+		formats = append(formats, SurfaceFormat{Format: Format(f.format), ColorSpace: ColorSpace(f.colorSpace)})
+	}
+	return formats
+}
+
+/*
+SetHDRMetadata submits the metadata to the swapchain, it is kept and resubmitted whenever the swapchain is recreated.
+It requires the device to support VK_EXT_hdr_metadata, see Properties.EnabledExtensions,
+and only has an effect on displays using an HDR color space.
+*/
+func (s *Swapchain) SetHDRMetadata(metadata HDRMetadata) {
+	s.noCopy.Check()
+	if !instance.graphics.features.hdrMetadata {
+		abort("SetHDRMetadata requires VK_EXT_hdr_metadata")
+	}
+	s.hdrMetadata = &metadata
+	C.vxr_vk_graphics_swapchain_setHdrMetadata(instance.cInstance, s.cSwapchain, metadata.vkHdrMetadata())
+}
+
+/*
+Destroy waits for every frame to finish presenting and destroys the swapchain, it must not be called while there is
+an active frame. The swapchain created by InitDevice is destroyed by Destroy instead, as is any swapchain
+that is still alive at that point after which it must not be used.
+*/
+func (s *Swapchain) Destroy() {
+	s.noCopy.Check()
+	if s == instance.swapchain {
+		abort("Cannot destroy the swapchain created by InitDevice")
+	}
+	if instance.graphics.frameStarted {
+		abort("Swapchain.Destroy called when there's an active frame")
+	}
+	for i := 0; i < len(instance.graphics.framesInFlight); i++ {
+		instance.graphics.framesInFlight[i].waitSurface()
+	}
+	C.vxr_vk_graphics_destroySwapchain(instance.cInstance, s.cSwapchain)
+	delete(instance.swapchains, s)
+	s.noCopy.Close()
+}
@@ -58,28 +58,85 @@ func toHex(v any) string {
 	return ""
 }
 
-func genID(items ...any) string {
-	sb := strings.Builder{}
-	for _, i := range items {
-		switch t := i.(type) {
-		case string:
-			sb.WriteString(t)
-		case fmt.Stringer:
-			sb.WriteString(t.String())
-		default:
-			sb.WriteString(toHex(i))
+// hashKey is a 64bit FNV-1a hash of the structural data that describes a cached object.
+type hashKey uint64
+
+func (k hashKey) String() string {
+	return fmt.Sprintf("0x%016X", uint64(k))
+}
+
+/*
+hashBucket holds the entries of a cache whose keys hash to the same hashKey,
+lookups compare the full key so a hash collision can never return the wrong entry.
+*/
+type hashBucket[K interface{ equal(K) bool }, V any] []hashBucketEntry[K, V]
+
+type hashBucketEntry[K any, V any] struct {
+	key   K
+	value V
+}
+
+func (b hashBucket[K, V]) find(key K) (V, bool) {
+	for _, e := range b {
+		if e.key.equal(key) {
+			return e.value, true
 		}
-		sb.WriteRune(',')
 	}
-	return "[" + sb.String()[:sb.Len()-1] + "]"
+	var v V
+	return v, false
+}
+
+// bucketKeyString is the json key of the i'th entry of the bucket of k, only colliding entries get a suffix.
+func bucketKeyString(k hashKey, i int) string {
+	if i == 0 {
+		return k.String()
+	}
+	return fmt.Sprintf("%s#%d", k.String(), i)
+}
+
+const (
+	fnvOffset64 = 14695981039346656037
+	fnvPrime64  = 1099511628211
+)
+
+type hasher struct {
+	sum hashKey
+}
+
+func newHasher() hasher {
+	return hasher{sum: fnvOffset64}
+}
+
+func (h *hasher) writeByte(b byte) {
+	h.sum ^= hashKey(b)
+	h.sum *= fnvPrime64
+}
+
+func (h *hasher) writeUint32(v uint32) {
+	for i := 0; i < 4; i++ {
+		h.writeByte(byte(v >> (i * 8)))
+	}
+}
+
+func (h *hasher) writeUint64(v uint64) {
+	for i := 0; i < 8; i++ {
+		h.writeByte(byte(v >> (i * 8)))
+	}
+}
+
+func (h *hasher) writeString(s string) {
+	h.writeUint64(uint64(len(s)))
+	for i := 0; i < len(s); i++ {
+		h.writeByte(s[i])
+	}
 }
 
-func chainIDs(ids ...string) string {
-	sb := strings.Builder{}
-	for _, id := range ids {
-		sb.WriteString(id)
+// Write implements io.Writer so that file contents can be hashed with io.Copy.
+func (h *hasher) Write(b []byte) (int, error) {
+	for _, v := range b {
+		h.writeByte(v)
 	}
-	return strings.Clone(sb.String())
+	return len(b), nil
 }
 
 func jsonString(target any) string {